@@ -0,0 +1,213 @@
+package policy
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/eozturk1/genomic-security-journal-code/helpers/env"
+)
+
+// A policy is Alice's consent to the queries of testers. Before she sends anything for a query, Alice checks the tester
+// that asks, the ranges that it queries and the number of her ciphertexts that she would send against her rules, and
+// refuses the query if one of them is broken. A tester is known by the key that signs its queries, not by the ID that it
+// claims. Every decision is recorded, refused or not, so that Alice can review which testers asked for what, and the
+// record can be saved to a file with SaveDecisions.
+
+var (
+	ErrTesterNotAllowed       = errors.New("tester is not allowed by the policy")
+	ErrTesterNotAuthenticated = errors.New("signature of the query does not verify under the key of the tester")
+	ErrForbiddenRegion        = errors.New("queried range overlaps a forbidden region")
+	ErrRangeTooWide           = errors.New("queried ranges are wider than the policy allows")
+	ErrRangeNotBound          = errors.New("marker of the tester is not proven to be in the queried ranges")
+	ErrTooManyVariants        = errors.New("query discloses more variants than the policy allows")
+	ErrNotCertified           = errors.New("marker of the tester is not certified")
+	ErrInvalidQuery           = errors.New("queried range is empty")
+	ErrPerMarkerNotAllowed    = errors.New("query discloses a verdict per marker, which the policy does not allow")
+)
+
+// reasons are the errors of the rules, by message, for LoadDecisions.
+var reasons = []error{ErrTesterNotAllowed, ErrTesterNotAuthenticated, ErrForbiddenRegion, ErrRangeTooWide, ErrRangeNotBound,
+	ErrTooManyVariants, ErrNotCertified, ErrInvalidQuery, ErrPerMarkerNotAllowed}
+
+// Region is a region of the genome, such as a gene, in the positions of the sequencing lab.
+type Region struct {
+	Name       string
+	Start, End uint32
+}
+
+// Policy is the set of rules of Alice. A rule at its zero value does not restrict the queries, except AllowPerMarker:
+// a panel discloses only whether any of its markers matches unless Alice allows more.
+type Policy struct {
+	AllowedTesters   map[string]*ecdsa.PublicKey // the IDs of the testers that may query, with the keys that sign their queries; any tester if empty
+	ForbiddenRegions []Region                    // no query may overlap these, e.g. APOE or BRCA1 and BRCA2
+	MaxRangeWidth    uint32                      // the maximum total width End - Start + 1 of the ranges of a query
+	MaxVariants      int                         // the maximum number of ciphertexts of Alice that a query discloses
+	Regulator        *ecdsa.PublicKey            // if not nil, only markers certified by this regulator (see tester.SetupCertified)
+	AllowPerMarker   bool                        // whether a panel may disclose a verdict per marker (see tester.Panel)
+
+	decisions []*Decision
+	mutex     sync.Mutex
+}
+
+// Query is what Alice knows of a query before she answers it.
+type Query struct {
+	Tester     string
+	Ranges     []Region
+	Variants   int                 // the number of Alice's ciphertexts that she would send, boundaries included
+	RangeBound bool                // whether the results can only depend on Alice's variants in Ranges
+	Certified  bool                // whether the marker is certified by the regulator of the policy
+	PerMarker  bool                // whether the results disclose a verdict per marker of a panel
+	Digest     []byte              // the digest of the query, which Alice computes from what the tester sends her
+	Signature  *env.ECDSASignature // the signature of Digest by the tester
+}
+
+// Decision is the record of a query and of the answer of the policy: the rule that refused it, or nil if it is allowed.
+// The tester of the query is authenticated if its signature verifies under a key of AllowedTesters.
+type Decision struct {
+	Time          time.Time
+	Query         Query
+	Authenticated bool
+	Reason        error
+}
+
+func (decision *Decision) Allowed() bool {
+	return decision.Reason == nil
+}
+
+// Check decides on the query, records the decision, and returns the rule that refuses it, or nil.
+func (p *Policy) Check(query Query) error {
+
+	reason := p.reason(query)
+	decision := &Decision{Time: time.Now(), Query: query, Authenticated: p.authenticate(query) == nil, Reason: reason}
+	p.mutex.Lock()
+	p.decisions = append(p.decisions, decision)
+	p.mutex.Unlock()
+	return reason
+
+}
+
+func (p *Policy) reason(query Query) error {
+
+	if len(query.Ranges) == 0 {
+		return ErrInvalidQuery
+	}
+	width := uint64(0)
+	for _, queried := range query.Ranges {
+		if queried.Start > queried.End {
+			return ErrInvalidQuery
+		}
+		width += uint64(queried.End-queried.Start) + 1
+	}
+	if len(p.AllowedTesters) > 0 {
+		if err := p.authenticate(query); err != nil {
+			return err
+		}
+	}
+	if (len(p.ForbiddenRegions) > 0 || p.MaxRangeWidth > 0) && !query.RangeBound {
+		return ErrRangeNotBound
+	}
+	for _, region := range p.ForbiddenRegions {
+		for _, queried := range query.Ranges {
+			if queried.Start <= region.End && region.Start <= queried.End {
+				return ErrForbiddenRegion
+			}
+		}
+	}
+	if p.MaxRangeWidth > 0 && width > uint64(p.MaxRangeWidth) {
+		return ErrRangeTooWide
+	}
+	if p.MaxVariants > 0 && query.Variants > p.MaxVariants {
+		return ErrTooManyVariants
+	}
+	if p.Regulator != nil && !query.Certified {
+		return ErrNotCertified
+	}
+	if query.PerMarker && !p.AllowPerMarker {
+		return ErrPerMarkerNotAllowed
+	}
+	return nil
+
+}
+
+// authenticate checks that the tester of the query is allowed, and that the query is signed with its key.
+func (p *Policy) authenticate(query Query) error {
+
+	key, ok := p.AllowedTesters[query.Tester]
+	if !ok || key == nil {
+		return ErrTesterNotAllowed
+	}
+	sig := query.Signature
+	if sig == nil || sig.R == nil || sig.S == nil || !ecdsa.Verify(key, query.Digest, sig.R, sig.S) {
+		return ErrTesterNotAuthenticated
+	}
+	return nil
+
+}
+
+// Decisions returns the decisions recorded so far, in order.
+func (p *Policy) Decisions() []*Decision {
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return append([]*Decision{}, p.decisions...)
+
+}
+
+// record is a decision as it is saved, with its reason by message.
+type record struct {
+	Time          time.Time
+	Query         Query
+	Authenticated bool
+	Reason        string
+}
+
+// SaveDecisions writes the decisions recorded so far to fileName, in JSON, for LoadDecisions.
+func (p *Policy) SaveDecisions(fileName string) error {
+
+	decisions := p.Decisions()
+	records := make([]record, len(decisions))
+	for i, decision := range decisions {
+		records[i] = record{Time: decision.Time, Query: decision.Query, Authenticated: decision.Authenticated}
+		if decision.Reason != nil {
+			records[i].Reason = decision.Reason.Error()
+		}
+	}
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(fileName, data, 0644)
+
+}
+
+// LoadDecisions reads decisions saved with SaveDecisions. The reasons are the errors of the rules above, or a new error
+// with the saved message for a reason that is not one of them.
+func LoadDecisions(fileName string) ([]*Decision, error) {
+
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	var records []record
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, err
+	}
+	decisions := make([]*Decision, len(records))
+	for i, saved := range records {
+		decisions[i] = &Decision{Time: saved.Time, Query: saved.Query, Authenticated: saved.Authenticated}
+		if saved.Reason != "" {
+			decisions[i].Reason = errors.New(saved.Reason)
+			for _, reason := range reasons {
+				if reason.Error() == saved.Reason {
+					decisions[i].Reason = reason
+				}
+			}
+		}
+	}
+	return decisions, nil
+
+}
@@ -0,0 +1,139 @@
+package policy
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/eozturk1/genomic-security-journal-code/helpers/env"
+)
+
+// signedQuery returns a query of the tester over the ranges, with a digest signed with the key.
+func signedQuery(t *testing.T, tester string, key *ecdsa.PrivateKey, ranges ...Region) Query {
+
+	query := Query{Tester: tester, Ranges: ranges, Variants: 10, RangeBound: true, Digest: []byte("digest of the query")}
+	r, s, err := ecdsa.Sign(rand.Reader, key, query.Digest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query.Signature = &env.ECDSASignature{R: r, S: s}
+	return query
+
+}
+
+func newKey(t *testing.T) *ecdsa.PrivateKey {
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+
+}
+
+func TestReason(t *testing.T) {
+
+	key := newKey(t)
+	region := Region{Name: "APOE", Start: 1000, End: 1999}
+	query := signedQuery(t, "clinic", key, Region{Start: 100, End: 199})
+
+	cases := map[string]struct {
+		policy *Policy
+		query  func(q Query) Query
+		want   error
+	}{
+		"no rule":           {&Policy{}, func(q Query) Query { return q }, nil},
+		"no range":          {&Policy{}, func(q Query) Query { q.Ranges = nil; return q }, ErrInvalidQuery},
+		"empty range":       {&Policy{}, func(q Query) Query { q.Ranges = []Region{{Start: 200, End: 100}}; return q }, ErrInvalidQuery},
+		"forbidden region":  {&Policy{ForbiddenRegions: []Region{region}}, func(q Query) Query { q.Ranges = append(q.Ranges, Region{Start: 1999, End: 2100}); return q }, ErrForbiddenRegion},
+		"next to forbidden": {&Policy{ForbiddenRegions: []Region{region}}, func(q Query) Query { q.Ranges = []Region{{Start: 2000, End: 2100}}; return q }, nil},
+		"width at maximum":  {&Policy{MaxRangeWidth: 100}, func(q Query) Query { return q }, nil},
+		"total width":       {&Policy{MaxRangeWidth: 150}, func(q Query) Query { q.Ranges = append(q.Ranges, Region{Start: 300, End: 399}); return q }, ErrRangeTooWide},
+		"range not bound":   {&Policy{MaxRangeWidth: 100}, func(q Query) Query { q.RangeBound = false; return q }, ErrRangeNotBound},
+		"unbound, no rule":  {&Policy{MaxVariants: 10}, func(q Query) Query { q.RangeBound = false; return q }, nil},
+		"too many variants": {&Policy{MaxVariants: 9}, func(q Query) Query { return q }, ErrTooManyVariants},
+		"not certified":     {&Policy{Regulator: &key.PublicKey}, func(q Query) Query { return q }, ErrNotCertified},
+		"certified":         {&Policy{Regulator: &key.PublicKey}, func(q Query) Query { q.Certified = true; return q }, nil},
+		"per marker":        {&Policy{}, func(q Query) Query { q.PerMarker = true; return q }, ErrPerMarkerNotAllowed},
+		"per marker, allow": {&Policy{AllowPerMarker: true}, func(q Query) Query { q.PerMarker = true; return q }, nil},
+	}
+	for name, c := range cases {
+		if got := c.policy.reason(c.query(query)); got != c.want {
+			t.Errorf("%s: got %v, want %v", name, got, c.want)
+		}
+	}
+
+}
+
+func TestReasonAuthenticatesTesters(t *testing.T) {
+
+	clinicKey, intruderKey := newKey(t), newKey(t)
+	p := Policy{AllowedTesters: map[string]*ecdsa.PublicKey{"clinic": &clinicKey.PublicKey}}
+	queried := Region{Start: 100, End: 199}
+
+	if err := p.Check(signedQuery(t, "clinic", clinicKey, queried)); err != nil {
+		t.Fatalf("query of the clinic is refused: %v", err)
+	}
+	if err := p.Check(signedQuery(t, "clinic", intruderKey, queried)); err != ErrTesterNotAuthenticated {
+		t.Fatalf("query signed with another key: got %v", err)
+	}
+	if err := p.Check(signedQuery(t, "intruder", intruderKey, queried)); err != ErrTesterNotAllowed {
+		t.Fatalf("query of another tester: got %v", err)
+	}
+	unsigned := signedQuery(t, "clinic", clinicKey, queried)
+	unsigned.Signature = nil
+	if err := p.Check(unsigned); err != ErrTesterNotAuthenticated {
+		t.Fatalf("unsigned query: got %v", err)
+	}
+	tampered := signedQuery(t, "clinic", clinicKey, queried)
+	tampered.Digest = []byte("digest of another query")
+	if err := p.Check(tampered); err != ErrTesterNotAuthenticated {
+		t.Fatalf("query with another digest: got %v", err)
+	}
+
+	decisions := p.Decisions()
+	if len(decisions) != 5 || !decisions[0].Allowed() || !decisions[0].Authenticated {
+		t.Fatal("query of the clinic is not recorded as allowed and authenticated")
+	}
+	for _, decision := range decisions[1:] {
+		if decision.Allowed() || decision.Authenticated {
+			t.Fatalf("refused query of %s is recorded as allowed or authenticated", decision.Query.Tester)
+		}
+	}
+
+}
+
+func TestSaveAndLoadDecisions(t *testing.T) {
+
+	key := newKey(t)
+	p := Policy{MaxVariants: 10, ForbiddenRegions: []Region{{Name: "BRCA1", Start: 1000, End: 1999}}}
+	allowed := signedQuery(t, "clinic", key, Region{Start: 100, End: 199})
+	refused := signedQuery(t, "clinic", key, Region{Start: 100, End: 199}, Region{Start: 1500, End: 1600})
+	p.Check(allowed)
+	p.Check(refused)
+
+	fileName := filepath.Join(t.TempDir(), "decisions.json")
+	if err := p.SaveDecisions(fileName); err != nil {
+		t.Fatal(err)
+	}
+	decisions, err := LoadDecisions(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := p.Decisions()
+	if len(decisions) != len(saved) {
+		t.Fatalf("%d decisions loaded, want %d", len(decisions), len(saved))
+	}
+	for i, decision := range decisions {
+		if !decision.Time.Equal(saved[i].Time) || decision.Reason != saved[i].Reason || !reflect.DeepEqual(decision.Query, saved[i].Query) {
+			t.Fatalf("decision %d is not loaded as it was saved", i)
+		}
+	}
+	if decisions[1].Reason != ErrForbiddenRegion {
+		t.Fatalf("reason of the refused query: got %v", decisions[1].Reason)
+	}
+
+}
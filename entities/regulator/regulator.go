@@ -0,0 +1,135 @@
+package regulator
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"math/big"
+
+	sl "github.com/eozturk1/genomic-security-journal-code/entities/sequencinglab"
+	"github.com/eozturk1/genomic-security-journal-code/helpers/addhomencer"
+	"github.com/eozturk1/genomic-security-journal-code/helpers/env"
+	"github.com/eozturk1/genomic-security-journal-code/helpers/profiles"
+)
+
+// The regulator approves the markers of clinical tests. It signs a certificate of each approved marker: commitments to
+// Hash(position, base) for the bases of the marker (see addhomencer.Commit), the range that the test may query and its
+// intended use. The commitments hide the marker from Alice, and the openings, which only the tester gets, let the
+// tester prove that its ciphertexts encrypt the certified marker (see tester.SetupCertified).
+
+var (
+	ErrMarkerOutOfRange     = errors.New("marker is not in the certified range")
+	ErrInvalidCertificate   = errors.New("certificate is not signed by the regulator")
+	ErrCertificateMalformed = errors.New("certificate is malformed")
+)
+
+type Regulator struct {
+	signingKey   *ecdsa.PrivateKey
+	VerifyingKey *ecdsa.PublicKey
+	Profile      *profiles.Profile
+}
+
+// Certificate is the signed definition of an approved marker.
+type Certificate struct {
+	ProfileID   string
+	Commitments []*big.Int // G^b H^rho for b = Hash(position, base), for the bases of the marker in order
+	RangeStart  uint32
+	RangeEnd    uint32
+	IntendedUse string
+	Sig         *env.ECDSASignature
+}
+
+// Setup takes the signature curve, the hash and the group of the commitments from the profile.
+func (r *Regulator) Setup(profile *profiles.Profile) {
+
+	var err error
+	r.signingKey, err = ecdsa.GenerateKey(profile.SignatureCurve, rand.Reader)
+	if err != nil {
+		panic("Regulator key generation error: " + err.Error())
+	}
+	r.VerifyingKey = &r.signingKey.PublicKey
+	r.Profile = profile
+
+}
+
+// Certify approves the marker for the range [rangeStart, rangeEnd] and the intended use. It returns the certificate,
+// which the tester shows to Alice, and the openings of the commitments, which the tester keeps. The hash of the bases is
+// the one of the sequencing lab, so that the certificate is for the ciphertexts that the tester computes under it.
+func (r *Regulator) Certify(lab *sl.SequencingLab, marker []*env.Base, rangeStart, rangeEnd uint32, use string) (*Certificate, []*big.Int, error) {
+
+	if lab.Profile != r.Profile {
+		return nil, nil, profiles.ErrProfileMismatch
+	}
+	if len(marker) == 0 || rangeStart > rangeEnd {
+		return nil, nil, ErrCertificateMalformed
+	}
+	cert := &Certificate{
+		ProfileID:   r.Profile.ID,
+		Commitments: make([]*big.Int, len(marker)),
+		RangeStart:  rangeStart,
+		RangeEnd:    rangeEnd,
+		IntendedUse: use,
+	}
+	openings := make([]*big.Int, len(marker))
+	for j, base := range marker {
+		if base.Position < rangeStart || base.Position > rangeEnd {
+			return nil, nil, ErrMarkerOutOfRange
+		}
+		b := new(big.Int).SetBytes(env.HashPositionAndBase(lab.Hash, base.Position, base))
+		var err error
+		if cert.Commitments[j], openings[j], err = addhomencer.Commit(r.Profile, b, nil); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	sigR, sigS, err := ecdsa.Sign(rand.Reader, r.signingKey, cert.Digest(r.Profile))
+	if err != nil {
+		return nil, nil, err
+	}
+	cert.Sig = &env.ECDSASignature{R: sigR, S: sigS}
+	return cert, openings, nil
+
+}
+
+// Digest hashes everything that the signature of the certificate covers.
+func (cert *Certificate) Digest(profile *profiles.Profile) []byte {
+
+	h := profile.NewHash()
+	var length [4]byte
+	write := func(b []byte) {
+		binary.BigEndian.PutUint32(length[:], uint32(len(b)))
+		h.Write(length[:])
+		h.Write(b)
+	}
+	write([]byte(cert.ProfileID))
+	for _, commitment := range cert.Commitments {
+		write(commitment.Bytes())
+	}
+	write(env.Uint32ToBytes(cert.RangeStart))
+	write(env.Uint32ToBytes(cert.RangeEnd))
+	write([]byte(cert.IntendedUse))
+	return h.Sum(nil)
+
+}
+
+// Verify checks that the certificate is signed with the key of a regulator, under the profile.
+func (cert *Certificate) Verify(profile *profiles.Profile, regulatorKey *ecdsa.PublicKey) error {
+
+	if cert == nil || cert.Sig == nil || cert.Sig.R == nil || cert.Sig.S == nil || regulatorKey == nil {
+		return ErrCertificateMalformed
+	}
+	if cert.ProfileID != profile.ID {
+		return profiles.ErrProfileMismatch
+	}
+	for _, commitment := range cert.Commitments {
+		if commitment == nil {
+			return ErrCertificateMalformed
+		}
+	}
+	if !ecdsa.Verify(regulatorKey, cert.Digest(profile), cert.Sig.R, cert.Sig.S) {
+		return ErrInvalidCertificate
+	}
+	return nil
+
+}
@@ -4,18 +4,20 @@ import (
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
+	"errors"
 
 	//	"fmt"
-	"crypto/sha256"
 	"hash"
 	"io"
 	"log"
 	"math/big"
+	"os"
 	"strings"
 	"sync"
 
 	"github.com/eozturk1/genomic-security-journal-code/helpers/addhomencer"
 	"github.com/eozturk1/genomic-security-journal-code/helpers/env"
+	"github.com/eozturk1/genomic-security-journal-code/helpers/profiles"
 	"github.com/ing-bank/zkrp/bulletproofs"
 	"github.com/ing-bank/zkrp/crypto/p256"
 	"github.com/ing-bank/zkrp/util"
@@ -25,6 +27,10 @@ import (
 //var genomeSizeInBases = /* 3000000000 */ 1000
 const SaltSecretSizeInBytes = 16
 
+// RangeProofValues is the number of values the Bulletproofs parameters are set up for: the two boundaries of each of up
+// to four ranges of a query, each proven by two values of a generic range proof (see bulletproofs.ProveGenericAggregated).
+const RangeProofValues = 16
+
 var mAX_HUMAN_GENOME_SIZE = 3200000000
 
 type SequencingLab struct {
@@ -32,50 +38,137 @@ type SequencingLab struct {
 	signingKey   *ecdsa.PrivateKey
 	VerifyingKey *ecdsa.PublicKey
 	Hash         hash.Hash
-	BPparams     bulletproofs.BulletProofSetupParams
+	BPparams     bulletproofs.BulletProofSetupParams // shared by the provers and the verifiers of the range proofs
+	CommitTables *util.CommitTables                  // fixed-base tables for commitments with BPparams.H
+	Profile      *profiles.Profile
 }
 
-func (sl *SequencingLab) Setup(scheme addhomencer.AddHomEncer) {
+// Setup takes the signature curve, the hash and the range of the range proofs from the profile. The scheme has to be set
+// up under the same profile, and the commitments of the profile have to be over the curve of the range proofs.
+func (sl *SequencingLab) Setup(scheme addhomencer.AddHomEncer, profile *profiles.Profile) {
 
+	if scheme.Profile() != profile {
+		panic("SL setup error: " + profiles.ErrProfileMismatch.Error())
+	}
+	if profile.CommitmentCurve != elliptic.Curve(p256.CURVE) {
+		panic("SL setup error: " + profiles.ErrUnsupportedCurve.Error())
+	}
 	sl.Ahe = scheme
+	sl.Profile = profile
 
-	p256 := elliptic.P256()
 	var err error
-	sl.signingKey, err = ecdsa.GenerateKey(p256, rand.Reader)
+	sl.signingKey, err = ecdsa.GenerateKey(profile.SignatureCurve, rand.Reader)
 	if err != nil {
 		panic("SL key generation error: " + err.Error())
 	}
 	sl.VerifyingKey = &sl.signingKey.PublicKey
 
 	testString := strings.NewReader("Foo")
-	sl.Hash = sha256.New()
+	sl.Hash = profile.NewHash()
 	if _, err := io.Copy(sl.Hash, testString); err != nil {
 		log.Fatal(err)
 	}
 
-	sl.BPparams, err = bulletproofs.Setup(bulletproofs.MAX_RANGE_END)
+	sl.BPparams, err = bulletproofs.SetupAggregated(int64(profile.RangeBits), RangeProofValues)
 	if err != nil {
 		panic(err)
 	}
+	sl.CommitTables = util.NewCommitTables(sl.BPparams.H)
+}
+
+// ErrInvalidBPparams is returned by LoadBPparams for a file that is not of SaveBPparams.
+var ErrInvalidBPparams = errors.New("malformed file of Bulletproofs parameters")
+
+// SaveBPparams writes the Bulletproofs parameters to fileName, so that provers and verifiers can load them instead of
+// deriving the generators. They are prefixed with the profile ID, itself prefixed by its length in one byte.
+func (sl *SequencingLab) SaveBPparams(fileName string) error {
+
+	data, err := sl.BPparams.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	id := sl.Profile.ID
+	out := make([]byte, 1+len(id)+len(data))
+	out[0] = byte(len(id))
+	copy(out[1:], id)
+	copy(out[1+len(id):], data)
+	return os.WriteFile(fileName, out, 0644)
+
+}
+
+// LoadBPparams reads the Bulletproofs parameters written by SaveBPparams. They have to be saved under the profile of
+// the lab, and their range has to be the one of the profile.
+func (sl *SequencingLab) LoadBPparams(fileName string) error {
+
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return err
+	}
+	if len(data) == 0 || len(data) < 1+int(data[0]) {
+		return ErrInvalidBPparams
+	}
+	if err := sl.Profile.Check(string(data[1 : 1+data[0]])); err != nil {
+		return err
+	}
+	var params bulletproofs.BulletProofSetupParams
+	if err := params.UnmarshalBinary(data[1+data[0]:]); err != nil {
+		return err
+	}
+	if params.N != int64(sl.Profile.RangeBits) || int64(len(params.Gg)) < params.N*RangeProofValues {
+		return profiles.ErrProfileMismatch
+	}
+	sl.BPparams = params
+	sl.CommitTables = util.NewCommitTables(sl.BPparams.H)
+	return nil
+
 }
 
 func (sl *SequencingLab) SequenceWholeSetRange(baseArray []*env.Base) ([]*env.Cipher, []*env.ECDSASignature) {
 	// Encrypt each input bases and sign on Hash(position, ciphertext) for each ciphertext
+	return sl.sequenceWholeSet(baseArray, func(uint32) bool { return true })
+}
+
+// SequenceWholeSetUnpacked is SequenceWholeSetRange for the positions of the given packed blocks only: the positions at
+// both ends of a marker that do not fill a whole block (see Tester.UnpackedBlocks). Entries of other positions are nil.
+func (sl *SequencingLab) SequenceWholeSetUnpacked(baseArray []*env.Base, blocks []uint32) ([]*env.Cipher, []*env.ECDSASignature) {
+
+	layout := sl.PackingLayout()
+	requested := make(map[uint32]bool, len(blocks))
+	for _, block := range blocks {
+		requested[block] = true
+	}
+	return sl.sequenceWholeSet(baseArray, func(position uint32) bool {
+		block, _ := layout.BlockOf(position)
+		return requested[block]
+	})
+
+}
+
+func (sl *SequencingLab) sequenceWholeSet(baseArray []*env.Base, selected func(uint32) bool) ([]*env.Cipher, []*env.ECDSASignature) {
+
 	var wg sync.WaitGroup
 
 	numberOfBases := len(baseArray)
 
+	var indices []int
+	var hashedGenome []*big.Int
+	for i := 0; i < numberOfBases; i++ {
+		if selected(baseArray[i].Position) {
+			indices = append(indices, i)
+			hashedGenome = append(hashedGenome, new(big.Int).SetBytes(env.HashPositionAndBase(sl.Hash, baseArray[i].Position, baseArray[i])))
+		}
+	}
 	encryptedGenome := make([]*env.Cipher, numberOfBases)
+	for k, cipher := range sl.Ahe.EncryptBatch(hashedGenome) {
+		encryptedGenome[indices[k]] = cipher
+	}
 	signatures := make([]*env.ECDSASignature, numberOfBases)
 
-	wg.Add(numberOfBases)
+	wg.Add(len(indices))
 
-	for i := uint32(0); i < uint32(numberOfBases); i++ {
-
-		go func(i uint32, wg *sync.WaitGroup) {
-			hashBase := env.HashPositionAndBase(sl.Hash, baseArray[i].Position, baseArray[i])
-			encryptedGenome[i] = sl.Ahe.Encrypt(new(big.Int).SetBytes(hashBase))
+	for _, i := range indices {
 
+		go func(i int, wg *sync.WaitGroup) {
 			hashResult := env.HashPositionAndCipher(sl.Hash, baseArray[i].Position, encryptedGenome[i])
 
 			r, s, serr := ecdsa.Sign(rand.Reader, sl.signingKey, hashResult)
@@ -94,6 +187,61 @@ func (sl *SequencingLab) SequenceWholeSetRange(baseArray []*env.Base) ([]*env.Ci
 
 }
 
+// SequenceWholeSetPacked encrypts the genome in packed blocks (see env.PackingLayout) and signs Hash(block, ciphertext)
+// for each block. The tester still needs per-position ciphertexts for the positions of the marker that do not fill a
+// whole block, which SequenceWholeSetUnpacked gives for those blocks only.
+func (sl *SequencingLab) SequenceWholeSetPacked(baseArray []*env.Base) ([]*env.Cipher, []*env.ECDSASignature) {
+
+	var wg sync.WaitGroup
+
+	layout := sl.PackingLayout()
+	packed := layout.Pack(baseArray)
+	numberOfBlocks := uint32(0)
+	for block := range packed {
+		if block+1 > numberOfBlocks {
+			numberOfBlocks = block + 1
+		}
+	}
+
+	plains := make([]*big.Int, numberOfBlocks)
+	for b := range plains {
+		plains[b] = packed[uint32(b)]
+		if plains[b] == nil {
+			plains[b] = big.NewInt(0)
+		}
+	}
+	encryptedBlocks := sl.Ahe.EncryptBatch(plains)
+	signatures := make([]*env.ECDSASignature, numberOfBlocks)
+
+	wg.Add(int(numberOfBlocks))
+
+	for b := uint32(0); b < numberOfBlocks; b++ {
+
+		go func(b uint32, wg *sync.WaitGroup) {
+			hashResult := env.HashBlockAndCipher(sl.Hash, b, encryptedBlocks[b])
+
+			r, s, serr := ecdsa.Sign(rand.Reader, sl.signingKey, hashResult)
+			if serr != nil {
+				panic(serr)
+			}
+			signatures[b] = &env.ECDSASignature{R: r, S: s}
+
+			wg.Done()
+		}(b, &wg)
+	}
+
+	wg.Wait()
+
+	return encryptedBlocks, signatures
+
+}
+
+// PackingLayout returns the layout of packed blocks for the scheme of the lab. The plaintext order has to be the one of
+// GetGroupOrder, as for Paillier and DamgardJurik.
+func (sl *SequencingLab) PackingLayout() env.PackingLayout {
+	return env.NewPackingLayout(sl.Ahe.GetGroupOrder())
+}
+
 func (sl *SequencingLab) SequenceSNPSetRange(baseArray []*env.Base) ([]uint32, []*env.Cipher, []*big.Int, []*env.ECDSASignature) {
 	// Generate two additional bases for boundaries, encrypt each input base, generate commitments for each position values, and sign on the tuple (comm_i, cipher_i, comm_i+1, cipher_i+1)
 
@@ -103,7 +251,6 @@ func (sl *SequencingLab) SequenceSNPSetRange(baseArray []*env.Base) ([]uint32, [
 	numberOfBases := len(baseArray)
 
 	positions := make([]uint32, numberOfBases+2)
-	encryptedGenome := make([]*env.Cipher, numberOfBases+2)
 	commitments := make([]*p256.P256, numberOfBases+2)
 	signatures := make([]*env.ECDSASignature, numberOfBases+1)
 	salts := make([]*big.Int, numberOfBases+2)
@@ -117,13 +264,20 @@ func (sl *SequencingLab) SequenceSNPSetRange(baseArray []*env.Base) ([]uint32, [
 		}(i, &wg)
 	}
 
-	// Compute encrypted genome and commitments
-	// Add m_0
+	// Compute encrypted genome, with m_0 and m_{n+1} added for the boundaries, and commitments
 	positions[0] = uint32(0)
-	encryptedGenome[0] = sl.GetEncryptedBase(positions[0])
+	positions[numberOfBases+1] = uint32(mAX_HUMAN_GENOME_SIZE + 1) // any fixed number > N
+	hashedGenome := make([]*big.Int, numberOfBases+2)
+	hashedGenome[0] = sl.hashBoundary(positions[0])
+	for i := 0; i < numberOfBases; i++ {
+		positions[i+1] = baseArray[i].Position
+		hashedGenome[i+1] = new(big.Int).SetBytes(env.HashPositionAndBase(sl.Hash, baseArray[i].Position, baseArray[i]))
+	}
+	hashedGenome[numberOfBases+1] = sl.hashBoundary(positions[numberOfBases+1])
+	encryptedGenome := sl.Ahe.EncryptBatch(hashedGenome)
 	wg.Wait()
 
-	commitments[0], err = util.CommitG1(big.NewInt(int64(positions[0])), salts[0], sl.BPparams.H)
+	commitments[0], err = sl.CommitTables.CommitG1(big.NewInt(int64(positions[0])), salts[0])
 	if err != nil {
 		panic(err)
 	}
@@ -131,16 +285,7 @@ func (sl *SequencingLab) SequenceSNPSetRange(baseArray []*env.Base) ([]uint32, [
 	wg.Add(numberOfBases + 1)
 	for i := uint32(0); i <= uint32(numberOfBases); i++ { // from (0,1), (1,2) ..., (N, N+1)
 		go func(i uint32, wg *sync.WaitGroup) {
-			if i == uint32(numberOfBases) { // Add m_{n+1}
-				positions[i+1] = uint32(mAX_HUMAN_GENOME_SIZE + 1) // any fixed number > N
-				encryptedGenome[i+1] = sl.GetEncryptedBase(positions[i+1])
-			} else {
-				positions[i+1] = baseArray[i].Position
-				hashBase := env.HashPositionAndBase(sl.Hash, baseArray[i].Position, baseArray[i])
-				encryptedGenome[i+1] = sl.Ahe.Encrypt(new(big.Int).SetBytes(hashBase))
-			}
-
-			commitments[i+1], err = util.CommitG1(big.NewInt(int64(positions[i+1])), salts[i+1], sl.BPparams.H)
+			commitments[i+1], err = sl.CommitTables.CommitG1(big.NewInt(int64(positions[i+1])), salts[i+1])
 			if err != nil {
 				panic(err)
 			}
@@ -171,9 +316,12 @@ func (sl *SequencingLab) SequenceSNPSetRange(baseArray []*env.Base) ([]uint32, [
 }
 
 func (sl *SequencingLab) GetEncryptedBase(position uint32) *env.Cipher {
+	return sl.Ahe.Encrypt(sl.hashBoundary(position))
+}
+
+func (sl *SequencingLab) hashBoundary(position uint32) *big.Int {
 	base := env.Base{Position: position, Letter: uint8('Z')} // additional base for boundaries
-	hashBase := env.HashPositionAndBase(sl.Hash, base.Position, &base)
-	return sl.Ahe.Encrypt(new(big.Int).SetBytes(hashBase))
+	return new(big.Int).SetBytes(env.HashPositionAndBase(sl.Hash, base.Position, &base))
 }
 
 func (sl *SequencingLab) SetMaxHumanGenomeSize(val int) {
@@ -0,0 +1,80 @@
+package sequencinglab
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/eozturk1/genomic-security-journal-code/helpers/addhomencer"
+	"github.com/eozturk1/genomic-security-journal-code/helpers/profiles"
+	"github.com/ing-bank/zkrp/bulletproofs"
+)
+
+func TestBPparamsSaveLoad(t *testing.T) {
+
+	scheme := addhomencer.AHElGamal{}
+	scheme.SetupProfile(profiles.Default)
+	lab := SequencingLab{}
+	lab.Setup(&scheme, profiles.Default)
+
+	fileName := filepath.Join(t.TempDir(), "bpparams")
+	if err := lab.SaveBPparams(fileName); err != nil {
+		t.Fatal(err)
+	}
+	other := SequencingLab{}
+	other.Setup(&scheme, profiles.Default)
+	other.BPparams = bulletproofs.BulletProofSetupParams{}
+	if err := other.LoadBPparams(fileName); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(other.BPparams, lab.BPparams) {
+		t.Fatal("loaded parameters differ from the saved ones")
+	}
+	if other.CommitTables == nil {
+		t.Fatal("commitment tables are not rebuilt")
+	}
+
+	// a generator that is not the one of the seeds
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[len(data)-1] ^= 1
+	if err := os.WriteFile(fileName, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := other.LoadBPparams(fileName); err == nil {
+		t.Fatal("tampered parameters are loaded")
+	}
+
+	// parameters saved under another profile, or of another range
+	another := SequencingLab{Profile: profiles.Profile192, BPparams: lab.BPparams}
+	if err := another.SaveBPparams(fileName); err != nil {
+		t.Fatal(err)
+	}
+	if err := other.LoadBPparams(fileName); err != profiles.ErrProfileMismatch {
+		t.Fatalf("parameters of another profile: %v, want ErrProfileMismatch", err)
+	}
+	params, err := bulletproofs.SetupAggregated(64, RangeProofValues)
+	if err != nil {
+		t.Fatal(err)
+	}
+	another = SequencingLab{Profile: profiles.Default, BPparams: params}
+	if err := another.SaveBPparams(fileName); err != nil {
+		t.Fatal(err)
+	}
+	if err := other.LoadBPparams(fileName); err != profiles.ErrProfileMismatch {
+		t.Fatalf("parameters of another range: %v, want ErrProfileMismatch", err)
+	}
+	if err := os.WriteFile(fileName, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := other.LoadBPparams(fileName); err != ErrInvalidBPparams {
+		t.Fatalf("empty file: %v, want ErrInvalidBPparams", err)
+	}
+	if !reflect.DeepEqual(other.BPparams, lab.BPparams) {
+		t.Fatal("failed loads replaced the parameters")
+	}
+
+}
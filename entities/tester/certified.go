@@ -0,0 +1,93 @@
+package tester
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"sync"
+
+	"github.com/eozturk1/genomic-security-journal-code/entities/regulator"
+	sl "github.com/eozturk1/genomic-security-journal-code/entities/sequencinglab"
+	"github.com/eozturk1/genomic-security-journal-code/helpers/addhomencer"
+	"github.com/eozturk1/genomic-security-journal-code/helpers/env"
+)
+
+// In certified mode, the marker of the tester is one that a regulator approved (see regulator.Certify), and gave to the
+// tester with the certificate (see SetCertificate). The tester shows the certificate with EncryptedMarker, and proves
+// that each ciphertext encrypts the base of the marker whose commitment is in the certificate, so that Alice, who checks
+// them with VerifyCertifiedMarker, knows that the test is the approved one without learning the marker. The
+// certificate fixes the range of the test, and the regulator checked that the marker is in it, so the proofs of
+// well-formed mode are not needed for the marker; those of the results still are.
+
+var ErrQueryOutOfCertifiedRange = errors.New("queried range is not in the certified range")
+
+// SetCertificate gives the tester the certificate of its marker and the openings of the commitments, as the regulator
+// returned them. They are kept across setups.
+func (t *Tester) SetCertificate(cert *regulator.Certificate, openings []*big.Int) {
+	t.Certificate, t.openings = cert, openings
+}
+
+// SetupCertified is Setup for the marker of the certificate. The queried range has to be in the certified range.
+func (t *Tester) SetupCertified(lab *sl.SequencingLab, baseArray []*env.Base, secParam uint32) error {
+
+	prover, ok := lab.Ahe.(addhomencer.WellFormednessProver)
+	if !ok {
+		return addhomencer.ErrNoWellFormednessProof
+	}
+	cert, openings := t.Certificate, t.openings
+	if cert == nil || len(cert.Commitments) != len(baseArray) || len(openings) != len(baseArray) {
+		return regulator.ErrCertificateMalformed
+	}
+	t.setRange(lab, baseArray, secParam)
+	t.PackedMarker = nil
+	if t.RangeStart < cert.RangeStart || t.RangeEnd > cert.RangeEnd {
+		return ErrQueryOutOfCertifiedRange
+	}
+
+	marker := make([]*env.Cipher, len(baseArray))
+	proofs := make([]*addhomencer.ORProof, len(baseArray))
+	errs := make([]error, len(baseArray))
+	var wg sync.WaitGroup
+	wg.Add(len(baseArray))
+	for j := range baseArray {
+		go func(j int, wg *sync.WaitGroup) {
+			hashBase := env.HashPositionAndBase(lab.Hash, baseArray[j].Position, baseArray[j])
+			marker[j], proofs[j], errs[j] = prover.EncryptInverseOfCommitted(t.Session, new(big.Int).SetBytes(hashBase), openings[j], cert.Commitments[j])
+			wg.Done()
+		}(j, &wg)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	t.EncryptedMarker, t.CertificateProofs = marker, proofs
+	return nil
+
+}
+
+// VerifyCertifiedMarker is Alice's check of a marker of the tester for the range [rangeStart, rangeEnd] in the session:
+// the certificate is signed by the regulator whose key she trusts, the range is in the certified one, and the
+// ciphertexts encrypt the certified bases.
+func VerifyCertifiedMarker(lab *sl.SequencingLab, regulatorKey *ecdsa.PublicKey, session []byte, rangeStart, rangeEnd uint32, marker []*env.Cipher, cert *regulator.Certificate, proofs []*addhomencer.ORProof) error {
+
+	verifier, ok := lab.Ahe.(addhomencer.WellFormednessProver)
+	if !ok {
+		return addhomencer.ErrNoWellFormednessProof
+	}
+	if err := cert.Verify(lab.Profile, regulatorKey); err != nil {
+		return err
+	}
+	if rangeStart < cert.RangeStart || rangeEnd > cert.RangeEnd || rangeStart > rangeEnd {
+		return ErrQueryOutOfCertifiedRange
+	}
+	if len(marker) != len(cert.Commitments) || len(proofs) != len(marker) {
+		return regulator.ErrCertificateMalformed
+	}
+	return verifyAll(len(marker), func(j int) error {
+		return verifier.VerifyEncryptedCommitted(session, marker[j], cert.Commitments[j], proofs[j])
+	})
+
+}
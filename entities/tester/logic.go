@@ -0,0 +1,493 @@
+package tester
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	sl "github.com/eozturk1/genomic-security-journal-code/entities/sequencinglab"
+	"github.com/eozturk1/genomic-security-journal-code/helpers/env"
+	bp "github.com/ing-bank/zkrp/bulletproofs"
+)
+
+// ========================== Boolean test logic ==========================
+// A test over a panel can be a boolean expression over the names of its markers:
+//
+//	expr    := and { "|" and }
+//	and     := unary { "&" unary }
+//	unary   := "!" unary | "(" expr ")" | "atleast(" k "," expr { "," expr } ")" | name
+//
+// e.g., "A & !B" or "atleast(2, A, B, C, D, E)". The tester evaluates it homomorphically, and Alice learns only its value.
+//
+// With additively homomorphic encryption, "x = 0" is testable but "x != 0" is not, so a marker that does not match has to
+// be told apart positively: each marker is queried in its own range, with no security parameter, and its positions have
+// to be contiguous, so that its j-th position is the one of the j-th ciphertext of the slice if the slice has as many
+// ciphertexts as the marker has positions (otherwise, the marker does not match). The marker does not match iff some
+// ciphertext is the one of another base of alphabet at its position. For every assignment of the markers that satisfies
+// the expression, the tester combines one test of each marker, "matches" or "first mismatch at position j with base x",
+// with random coefficients, so that exactly one result is an encryption of 0 iff the expression holds.
+// The queried ranges are the positions of the markers, so Alice learns them. A base outside of the alphabet would be
+// neither a match nor a mismatch, so the tester refuses markers with such bases, and Alice aborts if she has one in the
+// ranges (see BasesInAlphabet).
+
+const (
+	maxLogicMarkers = 12      // assignments are enumerated
+	maxLogicResults = 1 << 14 // results of the evaluation, of which at most one is an encryption of 0
+)
+
+// Logic is a parsed boolean expression over the names of markers.
+type Logic struct {
+	root    *logicNode
+	markers []string
+}
+
+type logicNode struct {
+	op       byte // 'm' for a marker, '!', '&', '|', or 'k' for atleast
+	name     string
+	k        int
+	children []*logicNode
+}
+
+// ParseLogic parses the expression.
+func ParseLogic(expression string) (*Logic, error) {
+
+	parser := &logicParser{input: expression}
+	root, err := parser.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if parser.skipSpaces(); parser.pos != len(parser.input) {
+		return nil, fmt.Errorf("unexpected %q at %d", parser.input[parser.pos:], parser.pos)
+	}
+	logic := &Logic{root: root}
+	seen := make(map[string]bool)
+	root.walk(func(node *logicNode) {
+		if node.op == 'm' && !seen[node.name] {
+			seen[node.name] = true
+			logic.markers = append(logic.markers, node.name)
+		}
+	})
+	return logic, nil
+
+}
+
+// Markers returns the names of the markers of the expression, in order of appearance.
+func (logic *Logic) Markers() []string {
+	return logic.markers
+}
+
+// Eval returns the value of the expression for the verdicts of the markers. A marker with no verdict does not match.
+func (logic *Logic) Eval(verdicts map[string]bool) bool {
+	return logic.root.eval(verdicts)
+}
+
+func (node *logicNode) eval(verdicts map[string]bool) bool {
+
+	switch node.op {
+	case 'm':
+		return verdicts[node.name]
+	case '!':
+		return !node.children[0].eval(verdicts)
+	case '&':
+		for _, child := range node.children {
+			if !child.eval(verdicts) {
+				return false
+			}
+		}
+		return true
+	case '|':
+		for _, child := range node.children {
+			if child.eval(verdicts) {
+				return true
+			}
+		}
+		return false
+	default:
+		count := 0
+		for _, child := range node.children {
+			if child.eval(verdicts) {
+				count++
+			}
+		}
+		return count >= node.k
+	}
+
+}
+
+func (node *logicNode) walk(visit func(*logicNode)) {
+	visit(node)
+	for _, child := range node.children {
+		child.walk(visit)
+	}
+}
+
+type logicParser struct {
+	input string
+	pos   int
+}
+
+func (parser *logicParser) skipSpaces() {
+	for parser.pos < len(parser.input) && strings.ContainsRune(" \t\n", rune(parser.input[parser.pos])) {
+		parser.pos++
+	}
+}
+
+// accept consumes the token if it is next.
+func (parser *logicParser) accept(token string) bool {
+	parser.skipSpaces()
+	if strings.HasPrefix(parser.input[parser.pos:], token) {
+		parser.pos += len(token)
+		return true
+	}
+	return false
+}
+
+func (parser *logicParser) expect(token string) error {
+	if !parser.accept(token) {
+		return fmt.Errorf("expected %q at %d", token, parser.pos)
+	}
+	return nil
+}
+
+func (parser *logicParser) parseOr() (*logicNode, error) {
+	return parser.parseList('|', parser.parseAnd)
+}
+
+func (parser *logicParser) parseAnd() (*logicNode, error) {
+	return parser.parseList('&', parser.parseUnary)
+}
+
+// parseList parses operands separated by the operator, into one node if there are several.
+func (parser *logicParser) parseList(op byte, parseOperand func() (*logicNode, error)) (*logicNode, error) {
+
+	node, err := parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	if parser.skipSpaces(); parser.pos == len(parser.input) || parser.input[parser.pos] != op {
+		return node, nil
+	}
+	list := &logicNode{op: op, children: []*logicNode{node}}
+	for parser.accept(string(op)) {
+		operand, err := parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		list.children = append(list.children, operand)
+	}
+	return list, nil
+
+}
+
+func (parser *logicParser) parseUnary() (*logicNode, error) {
+
+	if parser.accept("!") {
+		operand, err := parser.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &logicNode{op: '!', children: []*logicNode{operand}}, nil
+	}
+	if parser.accept("(") {
+		node, err := parser.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		return node, parser.expect(")")
+	}
+
+	start := parser.pos
+	for parser.pos < len(parser.input) && isNameByte(parser.input[parser.pos]) {
+		parser.pos++
+	}
+	name := parser.input[start:parser.pos]
+	if name == "" {
+		return nil, fmt.Errorf("expected a marker name at %d", start)
+	}
+	if name != "atleast" || !parser.accept("(") {
+		return &logicNode{op: 'm', name: name}, nil
+	}
+
+	// atleast(k, expr, ...)
+	parser.skipSpaces()
+	start = parser.pos
+	for parser.pos < len(parser.input) && parser.input[parser.pos] >= '0' && parser.input[parser.pos] <= '9' {
+		parser.pos++
+	}
+	k, err := strconv.Atoi(parser.input[start:parser.pos])
+	if err != nil {
+		return nil, fmt.Errorf("expected a threshold at %d", start)
+	}
+	node := &logicNode{op: 'k', k: k}
+	for parser.accept(",") {
+		operand, err := parser.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		node.children = append(node.children, operand)
+	}
+	if len(node.children) == 0 {
+		return nil, fmt.Errorf("atleast with no operand at %d", parser.pos)
+	}
+	return node, parser.expect(")")
+
+}
+
+func isNameByte(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9' || strings.IndexByte("_.-:", b) >= 0
+}
+
+// SetupLogic prepares a test of the expression over the markers of the panel, all of which it has to use, under the
+// conditions on the markers above. Ranges[j] is the range of the j-th marker of the panel.
+func (t *Tester) SetupLogic(lab *sl.SequencingLab, panel *Panel, expression string) error {
+
+	logic, err := ParseLogic(expression)
+	if err != nil {
+		return err
+	}
+	if len(logic.Markers()) > maxLogicMarkers {
+		return fmt.Errorf("the expression uses more than %d markers", maxLogicMarkers)
+	}
+	used := make(map[string]bool)
+	for _, name := range logic.Markers() {
+		used[name] = true
+	}
+	named := make(map[string]bool)
+	for _, marker := range panel.Markers {
+		if named[marker.Name] {
+			return errors.New("duplicate marker name in the panel: " + marker.Name)
+		}
+		named[marker.Name] = true
+		if !used[marker.Name] {
+			return errors.New("marker not used by the expression: " + marker.Name)
+		}
+		bases := marker.Bases
+		if len(bases) == 0 || bases[len(bases)-1].Position-bases[0].Position != uint32(len(bases)-1) {
+			return errors.New("positions of the marker are not contiguous: " + marker.Name)
+		}
+		for _, base := range bases {
+			if !InAlphabet(base.Letter) {
+				return errors.New(ErrBaseNotInAlphabet.Error() + ": " + marker.Name)
+			}
+		}
+	}
+	for _, name := range logic.Markers() {
+		if !named[name] {
+			return errors.New("marker not in the panel: " + name)
+		}
+	}
+
+	markers := make([]*PanelMarker, len(panel.Markers))
+	ranges := make([]*RangeQuery, len(panel.Markers))
+	for j, marker := range panel.Markers {
+		t.setRange(lab, marker.Bases, 0)
+		markers[j] = &PanelMarker{
+			Name:            marker.Name,
+			Range:           j,
+			EncryptedMarker: t.encryptMarker(marker.Bases, func(uint32) bool { return true }),
+			alternatives:    t.encryptAlternatives(marker.Bases),
+		}
+		ranges[j] = &RangeQuery{RangeStart: t.RangeStart, RangeEnd: t.RangeEnd}
+	}
+
+	t.Ranges = ranges
+	t.PanelMarkers = markers
+	t.PerMarker = false
+	t.Logic = logic
+	t.EncryptedMarker = nil
+	t.PackedMarker = nil
+	return nil
+
+}
+
+// BasesInAlphabet returns true if and only if the bases of the genome in the ranges of a test of SetupLogic are all in
+// the alphabet, which Alice checks before she reveals her slices.
+func BasesInAlphabet(genome []*env.Base, ranges []*RangeQuery) bool {
+
+	for _, base := range genome {
+		for _, query := range ranges {
+			if base.Position >= query.RangeStart && base.Position <= query.RangeEnd && !InAlphabet(base.Letter) {
+				return false
+			}
+		}
+	}
+	return true
+
+}
+
+// encryptAlternatives returns E(-Hash(position, x)) for the other bases x of alphabet at each position of the marker.
+func (t *Tester) encryptAlternatives(baseArray []*env.Base) [][]*env.Cipher {
+
+	alternatives := make([][]*env.Cipher, len(baseArray))
+	for j, base := range baseArray {
+		var hashes []*big.Int
+		for _, letter := range alphabet {
+			if letter != base.Letter {
+				alternative := &env.Base{Position: base.Position, Letter: letter}
+				hashes = append(hashes, new(big.Int).SetBytes(env.HashPositionAndBase(t.lab.Hash, base.Position, alternative)))
+			}
+		}
+		alternatives[j] = t.lab.Ahe.EncryptInverseBatch(hashes)
+	}
+	return alternatives
+
+}
+
+// zkrp: bulletproof, for a test of SetupLogic, with the slices and the proofs of a multi-range query over Ranges (see
+// TestingSNPRanges). Exactly one of the results is an encryption of 0 if the expression holds, and none otherwise.
+func (t *Tester) TestingSNPLogic(slices []*RangeSlice, proofs []*bp.AggregatedBulletProof) []*env.Cipher {
+
+	if t.Logic == nil || !t.verifyRangeProofs(slices, proofs) || !t.verifySlices(slices) {
+		return nil
+	}
+	return t.testLogic(slices)
+
+}
+
+// zkrp: ccs08, for a test of SetupLogic, with the slices and the proofs of a multi-range query over Ranges (see
+// TestingSNPRangesCCS08)
+func (t *Tester) TestingSNPLogicCCS08(slices []*RangeSlice, lproofData, hproofData [][]byte) []*env.Cipher {
+
+	if t.Logic == nil || !t.verifyRangeProofsCCS08(slices, lproofData, hproofData) || !t.verifySlices(slices) {
+		return nil
+	}
+	return t.testLogic(slices)
+
+}
+
+// zeroTests is a predicate over Alice's bases: it holds iff one of the ciphertexts is an encryption of 0, or always if
+// holds is set.
+type zeroTests struct {
+	holds   bool
+	ciphers []*env.Cipher
+}
+
+func (t *Tester) testLogic(slices []*RangeSlice) []*env.Cipher {
+
+	ahe := t.lab.Ahe
+
+	// the tests of "matches" and "does not match" of each marker
+	matches := make(map[string]*zeroTests)
+	mismatches := make(map[string]*zeroTests)
+	for _, marker := range t.PanelMarkers {
+		slice := slices[marker.Range]
+		if len(slice.Cipher)-2 != len(marker.EncryptedMarker) {
+			// some position of the marker is missing
+			matches[marker.Name] = &zeroTests{}
+			mismatches[marker.Name] = &zeroTests{holds: true}
+			continue
+		}
+
+		// prefix = sum_j' rho_j' (a_j' - t_j') over the positions before j, zero iff they all match
+		prefix := ahe.Encrypt(big.NewInt(0))
+		mismatch := &zeroTests{}
+		for j, encryptedBase := range marker.EncryptedMarker {
+			for _, alternative := range marker.alternatives[j] {
+				difference, err := ahe.MultCiphers(slice.Cipher[j+1], alternative)
+				if err == nil {
+					difference, err = t.addBlinded(prefix, difference)
+				}
+				if err != nil {
+					fmt.Println("testing failed (", err, "), so ABORT!")
+					return nil
+				}
+				mismatch.ciphers = append(mismatch.ciphers, difference)
+			}
+			difference, err := ahe.MultCiphers(slice.Cipher[j+1], encryptedBase)
+			if err == nil {
+				prefix, err = t.addBlinded(prefix, difference)
+			}
+			if err != nil {
+				fmt.Println("testing failed (", err, "), so ABORT!")
+				return nil
+			}
+		}
+		matches[marker.Name] = &zeroTests{ciphers: []*env.Cipher{prefix}}
+		mismatches[marker.Name] = mismatch
+	}
+
+	// one conjunction of the tests of the markers for every satisfying assignment
+	names := t.Logic.Markers()
+	var result []*env.Cipher
+	for assignment := 0; assignment < 1<<len(names); assignment++ {
+		verdicts := make(map[string]bool)
+		terms := make([]*zeroTests, len(names))
+		for i, name := range names {
+			verdicts[name] = assignment>>i&1 == 1
+			if verdicts[name] {
+				terms[i] = matches[name]
+			} else {
+				terms[i] = mismatches[name]
+			}
+		}
+		if !t.Logic.Eval(verdicts) {
+			continue
+		}
+		conjunction, ok, err := t.conjunction(terms, maxLogicResults-len(result))
+		if err != nil {
+			fmt.Println("testing failed (", err, "), so ABORT!")
+			return nil
+		}
+		if !ok {
+			fmt.Println("Expression has too many results, so ABORT!")
+			return nil
+		}
+		result = append(result, conjunction...)
+	}
+
+	// fresh blinding, since a conjunction of one test is the test itself
+	for i := range result {
+		var err error
+		if result[i], err = t.blind(result[i]); err != nil {
+			fmt.Println("testing failed (", err, "), so ABORT!")
+			return nil
+		}
+	}
+	if err := shuffle(len(result), func(i, j int) { result[i], result[j] = result[j], result[i] }); err != nil {
+		fmt.Println("testing failed (", err, "), so ABORT!")
+		return nil
+	}
+	return result
+
+}
+
+// conjunction returns a random linear combination of each choice of one ciphertext per term, so that at most one of them
+// is an encryption of 0, or false if there would be more than limit of them.
+func (t *Tester) conjunction(terms []*zeroTests, limit int) ([]*env.Cipher, bool, error) {
+
+	combinations := []*env.Cipher{t.lab.Ahe.Encrypt(big.NewInt(0))}
+	for _, term := range terms {
+		if term.holds {
+			continue
+		}
+		if len(combinations)*len(term.ciphers) > limit {
+			return nil, false, nil
+		}
+		next := make([]*env.Cipher, 0, len(combinations)*len(term.ciphers))
+		for _, combination := range combinations {
+			for _, cipher := range term.ciphers {
+				sum, err := t.addBlinded(combination, cipher)
+				if err != nil {
+					return nil, false, err
+				}
+				next = append(next, sum)
+			}
+		}
+		combinations = next
+	}
+	return combinations, true, nil
+
+}
+
+// addBlinded returns E(a + rho * c) for acc = E(a), cipher = E(c) and a random unit rho.
+func (t *Tester) addBlinded(acc, cipher *env.Cipher) (*env.Cipher, error) {
+
+	blinded, err := t.blind(cipher)
+	if err != nil {
+		return nil, err
+	}
+	return t.lab.Ahe.MultCiphers(acc, blinded)
+
+}
@@ -0,0 +1,134 @@
+package tester
+
+import (
+	"strings"
+	"testing"
+
+	sl "github.com/eozturk1/genomic-security-journal-code/entities/sequencinglab"
+	"github.com/eozturk1/genomic-security-journal-code/helpers/env"
+)
+
+// verdicts returns the verdicts of the markers named by the letters of matching, out of A to E.
+func verdicts(matching string) map[string]bool {
+
+	verdicts := make(map[string]bool)
+	for _, name := range "ABCDE" {
+		verdicts[string(name)] = strings.ContainsRune(matching, name)
+	}
+	return verdicts
+
+}
+
+func TestParseLogicPrecedence(t *testing.T) {
+
+	cases := []struct {
+		expression string
+		want       func(v map[string]bool) bool
+	}{
+		{"A | B & C", func(v map[string]bool) bool { return v["A"] || (v["B"] && v["C"]) }},
+		{"(A | B) & C", func(v map[string]bool) bool { return (v["A"] || v["B"]) && v["C"] }},
+		{"!A & B", func(v map[string]bool) bool { return !v["A"] && v["B"] }},
+		{"!(A & B)", func(v map[string]bool) bool { return !(v["A"] && v["B"]) }},
+		{"!!A", func(v map[string]bool) bool { return v["A"] }},
+		{"A & B | C & !D", func(v map[string]bool) bool { return (v["A"] && v["B"]) || (v["C"] && !v["D"]) }},
+		{"atleast(2, A, B, C)", func(v map[string]bool) bool {
+			return v["A"] && v["B"] || v["A"] && v["C"] || v["B"] && v["C"]
+		}},
+		{"atleast(1, A & B, C) & !D", func(v map[string]bool) bool { return (v["A"] && v["B"] || v["C"]) && !v["D"] }},
+		{"atleast(0, A)", func(v map[string]bool) bool { return true }},
+		{" A|B ", func(v map[string]bool) bool { return v["A"] || v["B"] }},
+	}
+	for _, c := range cases {
+		logic, err := ParseLogic(c.expression)
+		if err != nil {
+			t.Fatalf("%q: %v", c.expression, err)
+		}
+		for assignment := 0; assignment < 1<<4; assignment++ {
+			var matching string
+			for j, name := range "ABCD" {
+				if assignment>>j&1 == 1 {
+					matching += string(name)
+				}
+			}
+			if want := c.want(verdicts(matching)); logic.Eval(verdicts(matching)) != want {
+				t.Errorf("%q with %q matching: got %v", c.expression, matching, !want)
+			}
+		}
+	}
+
+}
+
+func TestParseLogicMarkers(t *testing.T) {
+
+	logic, err := ParseLogic("rs53576_G & (brca1:c.68 | !rs53576_G) | atleast(1, x-1.snp)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"rs53576_G", "brca1:c.68", "x-1.snp"}
+	if strings.Join(logic.Markers(), " ") != strings.Join(want, " ") {
+		t.Fatalf("markers %v, want %v", logic.Markers(), want)
+	}
+	if logic.Eval(map[string]bool{}) {
+		t.Fatal("markers with no verdict match")
+	}
+
+}
+
+func TestParseLogicMalformed(t *testing.T) {
+
+	for _, expression := range []string{
+		"", " ", "A &", "& A", "A | | B", "(A", "A)", "A B", "!", "()", "A & (B | )",
+		"atleast(", "atleast(2)", "atleast(x, A)", "atleast(2, A", "atleast(2 A)", "A # B",
+	} {
+		if logic, err := ParseLogic(expression); err == nil {
+			t.Errorf("%q parses, to markers %v", expression, logic.Markers())
+		}
+	}
+
+}
+
+func TestSetupLogicRejectsMarkers(t *testing.T) {
+
+	marker := func(name string, letters string) *Marker {
+		bases := make([]*env.Base, len(letters))
+		for j := range letters {
+			bases[j] = &env.Base{Position: uint32(100 + j), Letter: letters[j]}
+		}
+		return &Marker{Name: name, Bases: bases}
+	}
+	gap := marker("B", "AC")
+	gap.Bases[1].Position++
+
+	cases := map[string]struct {
+		expression string
+		markers    []*Marker
+	}{
+		"unknown name":         {"A & C", []*Marker{marker("A", "AC")}},
+		"unused marker":        {"A", []*Marker{marker("A", "AC"), marker("B", "G")}},
+		"duplicate name":       {"A", []*Marker{marker("A", "AC"), marker("A", "G")}},
+		"not contiguous":       {"A | B", []*Marker{marker("A", "AC"), gap}},
+		"empty marker":         {"A", []*Marker{marker("A", "")}},
+		"base not in alphabet": {"A & !B", []*Marker{marker("A", "AC"), marker("B", "GN")}},
+		"too many markers":     {"A|B|C|D|E|F|G|H|I|J|K|L|M", nil},
+		"malformed":            {"A &", []*Marker{marker("A", "AC")}},
+	}
+	for name, c := range cases {
+		tester := &Tester{}
+		if err := tester.SetupLogic(&sl.SequencingLab{}, &Panel{Markers: c.markers}, c.expression); err == nil {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+
+}
+
+func TestBasesInAlphabet(t *testing.T) {
+
+	genome := []*env.Base{{Position: 10, Letter: 'A'}, {Position: 20, Letter: 'N'}, {Position: 30, Letter: 'T'}}
+	if !BasesInAlphabet(genome, []*RangeQuery{{RangeStart: 0, RangeEnd: 15}, {RangeStart: 25, RangeEnd: 40}}) {
+		t.Fatal("a base outside of the ranges is checked")
+	}
+	if BasesInAlphabet(genome, []*RangeQuery{{RangeStart: 0, RangeEnd: 15}, {RangeStart: 20, RangeEnd: 20}}) {
+		t.Fatal("a base not in the alphabet is accepted in a range")
+	}
+
+}
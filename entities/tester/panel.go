@@ -0,0 +1,164 @@
+package tester
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	sl "github.com/eozturk1/genomic-security-journal-code/entities/sequencinglab"
+	"github.com/eozturk1/genomic-security-journal-code/helpers/env"
+	bp "github.com/ing-bank/zkrp/bulletproofs"
+)
+
+// Panel is a set of named markers tested in one session. PerMarker is what the tester asks to disclose: a verdict per
+// marker if it is set, which the policy of Alice has to allow (see policy.Policy.AllowPerMarker), and only whether any
+// marker of the panel matches otherwise.
+type Panel struct {
+	Markers   []*Marker
+	PerMarker bool
+}
+
+// Marker is one named marker of a panel, with its bases sorted by position.
+type Marker struct {
+	Name  string
+	Bases []*env.Base
+}
+
+// PanelMarker is the encrypted marker of a panel, tested against the slice of Ranges[Range].
+type PanelMarker struct {
+	Name            string
+	Range           int
+	EncryptedMarker []*env.Cipher
+
+	alternatives [][]*env.Cipher // for a test of SetupLogic, see encryptAlternatives
+}
+
+// PanelResult is the encrypted results of the marker of the given name, in random order: the marker matches iff one of
+// them is an encryption of 0. Name is empty for the combined results of all the markers of a panel, where every marker
+// has as many results, padded as in SingleBit mode, so that they reveal neither the size nor the range of a marker. A
+// marker matches in one window at most, so the combined results still have one encryption of 0 per matching marker.
+type PanelResult struct {
+	Name    string
+	Results []*env.Cipher
+}
+
+// SetupPanel prepares a session over the markers of the panel. Each marker is queried in its own range, as in
+// SetupRanges, but the ranges may overlap: overlapping ranges are merged into one range of Ranges, so that Alice
+// reveals, and the tester verifies, each signed tuple once however many markers cover it.
+func (t *Tester) SetupPanel(lab *sl.SequencingLab, panel *Panel, secParam uint32) error {
+
+	if len(panel.Markers) == 0 {
+		return errors.New("no marker in the panel")
+	}
+	names := make(map[string]bool)
+	markers := make([]*PanelMarker, len(panel.Markers))
+	ranges := make([]*RangeQuery, len(panel.Markers))
+	for j, marker := range panel.Markers {
+		if names[marker.Name] {
+			return errors.New("duplicate marker name in the panel: " + marker.Name)
+		}
+		names[marker.Name] = true
+		t.setRange(lab, marker.Bases, secParam)
+		markers[j] = &PanelMarker{Name: marker.Name, EncryptedMarker: t.encryptMarker(marker.Bases, func(uint32) bool { return true })}
+		ranges[j] = &RangeQuery{RangeStart: t.RangeStart, RangeEnd: t.RangeEnd}
+	}
+
+	// merge the ranges that overlap or are adjacent, and assign each marker to the merged range that contains its own
+	order := make([]int, len(ranges))
+	for j := range order {
+		order[j] = j
+	}
+	sort.Slice(order, func(i, j int) bool { return ranges[order[i]].RangeStart < ranges[order[j]].RangeStart })
+	var merged []*RangeQuery
+	for _, j := range order {
+		last := len(merged) - 1
+		if last >= 0 && ranges[j].RangeStart <= merged[last].RangeEnd+1 {
+			if ranges[j].RangeEnd > merged[last].RangeEnd {
+				merged[last].RangeEnd = ranges[j].RangeEnd
+			}
+		} else {
+			merged = append(merged, &RangeQuery{RangeStart: ranges[j].RangeStart, RangeEnd: ranges[j].RangeEnd})
+			last++
+		}
+		markers[j].Range = last
+	}
+
+	t.Ranges = merged
+	t.RangeStart, t.RangeEnd = merged[0].RangeStart, merged[len(merged)-1].RangeEnd
+	t.PanelMarkers = markers
+	t.PerMarker = panel.PerMarker
+	t.Logic = nil
+	t.EncryptedMarker = nil
+	t.PackedMarker = nil
+	return nil
+
+}
+
+// zkrp: bulletproof, for a panel, with the slices and the proofs of a multi-range query over Ranges (see
+// TestingSNPRanges). The results are per marker if the panel allows it, and combined in one PanelResult otherwise.
+func (t *Tester) TestingSNPPanel(slices []*RangeSlice, proofs []*bp.AggregatedBulletProof, withOpt bool) []*PanelResult {
+
+	if t.PanelMarkers == nil || t.Logic != nil || !t.verifyRangeProofs(slices, proofs) || !t.verifySlices(slices) {
+		return nil
+	}
+	return t.testPanel(slices, withOpt)
+
+}
+
+// zkrp: ccs08, for a panel, with the slices and the proofs of a multi-range query over Ranges (see
+// TestingSNPRangesCCS08)
+func (t *Tester) TestingSNPPanelCCS08(slices []*RangeSlice, lproofData, hproofData [][]byte, withOpt bool) []*PanelResult {
+
+	if t.PanelMarkers == nil || t.Logic != nil || !t.verifyRangeProofsCCS08(slices, lproofData, hproofData) || !t.verifySlices(slices) {
+		return nil
+	}
+	return t.testPanel(slices, withOpt)
+
+}
+
+// testPanel tests every marker of the panel against the slice of its range, whose signatures are verified.
+func (t *Tester) testPanel(slices []*RangeSlice, withOpt bool) []*PanelResult {
+
+	// the combined results of every marker are padded to the longest slice, with padding that decrypts like the results of
+	// the windows that do not match, whatever the result mode
+	length := 0
+	if !t.PerMarker {
+		mode := t.ResultMode
+		t.ResultMode |= SingleBit
+		defer func() { t.ResultMode = mode }()
+		for _, slice := range slices {
+			if len(slice.Cipher)-2 > length {
+				length = len(slice.Cipher) - 2
+			}
+		}
+	}
+
+	results := make([]*PanelResult, len(t.PanelMarkers))
+	for k, marker := range t.PanelMarkers {
+		slice := slices[marker.Range]
+		result := t.privateTestingForSNP(len(slice.Cipher)-2, slice.Cipher, marker.EncryptedMarker, withOpt)
+		if result == nil {
+			return nil
+		}
+		if !t.PerMarker {
+			result = append(result, t.padding(length-len(result))...)
+		}
+		results[k] = &PanelResult{Name: marker.Name, Results: result}
+	}
+	if t.PerMarker {
+		return results
+	}
+
+	combined := &PanelResult{}
+	for _, result := range results {
+		combined.Results = append(combined.Results, result.Results...)
+	}
+	if err := shuffle(len(combined.Results), func(i, j int) {
+		combined.Results[i], combined.Results[j] = combined.Results[j], combined.Results[i]
+	}); err != nil {
+		fmt.Println("testing failed (", err, "), so ABORT!")
+		return nil
+	}
+	return []*PanelResult{combined}
+
+}
@@ -0,0 +1,129 @@
+package tester
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/eozturk1/genomic-security-journal-code/entities/policy"
+	sl "github.com/eozturk1/genomic-security-journal-code/entities/sequencinglab"
+	"github.com/eozturk1/genomic-security-journal-code/helpers/env"
+)
+
+// A tester has an identity, an ID and the key that signs its queries (see SetIdentity), which the policies of Alice
+// allow or not by key. The signature covers the ID, the session of the setup, the queried ranges and the encrypted
+// markers, so that a query cannot be replayed by another tester under the ID, nor with another marker.
+
+var ErrNoIdentity = errors.New("tester has no identity key")
+
+// SetIdentity sets the ID of the tester and the key that signs its queries. They are kept across setups.
+func (t *Tester) SetIdentity(id string, key *ecdsa.PrivateKey) {
+	t.ID, t.identityKey = id, key
+}
+
+// QueryRanges returns the ranges of the query of the last setup: Ranges in the modes over several ranges, and
+// [RangeStart, RangeEnd] otherwise.
+func (t *Tester) QueryRanges() []policy.Region {
+
+	if t.Ranges == nil {
+		rangeStart, rangeEnd := t.GetRangeQuery()
+		return []policy.Region{{Start: rangeStart, End: rangeEnd}}
+	}
+	ranges := make([]policy.Region, len(t.Ranges))
+	for j, query := range t.Ranges {
+		ranges[j] = policy.Region{Start: query.RangeStart, End: query.RangeEnd}
+	}
+	return ranges
+
+}
+
+// QueryDigest hashes what the signature of a query covers, with the hash of the profile of the lab.
+func QueryDigest(lab *sl.SequencingLab, tester *Tester) []byte {
+
+	h := lab.Profile.NewHash()
+	var length [4]byte
+	write := func(b []byte) {
+		binary.BigEndian.PutUint32(length[:], uint32(len(b)))
+		h.Write(length[:])
+		h.Write(b)
+	}
+	writeCiphers := func(ciphers []*env.Cipher) {
+		binary.BigEndian.PutUint32(length[:], uint32(len(ciphers)))
+		h.Write(length[:])
+		for _, c := range ciphers {
+			parts := []*big.Int{nil, nil} // the entries of the positions that a packed marker leaves out are nil
+			if c != nil {
+				parts = []*big.Int{c.C1, c.C2}
+			}
+			for _, part := range parts {
+				if part == nil {
+					write(nil)
+				} else {
+					write(part.Bytes())
+				}
+			}
+		}
+	}
+	write([]byte(tester.ID))
+	write(tester.Session)
+	for _, queried := range tester.QueryRanges() {
+		write(env.Uint32ToBytes(queried.Start))
+		write(env.Uint32ToBytes(queried.End))
+	}
+	writeCiphers(tester.EncryptedMarker)
+	writeCiphers(tester.PackedMarker)
+	for _, query := range tester.Ranges {
+		writeCiphers(query.EncryptedMarker)
+	}
+	for _, marker := range tester.PanelMarkers {
+		write([]byte(marker.Name))
+		writeCiphers(marker.EncryptedMarker)
+	}
+	return h.Sum(nil)
+
+}
+
+// SignQuery signs the query of the last setup with the identity key, for the tester to send with it. A tester without
+// identity gets ErrNoIdentity and sends its query unsigned, which policies that only allow some testers refuse.
+func (t *Tester) SignQuery() (*env.ECDSASignature, error) {
+
+	if t.identityKey == nil {
+		return nil, ErrNoIdentity
+	}
+	r, s, err := ecdsa.Sign(rand.Reader, t.identityKey, QueryDigest(t.lab, t))
+	if err != nil {
+		return nil, err
+	}
+	return &env.ECDSASignature{R: r, S: s}, nil
+
+}
+
+// CheckQuery is Alice's check of the query of the tester against her policy, before she sends anything for it:
+// signature is what the tester sent with its query (see SignQuery), nil for a tester without identity, and Alice would
+// send it the given number of her ciphertexts. rangeBound is whether the protocol ensures that the results depend only
+// on her variants in the queried ranges. Alice only verifies the signature, against the keys of her policy.
+func CheckQuery(lab *sl.SequencingLab, alicePolicy *policy.Policy, tester *Tester, signature *env.ECDSASignature, variants int, rangeBound bool) bool {
+
+	rangeStart, rangeEnd := tester.GetRangeQuery()
+	certified := alicePolicy.Regulator != nil && tester.Ranges == nil && VerifyCertifiedMarker(lab, alicePolicy.Regulator, tester.Session,
+		rangeStart, rangeEnd, tester.EncryptedMarker, tester.Certificate, tester.CertificateProofs) == nil
+	query := policy.Query{
+		Tester:     tester.ID,
+		Ranges:     tester.QueryRanges(),
+		Variants:   variants,
+		RangeBound: rangeBound,
+		Certified:  certified,
+		PerMarker:  tester.PanelMarkers != nil && tester.PerMarker,
+		Digest:     QueryDigest(lab, tester),
+		Signature:  signature,
+	}
+	if err := alicePolicy.Check(query); err != nil {
+		fmt.Println("query of the tester is refused by Alice's policy (" + err.Error() + "), so ABORT!")
+		return false
+	}
+	return true
+
+}
@@ -0,0 +1,87 @@
+package tester
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"math/big"
+	"testing"
+
+	"github.com/eozturk1/genomic-security-journal-code/entities/policy"
+	sl "github.com/eozturk1/genomic-security-journal-code/entities/sequencinglab"
+	"github.com/eozturk1/genomic-security-journal-code/helpers/env"
+	"github.com/eozturk1/genomic-security-journal-code/helpers/profiles"
+)
+
+func TestQueryDigestBindsTheQuery(t *testing.T) {
+
+	lab := &sl.SequencingLab{Profile: profiles.Default}
+	query := func() *Tester {
+		return &Tester{
+			ID:              "clinic",
+			Session:         []byte("session"),
+			RangeStart:      100,
+			RangeEnd:        199,
+			EncryptedMarker: []*env.Cipher{{C1: big.NewInt(1), C2: big.NewInt(2)}, nil},
+		}
+	}
+	digest := string(QueryDigest(lab, query()))
+
+	changes := map[string]func(tester *Tester){
+		"ID":      func(tester *Tester) { tester.ID = "intruder" },
+		"session": func(tester *Tester) { tester.Session = []byte("another session") },
+		"range":   func(tester *Tester) { tester.RangeEnd++ },
+		"marker":  func(tester *Tester) { tester.EncryptedMarker[0] = &env.Cipher{C1: big.NewInt(2), C2: big.NewInt(1)} },
+		"ranges":  func(tester *Tester) { tester.Ranges = []*RangeQuery{{RangeStart: 100, RangeEnd: 199}} },
+	}
+	for name, change := range changes {
+		tester := query()
+		change(tester)
+		if string(QueryDigest(lab, tester)) == digest {
+			t.Errorf("digest does not change with the %s", name)
+		}
+	}
+
+}
+
+func TestCheckQueryAuthenticatesTheTester(t *testing.T) {
+
+	lab := &sl.SequencingLab{Profile: profiles.Default}
+	clinicKey, err := ecdsa.GenerateKey(profiles.Default.SignatureCurve, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	intruderKey, err := ecdsa.GenerateKey(profiles.Default.SignatureCurve, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	alicePolicy := &policy.Policy{AllowedTesters: map[string]*ecdsa.PublicKey{"clinic": &clinicKey.PublicKey}}
+
+	for _, c := range []struct {
+		name string
+		key  *ecdsa.PrivateKey
+		want bool
+	}{{"clinic", clinicKey, true}, {"intruder", intruderKey, false}, {"no", nil, false}} {
+		tester := &Tester{lab: lab, Session: []byte("session"), RangeStart: 100, RangeEnd: 199}
+		tester.SetIdentity("clinic", c.key)
+		signature, err := tester.SignQuery()
+		if c.key == nil && err != ErrNoIdentity || c.key != nil && err != nil {
+			t.Fatalf("signing with %s key: %v", c.name, err)
+		}
+		if CheckQuery(lab, alicePolicy, tester, signature, 3, true) != c.want {
+			t.Errorf("query of the clinic with %s key: allowed %v", c.name, !c.want)
+		}
+	}
+
+	// the signature of one query of the clinic does not authenticate another one
+	tester := &Tester{lab: lab, Session: []byte("session"), RangeStart: 100, RangeEnd: 199}
+	tester.SetIdentity("clinic", clinicKey)
+	signature, err := tester.SignQuery()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tester.RangeEnd++
+	if CheckQuery(lab, alicePolicy, tester, signature, 3, true) {
+		t.Error("signature of another query is accepted")
+	}
+
+}
@@ -3,41 +3,205 @@ package tester
 import (
 	"crypto/ecdsa"
 	"crypto/rand"
+	"errors"
 	"fmt"
-	"log"
 	"math/big"
-	mathRand "math/rand"
+	"os"
+	"sort"
 	"sync"
-	"time"
 
+	"github.com/eozturk1/genomic-security-journal-code/entities/regulator"
 	sl "github.com/eozturk1/genomic-security-journal-code/entities/sequencinglab"
+	"github.com/eozturk1/genomic-security-journal-code/helpers/addhomencer"
 	"github.com/eozturk1/genomic-security-journal-code/helpers/env"
 	bp "github.com/ing-bank/zkrp/bulletproofs"
 	"github.com/ing-bank/zkrp/ccs08"
 	"github.com/ing-bank/zkrp/crypto/p256"
-	"github.com/ing-bank/zkrp/util"
 )
 
 type Tester struct {
+	ID               string // the identity that the policies of Alice allow or not, proven by identityKey (see SetIdentity)
+	identityKey      *ecdsa.PrivateKey
 	EncryptedMarker  []*env.Cipher
 	lab              *sl.SequencingLab
 	startingPosition uint32
 	endingPosition   uint32
 	RangeStart       uint32
 	RangeEnd         uint32
+
+	// packed mode (see SetupPacked): E(-T_b) for the blocks that the marker covers entirely, from firstBlock on
+	PackedMarker []*env.Cipher
+	firstBlock   uint32
+
+	// output of the setup ceremony of CCS08 range proofs (see SetupCCS08), with the tester's private key
+	CCS08Params *ccs08.CCS08Params
+
+	// set mode (see SetupSet): the setup ceremony of the proofs that positions are in the panel, or in a gap between two
+	// consecutive positions of the panel
+	CCS08SetParams *ccs08.CCS08SetParams
+
+	// multi-range mode (see SetupRanges): the disjoint ranges of the query, sorted, each with its own marker
+	Ranges []*RangeQuery
+
+	// panel mode (see SetupPanel): the markers of the panel, each tested in one of Ranges, and the disclosure policy
+	PanelMarkers []*PanelMarker
+	PerMarker    bool
+
+	// logic mode (see SetupLogic): the expression over the markers of PanelMarkers
+	Logic *Logic
+
+	// what the results of a test reveal to Alice when she decrypts them
+	ResultMode ResultMode
+
+	// well-formed mode (see SetupWellFormed): the proofs of the ciphertexts of EncryptedMarker, and of the results of
+	// the last test, all bound to Session, a nonce of the setup
+	MarkerProofs []*addhomencer.ORProof
+	ResultProof  *addhomencer.HidingProof
+	Session      []byte
+
+	// certified mode (see SetupCertified): the certificate of the marker by a regulator, the openings of its
+	// commitments, and the proofs that the ciphertexts of EncryptedMarker encrypt the certified bases
+	Certificate       *regulator.Certificate
+	openings          []*big.Int
+	CertificateProofs []*addhomencer.ORProof
+}
+
+// ResultMode is how the results of a test of a marker against the windows of Alice's ciphertexts are disclosed and
+// proven, as a set of the flags below.
+type ResultMode int
+
+const (
+	// PerWindow returns a blinded result per window, padded with encryptions of 1 up to the number of Alice's
+	// ciphertexts. A test by IsZero reveals only whether a window matches, but the padding decrypts to 1, so Alice learns
+	// the number of windows, hence the size of the marker, when she decrypts the results.
+	PerWindow ResultMode = 0
+
+	// SingleBit pads with encryptions of uniformly random nonzero values instead, which decrypt like the blinded results
+	// of the windows that do not match. Alice gets as many ciphertexts as she sent, whatever the marker, and learns only
+	// whether some window matches: the hashes bind positions, so at most one window can. Combining the windows by random
+	// linear combinations would not do, since a combination is 0 iff all of its windows are (a test of AND, not OR).
+	SingleBit ResultMode = 1
+
+	// TesterLearns has Alice return her verdict to the tester, with proofs of decryption of the results under her key
+	// (see addhomencer.ProveVerdict), which the tester verifies with VerifyVerdict.
+	TesterLearns ResultMode = 2
+
+	// WellFormed has the tester prove that its marker and its results are well formed (see SetupWellFormed), which
+	// Alice verifies before she decrypts the results.
+	WellFormed ResultMode = 4
+)
+
+// RangeQuery is one of the ranges of a query over several regions, with the marker tested in it.
+type RangeQuery struct {
+	RangeStart      uint32
+	RangeEnd        uint32
+	EncryptedMarker []*env.Cipher
+}
+
+// RangeSlice is the slice of Alice's signed tuples revealed for one range of a query: the positions in the range, with
+// the lower and upper boundary positions around them.
+type RangeSlice struct {
+	Comm   []*p256.P256
+	Cipher []*env.Cipher
+	Sig    []*env.ECDSASignature
 }
 
+// RangesPerProof is the number of ranges whose boundaries are proven by one aggregated Bulletproof in a multi-range query:
+// each range has two boundaries, of two values each, and the lab's parameters are set up for sl.RangeProofValues values.
+const RangesPerProof = sl.RangeProofValues / 4
+
+// ErrInvalidSignature is why the tester aborts on a ciphertext of Alice that is not signed by the lab.
+var ErrInvalidSignature = errors.New("signature of the sequencing lab does not verify")
+
+// alphabet is the alphabet of the bases of the sequencing lab.
+var alphabet = []uint8{'A', 'C', 'G', 'T'}
+
+// InAlphabet returns true if and only if the letter is a base of the alphabet of the sequencing lab.
+func InAlphabet(letter uint8) bool {
+
+	for _, base := range alphabet {
+		if base == letter {
+			return true
+		}
+	}
+	return false
+
+}
+
+// blinding weights of packed blocks: two nonzero differences cancel out with probability at most 2^(-packingWeightBits)
+const packingWeightBits = 128
+
 func (t *Tester) GetRangeQuery() (uint32, uint32) {
 
 	return t.RangeStart, t.RangeEnd
 
 }
 
+// Setup encrypts the marker under the lab's key. The profile is the one of the lab.
 func (t *Tester) Setup(lab *sl.SequencingLab, baseArray []*env.Base, secParam uint32) {
 
-	var wg sync.WaitGroup
+	t.setRange(lab, baseArray, secParam)
+	t.EncryptedMarker = t.encryptMarker(baseArray, func(uint32) bool { return true })
+	t.PackedMarker = nil
+
+}
+
+// SetupPacked prepares the marker for TestingWholePacked: the blocks entirely covered by the marker are encrypted in packed
+// form, and only the remaining positions at both ends of the marker are encrypted one by one. Entries of EncryptedMarker
+// of positions in packed blocks are left nil.
+func (t *Tester) SetupPacked(lab *sl.SequencingLab, baseArray []*env.Base, secParam uint32) {
+
+	t.setRange(lab, baseArray, secParam)
+
+	layout := lab.PackingLayout()
+	t.firstBlock = (t.startingPosition - 1 + layout.Slots - 1) / layout.Slots
+	numberOfBlocks := 0
+	if end := t.endingPosition / layout.Slots; end > t.firstBlock {
+		numberOfBlocks = int(end - t.firstBlock)
+	}
+	inBlock := func(position uint32) bool {
+		block, _ := layout.BlockOf(position)
+		return block >= t.firstBlock && block < t.firstBlock+uint32(numberOfBlocks)
+	}
+
+	t.EncryptedMarker = t.encryptMarker(baseArray, func(position uint32) bool { return !inBlock(position) })
+
+	packed := layout.Pack(baseArray)
+	plains := make([]*big.Int, numberOfBlocks)
+	for b := range plains {
+		plains[b] = packed[t.firstBlock+uint32(b)]
+	}
+	t.PackedMarker = lab.Ahe.EncryptInverseBatch(plains)
+
+}
+
+// UnpackedBlocks returns the blocks that hold the positions of the marker that SetupPacked left out of packed blocks, for
+// which the tester needs per-position ciphertexts (see SequencingLab.SequenceWholeSetUnpacked). They are the blocks of the
+// two ends of the marker, so the lab that serves them learns where the marker starts and ends, up to a block.
+func (t *Tester) UnpackedBlocks() []uint32 {
+
+	layout := t.lab.PackingLayout()
+	var blocks []uint32
+	for i, cipher := range t.EncryptedMarker {
+		if cipher == nil {
+			continue
+		}
+		block, _ := layout.BlockOf(t.startingPosition + uint32(i))
+		if len(blocks) == 0 || blocks[len(blocks)-1] != block {
+			blocks = append(blocks, block)
+		}
+	}
+	return blocks
+
+}
+
+func (t *Tester) setRange(lab *sl.SequencingLab, baseArray []*env.Base, secParam uint32) {
 
 	t.lab = lab
+	t.MarkerProofs, t.ResultProof = nil, nil
+	t.CertificateProofs = nil
+	t.Session = make([]byte, 16)
+	rand.Read(t.Session)
 	len := len(baseArray)
 	t.startingPosition = baseArray[0].Position
 	t.endingPosition = baseArray[len-1].Position
@@ -57,81 +221,226 @@ func (t *Tester) Setup(lab *sl.SequencingLab, baseArray []*env.Base, secParam ui
 	}
 	//fmt.Println("tester range: ", t.RangeStart, t.RangeEnd)
 
-	encryptedMarker := make([]*env.Cipher, len)
-
-	//fmt.Println("Tester's marker: [")
-	wg.Add(len)
-
-	for i := uint32(0); i < uint32(len); i++ {
+}
 
-		go func(i uint32, wg *sync.WaitGroup) {
-			hashBase := env.HashPositionAndBase(lab.Hash, baseArray[i].Position, baseArray[i])
-			encryptedMarker[i] = lab.Ahe.EncryptInverse(new(big.Int).SetBytes(hashBase))
+// encryptMarker returns E(-Hash(position, base)) for the bases of the marker selected by encrypt, and nil for the others.
+func (t *Tester) encryptMarker(baseArray []*env.Base, encrypt func(position uint32) bool) []*env.Cipher {
 
-			//fmt.Printf("%v ", baseArray[i])
-			wg.Done()
-		}(i, &wg)
+	lab := t.lab
+	encryptedMarker := make([]*env.Cipher, len(baseArray))
 
+	//fmt.Println("Tester's marker: [")
+	var selected []int
+	var hashedMarker []*big.Int
+	for i, base := range baseArray {
+		if encrypt(base.Position) {
+			hashBase := env.HashPositionAndBase(lab.Hash, base.Position, base)
+			selected = append(selected, i)
+			hashedMarker = append(hashedMarker, new(big.Int).SetBytes(hashBase))
+		}
+		//fmt.Printf("%v ", base)
 	}
 	//fmt.Println("]\n")
-	wg.Wait()
 
-	t.EncryptedMarker = encryptedMarker
+	for k, cipher := range lab.Ahe.EncryptInverseBatch(hashedMarker) {
+		encryptedMarker[selected[k]] = cipher
+	}
+
+	return encryptedMarker
 
 }
 
 func (t *Tester) TestingWhole(ciphers []*env.Cipher, sigs []*env.ECDSASignature) *env.Cipher {
 
-	var wg sync.WaitGroup
+	// Check if given ciphertexts are verified by signatures in marker's positions
+	if int(t.startingPosition)+len(t.EncryptedMarker)-1 > len(ciphers) || len(sigs) != len(ciphers) {
+		fmt.Println("ciphertexts of the marker's positions are missing, so ABORT!")
+		return nil
+	}
+	err := verifyAll(len(t.EncryptedMarker), func(k int) error {
+		return t.verifyPosition(t.startingPosition+uint32(k), ciphers, sigs)
+	})
+	if err != nil {
+		fmt.Println("verification failed (", err, "), so ABORT!")
+		return nil
+	}
+	//fmt.Printf("All verifications from position %d to %d are PASSed!\n", t.startingPosition, t.startingPosition + uint32(len(t.EncryptedMarker)))
 
-	wg.Add(len(t.EncryptedMarker))
+	// Perform private testing: E(0) * E(a_1) * E(-t_1) * ... * E(a_m) * E(-t_m) in one batch product
+	//fmt.Println("Performing test..")
+	factors := make([]*env.Cipher, 0, 2*len(t.EncryptedMarker)+1)
+	factors = append(factors, t.lab.Ahe.Encrypt(big.NewInt(0)))
+	for i := uint32(0); i < uint32(len(t.EncryptedMarker)); i++ {
+		j := t.startingPosition + i - 1
+		factors = append(factors, ciphers[j], t.EncryptedMarker[i])
+	}
+	result, err := t.lab.Ahe.MultCipherArray(factors)
+	if err == nil {
+		result, err = t.blind(result)
+	}
+	if err != nil {
+		fmt.Println("testing failed (", err, "), so ABORT!")
+		return nil
+	}
 
-	// Check if given ciphertexts are verified by signatures in marker's positions
-	for i := t.startingPosition; i < t.startingPosition+uint32(len(t.EncryptedMarker)); i++ {
+	//fmt.Println("Returning the result..")
 
-		go func(i uint32, wg *sync.WaitGroup) {
-			hashResult := env.HashPositionAndCipher(t.lab.Hash, i, ciphers[i-1])
+	return result
 
-			verificationResult := ecdsa.Verify(t.lab.VerifyingKey, hashResult, sigs[i-1].R, sigs[i-1].S)
-			if !verificationResult {
-				fmt.Println("verification failed, so ABORT!")
-				log.Fatal()
-			}
-			wg.Done()
-		}(i, &wg)
+}
+
+// TestingWholePacked is TestingWhole for a marker set up by SetupPacked. Each packed block costs one multiplication and one
+// short exponentiation instead of a pair of multiplications per position; the positions at both ends of the marker are
+// tested on the per-position ciphertexts. The result is
+// E(r * (w_0 * sum_i (a_i - t_i) + sum_b w_b * (A_b - T_b))), with short random weights w so that differences cannot
+// cancel out across blocks, and r random so that only whether the result is zero is revealed.
+func (t *Tester) TestingWholePacked(ciphers []*env.Cipher, sigs []*env.ECDSASignature, blocks []*env.Cipher, blockSigs []*env.ECDSASignature) *env.Cipher {
 
+	numberOfBlocks := len(t.PackedMarker)
+	if int(t.firstBlock)+numberOfBlocks > len(blocks) {
+		fmt.Println("packed blocks are missing, so ABORT!")
+		return nil
+	}
+	for i, cipher := range t.EncryptedMarker {
+		j := int(t.startingPosition) + i
+		if cipher != nil && (j > len(ciphers) || j > len(sigs)) {
+			fmt.Println("per-position ciphertexts are missing, so ABORT!")
+			return nil
+		}
 	}
-	wg.Wait()
-	//fmt.Printf("All verifications from position %d to %d are PASSed!\n", t.startingPosition, t.startingPosition + uint32(len(t.EncryptedMarker)))
 
-	wg.Add(len(t.EncryptedMarker))
-	// Perform private testing
-	//fmt.Println("Performing test..")
-	result := t.lab.Ahe.Encrypt(big.NewInt(0))
+	// Check the signatures of the per-position ciphertexts at both ends of the marker and of the packed blocks
+	err := verifyAll(len(t.EncryptedMarker)+numberOfBlocks, func(k int) error {
+		if k >= len(t.EncryptedMarker) {
+			j := t.firstBlock + uint32(k-len(t.EncryptedMarker))
+			if err := t.lab.Ahe.ValidateCipher(blocks[j]); err != nil {
+				return err
+			}
+			if j >= uint32(len(blockSigs)) {
+				return ErrInvalidSignature
+			}
+			return t.verifySignature(env.HashBlockAndCipher(t.lab.Hash, j, blocks[j]), blockSigs[j])
+		}
+		if t.EncryptedMarker[k] == nil {
+			return nil
+		}
+		return t.verifyPosition(t.startingPosition+uint32(k), ciphers, sigs)
+	})
+	if err != nil {
+		fmt.Println("verification failed (", err, "), so ABORT!")
+		return nil
+	}
 
-	for i := uint32(0); i < uint32(len(t.EncryptedMarker)); i++ {
+	// Perform private testing: terms[0] is the sum over the ends of the marker, terms[b+1] the difference of block b, and
+	// the weighted sum of the terms is one multi-exponentiation
+	terms := make([]*env.Cipher, numberOfBlocks+1)
+	weights := make([]*big.Int, numberOfBlocks+1)
+	weightBound := new(big.Int).Lsh(big.NewInt(1), packingWeightBits)
 
-		go func(i uint32, wg *sync.WaitGroup) {
+	factors := []*env.Cipher{t.lab.Ahe.Encrypt(big.NewInt(0))}
+	for i := uint32(0); i < uint32(len(t.EncryptedMarker)); i++ {
+		if t.EncryptedMarker[i] != nil {
 			j := t.startingPosition + i - 1
-			mult := t.lab.Ahe.MultCiphers(ciphers[j], t.EncryptedMarker[i])
-			result = t.lab.Ahe.MultCiphers(result, mult)
-			wg.Done()
-		}(i, &wg)
+			factors = append(factors, ciphers[j], t.EncryptedMarker[i])
+		}
+	}
+	terms[0], err = t.lab.Ahe.MultCipherArray(factors)
+	weights[0] = packingWeight(weightBound)
+	for b := 0; b < numberOfBlocks && err == nil; b++ {
+		terms[b+1], err = t.lab.Ahe.MultCiphers(blocks[int(t.firstBlock)+b], t.PackedMarker[b])
+		weights[b+1] = packingWeight(weightBound)
+	}
+	var result *env.Cipher
+	if err == nil {
+		result, err = t.lab.Ahe.MultiExpCiphers(terms, weights)
+	}
+	if err == nil {
+		result, err = t.blind(result)
+	}
+	if err != nil {
+		fmt.Println("testing failed (", err, "), so ABORT!")
+		return nil
 	}
-	wg.Wait()
 
-	r, _ := rand.Int(rand.Reader, t.lab.Ahe.GetGroupOrder())
-	result = t.lab.Ahe.HideCipherWithR(result, r)
+	return result
 
-	//fmt.Println("Returning the result..")
+}
 
-	return result
+// verifyTuples checks the signed tuples of Alice: every ciphertext is valid, and sig[i] is the signature of the lab on
+// (comm[i], cipher[i], comm[i+1], cipher[i+1]) for i = 0..n, with n = len(cipher) - 2. It says why it aborts.
+func (t *Tester) verifyTuples(comm []*p256.P256, cipher []*env.Cipher, sig []*env.ECDSASignature) bool {
+
+	n := len(cipher) - 2
+	if n < 0 || len(comm) != n+2 || len(sig) != n+1 {
+		fmt.Println("tuples are malformed, so ABORT!")
+		return false
+	}
+	err := verifyAll(n+2, func(i int) error {
+		return t.lab.Ahe.ValidateCipher(cipher[i])
+	})
+	if err == nil {
+		err = verifyAll(n+1, func(i int) error {
+			return t.verifySignature(env.HashTuple(t.lab.Hash, comm[i], cipher[i], comm[i+1], cipher[i+1]), sig[i])
+		})
+	}
+	if err != nil {
+		fmt.Println("verification failed (", err, "), so ABORT!")
+		return false
+	}
+	return true
 
 }
 
-func (t *Tester) TestingSNP(comm []*p256.P256, cipher []*env.Cipher, sig []*env.ECDSASignature, pos_init, pos_end, salt_init, salt_end *big.Int, withOpt bool) []*env.Cipher {
+// verifyPosition checks that the ciphertext of the position is valid and signed by the lab.
+func (t *Tester) verifyPosition(position uint32, ciphers []*env.Cipher, sigs []*env.ECDSASignature) error {
 
-	var wg sync.WaitGroup
+	if err := t.lab.Ahe.ValidateCipher(ciphers[position-1]); err != nil {
+		return err
+	}
+	return t.verifySignature(env.HashPositionAndCipher(t.lab.Hash, position, ciphers[position-1]), sigs[position-1])
+
+}
+
+func (t *Tester) verifySignature(hashResult []byte, sig *env.ECDSASignature) error {
+
+	if sig == nil || sig.R == nil || sig.S == nil || !ecdsa.Verify(t.lab.VerifyingKey, hashResult, sig.R, sig.S) {
+		return ErrInvalidSignature
+	}
+	return nil
+
+}
+
+// blind raises the result of a test to a random unit of the plaintext group, so that it reveals only whether it is zero.
+func (t *Tester) blind(c *env.Cipher) (*env.Cipher, error) {
+
+	r, err := addhomencer.RandomBlinding(t.lab.Ahe)
+	if err != nil {
+		return nil, err
+	}
+	return t.lab.Ahe.HideCipherWithR(c, r)
+
+}
+
+// packingWeight returns a random weight in [1, bound)
+func packingWeight(bound *big.Int) *big.Int {
+	for {
+		w, err := rand.Int(rand.Reader, bound)
+		if err != nil {
+			panic(err)
+		}
+		if w.Sign() != 0 {
+			return w
+		}
+	}
+}
+
+// PrecomputeTesting moves the public-key work of the encryptions of TestingSNP and TestingSNPRange to the offline phase:
+// a test over numOfCiphers ciphertexts of Alice outputs numOfCiphers fresh encryptions, of 0 or 1.
+func (t *Tester) PrecomputeTesting(numOfCiphers int) {
+	t.lab.Ahe.PrecomputeRandomness(numOfCiphers)
+}
+
+func (t *Tester) TestingSNP(comm []*p256.P256, cipher []*env.Cipher, sig []*env.ECDSASignature, pos_init, pos_end, salt_init, salt_end *big.Int, withOpt bool) []*env.Cipher {
 
 	// Check if all given commitments and ciphertexts are verified by signatures
 	n := len(cipher) - 2
@@ -144,45 +453,139 @@ func (t *Tester) TestingSNP(comm []*p256.P256, cipher []*env.Cipher, sig []*env.
 	}
 	//fmt.Println("Two boundary postions check passed!")
 
-	com_init, _ := util.CommitG1(pos_init, salt_init, t.lab.BPparams.H)
-	com_end, _ := util.CommitG1(pos_end, salt_end, t.lab.BPparams.H)
+	if !t.verifyTuples(comm, cipher, sig) {
+		return nil
+	}
+	//fmt.Printf("All tuple verifications (from %d to %d) PASSed!\n", 0, n)
+
+	com_init, _ := t.lab.CommitTables.CommitG1(pos_init, salt_init)
+	com_end, _ := t.lab.CommitTables.CommitG1(pos_end, salt_end)
 	if !env.CompareP256s(com_init, comm[0]) || !env.CompareP256s(com_end, comm[n+1]) {
 		fmt.Println("commitments for boundaries are not matching, so ABORT!")
 		return nil
 	}
 	//fmt.Println("Commitment checks for boundary positions passed!")
 
-	wg.Add(n + 1)
-	for i := uint32(0); i <= uint32(n); i++ {
+	result := t.privateTestingForSNP(n, cipher, t.EncryptedMarker, withOpt)
+	return result
+
+}
 
-		go func(i uint32, wg *sync.WaitGroup) {
-			hashResult := env.HashTuple(t.lab.Hash, comm[i], cipher[i], comm[i+1], cipher[i+1])
+// RangeProofIntervals returns the intervals [a[j], b[j]) of the range proof of the boundaries of the query:
+// the lower bound position in [0, RangeStart) and the upper bound position in [RangeEnd + 1, N + 10).
+// The proof is bound to them, so Alice must prove for exactly these (see bp.NewGenericAggregated).
+func (t *Tester) RangeProofIntervals() ([]int64, []int64) {
+	return []int64{0, int64(t.RangeEnd + 1)}, []int64{int64(t.RangeStart), int64(t.lab.GetMaxHumanGenomeSize() + 10)}
+}
 
-			verificationResult := ecdsa.Verify(t.lab.VerifyingKey, hashResult, sig[i].R, sig[i].S)
-			if !verificationResult {
-				fmt.Println("verification failed, so ABORT!")
-				log.Fatal()
-			}
-			wg.Done()
-		}(i, &wg)
+// zkrp:  bulletproof, one aggregated proof for both boundaries, made with the salts of the boundaries so that it proves
+// the positions of the signed commitments comm[0] and comm[len(comm)-1] (see bp.ProveGenericAggregatedCommitted)
+func (t *Tester) TestingSNPRange(comm []*p256.P256, cipher []*env.Cipher, sig []*env.ECDSASignature, proof *bp.AggregatedBulletProof, withOpt bool) []*env.Cipher {
 
+	if !t.verifyRangeProof(comm, proof) {
+		return nil
 	}
-	//fmt.Printf("All tuple verifications (from %d to %d) PASSed!\n", 0, n)
-	wg.Wait()
 
-	result := t.privateTestingForSNP(n, cipher, withOpt)
+	// Verify all the signatures
+	n := len(cipher) - 2
+	if !t.verifyTuples(comm, cipher, sig) {
+		return nil
+	}
+
+	result := t.privateTestingForSNP(n, cipher, t.EncryptedMarker, withOpt)
 	return result
 
 }
 
-// zkrp:  bulletproof
-func (t *Tester) TestingSNPRange(comm []*p256.P256, cipher []*env.Cipher, sig []*env.ECDSASignature, lproof *bp.ProofBPRP, hproof *bp.ProofBPRP, withOpt bool) []*env.Cipher {
+// verifyRangeProof verifies the aggregated Bulletproof of the boundaries of a range query, and that it is of the
+// commitments of the boundaries comm[0] and comm[len(comm)-1].
+func (t *Tester) verifyRangeProof(comm []*p256.P256, proof *bp.AggregatedBulletProof) bool {
 
-	var wg sync.WaitGroup
+	if len(comm) < 2 || proof == nil {
+		fmt.Println("Range query is malformed, so ABORT!")
+		return false
+	}
 
-	// Verify range proofs for boundaries
-	ok_l, _ := lproof.Verify()
-	ok_h, _ := hproof.Verify()
+	// Verify range proof for boundaries, and that it is of the boundaries of the query
+	a, b := t.RangeProofIntervals()
+	params, err := bp.NewGenericAggregated(a, b, t.lab.BPparams)
+	if err != nil {
+		fmt.Println("Range proof parameters are invalid, so ABORT!")
+		return false
+	}
+	ok, _ := proof.VerifyGeneric(params)
+	if !ok {
+		fmt.Println("Range proof result is invalid, so ABORT!")
+		return false
+	}
+	if !proof.CommitsTo(0, comm[0], params) || !proof.CommitsTo(1, comm[len(comm)-1], params) {
+		fmt.Println("Range proof is not of the boundaries, so ABORT!")
+		return false
+	}
+	//fmt.Println("Range proofs are passed!\n")
+	return true
+
+}
+
+// SetupCCS08 runs the setup ceremony of CCS08 range proofs for positions up to the genome size of the lab, with the base
+// of the profile. The signatures are under the tester's key, so that Alice cannot forge proofs: the tester runs it once
+// and publishes the parameters (see SaveCCS08Params), and later calls keep them.
+func (t *Tester) SetupCCS08(lab *sl.SequencingLab) error {
+
+	if t.CCS08Params != nil {
+		return nil
+	}
+	params, err := ccs08.NewCCS08Params(int64(lab.GetMaxHumanGenomeSize()+10), lab.Profile.CCS08Base)
+	if err != nil {
+		return err
+	}
+	t.CCS08Params = params
+	return nil
+
+}
+
+// SaveCCS08Params writes the public part of the CCS08 parameters to fileName, for the provers to load with
+// LoadCCS08Params.
+func (t *Tester) SaveCCS08Params(fileName string) error {
+
+	data, err := t.CCS08Params.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(fileName, data, 0644)
+
+}
+
+// LoadCCS08Params reads the CCS08 parameters published by a tester with SaveCCS08Params and checks their signatures.
+func LoadCCS08Params(fileName string) (*ccs08.CCS08Params, error) {
+
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	params := new(ccs08.CCS08Params)
+	if err := params.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	return params, nil
+
+}
+
+// zkrp: ccs08, the ccs08.IntervalProof of the boundaries for the intervals of RangeProofIntervals and the signed
+// commitments comm[0] and comm[len(comm)-1], made with the parameters of SetupCCS08
+func (t *Tester) TestingSNPRangeCCS08(comm []*p256.P256, cipher []*env.Cipher, sig []*env.ECDSASignature, lproofData, hproofData []byte, withOpt bool) []*env.Cipher {
+
+	// Verify range proofs for boundaries, against the tester's own parameters and intervals and the signed commitments
+	a, b := t.RangeProofIntervals()
+	var lproof, hproof ccs08.IntervalProof
+	if t.CCS08Params == nil || len(comm) < 2 || lproof.UnmarshalBinary(lproofData) != nil || hproof.UnmarshalBinary(hproofData) != nil {
+		fmt.Println("Range proofs are malformed, so ABORT!")
+		return nil
+	}
+	params := t.CCS08Params.Intervals()
+	h := t.lab.BPparams.H
+	ok_l := lproof.Verify(comm[0], h, a[0], b[0], params)
+	ok_h := hproof.Verify(comm[len(comm)-1], h, a[1], b[1], params)
 	if !(ok_l && ok_h) {
 		fmt.Println("l: ", ok_l, ", h: ", ok_h)
 		fmt.Println("Range proof result is invalid, so ABORT!")
@@ -192,140 +595,484 @@ func (t *Tester) TestingSNPRange(comm []*p256.P256, cipher []*env.Cipher, sig []
 
 	// Verify all the signatures
 	n := len(cipher) - 2
+	if !t.verifyTuples(comm, cipher, sig) {
+		return nil
+	}
+
+	result := t.privateTestingForSNP(n, cipher, t.EncryptedMarker, withOpt)
+	return result
+
+}
+
+// SetupRanges prepares a query over several regions: each marker is queried in its own range, [s - p, e + p] for its
+// first and last positions s and e, and the ranges have to be disjoint. The ranges are sorted by position.
+func (t *Tester) SetupRanges(lab *sl.SequencingLab, markers [][]*env.Base, secParam uint32) error {
+
+	if len(markers) == 0 {
+		return errors.New("no marker to query")
+	}
+	ranges := make([]*RangeQuery, len(markers))
+	for j, marker := range markers {
+		t.setRange(lab, marker, secParam)
+		ranges[j] = &RangeQuery{
+			RangeStart:      t.RangeStart,
+			RangeEnd:        t.RangeEnd,
+			EncryptedMarker: t.encryptMarker(marker, func(uint32) bool { return true }),
+		}
+	}
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].RangeStart < ranges[j].RangeStart })
+	for j := 1; j < len(ranges); j++ {
+		if ranges[j].RangeStart <= ranges[j-1].RangeEnd {
+			return errors.New("ranges of the markers overlap")
+		}
+	}
+
+	// RangeStart and RangeEnd span all the ranges, and the single-range marker is unused
+	t.Ranges = ranges
+	t.RangeStart, t.RangeEnd = ranges[0].RangeStart, ranges[len(ranges)-1].RangeEnd
+	t.EncryptedMarker = nil
+	t.PackedMarker = nil
+	t.PanelMarkers = nil
+	t.Logic = nil
+	return nil
+
+}
+
+// RangesProofIntervals returns the intervals of the k-th aggregated range proof of a multi-range query, for the ranges
+// from k*RangesPerProof on: as in RangeProofIntervals, the lower and then the upper boundary of each range.
+func (t *Tester) RangesProofIntervals(k int) ([]int64, []int64) {
+
+	var a, b []int64
+	N := int64(t.lab.GetMaxHumanGenomeSize())
+	for j := k * RangesPerProof; j < (k+1)*RangesPerProof && j < len(t.Ranges); j++ {
+		a = append(a, 0, int64(t.Ranges[j].RangeEnd+1))
+		b = append(b, int64(t.Ranges[j].RangeStart), N+10)
+	}
+	return a, b
+
+}
+
+// NumOfRangeProofs returns the number of aggregated range proofs of a multi-range query.
+func (t *Tester) NumOfRangeProofs() int {
+	return (len(t.Ranges) + RangesPerProof - 1) / RangesPerProof
+}
+
+// zkrp: bulletproof, for a multi-range query, with slices[j] the slice of the j-th range and proofs[k] the aggregated
+// proof of the boundaries for the intervals of RangesProofIntervals(k), made with the salts of the boundaries so that
+// they prove the positions of the signed commitments (see bp.ProveGenericAggregatedCommitted)
+func (t *Tester) TestingSNPRanges(slices []*RangeSlice, proofs []*bp.AggregatedBulletProof, withOpt bool) []*env.Cipher {
+
+	if t.PanelMarkers != nil || !t.verifyRangeProofs(slices, proofs) || !t.verifySlices(slices) {
+		return nil
+	}
+	return t.testRanges(slices, withOpt)
+
+}
+
+// zkrp: ccs08, for a multi-range query, with lproofData[j] and hproofData[j] the ccs08.IntervalProof of the boundaries
+// of the j-th range, for its intervals and the signed commitments, made with the parameters of SetupCCS08
+func (t *Tester) TestingSNPRangesCCS08(slices []*RangeSlice, lproofData, hproofData [][]byte, withOpt bool) []*env.Cipher {
 
-	wg.Add(n + 1)
+	if t.PanelMarkers != nil || !t.verifyRangeProofsCCS08(slices, lproofData, hproofData) || !t.verifySlices(slices) {
+		return nil
+	}
+	return t.testRanges(slices, withOpt)
+
+}
 
-	for i := uint32(0); i <= uint32(n); i++ {
+// verifyRangeProofs verifies the aggregated Bulletproofs of the boundaries of the slices of Ranges.
+func (t *Tester) verifyRangeProofs(slices []*RangeSlice, proofs []*bp.AggregatedBulletProof) bool {
 
-		go func(i uint32, wg *sync.WaitGroup) {
-			hashResult := env.HashTuple(t.lab.Hash, comm[i], cipher[i], comm[i+1], cipher[i+1])
+	if len(slices) != len(t.Ranges) || len(proofs) != t.NumOfRangeProofs() {
+		fmt.Println("Multi-range query is malformed, so ABORT!")
+		return false
+	}
 
-			verificationResult := ecdsa.Verify(t.lab.VerifyingKey, hashResult, sig[i].R, sig[i].S)
-			if !verificationResult {
-				fmt.Println("verification failed, so ABORT!")
-				log.Fatal()
+	if !boundariesPresent(slices) {
+		return false
+	}
+
+	// Verify range proofs for boundaries, and that they are of the boundaries of the slices
+	for k, proof := range proofs {
+		a, b := t.RangesProofIntervals(k)
+		params, err := bp.NewGenericAggregated(a, b, t.lab.BPparams)
+		if err != nil {
+			fmt.Println("Range proof parameters are invalid, so ABORT!")
+			return false
+		}
+		ok, _ := proof.VerifyGeneric(params)
+		if !ok {
+			fmt.Println("Range proof result is invalid, so ABORT!")
+			return false
+		}
+		for i := 0; i < len(a)/2; i++ {
+			comm := slices[k*RangesPerProof+i].Comm
+			if !proof.CommitsTo(2*i, comm[0], params) || !proof.CommitsTo(2*i+1, comm[len(comm)-1], params) {
+				fmt.Println("Range proof is not of the boundaries of slice ", k*RangesPerProof+i, ", so ABORT!")
+				return false
 			}
-			wg.Done()
-		}(i, &wg)
+		}
+	}
+	//fmt.Println("Range proofs are passed!\n")
+	return true
+
+}
+
+// verifyRangeProofsCCS08 verifies the CCS08 proofs of the boundaries of the slices of Ranges.
+func (t *Tester) verifyRangeProofsCCS08(slices []*RangeSlice, lproofData, hproofData [][]byte) bool {
+
+	if t.CCS08Params == nil || len(slices) != len(t.Ranges) || len(lproofData) != len(t.Ranges) || len(hproofData) != len(t.Ranges) {
+		fmt.Println("Multi-range query is malformed, so ABORT!")
+		return false
+	}
+	if !boundariesPresent(slices) {
+		return false
+	}
+
+	// Verify range proofs for boundaries, against the tester's own parameters and intervals and the signed commitments
+	params := t.CCS08Params.Intervals()
+	h := t.lab.BPparams.H
+	for k := 0; k < t.NumOfRangeProofs(); k++ {
+		a, b := t.RangesProofIntervals(k)
+		for i := 0; i < len(a)/2; i++ {
+			j := k*RangesPerProof + i
+			var lproof, hproof ccs08.IntervalProof
+			if lproof.UnmarshalBinary(lproofData[j]) != nil || hproof.UnmarshalBinary(hproofData[j]) != nil {
+				fmt.Println("Range proofs are malformed, so ABORT!")
+				return false
+			}
+			comm := slices[j].Comm
+			ok_l := lproof.Verify(comm[0], h, a[2*i], b[2*i], params)
+			ok_h := hproof.Verify(comm[len(comm)-1], h, a[2*i+1], b[2*i+1], params)
+			if !(ok_l && ok_h) {
+				fmt.Println("range: ", j, ", l: ", ok_l, ", h: ", ok_h)
+				fmt.Println("Range proof result is invalid, so ABORT!")
+				return false
+			}
+		}
+	}
+	//fmt.Println("Range proofs are passed!\n")
+	return true
+
+}
+
+// boundariesPresent returns true if and only if every slice of a multi-range query has commitments of its boundaries.
+func boundariesPresent(slices []*RangeSlice) bool {
+
+	for _, slice := range slices {
+		if slice == nil || len(slice.Comm) < 2 {
+			fmt.Println("Multi-range query is malformed, so ABORT!")
+			return false
+		}
+	}
+	return true
+
+}
+
+// verifySlices verifies the signatures of every slice of a multi-range query, once per slice.
+func (t *Tester) verifySlices(slices []*RangeSlice) bool {
+
+	for _, slice := range slices {
+		if !t.verifyTuples(slice.Comm, slice.Cipher, slice.Sig) {
+			return false
+		}
 	}
 	//fmt.Println("All tuple verifications of input values PASSed!")
-	wg.Wait()
+	return true
 
-	result := t.privateTestingForSNP(n, cipher, withOpt)
+}
+
+// testRanges tests the marker of each range against its slice, and returns all the results shuffled together.
+func (t *Tester) testRanges(slices []*RangeSlice, withOpt bool) []*env.Cipher {
+
+	var result []*env.Cipher
+	for j, slice := range slices {
+		sliceResult := t.privateTestingForSNP(len(slice.Cipher)-2, slice.Cipher, t.Ranges[j].EncryptedMarker, withOpt)
+		if sliceResult == nil {
+			return nil
+		}
+		result = append(result, sliceResult...)
+	}
+	if err := shuffle(len(result), func(i, j int) { result[i], result[j] = result[j], result[i] }); err != nil {
+		fmt.Println("testing failed (", err, "), so ABORT!")
+		return nil
+	}
 	return result
 
 }
 
-// zkrp: ccs08
-func (t *Tester) TestingSNPRangeCCS08(comm []*p256.P256, cipher []*env.Cipher, sig []*env.ECDSASignature, lproof *ccs08.CCS08Custom, hproof *ccs08.CCS08Custom, withOpt bool) []*env.Cipher {
+// SetupSet prepares a query over a sparse panel of positions, the positions of the marker, instead of a range: the
+// queried range is [first, last] position of the panel, with no security parameter, and the sets of the panel and of its
+// gaps are signed under the tester's key (see ccs08.NewCCS08SetParams). The tester publishes CCS08SetParams.MarshalBinary.
+func (t *Tester) SetupSet(lab *sl.SequencingLab, baseArray []*env.Base) error {
+
+	t.setRange(lab, baseArray, 0)
+	t.EncryptedMarker = t.encryptMarker(baseArray, func(uint32) bool { return true })
+	t.PackedMarker = nil
+
+	if err := t.SetupCCS08(lab); err != nil {
+		return err
+	}
+	panel := make([]int64, len(baseArray))
+	for i, base := range baseArray {
+		panel[i] = int64(base.Position)
+	}
+	params, err := ccs08.NewCCS08SetParams(panel, t.CCS08Params)
+	if err != nil {
+		return err
+	}
+	t.CCS08SetParams = params
+	return nil
+
+}
+
+// SetQueryProofs are the proofs of Alice for a query over a panel: Lower and Upper are the ccs08.IntervalProof of the
+// boundaries for the intervals of RangeProofIntervals, and Interior[i] is the ccs08.MembershipProof of the i-th revealed
+// position if Members[i], or its ccs08.GapProof otherwise. All of them are exported with MarshalBinary. Members is sent
+// in the clear, as the tester has to know which ciphertexts to test, so it reveals which revealed positions are in the
+// panel (see TestingSNPSet).
+type SetQueryProofs struct {
+	Lower, Upper []byte
+	Members      []bool
+	Interior     [][]byte
+}
+
+// zkrp: ccs08 set membership, with the parameters of SetupSet. Each revealed position is proven to be either in the panel
+// or in a gap of it, so that the signed tuples cover the panel with no position left out. The tester learns Members, the
+// pattern of the revealed positions that are in the panel among those in its gaps: with the panel, it learns how many of
+// Alice's SNP positions lie before, between and after the members that she has, and, when she has all the positions of
+// the panel, how many lie in each gap. It does not learn the positions in the gaps, nor, when she lacks some positions
+// of the panel, which ones.
+func (t *Tester) TestingSNPSet(comm []*p256.P256, cipher []*env.Cipher, sig []*env.ECDSASignature, proofs *SetQueryProofs, withOpt bool) []*env.Cipher {
 
 	var wg sync.WaitGroup
 
+	n := len(cipher) - 2
+	if t.CCS08SetParams == nil || n < 0 || len(comm) != n+2 || len(sig) != n+1 ||
+		len(proofs.Members) != n || len(proofs.Interior) != n {
+		fmt.Println("Set query is malformed, so ABORT!")
+		return nil
+	}
+	h := t.lab.BPparams.H
+
 	// Verify range proofs for boundaries
-	ok_l := lproof.Verify()
-	ok_h := hproof.Verify()
+	a, b := t.RangeProofIntervals()
+	var lproof, hproof ccs08.IntervalProof
+	if lproof.UnmarshalBinary(proofs.Lower) != nil || hproof.UnmarshalBinary(proofs.Upper) != nil {
+		fmt.Println("Range proofs are malformed, so ABORT!")
+		return nil
+	}
+	ok_l := lproof.Verify(comm[0], h, a[0], b[0], t.CCS08SetParams)
+	ok_h := hproof.Verify(comm[n+1], h, a[1], b[1], t.CCS08SetParams)
 	if !(ok_l && ok_h) {
 		fmt.Println("l: ", ok_l, ", h: ", ok_h)
 		fmt.Println("Range proof result is invalid, so ABORT!")
 		return nil
 	}
-	//fmt.Println("Range proofs are passed!\n")
-
-	// Verify all the signatures
-	n := len(cipher) - 2
 
-	wg.Add(n + 1)
-
-	for i := uint32(0); i <= uint32(n); i++ {
-
-		go func(i uint32, wg *sync.WaitGroup) {
-			hashResult := env.HashTuple(t.lab.Hash, comm[i], cipher[i], comm[i+1], cipher[i+1])
-
-			verificationResult := ecdsa.Verify(t.lab.VerifyingKey, hashResult, sig[i].R, sig[i].S)
-			if !verificationResult {
-				fmt.Println("verification failed, so ABORT!")
-				log.Fatal()
+	// Verify the membership and gap proofs of the revealed positions
+	valid := make([]bool, n)
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func(i int, wg *sync.WaitGroup) {
+			if proofs.Members[i] {
+				var proof ccs08.MembershipProof
+				valid[i] = proof.UnmarshalBinary(proofs.Interior[i]) == nil && proof.Verify(comm[i+1], h, t.CCS08SetParams)
+			} else {
+				var proof ccs08.GapProof
+				valid[i] = proof.UnmarshalBinary(proofs.Interior[i]) == nil && proof.Verify(comm[i+1], h, t.CCS08SetParams)
 			}
 			wg.Done()
 		}(i, &wg)
 	}
-	//fmt.Println("All tuple verifications of input values PASSed!")
+	wg.Wait()
+	for i := range valid {
+		if !valid[i] {
+			fmt.Println("Set membership proof ", i, " is invalid, so ABORT!")
+			return nil
+		}
+	}
+	//fmt.Println("Set membership proofs are passed!\n")
 
-	result := t.privateTestingForSNP(n, cipher, withOpt)
+	// Verify all the signatures
+	if !t.verifyTuples(comm, cipher, sig) {
+		return nil
+	}
+
+	// test the ciphertexts of the positions in the panel, in order, between the boundaries
+	selected := []*env.Cipher{cipher[0]}
+	for i, member := range proofs.Members {
+		if member {
+			selected = append(selected, cipher[i+1])
+		}
+	}
+	selected = append(selected, cipher[n+1])
+	result := t.privateTestingForSNP(len(selected)-2, selected, t.EncryptedMarker, withOpt)
 	return result
 
 }
 
-func (t *Tester) privateTestingForSNP(numOfCiphers int, inputCipher []*env.Cipher, withOpt bool) []*env.Cipher {
+// privateTestingForSNP tests the marker against every window of consecutive ciphertexts of inputCipher[1:numOfCiphers+1].
+func (t *Tester) privateTestingForSNP(numOfCiphers int, inputCipher []*env.Cipher, marker []*env.Cipher, withOpt bool) []*env.Cipher {
 
 	var wg sync.WaitGroup
 
-	numOfMarkers := len(t.EncryptedMarker)
+	numOfMarkers := len(marker)
+
+	if t.ResultMode&WellFormed != 0 && (t.MarkerProofs != nil || t.CertificateProofs != nil) {
+		return t.provenTestingForSNP(numOfCiphers, inputCipher, marker)
+	}
+
+	// fewer positions than the marker, so there is no match
+	if numOfCiphers < numOfMarkers {
+		return t.padding(numOfCiphers)
+	}
 
 	// random permutation for shuffling the order
-	mathRand.Seed(time.Now().UnixNano())
-	perm := mathRand.Perm(numOfCiphers)
+	perm, err := randomPerm(numOfCiphers)
+	if err != nil {
+		fmt.Println("testing failed (", err, "), so ABORT!")
+		return nil
+	}
 
 	result := make([]*env.Cipher, numOfCiphers)
 
 	// with no optimization
 	if !withOpt {
+		errs := make([]error, numOfCiphers-numOfMarkers+1)
 		wg.Add(numOfCiphers - numOfMarkers + 1)
 		for i := 0; i <= numOfCiphers-numOfMarkers; i++ {
 			go func(i int, wg *sync.WaitGroup) {
-				result[perm[i]] = t.lab.Ahe.Encrypt(big.NewInt(0))
+				factors := make([]*env.Cipher, 0, 2*numOfMarkers+1)
+				factors = append(factors, t.lab.Ahe.Encrypt(big.NewInt(0)))
 
 				for j := 0; j <= numOfMarkers-1; j++ {
 
 					k := i + j + 1
-					mult := t.lab.Ahe.MultCiphers(inputCipher[k], t.EncryptedMarker[j])
-					result[perm[i]] = t.lab.Ahe.MultCiphers(result[perm[i]], mult)
+					factors = append(factors, inputCipher[k], marker[j])
 
 				}
 
-				r, _ := rand.Int(rand.Reader, t.lab.Ahe.GetGroupOrder())
-				result[perm[i]] = t.lab.Ahe.HideCipherWithR(result[perm[i]], r)
+				result[perm[i]], errs[i] = t.lab.Ahe.MultCipherArray(factors)
+				if errs[i] == nil {
+					result[perm[i]], errs[i] = t.blind(result[perm[i]])
+				}
 				wg.Done()
 			}(i, &wg)
 
 		}
 		wg.Wait()
+		for _, err := range errs {
+			if err != nil {
+				fmt.Println("testing failed (", err, "), so ABORT!")
+				return nil
+			}
+		}
 	} else { // with optimization
-		// first round: compute (1) = E(a_1) E(a_2) ... E(a_m) E(-t_1) E(-t_2) ... E(-t_m)
-		result[perm[0]] = t.lab.Ahe.Encrypt(big.NewInt(0))
-		for j := 0; j <= numOfMarkers-1; j++ {
-			k := j + 1
-			mult := t.lab.Ahe.MultCiphers(inputCipher[k], t.EncryptedMarker[j])
-			result[perm[0]] = t.lab.Ahe.MultCiphers(result[perm[0]], mult)
+		if err := t.slideWindows(numOfCiphers, inputCipher, marker, perm, result); err != nil {
+			fmt.Println("testing failed (", err, "), so ABORT!")
+			return nil
 		}
+	}
+
+	// generate additional results, to hide the size of marker
+	for k, cipher := range t.padding(numOfMarkers - 1) {
+		result[perm[numOfCiphers-numOfMarkers+1+k]] = cipher
+	}
+
+	return result
+
+}
+
+// slideWindows computes the results of privateTestingForSNP with optimization, each window from the one before it.
+func (t *Tester) slideWindows(numOfCiphers int, inputCipher []*env.Cipher, marker []*env.Cipher, perm []int, result []*env.Cipher) error {
 
-		tmp := result[perm[0]]
-		r, _ := rand.Int(rand.Reader, t.lab.Ahe.GetGroupOrder())
-		result[perm[0]] = t.lab.Ahe.HideCipherWithR(result[perm[0]], r)
-		//fmt.Println("i: 0, isZero?: ", t.lab.Ahe.IsZero(result[perm[0]]))
+	ahe := t.lab.Ahe
+	numOfMarkers := len(marker)
 
-		// iterate this from second to n-m+1 round: (i+1) = (i) (E(a_i))^-1 E(a_m+i) (== E(a_i+1) E(a_i+2) ... E(a_i+m) E(-t_1) ... E(-t_m) )
-		for i := 1; i <= numOfCiphers-numOfMarkers; i++ {
-			result[perm[i]] = t.lab.Ahe.Encrypt(big.NewInt(0))
-			tmp = t.lab.Ahe.MultCiphers(tmp, t.lab.Ahe.InvertCipher(inputCipher[i]))
-			tmp = t.lab.Ahe.MultCiphers(tmp, inputCipher[i+numOfMarkers])
-			result[perm[i]] = t.lab.Ahe.MultCiphers(result[perm[i]], tmp)
-			r, _ := rand.Int(rand.Reader, t.lab.Ahe.GetGroupOrder())
-			result[perm[i]] = t.lab.Ahe.HideCipherWithR(result[perm[i]], r)
-			//fmt.Println("i: ", i, ", isZero?: ", t.lab.Ahe.IsZero(result[perm[i]]))
+	// first round: compute (1) = E(a_1) E(a_2) ... E(a_m) E(-t_1) E(-t_2) ... E(-t_m)
+	tmp := ahe.Encrypt(big.NewInt(0))
+	for j := 0; j <= numOfMarkers-1; j++ {
+		k := j + 1
+		mult, err := ahe.MultCiphers(inputCipher[k], marker[j])
+		if err != nil {
+			return err
+		}
+		if tmp, err = ahe.MultCiphers(tmp, mult); err != nil {
+			return err
 		}
 	}
 
-	// generate additional results, to hide the size of marker
-	wg.Add(numOfMarkers - 1)
-	for i := numOfCiphers - numOfMarkers + 1; i <= numOfCiphers-1; i++ {
-		go func(i int, wg *sync.WaitGroup) {
-			result[perm[i]] = t.lab.Ahe.Encrypt(big.NewInt(1))
-			wg.Done()
-		}(i, &wg)
+	var err error
+	if result[perm[0]], err = t.blind(tmp); err != nil {
+		return err
 	}
-	wg.Wait()
+	//fmt.Println("i: 0, isZero?: ", t.lab.Ahe.IsZero(result[perm[0]]))
 
-	return result
+	// iterate this from second to n-m+1 round: (i+1) = (i) (E(a_i))^-1 E(a_m+i) (== E(a_i+1) E(a_i+2) ... E(a_i+m) E(-t_1) ... E(-t_m) )
+	for i := 1; i <= numOfCiphers-numOfMarkers; i++ {
+		inverse, err := ahe.InvertCipher(inputCipher[i])
+		if err == nil {
+			tmp, err = ahe.MultCiphers(tmp, inverse)
+		}
+		if err == nil {
+			tmp, err = ahe.MultCiphers(tmp, inputCipher[i+numOfMarkers])
+		}
+		if err == nil {
+			result[perm[i]], err = ahe.MultCiphers(ahe.Encrypt(big.NewInt(0)), tmp)
+		}
+		if err == nil {
+			result[perm[i]], err = t.blind(result[perm[i]])
+		}
+		if err != nil {
+			return err
+		}
+		//fmt.Println("i: ", i, ", isZero?: ", t.lab.Ahe.IsZero(result[perm[i]]))
+	}
+	return nil
+
+}
+
+// randomPerm returns a uniformly random permutation of [0, n), drawn from crypto/rand: the order of the results hides
+// which window matches, so it must not be predictable.
+func randomPerm(n int) ([]int, error) {
+
+	perm := make([]int, n)
+	for i := range perm {
+		perm[i] = i
+	}
+	if err := shuffle(n, func(i, j int) { perm[i], perm[j] = perm[j], perm[i] }); err != nil {
+		return nil, err
+	}
+	return perm, nil
+
+}
+
+// shuffle is a Fisher-Yates shuffle of n elements with crypto/rand, which swap exchanges.
+func shuffle(n int, swap func(i, j int)) error {
+
+	for i := n - 1; i > 0; i-- {
+		j, err := rand.Int(rand.Reader, big.NewInt(int64(i+1)))
+		if err != nil {
+			return err
+		}
+		swap(i, int(j.Int64()))
+	}
+	return nil
+
+}
+
+// padding returns count results that match no window: encryptions of 1, or of random nonzero values in SingleBit mode.
+func (t *Tester) padding(count int) []*env.Cipher {
+
+	plains := make([]*big.Int, count)
+	for k := range plains {
+		if t.ResultMode&SingleBit != 0 {
+			plains[k], _ = addhomencer.RandomBlinding(t.lab.Ahe)
+		} else {
+			plains[k] = big.NewInt(1)
+		}
+	}
+	return t.lab.Ahe.EncryptBatch(plains)
 
 }
@@ -0,0 +1,79 @@
+package tester
+
+import (
+	"math/big"
+	"testing"
+
+	sl "github.com/eozturk1/genomic-security-journal-code/entities/sequencinglab"
+	"github.com/eozturk1/genomic-security-journal-code/helpers/profiles"
+	bp "github.com/ing-bank/zkrp/bulletproofs"
+	"github.com/ing-bank/zkrp/crypto/p256"
+	"github.com/ing-bank/zkrp/util"
+)
+
+func TestRangeProofIsOfTheBoundaries(t *testing.T) {
+
+	lab := &sl.SequencingLab{Profile: profiles.Default}
+	params, err := bp.SetupAggregated(int64(profiles.Default.RangeBits), sl.RangeProofValues)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lab.BPparams = params
+	lab.CommitTables = util.NewCommitTables(params.H)
+	tester := &Tester{lab: lab, RangeStart: 100, RangeEnd: 199}
+
+	positions := []*big.Int{big.NewInt(90), big.NewInt(150), big.NewInt(210)}
+	salts := []*big.Int{big.NewInt(11), big.NewInt(12), big.NewInt(13)}
+	comm := make([]*p256.P256, len(positions))
+	for i := range positions {
+		comm[i], _ = lab.CommitTables.CommitG1(positions[i], salts[i])
+	}
+	a, b := tester.RangeProofIntervals()
+	generic, err := bp.NewGenericAggregated(a, b, lab.BPparams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bounds := []*big.Int{positions[0], positions[2]}
+
+	committed, err := bp.ProveGenericAggregatedCommitted(bounds, []*big.Int{salts[0], salts[2]}, generic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !tester.verifyRangeProof(comm, &committed) {
+		t.Error("proof of the committed boundaries is rejected")
+	}
+
+	// a valid proof for the same positions, but with randomness of its own, proves nothing about the commitments
+	unbound, err := bp.ProveGenericAggregated(bounds, generic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tester.verifyRangeProof(comm, &unbound) {
+		t.Error("proof of other commitments is accepted")
+	}
+	if tester.verifyRangeProof(comm[:1], &committed) {
+		t.Error("query without boundaries is accepted")
+	}
+
+}
+
+func TestRandomPermIsAPermutation(t *testing.T) {
+
+	for _, n := range []int{0, 1, 2, 100} {
+		perm, err := randomPerm(n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		seen := make([]bool, n)
+		for _, i := range perm {
+			if i < 0 || i >= n || seen[i] {
+				t.Fatalf("not a permutation of %d elements: %v", n, perm)
+			}
+			seen[i] = true
+		}
+		if len(perm) != n {
+			t.Fatalf("permutation of %d elements has %d", n, len(perm))
+		}
+	}
+
+}
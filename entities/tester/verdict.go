@@ -0,0 +1,44 @@
+package tester
+
+import (
+	"bufio"
+	"fmt"
+	"time"
+
+	sl "github.com/eozturk1/genomic-security-journal-code/entities/sequencinglab"
+	"github.com/eozturk1/genomic-security-journal-code/helpers/addhomencer"
+	"github.com/eozturk1/genomic-security-journal-code/helpers/env"
+)
+
+// VerifyVerdict checks Alice's verdict on the results of a test, and returns it. The tester learns no verdict from a
+// verdict whose proofs fail, and the error says so.
+func (t *Tester) VerifyVerdict(results []*env.Cipher, verdict *addhomencer.Verdict) (bool, error) {
+	return addhomencer.VerifyVerdict(t.lab.Ahe, results, verdict)
+}
+
+// VerdictForTester ends the online phase of the protocols in TesterLearns mode: Alice proves her verdict on the results,
+// and the tester verifies it. The times of both are written to w.
+func VerdictForTester(w *bufio.Writer, lab *sl.SequencingLab, tester *Tester, results []*env.Cipher) bool {
+
+	timestart := time.Now()
+	verdict, err := addhomencer.ProveVerdict(lab.Ahe, results)
+	timecheck := time.Since(timestart)
+	fmt.Println("Alice online phase is done")
+	fmt.Fprintln(w, timecheck.Microseconds())
+	if err != nil {
+		fmt.Println("Alice cannot prove her verdict, so ABORT!")
+		return false
+	}
+
+	timestart = time.Now()
+	match, err := tester.VerifyVerdict(results, verdict)
+	timecheck = time.Since(timestart)
+	fmt.Println("Tester verification phase is done")
+	fmt.Fprintln(w, timecheck.Microseconds())
+	if err != nil {
+		fmt.Println("verdict of Alice is not proven, so ABORT!")
+		return false
+	}
+	return match
+
+}
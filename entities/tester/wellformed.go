@@ -0,0 +1,236 @@
+package tester
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+
+	sl "github.com/eozturk1/genomic-security-journal-code/entities/sequencinglab"
+	"github.com/eozturk1/genomic-security-journal-code/helpers/addhomencer"
+	"github.com/eozturk1/genomic-security-journal-code/helpers/env"
+)
+
+// In well-formed mode, Alice does not take the tester's ciphertexts on trust:
+//   - each ciphertext of EncryptedMarker comes with a proof that it is E(-Hash(p, x)) for a position p of the queried
+//     range and a base x of alphabet (see MarkerCandidates), so that the marker covers no position outside of the range;
+//   - the results of a test come with a proof that each of them is a window of Alice's ciphertexts and the marker, or
+//     the padding E(1), raised to a unit r and rerandomized, and that each window is among them, so that one of the
+//     results is an encryption of 0 iff one of the windows matches.
+//
+// Alice checks both with CheckWellFormed before she decrypts the results. The proofs do not tell which candidate or
+// which window they are about, and the proof of the results shows that they are a shuffle of the windows and of the
+// padding, each used once. Their size is linear in the candidates and the windows: a marker costs 4 (RangeEnd -
+// RangeStart + 1) statements per position, and a test over n of Alice's ciphertexts n^2.
+
+var ErrBaseNotInAlphabet = errors.New("base of the marker is not in the alphabet")
+
+// MarkerCandidates returns Hash(p, x) for the positions p of [rangeStart, rangeEnd] and the bases x of alphabet, in
+// this order: the plaintexts, up to sign, of the ciphertexts of a well-formed marker.
+func MarkerCandidates(lab *sl.SequencingLab, rangeStart, rangeEnd uint32) []*big.Int {
+
+	candidates := make([]*big.Int, 0, int(rangeEnd-rangeStart+1)*len(alphabet))
+	for position := rangeStart; position <= rangeEnd; position++ {
+		for _, letter := range alphabet {
+			hashBase := env.HashPositionAndBase(lab.Hash, position, &env.Base{Position: position, Letter: letter})
+			candidates = append(candidates, new(big.Int).SetBytes(hashBase))
+		}
+	}
+	return candidates
+
+}
+
+// SetupWellFormed is Setup, with the proofs of the ciphertexts of the marker in MarkerProofs. The tests of the marker
+// then leave the proof of their results in ResultProof.
+func (t *Tester) SetupWellFormed(lab *sl.SequencingLab, baseArray []*env.Base, secParam uint32) error {
+
+	prover, ok := lab.Ahe.(addhomencer.WellFormednessProver)
+	if !ok {
+		return addhomencer.ErrNoWellFormednessProof
+	}
+	t.setRange(lab, baseArray, secParam)
+	t.PackedMarker = nil
+
+	candidates := MarkerCandidates(lab, t.RangeStart, t.RangeEnd)
+	indices := make([]int, len(baseArray))
+	for j, base := range baseArray {
+		letter := -1
+		for x := range alphabet {
+			if alphabet[x] == base.Letter {
+				letter = x
+			}
+		}
+		if letter < 0 {
+			return ErrBaseNotInAlphabet
+		}
+		indices[j] = int(base.Position-t.RangeStart)*len(alphabet) + letter
+	}
+
+	marker := make([]*env.Cipher, len(baseArray))
+	proofs := make([]*addhomencer.ORProof, len(baseArray))
+	errs := make([]error, len(baseArray))
+	var wg sync.WaitGroup
+	wg.Add(len(baseArray))
+	for j := range baseArray {
+		go func(j int, wg *sync.WaitGroup) {
+			marker[j], proofs[j], errs[j] = prover.EncryptInverseWithProof(t.Session, candidates, indices[j])
+			wg.Done()
+		}(j, &wg)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	t.EncryptedMarker, t.MarkerProofs = marker, proofs
+	return nil
+
+}
+
+// provenTestingForSNP is privateTestingForSNP in well-formed mode: the windows, each raised to a blinding exponent, and
+// the padding, raised to one only in SingleBit mode, are shuffled by HideCiphersWithProof.
+func (t *Tester) provenTestingForSNP(numOfCiphers int, inputCipher []*env.Cipher, marker []*env.Cipher) []*env.Cipher {
+
+	prover := t.lab.Ahe.(addhomencer.WellFormednessProver)
+	bases, err := windows(t.lab.Ahe, numOfCiphers, inputCipher, marker)
+	if err != nil {
+		fmt.Println("testing failed (", err, "), so ABORT!")
+		return nil
+	}
+
+	perm, err := randomPerm(numOfCiphers)
+	if err != nil {
+		fmt.Println("testing failed (", err, "), so ABORT!")
+		return nil
+	}
+	sources := make([]int, numOfCiphers)
+	rs := make([]*big.Int, numOfCiphers)
+	for i := 0; i < numOfCiphers; i++ {
+		sources[perm[i]] = i
+		rs[i], _ = addhomencer.RandomBlinding(t.lab.Ahe)
+	}
+	padding := prover.EncryptTrivially(big.NewInt(1))
+	result, proof, err := prover.HideCiphersWithProof(t.Session, bases, padding, t.ResultMode&SingleBit == 0, sources, rs)
+	if err != nil {
+		fmt.Println("proof of the results failed (", err, "), so ABORT!")
+		return nil
+	}
+
+	t.ResultProof = proof
+	return result
+
+}
+
+// windows returns E(a_(i+1) + ... + a_(i+m) - t_1 - ... - t_m), with no randomization, for the windows of inputCipher[1:
+// numOfCiphers+1] under the marker, which both the tester and Alice can compute.
+func windows(ahe addhomencer.AddHomEncer, numOfCiphers int, inputCipher []*env.Cipher, marker []*env.Cipher) ([]*env.Cipher, error) {
+
+	numOfMarkers := len(marker)
+	if numOfCiphers < numOfMarkers {
+		return nil, nil
+	}
+	result := make([]*env.Cipher, numOfCiphers-numOfMarkers+1)
+	factors := make([]*env.Cipher, 0, 2*numOfMarkers)
+	for j := 0; j < numOfMarkers; j++ {
+		factors = append(factors, inputCipher[j+1], marker[j])
+	}
+	var err error
+	if result[0], err = ahe.MultCipherArray(factors); err != nil {
+		return nil, err
+	}
+	for i := 1; i < len(result); i++ {
+		inverse, err := ahe.InvertCipher(inputCipher[i])
+		if err == nil {
+			result[i], err = ahe.MultCiphers(result[i-1], inverse)
+		}
+		if err == nil {
+			result[i], err = ahe.MultCiphers(result[i], inputCipher[i+numOfMarkers])
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+
+}
+
+// CheckWellFormed is Alice's check of the marker and of the results of the tester in WellFormed mode, before she
+// decrypts the results of the test over inputCipher. A certified marker was checked against its certificate already.
+func CheckWellFormed(lab *sl.SequencingLab, tester *Tester, certified bool, inputCipher, results []*env.Cipher) bool {
+
+	rangeStart, rangeEnd := tester.GetRangeQuery()
+	if !certified {
+		if err := VerifyMarker(lab, tester.Session, rangeStart, rangeEnd, tester.EncryptedMarker, tester.MarkerProofs); err != nil {
+			fmt.Println("marker of the tester is not well formed, so ABORT!")
+			return false
+		}
+	}
+	if err := VerifyResults(lab, tester.Session, inputCipher, tester.EncryptedMarker, results, tester.ResultMode&SingleBit == 0, tester.ResultProof); err != nil {
+		fmt.Println("results of the tester are not well formed, so ABORT!")
+		return false
+	}
+	return true
+
+}
+
+// VerifyMarker is Alice's check of a marker of the tester for the range [rangeStart, rangeEnd] in the session.
+func VerifyMarker(lab *sl.SequencingLab, session []byte, rangeStart, rangeEnd uint32, marker []*env.Cipher, proofs []*addhomencer.ORProof) error {
+
+	verifier, ok := lab.Ahe.(addhomencer.WellFormednessProver)
+	if !ok {
+		return addhomencer.ErrNoWellFormednessProof
+	}
+	if rangeStart > rangeEnd || len(marker) == 0 || len(marker) > int(rangeEnd-rangeStart+1) || len(proofs) != len(marker) {
+		return addhomencer.ErrInvalidWellFormednessProof
+	}
+	candidates := MarkerCandidates(lab, rangeStart, rangeEnd)
+	return verifyAll(len(marker), func(j int) error {
+		return verifier.VerifyEncryptedInverse(session, marker[j], candidates, proofs[j])
+	})
+
+}
+
+// VerifyResults is Alice's check of the results of a test of the marker over inputCipher, the ciphertexts that she
+// revealed with the boundaries around them, in the session. The padding is E(1) if exact, as in PerWindow mode, and any
+// nonzero value otherwise.
+func VerifyResults(lab *sl.SequencingLab, session []byte, inputCipher []*env.Cipher, marker []*env.Cipher, results []*env.Cipher, exact bool, proof *addhomencer.HidingProof) error {
+
+	verifier, ok := lab.Ahe.(addhomencer.WellFormednessProver)
+	if !ok {
+		return addhomencer.ErrNoWellFormednessProof
+	}
+	numOfCiphers := len(inputCipher) - 2
+	if numOfCiphers < 0 || len(results) != numOfCiphers {
+		return addhomencer.ErrInvalidWellFormednessProof
+	}
+	bases, err := windows(lab.Ahe, numOfCiphers, inputCipher, marker)
+	if err != nil {
+		return err
+	}
+	return verifier.VerifyHiddenCiphers(session, results, bases, verifier.EncryptTrivially(big.NewInt(1)), exact, proof)
+
+}
+
+// verifyAll runs verify over [0, n) concurrently, and returns one of the errors.
+func verifyAll(n int, verify func(i int) error) error {
+
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func(i int, wg *sync.WaitGroup) {
+			errs[i] = verify(i)
+			wg.Done()
+		}(i, &wg)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+
+}
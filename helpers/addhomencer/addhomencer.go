@@ -1,45 +1,99 @@
 package addhomencer
 
 import (
+	"crypto/rand"
+	"errors"
 	"math/big"
 
 	env "github.com/eozturk1/genomic-security-journal-code/helpers/env"
+	"github.com/eozturk1/genomic-security-journal-code/helpers/profiles"
 )
 
-const PrimeBitLen = 512
-
-//const KeyLen = 1024
-const KeyLen = 2048
 const ConcurrencyLevel = 4
 
+// Errors of ValidateCipher and of the homomorphic operations
+var (
+	ErrMissingCipherComponent = errors.New("ciphertext component is missing")
+	ErrCipherOutOfRange       = errors.New("ciphertext component is out of range")
+	ErrCipherNotInSubgroup    = errors.New("ciphertext component is not in the prime-order subgroup")
+	ErrZeroBlinding           = errors.New("blinding exponent is zero in the plaintext group")
+	ErrCipherCountMismatch    = errors.New("numbers of ciphertexts and scalars differ")
+)
+
 // Main AH Encryption interface.
 // For each encryption method, fill out these methods.
 type AddHomEncer interface {
-	// Generate a key and set up anything else necessary for the encryption method.
+	// Generate a key and set up anything else necessary for the encryption method, with the parameters of
+	// profiles.Default. Each scheme also has SetupProfile(profile) for the other security profiles.
 	// Update the struct (See struct DidierCrunchPaillier) with variables as necessary.
 	Setup()
 
+	// Profile returns the security profile of the key
+	Profile() *profiles.Profile
+
 	// Main encryption function. Cipher has c1 and c2 (to support AH-ElGamal), will be used as needed.
 	Encrypt(b *big.Int) *env.Cipher
 
 	// We don't necessarily decrypt -- as is the case for AH El-Gamal. Therefore we will implement this function to determine whether the result
-	// is an encryption of zero.
-	IsZero(c *env.Cipher) bool
+	// is an encryption of zero. It fails on an invalid ciphertext (see ValidateCipher).
+	IsZero(c *env.Cipher) (bool, error)
+
+	// Decrypt recovers the plaintext of the input ciphertext. For AH El-Gamal, only small plaintexts (below the decryption bound
+	// of the scheme) can be recovered, since the message is in the exponent.
+	Decrypt(c *env.Cipher) (*big.Int, error)
 
 	// Encrypt the additive inverse of input message, i.e., EncryptInverse(m) = E(-m)
 	EncryptInverse(b *big.Int) *env.Cipher
 
+	// EncryptBatch and EncryptInverseBatch encrypt all the input messages at once, using the precomputed randomness first
+	EncryptBatch(bs []*big.Int) []*env.Cipher
+	EncryptInverseBatch(bs []*big.Int) []*env.Cipher
+
+	// PrecomputeRandomness is the offline phase of encryption: it computes the message-independent part of count
+	// encryptions ahead of time. SaveRandomness and LoadRandomness persist the precomputed values with their key, and
+	// hand each value out once: saving empties the pool, and a saved file can be loaded once.
+	PrecomputeRandomness(count int)
+	SaveRandomness(fileName string) error
+	LoadRandomness(fileName string) error
+
 	// MultCiphers multiplies two input ciphertexts, i.e., homomorphically adds two plaintexts of two input ciphertexts
 	// i.e., cipher1 * cipher2 =  E(m1 + m2), where cipher1 = E(m1) and cipher2  = E(m2)
-	MultCiphers(cipher1, cipher2 *env.Cipher) *env.Cipher
+	MultCiphers(cipher1, cipher2 *env.Cipher) (*env.Cipher, error)
 
 	// HideCipherWithR exponentiate the input ciphertext to the input constant, so that the original plaintext is hidden
-	// i.e., cipher ^ r = E(m * r), where cipher = E(m)
-	HideCipherWithR(cipher *env.Cipher, r *big.Int) *env.Cipher
+	// i.e., cipher ^ r = E(m * r), where cipher = E(m). It fails with ErrZeroBlinding for r = 0 in the plaintext group,
+	// which would turn every ciphertext into an encryption of zero.
+	HideCipherWithR(cipher *env.Cipher, r *big.Int) (*env.Cipher, error)
+
+	// MultCipherArray multiplies all the input ciphertexts, i.e., E(m1) * ... * E(mn) = E(m1 + ... + mn)
+	MultCipherArray(ciphers []*env.Cipher) (*env.Cipher, error)
+
+	// MultiExpCiphers computes the product of the input ciphertexts raised to the input scalars,
+	// i.e., E(m1)^s1 * ... * E(mn)^sn = E(s1*m1 + ... + sn*mn)
+	MultiExpCiphers(ciphers []*env.Cipher, scalars []*big.Int) (*env.Cipher, error)
 
 	// Output the group order
 	GetGroupOrder() *big.Int
 
 	// Compute the inverse of an input ciphertext
-	InvertCipher(inputcipher *env.Cipher) *env.Cipher
+	InvertCipher(inputcipher *env.Cipher) (*env.Cipher, error)
+
+	// ValidateCipher checks that a ciphertext received from another party is an element of the ciphertext group, and
+	// every party calls it on the ciphertexts that it receives. The homomorphic operations above keep ciphertexts in the
+	// group, so they only check that their inputs are well formed (every component present and in range, and
+	// invertible for InvertCipher), and fail with the error of ValidateCipher otherwise: a full check of every operand
+	// would cost an exponentiation per multiplication.
+	ValidateCipher(c *env.Cipher) error
+}
+
+// RandomBlinding returns a blinding exponent for HideCipherWithR, uniform in [1, GetGroupOrder()). For AH-ElGamal, the
+// group order is the prime q, so this is Z_q^*.
+func RandomBlinding(ahe AddHomEncer) (*big.Int, error) {
+
+	r, err := rand.Int(rand.Reader, new(big.Int).Sub(ahe.GetGroupOrder(), big.NewInt(1)))
+	if err != nil {
+		return nil, err
+	}
+	return r.Add(r, big.NewInt(1)), nil
+
 }
@@ -0,0 +1,103 @@
+package addhomencer
+
+import (
+	"math/big"
+	"testing"
+
+	env "github.com/eozturk1/genomic-security-journal-code/helpers/env"
+	"github.com/eozturk1/genomic-security-journal-code/helpers/profiles"
+)
+
+func testSchemes() map[string]AddHomEncer {
+
+	ahelgamal := &AHElGamal{}
+	ahelgamal.SetupProfile(profiles.Default)
+	dj := &DamgardJurik{S: 2}
+	dj.SetupProfile(profiles.Default)
+	pl := &Paillier{}
+	pl.SetupProfile(profiles.Default)
+	return map[string]AddHomEncer{"AH-ElGamal": ahelgamal, "Damgård–Jurik": dj, "Paillier": pl}
+
+}
+
+// outOfRange returns a ciphertext of the shape of the scheme with a component equal to its modulus.
+func outOfRange(scheme AddHomEncer) *env.Cipher {
+
+	switch s := scheme.(type) {
+	case *AHElGamal:
+		return &env.Cipher{C1: s.Pk.G, C2: s.Pk.P}
+	case *DamgardJurik:
+		return &env.Cipher{C1: s.ns1}
+	case *Paillier:
+		return &env.Cipher{C1: s.ns1}
+	}
+	return nil
+
+}
+
+func TestHomomorphicOperationsReturnErrors(t *testing.T) {
+
+	for name, scheme := range testSchemes() {
+		valid := scheme.Encrypt(big.NewInt(3))
+		for _, tc := range []struct {
+			cipher *env.Cipher
+			err    error
+		}{
+			{nil, ErrMissingCipherComponent},
+			{&env.Cipher{}, ErrMissingCipherComponent},
+			{outOfRange(scheme), ErrCipherOutOfRange},
+		} {
+			if _, err := scheme.MultCiphers(valid, tc.cipher); err != tc.err {
+				t.Errorf("%s: MultCiphers: %v, want %v", name, err, tc.err)
+			}
+			if _, err := scheme.InvertCipher(tc.cipher); err != tc.err {
+				t.Errorf("%s: InvertCipher: %v, want %v", name, err, tc.err)
+			}
+			if _, err := scheme.HideCipherWithR(tc.cipher, big.NewInt(5)); err != tc.err {
+				t.Errorf("%s: HideCipherWithR: %v, want %v", name, err, tc.err)
+			}
+			if _, err := scheme.MultCipherArray([]*env.Cipher{valid, tc.cipher}); err != tc.err {
+				t.Errorf("%s: MultCipherArray: %v, want %v", name, err, tc.err)
+			}
+			if _, err := scheme.MultiExpCiphers([]*env.Cipher{tc.cipher, valid}, []*big.Int{one, one}); err != tc.err {
+				t.Errorf("%s: MultiExpCiphers: %v, want %v", name, err, tc.err)
+			}
+			if _, err := scheme.IsZero(tc.cipher); err == nil {
+				t.Errorf("%s: IsZero of an invalid ciphertext", name)
+			}
+		}
+
+		if _, err := scheme.HideCipherWithR(valid, scheme.GetGroupOrder()); err != ErrZeroBlinding {
+			t.Errorf("%s: blinding by the group order: %v", name, err)
+		}
+		if _, err := scheme.MultiExpCiphers([]*env.Cipher{valid}, nil); err != ErrCipherCountMismatch {
+			t.Errorf("%s: MultiExpCiphers with no scalar: %v", name, err)
+		}
+
+		// E(3)^r E(3)^(-r) E(0)^7 = E(0), through every operation
+		inverse, err := scheme.InvertCipher(valid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, _ := RandomBlinding(scheme)
+		hidden, err := scheme.HideCipherWithR(valid, r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		product, err := scheme.MultCipherArray([]*env.Cipher{valid, inverse})
+		if err != nil {
+			t.Fatal(err)
+		}
+		combined, err := scheme.MultiExpCiphers([]*env.Cipher{hidden, valid, product}, []*big.Int{one, new(big.Int).Neg(r), big.NewInt(7)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if zero, err := scheme.IsZero(combined); err != nil || !zero {
+			t.Errorf("%s: E(3)^r E(3)^(-r) E(0)^7 is not E(0): %v", name, err)
+		}
+		if zero, err := scheme.IsZero(valid); err != nil || zero {
+			t.Errorf("%s: E(3) is E(0): %v", name, err)
+		}
+	}
+
+}
@@ -4,29 +4,21 @@ import (
 	"crypto/rand"
 	//"fmt"
 	"math/big"
+	"sync"
 
 	env "github.com/eozturk1/genomic-security-journal-code/helpers/env"
+	"github.com/eozturk1/genomic-security-journal-code/helpers/profiles"
 )
 
 // ========================== Additively homomorphic ElGamal code, modified from golang.org/x/crypto/openpgp/elgamal ==========================
-// This is the 1024-bit MODP group from RFC 5114, section 2.1:
-const primeHex = "B10B8F96A080E01DDE92DE5EAE5D54EC52C99FBCFB06A3C69A6A9DCA52D23B616073E28675A23D189838EF1E2EE652C013ECB4AEA906112324975C3CD49B83BFACCBDD7D90C4BD7098488E9C219A73724EFFD6FAE5644738FAA31A4FF55BCCC0A151AF5F0DC8B4BD45BF37DF365C1A65E68CFDA76D4DA708DF1FB2BC2E4A4371"
-const generatorHex = "A4D1CBD5C3FD34126765A442EFB99905F8104DD258AC507FD6406CFF14266D31266FEA1E5C41564B777E690F5504F213160217B4B01B886A5E91547F9E2749F4D7FBD7D3B9A92EE1909D0D2263F80A76A6A24C087A091F531DBF0A0169B6A28AD662A4D18E73AFA32D779D5918D08BC8858F4DCEF97C2A24855E6EEB22B3B2E5"
-
-// This is the 2048-bit MODP group from RFC 5114, section 2.2:
-const primeHex2 = "AD107E1E9123A9D0D660FAA79559C51FA20D64E5683B9FD1B54B1597B61D0A75E6FA141DF95A56DBAF9A3C407BA1DF15EB3D688A309C180E1DE6B85A1274A0A66D3F8152AD6AC2129037C9EDEFDA4DF8D91E8FEF55B7394B7AD5B7D0B6C12207C9F98D11ED34DBF6C6BA0B2C8BBC27BE6A00E0A0B9C49708B3BF8A317091883681286130BC8985DB1602E714415D9330278273C7DE31EFDC7310F7121FD5A07415987D9ADC0A486DCDF93ACC44328387315D75E198C641A480CD86A1B9E587E8BE60E69CC928B2B9C52172E413042E9B23F10B0E16E79763C9B53DCF4BA80A29E3FB73C16B8E75B97EF363E2FFA31F71CF9DE5384E71B81C0AC4DFFE0C10E64F"
-const generatorHex2 = "AC4032EF4F2D9AE39DF30B5C8FFDAC506CDEBE7B89998CAF74866A08CFE4FFE3A6824A4E10B9A6F0DD921F01A70C4AFAAB739D7700C29F52C57DB17C620A8652BE5E9001A8D66AD7C17669101999024AF4D027275AC1348BB8A762D0521BC98AE247150422EA1ED409939D54DA7460CDB5F6C6B250717CBEF180EB34118E98D119529A45D6F834566E3025E316A330EFBB77A86F0C1AB15B051AE3D428C8F8ACB70A8137150B8EEB10E183EDD19963DDD9E263E4770589EF6AA21E7F5F2FF381B539CCE3409D13CD566AFBB48D6C019181E1BCFE94B30269EDFE72FE9B6AA4BD7B5A0F1C71CFFF4C19C418E1F6EC017981BC087F2A7065B384B890D3191F2BFA"
-
-func fromHex(hex string) *big.Int {
-	n, ok := new(big.Int).SetString(hex, 16)
-	if !ok {
-		panic("failed to parse hex number")
-	}
-	return n
-}
+// G generates the subgroup of prime order q of Z_P^*. Exponents (k, x, and blinding exponents) are taken mod q, and a
+// ciphertext is only valid if both of its components are in the subgroup: outside of it, C1^x = C2 would not mean that
+// the plaintext is zero mod q, and an out-of-subgroup component could leak the plaintext mod small factors of P-1.
+// The group is the one of the security profile (see profiles.Profile).
 
 type ElGamalPublicKey struct {
 	G, P, Y *big.Int
+	Q       *big.Int // order of G
 }
 
 type ElGamalPrivateKey struct {
@@ -36,22 +28,47 @@ type ElGamalPrivateKey struct {
 type AHElGamal struct {
 	Pk ElGamalPublicKey
 	Sk ElGamalPrivateKey
+
+	dlog      *DLogTable
+	dlogMutex sync.Mutex
+
+	// fixed-base tables of G and Y, built in Setup
+	gTable, yTable *FixedBaseTable
+
+	pool randomnessPool // precomputed (G^k, Y^k)
+
+	profile *profiles.Profile
 }
 
 func (ahelgamal *AHElGamal) Setup() {
+	ahelgamal.SetupProfile(profiles.Default)
+}
+
+// SetupProfile generates a key in the group of the profile.
+func (ahelgamal *AHElGamal) SetupProfile(profile *profiles.Profile) {
 	Pk := ElGamalPublicKey{
-		G: fromHex(generatorHex2),
-		P: fromHex(primeHex2),
+		G: profile.ElGamal.G,
+		P: profile.ElGamal.P,
+		Q: profile.ElGamal.Q,
 	}
 
+	x, err := randomExponent(Pk.Q)
+	if err != nil {
+		panic("AHElGamal GenerateKey error: " + err.Error())
+	}
 	Sk := ElGamalPrivateKey{
-		X: fromHex("42"),
+		X: x,
 	}
 
 	Pk.Y = new(big.Int).Exp(Pk.G, Sk.X, Pk.P)
 
 	ahelgamal.Pk = Pk
 	ahelgamal.Sk = Sk
+	ahelgamal.profile = profile
+
+	ahelgamal.gTable = NewFixedBaseTable(Pk.G, Pk.P, Pk.Q.BitLen(), FixedBaseWindow)
+	ahelgamal.yTable = NewFixedBaseTable(Pk.Y, Pk.P, Pk.Q.BitLen(), FixedBaseWindow)
+	ahelgamal.pool.drop()
 
 	//fmt.Println("Setting up is done")
 	//fmt.Println("private key: ", ahelgamal.Sk, "public key - G: ", ahelgamal.Pk.G, " P: ", ahelgamal.Pk.P, " Y: ", ahelgamal.Pk.Y)
@@ -60,81 +77,287 @@ func (ahelgamal *AHElGamal) Setup() {
 
 func (ahelgamal *AHElGamal) Encrypt(b *big.Int) *env.Cipher {
 
-	k, err := rand.Int(rand.Reader, ahelgamal.Pk.P)
-	if err != nil {
+	gk := ahelgamal.pool.next(ahelgamal.freshGK)
+	if gk == nil {
 		return nil
 	}
 
-	c1 := new(big.Int).Exp(ahelgamal.Pk.G, k, ahelgamal.Pk.P)
-	s := new(big.Int).Exp(ahelgamal.Pk.Y, k, ahelgamal.Pk.P)
-	ms := new(big.Int).Exp(ahelgamal.Pk.G, b, ahelgamal.Pk.P)
-	c2 := s.Mul(s, ms)
+	//fmt.Println("Encryption of int ", b, " is done")
+
+	return ahelgamal.encryptWith(b, gk)
+
+}
+
+// encryptWith returns (G^k, Y^k * G^b) for the precomputed gk = (G^k, Y^k).
+func (ahelgamal *AHElGamal) encryptWith(b *big.Int, gk *env.Cipher) *env.Cipher {
+
+	ms := ahelgamal.expG(b)
+	c2 := ms.Mul(ms, gk.C2)
 	c2.Mod(c2, ahelgamal.Pk.P)
 
-	//fmt.Println("Encryption of int ", b, " is done")
+	return &env.Cipher{C1: gk.C1, C2: c2}
+
+}
+
+func (ahelgamal *AHElGamal) EncryptBatch(bs []*big.Int) []*env.Cipher {
+	return encryptBatch(bs, ahelgamal.pool.take(len(bs), ahelgamal.freshGK), ahelgamal.encryptWith)
+}
+
+// PrecomputeRandomness adds count fresh (G^k, Y^k) pairs to the pool. Encrypt takes pairs from the pool while it is
+// non-empty and computes them inline otherwise.
+func (ahelgamal *AHElGamal) PrecomputeRandomness(count int) {
+	ahelgamal.pool.fill(count, ahelgamal.freshGK)
+}
+
+// SaveRandomness writes the precomputed (G^k, Y^k) pairs to a file, with the public key they belong to.
+func (ahelgamal *AHElGamal) SaveRandomness(fileName string) error {
+	return ahelgamal.pool.save(fileName, ahelgamal.profile.ID, []*big.Int{ahelgamal.Pk.G, ahelgamal.Pk.P, ahelgamal.Pk.Y})
+}
+
+// LoadRandomness replaces the pool with the (G^k, Y^k) pairs of a file saved for the same public key.
+func (ahelgamal *AHElGamal) LoadRandomness(fileName string) error {
+	return ahelgamal.pool.load(fileName, ahelgamal.profile.ID, []*big.Int{ahelgamal.Pk.G, ahelgamal.Pk.P, ahelgamal.Pk.Y})
+}
+
+func (ahelgamal *AHElGamal) freshGK() *env.Cipher {
+
+	k, err := randomExponent(ahelgamal.Pk.Q)
+	if err != nil {
+		return nil
+	}
+	return &env.Cipher{C1: ahelgamal.expG(k), C2: ahelgamal.expY(k)}
+
+}
+
+// expG returns G^e mod P, with the table of G once Setup has built it. G has order q, so e is reduced mod q first.
+func (ahelgamal *AHElGamal) expG(e *big.Int) *big.Int {
+	e = new(big.Int).Mod(e, ahelgamal.Pk.Q)
+	if ahelgamal.gTable == nil {
+		return new(big.Int).Exp(ahelgamal.Pk.G, e, ahelgamal.Pk.P)
+	}
+	return ahelgamal.gTable.Exp(e)
+}
+
+// expY returns Y^e mod P, with the table of Y once Setup has built it. Y has order q, so e is reduced mod q first.
+func (ahelgamal *AHElGamal) expY(e *big.Int) *big.Int {
+	e = new(big.Int).Mod(e, ahelgamal.Pk.Q)
+	if ahelgamal.yTable == nil {
+		return new(big.Int).Exp(ahelgamal.Pk.Y, e, ahelgamal.Pk.P)
+	}
+	return ahelgamal.yTable.Exp(e)
+}
+
+// ValidateCipher checks that both components of the ciphertext are in the subgroup of order q: in [1, P) and of order
+// dividing q. Ciphertexts received from another party have to pass it before they are used.
+func (ahelgamal *AHElGamal) ValidateCipher(c *env.Cipher) error {
+
+	if c == nil || c.C1 == nil || c.C2 == nil {
+		return ErrMissingCipherComponent
+	}
+	for _, component := range []*big.Int{c.C1, c.C2} {
+		if component.Sign() <= 0 || component.Cmp(ahelgamal.Pk.P) >= 0 {
+			return ErrCipherOutOfRange
+		}
+		if new(big.Int).Exp(component, ahelgamal.Pk.Q, ahelgamal.Pk.P).Cmp(one) != 0 {
+			return ErrCipherNotInSubgroup
+		}
+	}
+	return nil
+
+}
+
+// checkCiphers is the check of the inputs of the homomorphic operations: every component is present and in [1, P).
+func (ahelgamal *AHElGamal) checkCiphers(ciphers ...*env.Cipher) error {
+
+	for _, c := range ciphers {
+		if c == nil || c.C1 == nil || c.C2 == nil {
+			return ErrMissingCipherComponent
+		}
+		if c.C1.Sign() <= 0 || c.C1.Cmp(ahelgamal.Pk.P) >= 0 || c.C2.Sign() <= 0 || c.C2.Cmp(ahelgamal.Pk.P) >= 0 {
+			return ErrCipherOutOfRange
+		}
+	}
+	return nil
+
+}
+
+// randomExponent returns an exponent uniform in Z_q^* = [1, q).
+func randomExponent(q *big.Int) (*big.Int, error) {
+	k, err := rand.Int(rand.Reader, new(big.Int).Sub(q, one))
+	if err != nil {
+		return nil, err
+	}
+	return k.Add(k, one), nil
+}
+
+// IsZero reports whether the ciphertext is an encryption of zero, or the error of ValidateCipher.
+func (ahelgamal *AHElGamal) IsZero(c *env.Cipher) (bool, error) {
+
+	if err := ahelgamal.ValidateCipher(c); err != nil {
+		return false, err
+	}
+
+	// if C1^x mod P == C2, that means G^m = 1, i.e., m = 0 mod q
+	s := new(big.Int).Exp(c.C1, ahelgamal.Sk.X, ahelgamal.Pk.P)
 
-	return &env.Cipher{C1: c1, C2: c2}
+	return s.Cmp(c.C2) == 0, nil
 
 }
 
-func (ahelgamal *AHElGamal) IsZero(c *env.Cipher) bool {
+func (ahelgamal *AHElGamal) Decrypt(c *env.Cipher) (*big.Int, error) {
 
-	// if C1^x mod P == C2, that means G^m = 1, i.e., m = 0 mod P-1
+	if err := ahelgamal.ValidateCipher(c); err != nil {
+		return nil, err
+	}
+
+	// G^m = C2 * (C1^x)^(-1) mod P, and m is recovered from the discrete log table
 	s := new(big.Int).Exp(c.C1, ahelgamal.Sk.X, ahelgamal.Pk.P)
+	s.ModInverse(s, ahelgamal.Pk.P)
+	gm := s.Mul(s, c.C2)
+	gm.Mod(gm, ahelgamal.Pk.P)
+
+	return ahelgamal.decryptionTable().Solve(gm)
 
-	if s.Cmp(c.C2) == 0 {
-		return true
+}
+
+// SetDecryptionBound builds the baby-step giant-step table so that Decrypt recovers every plaintext in [0, bound).
+// Without it, the first call to Decrypt builds a table for DefaultDecryptionBound.
+func (ahelgamal *AHElGamal) SetDecryptionBound(bound uint64) {
+
+	table := NewDLogTable(ahelgamal.Pk.G, ahelgamal.Pk.P, bound)
+
+	ahelgamal.dlogMutex.Lock()
+	ahelgamal.dlog = table
+	ahelgamal.dlogMutex.Unlock()
+
+}
+
+func (ahelgamal *AHElGamal) SaveDecryptionTable(fileName string) error {
+	return ahelgamal.decryptionTable().Save(fileName)
+}
+
+func (ahelgamal *AHElGamal) LoadDecryptionTable(fileName string) error {
+
+	table, err := LoadDLogTable(fileName, ahelgamal.Pk.G, ahelgamal.Pk.P)
+	if err != nil {
+		return err
 	}
 
-	return false
+	ahelgamal.dlogMutex.Lock()
+	ahelgamal.dlog = table
+	ahelgamal.dlogMutex.Unlock()
+
+	return nil
+
+}
+
+func (ahelgamal *AHElGamal) decryptionTable() *DLogTable {
+
+	ahelgamal.dlogMutex.Lock()
+	defer ahelgamal.dlogMutex.Unlock()
+
+	if ahelgamal.dlog == nil {
+		ahelgamal.dlog = NewDLogTable(ahelgamal.Pk.G, ahelgamal.Pk.P, DefaultDecryptionBound)
+	}
+	return ahelgamal.dlog
 
 }
 
 func (ahelgamal *AHElGamal) EncryptInverse(b *big.Int) *env.Cipher {
 
-	k, err := rand.Int(rand.Reader, ahelgamal.Pk.P)
-	if err != nil {
+	gk := ahelgamal.pool.next(ahelgamal.freshGK)
+	if gk == nil {
 		return nil
 	}
+	return ahelgamal.encryptInverseWith(b, gk)
+
+}
 
-	c1 := new(big.Int).Exp(ahelgamal.Pk.G, k, ahelgamal.Pk.P)
-	s := new(big.Int).Exp(ahelgamal.Pk.Y, k, ahelgamal.Pk.P)
-	ms := new(big.Int).ModInverse(new(big.Int).Exp(ahelgamal.Pk.G, b, ahelgamal.Pk.P), ahelgamal.Pk.P) // (G^b)^(-1) = G^(-b) mod P
-	c2 := s.Mul(s, ms)
+// encryptInverseWith returns (G^k, Y^k * G^(-b)) for the precomputed gk = (G^k, Y^k).
+func (ahelgamal *AHElGamal) encryptInverseWith(b *big.Int, gk *env.Cipher) *env.Cipher {
+
+	ms := new(big.Int).ModInverse(ahelgamal.expG(b), ahelgamal.Pk.P) // (G^b)^(-1) = G^(-b) mod P
+	c2 := ms.Mul(ms, gk.C2)
 	c2.Mod(c2, ahelgamal.Pk.P)
 
-	return &env.Cipher{C1: c1, C2: c2}
+	return &env.Cipher{C1: gk.C1, C2: c2}
+
+}
 
+func (ahelgamal *AHElGamal) EncryptInverseBatch(bs []*big.Int) []*env.Cipher {
+	return encryptBatch(bs, ahelgamal.pool.take(len(bs), ahelgamal.freshGK), ahelgamal.encryptInverseWith)
 }
 
-func (ahelgamal *AHElGamal) InvertCipher(inputcipher *env.Cipher) *env.Cipher {
+func (ahelgamal *AHElGamal) InvertCipher(inputcipher *env.Cipher) (*env.Cipher, error) {
 
+	if err := ahelgamal.checkCiphers(inputcipher); err != nil {
+		return nil, err
+	}
 	c1 := new(big.Int).ModInverse(inputcipher.C1, ahelgamal.Pk.P)
 	c2 := new(big.Int).ModInverse(inputcipher.C2, ahelgamal.Pk.P)
-	return &env.Cipher{C1: c1, C2: c2}
+	return &env.Cipher{C1: c1, C2: c2}, nil
 
 }
 
-func (ahelgamal *AHElGamal) MultCiphers(cipher1, cipher2 *env.Cipher) *env.Cipher {
+func (ahelgamal *AHElGamal) MultCiphers(cipher1, cipher2 *env.Cipher) (*env.Cipher, error) {
 
+	if err := ahelgamal.checkCiphers(cipher1, cipher2); err != nil {
+		return nil, err
+	}
 	c1 := new(big.Int).Mod(new(big.Int).Mul(cipher1.C1, cipher2.C1), ahelgamal.Pk.P)
 	c2 := new(big.Int).Mod(new(big.Int).Mul(cipher1.C2, cipher2.C2), ahelgamal.Pk.P)
 
-	return &env.Cipher{C1: c1, C2: c2}
+	return &env.Cipher{C1: c1, C2: c2}, nil
 
 }
 
-func (ahelgamal *AHElGamal) HideCipherWithR(cipher *env.Cipher, r *big.Int) *env.Cipher {
+// HideCipherWithR raises a valid ciphertext to r mod q. r has to be a unit of Z_q (see RandomBlinding).
+func (ahelgamal *AHElGamal) HideCipherWithR(cipher *env.Cipher, r *big.Int) (*env.Cipher, error) {
 
+	if err := ahelgamal.checkCiphers(cipher); err != nil {
+		return nil, err
+	}
+	r = new(big.Int).Mod(r, ahelgamal.Pk.Q)
+	if r.Sign() == 0 {
+		return nil, ErrZeroBlinding
+	}
 	c1 := new(big.Int).Exp(cipher.C1, r, ahelgamal.Pk.P)
 	c2 := new(big.Int).Exp(cipher.C2, r, ahelgamal.Pk.P)
 
-	return &env.Cipher{C1: c1, C2: c2}
+	return &env.Cipher{C1: c1, C2: c2}, nil
+
+}
+
+func (ahelgamal *AHElGamal) MultCipherArray(ciphers []*env.Cipher) (*env.Cipher, error) {
+
+	if err := ahelgamal.checkCiphers(ciphers...); err != nil {
+		return nil, err
+	}
+	c1s, c2s := splitCiphers(ciphers)
+	return &env.Cipher{C1: productMod(c1s, ahelgamal.Pk.P), C2: productMod(c2s, ahelgamal.Pk.P)}, nil
+
+}
+
+func (ahelgamal *AHElGamal) MultiExpCiphers(ciphers []*env.Cipher, scalars []*big.Int) (*env.Cipher, error) {
+
+	if len(ciphers) != len(scalars) {
+		return nil, ErrCipherCountMismatch
+	}
+	if err := ahelgamal.checkCiphers(ciphers...); err != nil {
+		return nil, err
+	}
+	c1s, c2s := splitCiphers(ciphers)
+	return &env.Cipher{C1: multiExpMod(c1s, scalars, ahelgamal.Pk.P), C2: multiExpMod(c2s, scalars, ahelgamal.Pk.P)}, nil
+
+}
 
+func (ahelgamal *AHElGamal) Profile() *profiles.Profile {
+	return ahelgamal.profile
 }
 
 func (ahelgamal *AHElGamal) GetGroupOrder() *big.Int {
-	return ahelgamal.Pk.P
+	// order q of the subgroup generated by G, so plaintexts are in Z_q
+	return ahelgamal.Pk.Q
 }
 
 // ========================== Additively homomorphic ElGamal code, modified from golang.org/x/crypto/openpgp/elgamal ==========================
@@ -0,0 +1,124 @@
+package addhomencer
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"math/big"
+
+	env "github.com/eozturk1/genomic-security-journal-code/helpers/env"
+	"github.com/eozturk1/genomic-security-journal-code/helpers/profiles"
+)
+
+// ========================== Proofs of encryptions of committed values ==========================
+// A Pedersen commitment G^b H^rho in the group of AH-ElGamal of a profile binds the one who opens it to b mod q, and
+// hides b. H is hashed into the group, so that nobody knows its logarithm to the base G. An encryptor who knows the
+// opening (b, rho) proves that E(-b) = (G^k, Y^k G^(-b)) encrypts the committed value with the statement, for
+// witnesses (k, -b, -rho): C1 = G^k, C2 = Y^k G^(-b) and commitment^(-1) = G^(-b) H^(-rho).
+
+var ErrCommitmentOutOfGroup = errors.New("commitment is not in the group of the profile")
+
+// CommitmentBase returns H, the second base of the commitments in the group of the profile.
+func CommitmentBase(profile *profiles.Profile) *big.Int {
+	return hashToGroup(profile, "marker commitment base")
+}
+
+// hashToGroup hashes the label and the data into an element other than 1 of the group of AH-ElGamal of the profile.
+func hashToGroup(profile *profiles.Profile, label string, data ...[]byte) *big.Int {
+
+	group := profile.ElGamal
+	cofactor := new(big.Int).Sub(group.P, one)
+	cofactor.Div(cofactor, group.Q)
+	size := (group.P.BitLen() + 7) / 8
+	var counter [4]byte
+	for i := uint32(0); ; i++ {
+		var seed []byte
+		for block := uint32(0); len(seed) < size+8; block++ {
+			h := profile.NewHash()
+			h.Write([]byte(label))
+			for _, d := range data {
+				binary.BigEndian.PutUint32(counter[:], uint32(len(d)))
+				h.Write(counter[:])
+				h.Write(d)
+			}
+			binary.BigEndian.PutUint32(counter[:], i)
+			h.Write(counter[:])
+			binary.BigEndian.PutUint32(counter[:], block)
+			h.Write(counter[:])
+			seed = h.Sum(seed)
+		}
+		base := new(big.Int).SetBytes(seed)
+		base.Mod(base, group.P).Exp(base, cofactor, group.P)
+		if base.Cmp(one) > 0 {
+			return base
+		}
+	}
+
+}
+
+// Commit returns G^b H^rho, and a random rho if it is nil, in the group of the profile.
+func Commit(profile *profiles.Profile, b, rho *big.Int) (*big.Int, *big.Int, error) {
+
+	group := profile.ElGamal
+	if rho == nil {
+		var err error
+		if rho, err = rand.Int(rand.Reader, group.Q); err != nil {
+			return nil, nil, err
+		}
+	}
+	commitment := multiExpMod([]*big.Int{group.G, CommitmentBase(profile)}, []*big.Int{new(big.Int).Mod(b, group.Q), new(big.Int).Mod(rho, group.Q)}, group.P)
+	return commitment, rho, nil
+
+}
+
+// EncryptInverseOfCommitted returns E(-b) with a proof that b is the value of the commitment G^b H^rho.
+func (ahelgamal *AHElGamal) EncryptInverseOfCommitted(session []byte, b, rho, commitment *big.Int) (*env.Cipher, *ORProof, error) {
+
+	q := ahelgamal.Pk.Q
+	k, err := randomExponent(q)
+	if err != nil {
+		return nil, nil, err
+	}
+	c := ahelgamal.encryptInverseWith(b, &env.Cipher{C1: ahelgamal.expG(k), C2: ahelgamal.expY(k)})
+	minusB := new(big.Int).Mod(b, q)
+	minusB.Sub(q, minusB).Mod(minusB, q)
+	minusRho := new(big.Int).Mod(rho, q)
+	minusRho.Sub(q, minusRho).Mod(minusRho, q)
+
+	statement, err := ahelgamal.committedStatement(c, commitment)
+	if err != nil {
+		return nil, nil, err
+	}
+	proof, err := ahelgamal.proveOR(committedDomain, session, []*linearStatement{statement}, 0, []*big.Int{k, minusB, minusRho})
+	if err != nil {
+		return nil, nil, err
+	}
+	return c, proof, nil
+
+}
+
+func (ahelgamal *AHElGamal) VerifyEncryptedCommitted(session []byte, c *env.Cipher, commitment *big.Int, proof *ORProof) error {
+	if err := ahelgamal.ValidateCipher(c); err != nil {
+		return err
+	}
+	statement, err := ahelgamal.committedStatement(c, commitment)
+	if err != nil {
+		return err
+	}
+	return ahelgamal.verifyOR(committedDomain, session, []*linearStatement{statement}, 3, proof)
+}
+
+// committedStatement is, for witnesses (k, -b, -rho): C1 = G^k, C2 = Y^k G^(-b) and commitment^(-1) = G^(-b) H^(-rho).
+func (ahelgamal *AHElGamal) committedStatement(c *env.Cipher, commitment *big.Int) (*linearStatement, error) {
+
+	p, g, y := ahelgamal.Pk.P, ahelgamal.Pk.G, ahelgamal.Pk.Y
+	if commitment == nil || commitment.Sign() <= 0 || commitment.Cmp(p) >= 0 ||
+		new(big.Int).Exp(commitment, ahelgamal.Pk.Q, p).Cmp(one) != 0 {
+		return nil, ErrCommitmentOutOfGroup
+	}
+	return &linearStatement{
+		targets: []*big.Int{c.C1, c.C2, new(big.Int).ModInverse(commitment, p)},
+		bases:   [][]*big.Int{{g, one, one}, {y, g, one}, {one, g, CommitmentBase(ahelgamal.profile)}},
+	}, nil
+
+}
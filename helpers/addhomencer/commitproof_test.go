@@ -0,0 +1,128 @@
+package addhomencer
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/eozturk1/genomic-security-journal-code/helpers/profiles"
+)
+
+func TestCommitmentBase(t *testing.T) {
+
+	group := profiles.Default.ElGamal
+	h := CommitmentBase(profiles.Default)
+	if h.Cmp(one) <= 0 || h.Cmp(group.P) >= 0 || new(big.Int).Exp(h, group.Q, group.P).Cmp(one) != 0 {
+		t.Fatal("H is not an element other than 1 of the subgroup of order q")
+	}
+	if h.Cmp(group.G) == 0 || h.Cmp(CommitmentBase(profiles.Default)) != 0 {
+		t.Fatal("H is G, or is not the same for each call")
+	}
+
+}
+
+func TestCommit(t *testing.T) {
+
+	group := profiles.Default.ElGamal
+	b, rho := big.NewInt(42), big.NewInt(7)
+	commitment, opening, err := Commit(profiles.Default, b, rho)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := new(big.Int).Exp(group.G, b, group.P)
+	want.Mul(want, new(big.Int).Exp(CommitmentBase(profiles.Default), rho, group.P)).Mod(want, group.P)
+	if commitment.Cmp(want) != 0 || opening.Cmp(rho) != 0 {
+		t.Fatal("commitment is not G^b H^rho")
+	}
+
+	first, rho1, err := Commit(profiles.Default, b, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, rho2, err := Commit(profiles.Default, b, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rho1.Cmp(rho2) == 0 || first.Cmp(second) == 0 {
+		t.Fatal("commitments of the same value with fresh randomness are equal")
+	}
+
+}
+
+func TestEncryptInverseOfCommittedRoundTrip(t *testing.T) {
+
+	ahelgamal := testElGamal()
+	b := big.NewInt(1234)
+	commitment, rho, err := Commit(ahelgamal.Profile(), b, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, proof, err := ahelgamal.EncryptInverseOfCommitted(session, b, rho, commitment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ahelgamal.VerifyEncryptedCommitted(session, c, commitment, proof); err != nil {
+		t.Fatal(err)
+	}
+	sum, err := ahelgamal.MultCiphers(c, ahelgamal.Encrypt(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isZero(t, ahelgamal, sum) {
+		t.Fatal("the ciphertext is not E(-b)")
+	}
+
+}
+
+func TestVerifyEncryptedCommittedRejectsForgedProofs(t *testing.T) {
+
+	ahelgamal := testElGamal()
+	b := big.NewInt(1234)
+	commitment, rho, err := Commit(ahelgamal.Profile(), b, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, proof, err := ahelgamal.EncryptInverseOfCommitted(session, b, rho, commitment)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other, _, err := Commit(ahelgamal.Profile(), big.NewInt(1235), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ahelgamal.VerifyEncryptedCommitted(session, c, other, proof); err != ErrInvalidWellFormednessProof {
+		t.Errorf("the proof is accepted for a commitment of another value: %v", err)
+	}
+	if err := ahelgamal.VerifyEncryptedCommitted(session, ahelgamal.EncryptInverse(b), commitment, proof); err != ErrInvalidWellFormednessProof {
+		t.Errorf("the proof is accepted for another encryption of -b: %v", err)
+	}
+	if err := ahelgamal.VerifyEncryptedCommitted([]byte("another session"), c, commitment, proof); err != ErrInvalidWellFormednessProof {
+		t.Errorf("the proof is accepted in another session: %v", err)
+	}
+	// a proof that c encrypts one of candidates is not one that c encrypts a committed value
+	_, inverseProof, err := ahelgamal.EncryptInverseWithProof(session, []*big.Int{b}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ahelgamal.VerifyEncryptedCommitted(session, c, commitment, inverseProof); err != ErrInvalidWellFormednessProof {
+		t.Errorf("a proof of another kind is accepted: %v", err)
+	}
+
+	// an opening of another value does not prove the committed one
+	forged, forgedProof, err := ahelgamal.EncryptInverseOfCommitted(session, big.NewInt(1235), rho, commitment)
+	if err == nil && ahelgamal.VerifyEncryptedCommitted(session, forged, commitment, forgedProof) == nil {
+		t.Error("a wrong opening is accepted")
+	}
+
+	for name, bad := range map[string]*big.Int{
+		"nil":          nil,
+		"zero":         new(big.Int),
+		"modulus":      ahelgamal.Pk.P,
+		"not in group": new(big.Int).Sub(ahelgamal.Pk.P, one),
+	} {
+		if err := ahelgamal.VerifyEncryptedCommitted(session, c, bad, proof); err != ErrCommitmentOutOfGroup {
+			t.Errorf("commitment %s: %v", name, err)
+		}
+	}
+
+}
@@ -0,0 +1,363 @@
+package addhomencer
+
+import (
+	"crypto/rand"
+	"errors"
+	"math/big"
+
+	env "github.com/eozturk1/genomic-security-journal-code/helpers/env"
+	"github.com/eozturk1/genomic-security-journal-code/helpers/profiles"
+)
+
+// ========================== Damgård–Jurik, generalizing Paillier to plaintexts mod N^s ==========================
+// E(m) = (1+N)^m * r^(N^s) mod N^(s+1), for m in Z_(N^s). The key is a Paillier key, and for s = 1 the scheme is Paillier:
+// decryption then takes the CRT path of Paillier. For s > 1, c^d = (1+N)^m mod N^(s+1) with d = 1 mod N^s and d = 0 mod
+// lambda, and m is extracted from (1+N)^m one power of N at a time (Damgård and Jurik, PKC 2001, Theorem 1).
+// Ciphertexts are (s+1)/2 times longer than Paillier's, while the plaintext space grows to s times the bits of N.
+
+const DefaultDamgardJurikS = 2
+
+var ErrInvalidCipherEncoding = errors.New("invalid ciphertext encoding")
+
+type DamgardJurik struct {
+	S  int
+	Pk *PaillierPublicKey
+	Sk *PaillierPrivateKey
+
+	ns, ns1 *big.Int // N^s and N^(s+1)
+	d       *big.Int // d = 1 mod N^s, d = 0 mod lambda
+
+	pool randomnessPool // precomputed r^(N^s) mod N^(s+1)
+
+	profile *profiles.Profile
+}
+
+// Setup generates a key for the scheme. S has to be set before; it defaults to DefaultDamgardJurikS.
+func (dj *DamgardJurik) Setup() {
+	dj.SetupProfile(profiles.Default)
+}
+
+// SetupProfile generates a key of the size of the profile.
+func (dj *DamgardJurik) SetupProfile(profile *profiles.Profile) {
+	if dj.S < 1 {
+		dj.S = DefaultDamgardJurikS
+	}
+	sk, err := GeneratePaillierKey(profile)
+	if err != nil {
+		panic("DamgardJurik GenerateKey error: " + err.Error())
+	}
+	dj.SetPrivateKey(sk)
+}
+
+func (dj *DamgardJurik) SetPrivateKey(sk *PaillierPrivateKey) {
+
+	sk.precompute()
+	dj.SetPublicKey(&sk.PaillierPublicKey)
+	dj.Sk = sk
+
+	// lambda = lcm(p-1, q-1), d = lambda * (lambda^(-1) mod N^s)
+	p1 := new(big.Int).Sub(sk.P, one)
+	q1 := new(big.Int).Sub(sk.Q, one)
+	lambda := new(big.Int).Mul(p1, q1)
+	lambda.Div(lambda, new(big.Int).GCD(nil, nil, p1, q1))
+	dj.d = new(big.Int).ModInverse(lambda, dj.ns)
+	dj.d.Mul(dj.d, lambda)
+
+}
+
+func (dj *DamgardJurik) SetPublicKey(pk *PaillierPublicKey) {
+
+	if dj.S < 1 {
+		dj.S = DefaultDamgardJurikS
+	}
+	dj.profile = pk.profile()
+	dj.Pk = pk
+	dj.Sk = nil
+	dj.d = nil
+	dj.ns = new(big.Int).Exp(pk.N, big.NewInt(int64(dj.S)), nil)
+	dj.ns1 = new(big.Int).Mul(dj.ns, pk.N)
+	dj.pool.drop()
+
+}
+
+func (dj *DamgardJurik) Encrypt(b *big.Int) *env.Cipher {
+
+	rns := dj.pool.next(dj.freshRNS)
+	if rns == nil {
+		return nil
+	}
+	return dj.encryptWith(b, rns)
+
+}
+
+func (dj *DamgardJurik) encryptWith(b *big.Int, rns *env.Cipher) *env.Cipher {
+
+	c := dj.powOnePlusN(new(big.Int).Mod(b, dj.ns))
+	c.Mul(c, rns.C1)
+	c.Mod(c, dj.ns1)
+
+	return &env.Cipher{C1: c}
+
+}
+
+func (dj *DamgardJurik) EncryptBatch(bs []*big.Int) []*env.Cipher {
+	return encryptBatch(bs, dj.pool.take(len(bs), dj.freshRNS), dj.encryptWith)
+}
+
+// IsZero reports whether the ciphertext is an encryption of zero, or the error of Decrypt.
+func (dj *DamgardJurik) IsZero(c *env.Cipher) (bool, error) {
+	plain, err := dj.Decrypt(c)
+	if err != nil {
+		return false, err
+	}
+	return plain.Sign() == 0, nil
+}
+
+func (dj *DamgardJurik) Decrypt(c *env.Cipher) (*big.Int, error) {
+
+	if dj.Sk == nil {
+		return nil, ErrNoPrivateKey
+	}
+	if err := dj.ValidateCipher(c); err != nil {
+		return nil, err
+	}
+	if dj.S == 1 {
+		return dj.Sk.decryptCRT(c.C1), nil
+	}
+
+	a := new(big.Int).Exp(c.C1, dj.d, dj.ns1)
+	return dj.logOnePlusN(a), nil
+
+}
+
+func (dj *DamgardJurik) EncryptInverse(b *big.Int) *env.Cipher {
+	// E(-b) = E(N^s - b mod N^s)
+	inv := new(big.Int).Mod(b, dj.ns)
+	inv.Sub(dj.ns, inv)
+	return dj.Encrypt(inv)
+}
+
+func (dj *DamgardJurik) EncryptInverseBatch(bs []*big.Int) []*env.Cipher {
+
+	inv := make([]*big.Int, len(bs))
+	for i, b := range bs {
+		inv[i] = new(big.Int).Mod(b, dj.ns)
+		inv[i].Sub(dj.ns, inv[i])
+	}
+	return dj.EncryptBatch(inv)
+
+}
+
+func (dj *DamgardJurik) InvertCipher(inputcipher *env.Cipher) (*env.Cipher, error) {
+
+	if err := checkUnitCiphers(dj.ns1, inputcipher); err != nil {
+		return nil, err
+	}
+	c1 := new(big.Int).ModInverse(inputcipher.C1, dj.ns1)
+	if c1 == nil {
+		return nil, ErrCipherOutOfRange
+	}
+	return &env.Cipher{C1: c1}, nil
+
+}
+
+func (dj *DamgardJurik) MultCiphers(cipher1, cipher2 *env.Cipher) (*env.Cipher, error) {
+
+	if err := checkUnitCiphers(dj.ns1, cipher1, cipher2); err != nil {
+		return nil, err
+	}
+	c1 := new(big.Int).Mul(cipher1.C1, cipher2.C1)
+	c1.Mod(c1, dj.ns1)
+	return &env.Cipher{C1: c1}, nil
+
+}
+
+func (dj *DamgardJurik) HideCipherWithR(cipher *env.Cipher, r *big.Int) (*env.Cipher, error) {
+
+	if err := checkUnitCiphers(dj.ns1, cipher); err != nil {
+		return nil, err
+	}
+	if new(big.Int).Mod(r, dj.ns).Sign() == 0 {
+		return nil, ErrZeroBlinding
+	}
+	c1 := new(big.Int).Exp(cipher.C1, r, dj.ns1)
+	return &env.Cipher{C1: c1}, nil
+
+}
+
+func (dj *DamgardJurik) MultCipherArray(ciphers []*env.Cipher) (*env.Cipher, error) {
+
+	if err := checkUnitCiphers(dj.ns1, ciphers...); err != nil {
+		return nil, err
+	}
+	c1s, _ := splitCiphers(ciphers)
+	return &env.Cipher{C1: productMod(c1s, dj.ns1)}, nil
+
+}
+
+func (dj *DamgardJurik) MultiExpCiphers(ciphers []*env.Cipher, scalars []*big.Int) (*env.Cipher, error) {
+
+	if len(ciphers) != len(scalars) {
+		return nil, ErrCipherCountMismatch
+	}
+	if err := checkUnitCiphers(dj.ns1, ciphers...); err != nil {
+		return nil, err
+	}
+	c1s, _ := splitCiphers(ciphers)
+	return &env.Cipher{C1: multiExpMod(c1s, scalars, dj.ns1)}, nil
+
+}
+
+func (dj *DamgardJurik) GetGroupOrder() *big.Int {
+	// order of the plaintext group Z_(N^s)
+	return dj.ns
+}
+
+// ValidateCipher checks that the ciphertext is a unit mod N^(s+1).
+func (dj *DamgardJurik) ValidateCipher(c *env.Cipher) error {
+	if c == nil || c.C1 == nil {
+		return ErrMissingCipherComponent
+	}
+	return validateUnit(c.C1, dj.Pk.N, dj.ns1)
+}
+
+// checkUnitCiphers is the check of the inputs of the homomorphic operations of the schemes mod N^(s+1): every ciphertext
+// is present and in [1, modulus).
+func checkUnitCiphers(modulus *big.Int, ciphers ...*env.Cipher) error {
+
+	for _, c := range ciphers {
+		if c == nil || c.C1 == nil {
+			return ErrMissingCipherComponent
+		}
+		if c.C1.Sign() <= 0 || c.C1.Cmp(modulus) >= 0 {
+			return ErrCipherOutOfRange
+		}
+	}
+	return nil
+
+}
+
+// PrecomputeRandomness adds count fresh r^(N^s) mod N^(s+1) values to the pool used by Encrypt.
+func (dj *DamgardJurik) PrecomputeRandomness(count int) {
+	dj.pool.fill(count, dj.freshRNS)
+}
+
+// SaveRandomness writes the precomputed r^(N^s) values to a file, with the public key and s they belong to.
+func (dj *DamgardJurik) SaveRandomness(fileName string) error {
+	return dj.pool.save(fileName, dj.profile.ID, []*big.Int{dj.Pk.N, dj.ns1})
+}
+
+// LoadRandomness replaces the pool with the r^(N^s) values of a file saved for the same public key and s.
+func (dj *DamgardJurik) LoadRandomness(fileName string) error {
+	return dj.pool.load(fileName, dj.profile.ID, []*big.Int{dj.Pk.N, dj.ns1})
+}
+
+// MarshalCipher encodes a ciphertext as the profile ID, prefixed by its length in one byte, followed by a fixed-length
+// big-endian byte string of the size of N^(s+1).
+func (dj *DamgardJurik) MarshalCipher(c *env.Cipher) []byte {
+	id := dj.profile.ID
+	out := make([]byte, 1+len(id)+dj.cipherLen())
+	out[0] = byte(len(id))
+	copy(out[1:], id)
+	c.C1.FillBytes(out[1+len(id):])
+	return out
+}
+
+// UnmarshalCipher decodes a ciphertext encoded by MarshalCipher. It returns profiles.ErrProfileMismatch for a
+// ciphertext of another profile.
+func (dj *DamgardJurik) UnmarshalCipher(data []byte) (*env.Cipher, error) {
+
+	if len(data) == 0 || len(data) < 1+int(data[0]) {
+		return nil, ErrInvalidCipherEncoding
+	}
+	if err := dj.profile.Check(string(data[1 : 1+data[0]])); err != nil {
+		return nil, err
+	}
+	data = data[1+data[0]:]
+	if len(data) != dj.cipherLen() {
+		return nil, ErrInvalidCipherEncoding
+	}
+	c1 := new(big.Int).SetBytes(data)
+	if c1.Sign() == 0 || c1.Cmp(dj.ns1) >= 0 {
+		return nil, ErrInvalidCipherEncoding
+	}
+	return &env.Cipher{C1: c1}, nil
+
+}
+
+func (dj *DamgardJurik) Profile() *profiles.Profile {
+	return dj.profile
+}
+
+func (dj *DamgardJurik) cipherLen() int {
+	return (dj.ns1.BitLen() + 7) / 8
+}
+
+func (dj *DamgardJurik) freshRNS() *env.Cipher {
+
+	// r in Z_N^*
+	for {
+		r, err := rand.Int(rand.Reader, dj.Pk.N)
+		if err != nil {
+			return nil
+		}
+		if r.Sign() == 0 || new(big.Int).GCD(nil, nil, r, dj.Pk.N).Cmp(one) != 0 {
+			continue
+		}
+		return &env.Cipher{C1: r.Exp(r, dj.ns, dj.ns1)}
+	}
+
+}
+
+// powOnePlusN computes (1+N)^m mod N^(s+1) with the binomial expansion sum_{k=0}^{s} C(m,k) N^k, which has only s+1
+// terms since N^(s+1) = 0.
+func (dj *DamgardJurik) powOnePlusN(m *big.Int) *big.Int {
+
+	result := big.NewInt(1)
+	binom := big.NewInt(1) // C(m,k)
+	nk := big.NewInt(1)    // N^k
+	for k := 1; k <= dj.S; k++ {
+		binom.Mul(binom, new(big.Int).Sub(m, big.NewInt(int64(k-1))))
+		binom.Div(binom, big.NewInt(int64(k)))
+		nk.Mul(nk, dj.Pk.N)
+		result.Add(result, new(big.Int).Mul(binom, nk))
+	}
+
+	return result.Mod(result, dj.ns1)
+
+}
+
+// logOnePlusN recovers m mod N^s from a = (1+N)^m mod N^(s+1).
+func (dj *DamgardJurik) logOnePlusN(a *big.Int) *big.Int {
+
+	n := dj.Pk.N
+	i := big.NewInt(0)
+	nj := big.NewInt(1)
+	for j := 1; j <= dj.S; j++ {
+		nj.Mul(nj, n)                                // N^j
+		nj1 := new(big.Int).Mul(nj, n)               // N^(j+1)
+		t1 := lFunction(new(big.Int).Mod(a, nj1), n) // L(a mod N^(j+1))
+		t2 := new(big.Int).Set(i)
+		nk := big.NewInt(1)
+		kFactorial := big.NewInt(1)
+		for k := 2; k <= j; k++ {
+			i.Sub(i, one)
+			t2.Mul(t2, i)
+			t2.Mod(t2, nj)
+			nk.Mul(nk, n) // N^(k-1)
+			kFactorial.Mul(kFactorial, big.NewInt(int64(k)))
+
+			// t1 = t1 - t2 * N^(k-1) / k! mod N^j
+			sub := new(big.Int).Mul(t2, nk)
+			sub.Mul(sub, new(big.Int).ModInverse(kFactorial, nj))
+			t1.Sub(t1, sub)
+			t1.Mod(t1, nj)
+		}
+		i = t1
+	}
+
+	return i
+
+}
+
+// ========================== Damgård–Jurik, generalizing Paillier to plaintexts mod N^s ==========================
@@ -0,0 +1,571 @@
+package addhomencer
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"sync"
+
+	env "github.com/eozturk1/genomic-security-journal-code/helpers/env"
+	"github.com/eozturk1/genomic-security-journal-code/helpers/profiles"
+)
+
+// ========================== Proofs of (non-)zero decryption ==========================
+// The key holder proves whether a ciphertext is an encryption of zero, so that a party with the public key only can trust
+// the verdict of IsZero. The proofs are sigma protocols made non-interactive by Fiat–Shamir, with the hash of the
+// profile over the public key, the ciphertext and the commitments of the prover.
+//
+// AH-ElGamal, with Y = G^x and c = (C1, C2):
+//   - zero: C2 = C1^x, by a Chaum–Pedersen proof that log_G Y = log_C1 C2.
+//   - nonzero: D = (C1^x / C2)^alpha != 1 for a random alpha, with a proof of (a, b) = (x alpha, -alpha) such that
+//     G^a Y^b = 1 and C1^a C2^b = D (Camenisch–Shoup). For C2 = C1^x, the first equation forces D = 1.
+//
+// Damgård–Jurik and Paillier (s = 1), with c = (1+N)^m r^(N^s) mod N^(s+1):
+//   - zero: c is an N^s-th residue, by a proof of knowledge of r.
+//   - nonzero: M = alpha m mod N^s != 0 for a random alpha, with a proof of (alpha, t) such that c^alpha t^(N^s) =
+//     (1+N)^M. For an N^s-th residue c, the left side is a residue, and (1+N)^M is one only for M = 0. M is uniform, so it
+//     tells nothing about m, which the tester could otherwise relate to the hashes of Alice's bases.
+//     Challenges are of decryptionChallengeBits bits, below the size of the factors of N, as special soundness requires.
+//
+// A verdict of a match proves that one of the results is an encryption of zero by the zero proofs above in an OR over
+// all of them (see orproof.go), with the challenges of Damgård–Jurik added up mod 2^decryptionChallengeBits.
+
+// decryptionChallengeBits is the size of the challenges of the proofs over Z_(N^(s+1)), and statisticalHidingBits the
+// margin of the integer responses over the values they hide.
+const (
+	decryptionChallengeBits = 128
+	statisticalHidingBits   = 80
+)
+
+var (
+	ErrInvalidDecryptionProof = errors.New("proof of decryption is invalid")
+	ErrNoDecryptionProof      = errors.New("the scheme has no proof of decryption")
+)
+
+// DecryptionProver is a scheme whose key holder can prove the verdict of IsZero. The Verify methods need the public key
+// only.
+type DecryptionProver interface {
+	// ProveZero returns a proof of IsZero(c), which is proof.Zero.
+	ProveZero(c *env.Cipher) (*DecryptionProof, error)
+
+	// VerifyZero returns the verdict that the proof proves for c, or ErrInvalidDecryptionProof.
+	VerifyZero(c *env.Cipher, proof *DecryptionProof) (bool, error)
+
+	// ProveOneZero returns an OR proof that one of the ciphertexts is an encryption of zero, which does not tell which
+	// one. The index of such a ciphertext has to be given.
+	ProveOneZero(cs []*env.Cipher, index int) (*ORProof, error)
+	VerifyOneZero(cs []*env.Cipher, proof *ORProof) error
+}
+
+// DecryptionProof proves that a ciphertext is an encryption of zero, or that it is not. Blinded is nil for the former,
+// and is D of AH-ElGamal, or M of Damgård–Jurik and Paillier, for the latter.
+type DecryptionProof struct {
+	Zero        bool
+	Blinded     *big.Int
+	Commitments []*big.Int
+	Responses   []*big.Int
+}
+
+// decryptionChallenge hashes the transcript of a proof into a challenge of the given bits.
+func decryptionChallenge(profile *profiles.Profile, bits int, zero bool, values ...*big.Int) *big.Int {
+
+	h := profile.NewHash()
+	if zero {
+		h.Write([]byte("zero"))
+	} else {
+		h.Write([]byte("nonzero"))
+	}
+	var length [4]byte
+	for _, v := range values {
+		b := v.Bytes()
+		binary.BigEndian.PutUint32(length[:], uint32(len(b)))
+		h.Write(length[:])
+		h.Write(b)
+	}
+	e := new(big.Int).SetBytes(h.Sum(nil))
+	return e.Rsh(e, uint(h.Size()*8-bits))
+
+}
+
+// hasShape checks that the proof has all of its values, with the given numbers of commitments and responses.
+func (proof *DecryptionProof) hasShape(commitments, responses int) bool {
+	if proof == nil || len(proof.Commitments) != commitments || len(proof.Responses) != responses {
+		return false
+	}
+	if !proof.Zero && proof.Blinded == nil {
+		return false
+	}
+	for _, v := range append(append([]*big.Int{}, proof.Commitments...), proof.Responses...) {
+		if v == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// ---------- AH-ElGamal ----------
+
+func (ahelgamal *AHElGamal) ProveZero(c *env.Cipher) (*DecryptionProof, error) {
+
+	if err := ahelgamal.ValidateCipher(c); err != nil {
+		return nil, err
+	}
+	p, q, x := ahelgamal.Pk.P, ahelgamal.Pk.Q, ahelgamal.Sk.X
+	bits := q.BitLen()
+
+	if zero, _ := ahelgamal.IsZero(c); zero {
+		// Chaum–Pedersen: T1 = G^w, T2 = C1^w, z = w + e x
+		w, err := randomExponent(q)
+		if err != nil {
+			return nil, err
+		}
+		t1, t2 := ahelgamal.expG(w), new(big.Int).Exp(c.C1, w, p)
+		e := decryptionChallenge(ahelgamal.profile, bits-1, true, p, ahelgamal.Pk.G, ahelgamal.Pk.Y, c.C1, c.C2, t1, t2)
+		z := new(big.Int).Mul(e, x)
+		z.Add(z, w).Mod(z, q)
+		return &DecryptionProof{Zero: true, Commitments: []*big.Int{t1, t2}, Responses: []*big.Int{z}}, nil
+	}
+
+	// D = (C1^x / C2)^alpha, a = x alpha, b = -alpha; T1 = G^wa Y^wb, T2 = C1^wa C2^wb, sa = wa + e a, sb = wb + e b
+	alpha, err := randomExponent(q)
+	if err != nil {
+		return nil, err
+	}
+	d := new(big.Int).Exp(c.C1, x, p)
+	d.Mul(d, new(big.Int).ModInverse(c.C2, p)).Mod(d, p)
+	d.Exp(d, alpha, p)
+	a := new(big.Int).Mul(x, alpha)
+	a.Mod(a, q)
+	b := new(big.Int).Sub(q, alpha)
+
+	wa, err := randomExponent(q)
+	if err != nil {
+		return nil, err
+	}
+	wb, err := randomExponent(q)
+	if err != nil {
+		return nil, err
+	}
+	t1 := multiExpMod([]*big.Int{ahelgamal.Pk.G, ahelgamal.Pk.Y}, []*big.Int{wa, wb}, p)
+	t2 := multiExpMod([]*big.Int{c.C1, c.C2}, []*big.Int{wa, wb}, p)
+	e := decryptionChallenge(ahelgamal.profile, bits-1, false, p, ahelgamal.Pk.G, ahelgamal.Pk.Y, c.C1, c.C2, d, t1, t2)
+	sa := new(big.Int).Mul(e, a)
+	sa.Add(sa, wa).Mod(sa, q)
+	sb := new(big.Int).Mul(e, b)
+	sb.Add(sb, wb).Mod(sb, q)
+	return &DecryptionProof{Blinded: d, Commitments: []*big.Int{t1, t2}, Responses: []*big.Int{sa, sb}}, nil
+
+}
+
+func (ahelgamal *AHElGamal) VerifyZero(c *env.Cipher, proof *DecryptionProof) (bool, error) {
+
+	if err := ahelgamal.ValidateCipher(c); err != nil {
+		return false, err
+	}
+	p, q, g, y := ahelgamal.Pk.P, ahelgamal.Pk.Q, ahelgamal.Pk.G, ahelgamal.Pk.Y
+	bits := q.BitLen()
+
+	if proof != nil && proof.Zero {
+		if !proof.hasShape(2, 1) || !ahelgamal.inSubgroup(proof.Commitments...) || !inRange(q, proof.Responses...) {
+			return false, ErrInvalidDecryptionProof
+		}
+		t1, t2, z := proof.Commitments[0], proof.Commitments[1], proof.Responses[0]
+		e := decryptionChallenge(ahelgamal.profile, bits-1, true, p, g, y, c.C1, c.C2, t1, t2)
+		// G^z = T1 Y^e and C1^z = T2 C2^e
+		if ahelgamal.expG(z).Cmp(multiExpMod([]*big.Int{t1, y}, []*big.Int{one, e}, p)) != 0 ||
+			new(big.Int).Exp(c.C1, z, p).Cmp(multiExpMod([]*big.Int{t2, c.C2}, []*big.Int{one, e}, p)) != 0 {
+			return false, ErrInvalidDecryptionProof
+		}
+		return true, nil
+	}
+
+	if !proof.hasShape(2, 2) || !ahelgamal.inSubgroup(append([]*big.Int{proof.Blinded}, proof.Commitments...)...) ||
+		!inRange(q, proof.Responses...) || proof.Blinded.Cmp(one) == 0 {
+		return false, ErrInvalidDecryptionProof
+	}
+	d, t1, t2, sa, sb := proof.Blinded, proof.Commitments[0], proof.Commitments[1], proof.Responses[0], proof.Responses[1]
+	e := decryptionChallenge(ahelgamal.profile, bits-1, false, p, g, y, c.C1, c.C2, d, t1, t2)
+	// G^sa Y^sb = T1 and C1^sa C2^sb = T2 D^e
+	if multiExpMod([]*big.Int{g, y}, []*big.Int{sa, sb}, p).Cmp(t1) != 0 ||
+		multiExpMod([]*big.Int{c.C1, c.C2}, []*big.Int{sa, sb}, p).Cmp(multiExpMod([]*big.Int{t2, d}, []*big.Int{one, e}, p)) != 0 {
+		return false, ErrInvalidDecryptionProof
+	}
+	return false, nil
+
+}
+
+// ProveOneZero proves, by a Chaum–Pedersen proof in an OR over the ciphertexts, that log_G Y = log_C1 C2 for one of them.
+func (ahelgamal *AHElGamal) ProveOneZero(cs []*env.Cipher, index int) (*ORProof, error) {
+
+	for _, c := range cs {
+		if err := ahelgamal.ValidateCipher(c); err != nil {
+			return nil, err
+		}
+	}
+	if index < 0 || index >= len(cs) {
+		return nil, errors.New("index of the encryption of zero is out of the ciphertexts")
+	}
+	return ahelgamal.proveOR(zeroDomain, nil, ahelgamal.zeroStatements(cs), index, []*big.Int{ahelgamal.Sk.X})
+
+}
+
+func (ahelgamal *AHElGamal) VerifyOneZero(cs []*env.Cipher, proof *ORProof) error {
+
+	for _, c := range cs {
+		if err := ahelgamal.ValidateCipher(c); err != nil {
+			return err
+		}
+	}
+	if ahelgamal.verifyOR(zeroDomain, nil, ahelgamal.zeroStatements(cs), 1, proof) != nil {
+		return ErrInvalidDecryptionProof
+	}
+	return nil
+
+}
+
+// zeroStatements are the statements Y = G^x and C2 = C1^x, one for each ciphertext.
+func (ahelgamal *AHElGamal) zeroStatements(cs []*env.Cipher) []*linearStatement {
+	statements := make([]*linearStatement, len(cs))
+	for i, c := range cs {
+		statements[i] = &linearStatement{targets: []*big.Int{ahelgamal.Pk.Y, c.C2}, bases: [][]*big.Int{{ahelgamal.Pk.G}, {c.C1}}}
+	}
+	return statements
+}
+
+// inSubgroup checks that the values are elements of the subgroup of order q, as ValidateCipher does for ciphertexts.
+func (ahelgamal *AHElGamal) inSubgroup(values ...*big.Int) bool {
+	for _, v := range values {
+		if ahelgamal.ValidateCipher(&env.Cipher{C1: v, C2: v}) != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// ---------- Damgård–Jurik and Paillier ----------
+
+// residueKey is the public key of Damgård–Jurik, and of Paillier (s = 1), with ns = N^s and ns1 = ns N.
+type residueKey struct {
+	profile    *profiles.Profile
+	n, ns, ns1 *big.Int
+}
+
+func (dj *DamgardJurik) ProveZero(c *env.Cipher) (*DecryptionProof, error) {
+	m, err := dj.Decrypt(c)
+	if err != nil {
+		return nil, err
+	}
+	return residueKey{dj.profile, dj.Pk.N, dj.ns, dj.ns1}.prove(dj.Sk, c.C1, m)
+}
+
+func (dj *DamgardJurik) VerifyZero(c *env.Cipher, proof *DecryptionProof) (bool, error) {
+	if err := dj.ValidateCipher(c); err != nil {
+		return false, err
+	}
+	return residueKey{dj.profile, dj.Pk.N, dj.ns, dj.ns1}.verify(c.C1, proof)
+}
+
+func (dj *DamgardJurik) ProveOneZero(cs []*env.Cipher, index int) (*ORProof, error) {
+
+	values := make([]*big.Int, len(cs))
+	for i, c := range cs {
+		if err := dj.ValidateCipher(c); err != nil {
+			return nil, err
+		}
+		values[i] = c.C1
+	}
+	if index < 0 || index >= len(cs) {
+		return nil, errors.New("index of the encryption of zero is out of the ciphertexts")
+	}
+	return residueKey{dj.profile, dj.Pk.N, dj.ns, dj.ns1}.proveOneZero(dj.Sk, values, index)
+
+}
+
+func (dj *DamgardJurik) VerifyOneZero(cs []*env.Cipher, proof *ORProof) error {
+
+	values := make([]*big.Int, len(cs))
+	for i, c := range cs {
+		if err := dj.ValidateCipher(c); err != nil {
+			return err
+		}
+		values[i] = c.C1
+	}
+	return residueKey{dj.profile, dj.Pk.N, dj.ns, dj.ns1}.verifyOneZero(values, proof)
+
+}
+
+// proveOneZero proves that one of cs, the one at index, is an N^s-th residue: the proof of knowledge of r for it, and
+// simulated ones, T = z^(N^s) c^(-e) for random e and z, for the others. The challenges add up to the hash mod
+// 2^decryptionChallengeBits.
+func (key residueKey) proveOneZero(sk *PaillierPrivateKey, cs []*big.Int, index int) (*ORProof, error) {
+
+	n, ns, ns1 := key.n, key.ns, key.ns1
+	bound := new(big.Int).Lsh(one, decryptionChallengeBits)
+	proof := &ORProof{Challenges: make([]*big.Int, len(cs)), Responses: make([][]*big.Int, len(cs))}
+	commitments := make([]*big.Int, len(cs))
+
+	sum := new(big.Int)
+	for i, c := range cs {
+		if i == index {
+			continue
+		}
+		e, err := rand.Int(rand.Reader, bound)
+		if err != nil {
+			return nil, err
+		}
+		z, err := randomUnit(n)
+		if err != nil {
+			return nil, err
+		}
+		proof.Challenges[i], proof.Responses[i] = e, []*big.Int{z}
+		commitments[i] = residueCommitment(c, e, z, ns, ns1)
+		sum.Add(sum, e)
+	}
+
+	phi := new(big.Int).Mul(new(big.Int).Sub(sk.P, one), new(big.Int).Sub(sk.Q, one))
+	r := new(big.Int).Exp(new(big.Int).Mod(cs[index], n), new(big.Int).ModInverse(ns, phi), n)
+	v, err := randomUnit(n)
+	if err != nil {
+		return nil, err
+	}
+	commitments[index] = new(big.Int).Exp(v, ns, ns1)
+	e := decryptionChallenge(key.profile, decryptionChallengeBits, true, append(append([]*big.Int{ns1}, cs...), commitments...)...)
+	e.Sub(e, sum).Mod(e, bound)
+	z := new(big.Int).Exp(r, e, n)
+	z.Mul(z, v).Mod(z, n)
+	proof.Challenges[index], proof.Responses[index] = e, []*big.Int{z}
+	return proof, nil
+
+}
+
+func (key residueKey) verifyOneZero(cs []*big.Int, proof *ORProof) error {
+
+	n, ns, ns1 := key.n, key.ns, key.ns1
+	bound := new(big.Int).Lsh(one, decryptionChallengeBits)
+	if proof == nil || len(proof.Challenges) != len(cs) || len(proof.Responses) != len(cs) {
+		return ErrInvalidDecryptionProof
+	}
+	commitments := make([]*big.Int, len(cs))
+	sum := new(big.Int)
+	for i, c := range cs {
+		e, responses := proof.Challenges[i], proof.Responses[i]
+		if e == nil || !inRange(bound, e) || len(responses) != 1 || responses[0] == nil ||
+			validateUnit(responses[0], n, n) != nil {
+			return ErrInvalidDecryptionProof
+		}
+		commitments[i] = residueCommitment(c, e, responses[0], ns, ns1)
+		sum.Add(sum, e)
+	}
+	e := decryptionChallenge(key.profile, decryptionChallengeBits, true, append(append([]*big.Int{ns1}, cs...), commitments...)...)
+	if sum.Mod(sum, bound).Cmp(e) != 0 {
+		return ErrInvalidDecryptionProof
+	}
+	return nil
+
+}
+
+// residueCommitment returns z^(N^s) c^(-e), the commitment that a proof of N^s-th residuosity of c verifies against.
+func residueCommitment(c, e, z, ns, ns1 *big.Int) *big.Int {
+	t := new(big.Int).Exp(new(big.Int).ModInverse(c, ns1), e, ns1)
+	return t.Mul(t, new(big.Int).Exp(z, ns, ns1)).Mod(t, ns1)
+}
+
+// prove proves that c, of plaintext m, is an encryption of zero or that it is not.
+func (key residueKey) prove(sk *PaillierPrivateKey, c, m *big.Int) (*DecryptionProof, error) {
+
+	n, ns, ns1 := key.n, key.ns, key.ns1
+
+	// r mod N, such that c = (1+N)^m r^(N^s): c = r^(N^s) mod N, and N^s is invertible mod phi(N)
+	phi := new(big.Int).Mul(new(big.Int).Sub(sk.P, one), new(big.Int).Sub(sk.Q, one))
+	r := new(big.Int).Exp(new(big.Int).Mod(c, n), new(big.Int).ModInverse(ns, phi), n)
+
+	v, err := randomUnit(n)
+	if err != nil {
+		return nil, err
+	}
+
+	if m.Sign() == 0 {
+		// T = v^(N^s), z = v r^e
+		t := new(big.Int).Exp(v, ns, ns1)
+		e := decryptionChallenge(key.profile, decryptionChallengeBits, true, ns1, c, t)
+		z := new(big.Int).Exp(r, e, n)
+		z.Mul(z, v).Mod(z, n)
+		return &DecryptionProof{Zero: true, Commitments: []*big.Int{t}, Responses: []*big.Int{z}}, nil
+	}
+
+	// M = alpha m and t = r^(-alpha), so that c^alpha t^(N^s) = (1+N)^M; T = c^u v^(N^s), z_alpha = u + e alpha and
+	// z_t = v t^e
+	var alpha, blinded *big.Int
+	for blinded == nil || blinded.Sign() == 0 {
+		if alpha, err = rand.Int(rand.Reader, ns); err != nil {
+			return nil, err
+		}
+		blinded = new(big.Int).Mul(alpha, m)
+		blinded.Mod(blinded, ns)
+	}
+	u, err := rand.Int(rand.Reader, new(big.Int).Lsh(one, uint(ns.BitLen()+decryptionChallengeBits+statisticalHidingBits)))
+	if err != nil {
+		return nil, err
+	}
+	rt := new(big.Int).Exp(new(big.Int).ModInverse(r, n), alpha, n)
+
+	t := multiExpMod([]*big.Int{c, v}, []*big.Int{u, ns}, ns1)
+	e := decryptionChallenge(key.profile, decryptionChallengeBits, false, ns1, c, blinded, t)
+	zAlpha := new(big.Int).Mul(e, alpha)
+	zAlpha.Add(zAlpha, u)
+	zT := new(big.Int).Exp(rt, e, n)
+	zT.Mul(zT, v).Mod(zT, n)
+	return &DecryptionProof{Blinded: blinded, Commitments: []*big.Int{t}, Responses: []*big.Int{zAlpha, zT}}, nil
+
+}
+
+func (key residueKey) verify(c *big.Int, proof *DecryptionProof) (bool, error) {
+
+	n, ns, ns1 := key.n, key.ns, key.ns1
+
+	if proof != nil && proof.Zero {
+		if !proof.hasShape(1, 1) || validateUnit(proof.Commitments[0], n, ns1) != nil || validateUnit(proof.Responses[0], n, n) != nil {
+			return false, ErrInvalidDecryptionProof
+		}
+		t, z := proof.Commitments[0], proof.Responses[0]
+		e := decryptionChallenge(key.profile, decryptionChallengeBits, true, ns1, c, t)
+		// z^(N^s) = T c^e
+		if new(big.Int).Exp(z, ns, ns1).Cmp(multiExpMod([]*big.Int{t, c}, []*big.Int{one, e}, ns1)) != 0 {
+			return false, ErrInvalidDecryptionProof
+		}
+		return true, nil
+	}
+
+	maxZAlpha := new(big.Int).Lsh(one, uint(ns.BitLen()+decryptionChallengeBits+statisticalHidingBits+1))
+	if !proof.hasShape(1, 2) || !inRange(ns, proof.Blinded) || proof.Blinded.Sign() == 0 ||
+		validateUnit(proof.Commitments[0], n, ns1) != nil || !inRange(maxZAlpha, proof.Responses[0]) ||
+		validateUnit(proof.Responses[1], n, n) != nil {
+		return false, ErrInvalidDecryptionProof
+	}
+	m, t, zAlpha, zT := proof.Blinded, proof.Commitments[0], proof.Responses[0], proof.Responses[1]
+	e := decryptionChallenge(key.profile, decryptionChallengeBits, false, ns1, c, m, t)
+	// c^z_alpha z_t^(N^s) = T ((1+N)^M)^e
+	x := new(big.Int).Mul(m, e)
+	x.Exp(new(big.Int).Add(n, one), x.Mod(x, ns), ns1)
+	x.Mul(x, t).Mod(x, ns1)
+	if multiExpMod([]*big.Int{c, zT}, []*big.Int{zAlpha, ns}, ns1).Cmp(x) != 0 {
+		return false, ErrInvalidDecryptionProof
+	}
+	return false, nil
+
+}
+
+func randomUnit(n *big.Int) (*big.Int, error) {
+	for {
+		v, err := rand.Int(rand.Reader, n)
+		if err != nil {
+			return nil, err
+		}
+		if v.Sign() != 0 && new(big.Int).GCD(nil, nil, v, n).Cmp(one) == 0 {
+			return v, nil
+		}
+	}
+}
+
+func inRange(bound *big.Int, values ...*big.Int) bool {
+	for _, v := range values {
+		if v.Sign() < 0 || v.Cmp(bound) >= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Verdict is the verdict of the key holder on the results of a test, which match iff one of them is an encryption of
+// zero: for a match, an OR proof that one of them is, and otherwise the proofs that none of them is. The proof of a match
+// does not tell which result is the zero one, which could tell the tester where the marker is.
+type Verdict struct {
+	Match  bool
+	Zero   *ORProof
+	Proofs []*DecryptionProof
+}
+
+// ProveVerdict returns the verdict on the results, with its proofs.
+func ProveVerdict(ahe AddHomEncer, results []*env.Cipher) (*Verdict, error) {
+
+	prover, ok := ahe.(DecryptionProver)
+	if !ok {
+		return nil, ErrNoDecryptionProof
+	}
+	for i, result := range results {
+		zero, err := ahe.IsZero(result)
+		if err != nil {
+			return nil, err
+		}
+		if zero {
+			proof, err := prover.ProveOneZero(results, i)
+			if err != nil {
+				return nil, err
+			}
+			return &Verdict{Match: true, Zero: proof}, nil
+		}
+	}
+
+	verdict := &Verdict{Proofs: make([]*DecryptionProof, len(results))}
+	errs := make([]error, len(results))
+	var wg sync.WaitGroup
+	wg.Add(len(results))
+	for i := range results {
+		go func(i int) {
+			verdict.Proofs[i], errs[i] = prover.ProveZero(results[i])
+			wg.Done()
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return verdict, nil
+
+}
+
+// VerifyVerdict checks the proofs of the verdict on the results with the public key of the scheme, and returns the
+// verdict.
+func VerifyVerdict(ahe AddHomEncer, results []*env.Cipher, verdict *Verdict) (bool, error) {
+
+	verifier, ok := ahe.(DecryptionProver)
+	if !ok {
+		return false, ErrNoDecryptionProof
+	}
+	if verdict == nil {
+		return false, ErrInvalidDecryptionProof
+	}
+	if verdict.Match {
+		if len(verdict.Proofs) != 0 || verifier.VerifyOneZero(results, verdict.Zero) != nil {
+			return false, ErrInvalidDecryptionProof
+		}
+		return true, nil
+	}
+
+	if verdict.Zero != nil || len(verdict.Proofs) != len(results) {
+		return false, ErrInvalidDecryptionProof
+	}
+	zeros := make([]bool, len(results))
+	errs := make([]error, len(results))
+	var wg sync.WaitGroup
+	wg.Add(len(results))
+	for i := range results {
+		go func(i int) {
+			zeros[i], errs[i] = verifier.VerifyZero(results[i], verdict.Proofs[i])
+			wg.Done()
+		}(i)
+	}
+	wg.Wait()
+	for i := range results {
+		if errs[i] != nil || zeros[i] {
+			return false, ErrInvalidDecryptionProof
+		}
+	}
+	return false, nil
+
+}
+
+// ========================== Proofs of (non-)zero decryption ==========================
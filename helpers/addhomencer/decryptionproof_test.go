@@ -0,0 +1,131 @@
+package addhomencer
+
+import (
+	"math/big"
+	"testing"
+
+	env "github.com/eozturk1/genomic-security-journal-code/helpers/env"
+)
+
+func TestProveZeroRoundTrip(t *testing.T) {
+
+	for name, scheme := range testSchemes() {
+		prover := scheme.(DecryptionProver)
+		zero, nonzero := scheme.Encrypt(big.NewInt(0)), scheme.Encrypt(big.NewInt(5))
+		for _, tc := range []struct {
+			cipher *env.Cipher
+			want   bool
+		}{{zero, true}, {nonzero, false}} {
+			proof, err := prover.ProveZero(tc.cipher)
+			if err != nil {
+				t.Fatalf("%s: ProveZero: %v", name, err)
+			}
+			if proof.Zero != tc.want {
+				t.Fatalf("%s: proof of zero is %v, want %v", name, proof.Zero, tc.want)
+			}
+			if got, err := prover.VerifyZero(tc.cipher, proof); err != nil || got != tc.want {
+				t.Errorf("%s: VerifyZero: %v, %v, want %v", name, got, err, tc.want)
+			}
+		}
+	}
+
+}
+
+func TestVerifyZeroRejectsForgedProofs(t *testing.T) {
+
+	for name, scheme := range testSchemes() {
+		prover := scheme.(DecryptionProver)
+		zero, nonzero := scheme.Encrypt(big.NewInt(0)), scheme.Encrypt(big.NewInt(5))
+		zeroProof, err := prover.ProveZero(zero)
+		if err != nil {
+			t.Fatal(err)
+		}
+		nonzeroProof, err := prover.ProveZero(nonzero)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := prover.VerifyZero(nonzero, zeroProof); err != ErrInvalidDecryptionProof {
+			t.Errorf("%s: a proof of zero is accepted for E(5): %v", name, err)
+		}
+		if _, err := prover.VerifyZero(zero, nonzeroProof); err != ErrInvalidDecryptionProof {
+			t.Errorf("%s: a proof of nonzero is accepted for E(0): %v", name, err)
+		}
+		tampered := *zeroProof
+		tampered.Responses = []*big.Int{new(big.Int).Add(zeroProof.Responses[0], one)}
+		if _, err := prover.VerifyZero(zero, &tampered); err != ErrInvalidDecryptionProof {
+			t.Errorf("%s: a tampered proof of zero is accepted: %v", name, err)
+		}
+		flipped := *nonzeroProof
+		flipped.Zero = true
+		if _, err := prover.VerifyZero(nonzero, &flipped); err != ErrInvalidDecryptionProof {
+			t.Errorf("%s: a proof of nonzero is accepted as one of zero: %v", name, err)
+		}
+		if _, err := prover.VerifyZero(zero, nil); err != ErrInvalidDecryptionProof {
+			t.Errorf("%s: no proof is accepted: %v", name, err)
+		}
+	}
+
+}
+
+func TestVerdictRoundTrip(t *testing.T) {
+
+	for name, scheme := range testSchemes() {
+		for _, index := range []int{-1, 0, 2} {
+			results := make([]*env.Cipher, 3)
+			for i := range results {
+				if i == index {
+					results[i] = scheme.Encrypt(big.NewInt(0))
+				} else {
+					results[i] = scheme.Encrypt(big.NewInt(int64(7 + i)))
+				}
+			}
+			verdict, err := ProveVerdict(scheme, results)
+			if err != nil {
+				t.Fatalf("%s: ProveVerdict: %v", name, err)
+			}
+			if match, err := VerifyVerdict(scheme, results, verdict); err != nil || match != (index >= 0) {
+				t.Errorf("%s, zero at %d: VerifyVerdict: %v, %v", name, index, match, err)
+			}
+			if index >= 0 && (len(verdict.Proofs) != 0 || len(verdict.Zero.Challenges) != len(results)) {
+				t.Errorf("%s, zero at %d: the proof of a match is not an OR over all results", name, index)
+			}
+		}
+	}
+
+}
+
+func TestVerifyVerdictRejectsForgedVerdicts(t *testing.T) {
+
+	for name, scheme := range testSchemes() {
+		match := []*env.Cipher{scheme.Encrypt(big.NewInt(3)), scheme.Encrypt(big.NewInt(0))}
+		noMatch := []*env.Cipher{scheme.Encrypt(big.NewInt(3)), scheme.Encrypt(big.NewInt(4))}
+		matchVerdict, err := ProveVerdict(scheme, match)
+		if err != nil {
+			t.Fatal(err)
+		}
+		noMatchVerdict, err := ProveVerdict(scheme, noMatch)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		cases := map[string]struct {
+			results []*env.Cipher
+			verdict *Verdict
+		}{
+			"match on other results":        {noMatch, matchVerdict},
+			"no match on other results":     {match, noMatchVerdict},
+			"match with no proof":           {match, &Verdict{Match: true}},
+			"no match with a missing proof": {noMatch, &Verdict{Proofs: noMatchVerdict.Proofs[:1]}},
+			"no match claimed as a match":   {noMatch, &Verdict{Match: true, Proofs: noMatchVerdict.Proofs}},
+			"match with a result dropped":   {match[1:], matchVerdict},
+			"no verdict":                    {match, nil},
+		}
+		for label, c := range cases {
+			if _, err := VerifyVerdict(scheme, c.results, c.verdict); err != ErrInvalidDecryptionProof {
+				t.Errorf("%s: %s: %v", name, label, err)
+			}
+		}
+	}
+
+}
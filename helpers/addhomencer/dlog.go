@@ -0,0 +1,134 @@
+package addhomencer
+
+import (
+	"encoding/gob"
+	"errors"
+	"math/big"
+	"os"
+)
+
+// ========================== Baby-step giant-step table for small discrete logarithms ==========================
+// AH-ElGamal keeps the message in the exponent, so decryption ends with G^m and m has to be found by solving a discrete
+// logarithm. This is only feasible for small m: with Steps = ceil(sqrt(Bound)) baby steps G^0, ..., G^(Steps-1) stored
+// in a table, any m in [0, Bound) is found after at most Steps giant steps of G^(-Steps).
+
+const DefaultDecryptionBound = 1 << 20
+
+var ErrPlaintextOutOfBound = errors.New("plaintext is not in the decryption bound")
+var ErrDLogTableMismatch = errors.New("discrete log table was built for another group")
+
+type DLogTable struct {
+	G, P  *big.Int
+	Bound uint64
+	Steps uint64
+	// Baby maps the lowest 64 bits of G^j mod P to j. A hit is confirmed before it is returned, so collisions on the
+	// truncated key cannot produce a wrong plaintext.
+	Baby map[uint64]uint64
+
+	giant *big.Int
+}
+
+func NewDLogTable(g, p *big.Int, bound uint64) *DLogTable {
+
+	steps := new(big.Int).Sqrt(new(big.Int).SetUint64(bound)).Uint64()
+	if steps*steps < bound {
+		steps++
+	}
+	if steps == 0 {
+		steps = 1
+	}
+
+	table := &DLogTable{
+		G:     new(big.Int).Set(g),
+		P:     new(big.Int).Set(p),
+		Bound: bound,
+		Steps: steps,
+		Baby:  make(map[uint64]uint64, steps),
+	}
+
+	e := big.NewInt(1)
+	for j := uint64(0); j < steps; j++ {
+		key := dlogKey(e)
+		if _, ok := table.Baby[key]; !ok {
+			table.Baby[key] = j
+		}
+		e.Mod(e.Mul(e, g), p)
+	}
+	table.setGiant()
+
+	return table
+
+}
+
+// Solve returns m in [0, Bound) such that G^m = h mod P.
+func (table *DLogTable) Solve(h *big.Int) (*big.Int, error) {
+
+	gamma := new(big.Int).Mod(h, table.P)
+	for i := uint64(0); i*table.Steps < table.Bound; i++ {
+		if j, ok := table.Baby[dlogKey(gamma)]; ok {
+			m := new(big.Int).SetUint64(i*table.Steps + j)
+			if m.Uint64() < table.Bound && new(big.Int).Exp(table.G, m, table.P).Cmp(new(big.Int).Mod(h, table.P)) == 0 {
+				return m, nil
+			}
+		}
+		gamma.Mod(gamma.Mul(gamma, table.giant), table.P)
+	}
+
+	return nil, ErrPlaintextOutOfBound
+
+}
+
+// Save writes the table to fileName, so it can be reused instead of being rebuilt for every run.
+func (table *DLogTable) Save(fileName string) error {
+
+	file, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return gob.NewEncoder(file).Encode(table)
+
+}
+
+// LoadDLogTable reads a table written by Save and checks that it was built for the group (g, p).
+func LoadDLogTable(fileName string, g, p *big.Int) (*DLogTable, error) {
+
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	table := &DLogTable{}
+	if err := gob.NewDecoder(file).Decode(table); err != nil {
+		return nil, err
+	}
+	if table.G.Cmp(g) != 0 || table.P.Cmp(p) != 0 {
+		return nil, ErrDLogTableMismatch
+	}
+	table.setGiant()
+
+	return table, nil
+
+}
+
+func (table *DLogTable) setGiant() {
+	// giant = G^(-Steps) mod P
+	gs := new(big.Int).Exp(table.G, new(big.Int).SetUint64(table.Steps), table.P)
+	table.giant = gs.ModInverse(gs, table.P)
+}
+
+func dlogKey(e *big.Int) uint64 {
+	b := e.Bytes()
+	key := uint64(0)
+	for i := len(b) - 8; i < len(b); i++ {
+		key <<= 8
+		if i >= 0 {
+			key |= uint64(b[i])
+		}
+	}
+	return key
+}
+
+// ========================== Baby-step giant-step table for small discrete logarithms ==========================
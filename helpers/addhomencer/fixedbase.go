@@ -0,0 +1,99 @@
+package addhomencer
+
+import (
+	"math/big"
+	"sync"
+)
+
+// ========================== Fixed-base exponentiation tables ==========================
+// For a base B that is fixed for the life of a key, the table holds B^(j * 2^(w*i)) for every window i of the exponent
+// and every digit j in [1, 2^w), so that B^e costs one multiplication per window of e instead of a square-and-multiply
+// over all the bits of e. With w = 6 and 2048-bit exponents, the table takes about 5.5 MB and B^e costs 342
+// multiplications instead of about 2048 squarings and 400 multiplications.
+
+const FixedBaseWindow = 6
+
+type FixedBaseTable struct {
+	Base, Modulus *big.Int
+	window        uint
+	table         [][]*big.Int
+}
+
+// NewFixedBaseTable builds the table of base mod modulus for exponents of up to bits bits. Larger exponents are still
+// supported by Exp, without the table.
+func NewFixedBaseTable(base, modulus *big.Int, bits int, window uint) *FixedBaseTable {
+
+	var wg sync.WaitGroup
+
+	windows := (bits + int(window) - 1) / int(window)
+	digits := 1 << window
+	t := &FixedBaseTable{Base: base, Modulus: modulus, window: window, table: make([][]*big.Int, windows)}
+
+	// the first entry of each window, B^(2^(w*i)), depends on the previous window
+	t.table[0] = make([]*big.Int, digits)
+	t.table[0][1] = new(big.Int).Mod(base, modulus)
+	for i := 1; i < windows; i++ {
+		t.table[i] = make([]*big.Int, digits)
+		first := new(big.Int).Set(t.table[i-1][1])
+		for k := uint(0); k < window; k++ {
+			first.Mul(first, first)
+			first.Mod(first, modulus)
+		}
+		t.table[i][1] = first
+	}
+
+	wg.Add(ConcurrencyLevel)
+	for w := 0; w < ConcurrencyLevel; w++ {
+		go func(w int, wg *sync.WaitGroup) {
+			for i := w; i < windows; i += ConcurrencyLevel {
+				for j := 2; j < digits; j++ {
+					entry := new(big.Int).Mul(t.table[i][j-1], t.table[i][1])
+					t.table[i][j] = entry.Mod(entry, modulus)
+				}
+			}
+			wg.Done()
+		}(w, &wg)
+	}
+	wg.Wait()
+
+	return t
+
+}
+
+// Exp returns Base^e mod Modulus.
+func (t *FixedBaseTable) Exp(e *big.Int) *big.Int {
+
+	if e.Sign() < 0 || e.BitLen() > len(t.table)*int(t.window) {
+		return new(big.Int).Exp(t.Base, e, t.Modulus)
+	}
+
+	result := big.NewInt(1)
+	words := e.Bits()
+	for i := range t.table {
+		if digit := windowDigit(words, uint(i)*t.window, t.window); digit != 0 {
+			result.Mul(result, t.table[i][digit])
+			result.Mod(result, t.Modulus)
+		}
+	}
+
+	return result
+
+}
+
+// windowDigit returns the window bits of the exponent starting from bit offset.
+func windowDigit(words []big.Word, offset, window uint) uint {
+
+	const wordBits = 32 << (^uint(0) >> 63)
+
+	digit := uint(0)
+	for k := uint(0); k < window; k++ {
+		bit := offset + k
+		if int(bit/wordBits) < len(words) && words[bit/wordBits]>>(bit%wordBits)&1 == 1 {
+			digit |= 1 << k
+		}
+	}
+	return digit
+
+}
+
+// ========================== Fixed-base exponentiation tables ==========================
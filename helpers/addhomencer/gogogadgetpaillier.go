@@ -7,23 +7,37 @@ import (
 
 	paillier "github.com/Roasbeef/go-go-gadget-paillier"
 	env "github.com/eozturk1/genomic-security-journal-code/helpers/env"
+	"github.com/eozturk1/genomic-security-journal-code/helpers/profiles"
 )
 
 // ========================== GoGoGadgetPaillier https://github.com/Roasbeef/go-go-gadget-paillier ===================
 type GoGoGadgetPaillier struct {
 	privateKey *paillier.PrivateKey
+
+	pool randomnessPool // precomputed r^n mod n^2, used instead of the library's inline randomness while non-empty
+
+	profile *profiles.Profile
 }
 
 func (gggp *GoGoGadgetPaillier) Setup() {
+	gggp.SetupProfile(profiles.Default)
+}
+
+func (gggp *GoGoGadgetPaillier) SetupProfile(profile *profiles.Profile) {
 	var err error
-	gggp.privateKey, err = paillier.GenerateKey(rand.Reader, KeyLen)
+	gggp.privateKey, err = paillier.GenerateKey(rand.Reader, profile.PaillierKeyLen)
 	if err != nil {
 		panic("GoGoGadgetPaillier GenerateKey error: " + err.Error())
 	}
+	gggp.profile = profile
+	gggp.pool.drop()
 	//fmt.Println("GGGP set up is done, privateKey info: ", gggp.privateKey)
 }
 
 func (gggp *GoGoGadgetPaillier) Encrypt(b *big.Int) *env.Cipher {
+	if rn := gggp.pool.pop(); rn != nil {
+		return gggp.encryptWith(b, rn)
+	}
 	cipher, err := paillier.Encrypt(&gggp.privateKey.PublicKey, b.Bytes())
 	if err != nil {
 		panic("GoGoGadgetPaillier Encrypt error: " + err.Error())
@@ -32,18 +46,87 @@ func (gggp *GoGoGadgetPaillier) Encrypt(b *big.Int) *env.Cipher {
 	return &env.Cipher{C1: cipherBigInt}
 }
 
-func (gggp *GoGoGadgetPaillier) IsZero(c *env.Cipher) bool {
+// encryptWith returns g^b * r^n mod n^2 for the precomputed rn = r^n mod n^2.
+func (gggp *GoGoGadgetPaillier) encryptWith(b *big.Int, rn *env.Cipher) *env.Cipher {
+
+	n2 := gggp.privateKey.PublicKey.NSquared
+	c := new(big.Int).Exp(gggp.privateKey.PublicKey.G, b, n2)
+	c.Mul(c, rn.C1)
+	return &env.Cipher{C1: c.Mod(c, n2)}
+
+}
+
+// encryptInverseWith returns g^(-b) * r^n mod n^2 for the precomputed rn = r^n mod n^2.
+func (gggp *GoGoGadgetPaillier) encryptInverseWith(b *big.Int, rn *env.Cipher) *env.Cipher {
+
+	n2 := gggp.privateKey.PublicKey.NSquared
+	c := new(big.Int).Exp(gggp.privateKey.PublicKey.G, b, n2)
+	c.ModInverse(c, n2)
+	c.Mul(c, rn.C1)
+	return &env.Cipher{C1: c.Mod(c, n2)}
+
+}
+
+func (gggp *GoGoGadgetPaillier) EncryptBatch(bs []*big.Int) []*env.Cipher {
+	return encryptBatch(bs, gggp.pool.take(len(bs), gggp.freshRN), gggp.encryptWith)
+}
+
+func (gggp *GoGoGadgetPaillier) EncryptInverseBatch(bs []*big.Int) []*env.Cipher {
+	return encryptBatch(bs, gggp.pool.take(len(bs), gggp.freshRN), gggp.encryptInverseWith)
+}
+
+// PrecomputeRandomness adds count fresh r^n mod n^2 values to the pool.
+func (gggp *GoGoGadgetPaillier) PrecomputeRandomness(count int) {
+	gggp.pool.fill(count, gggp.freshRN)
+}
+
+// SaveRandomness writes the precomputed r^n values to a file, with the public key they belong to.
+func (gggp *GoGoGadgetPaillier) SaveRandomness(fileName string) error {
+	return gggp.pool.save(fileName, gggp.profile.ID, []*big.Int{gggp.privateKey.PublicKey.N})
+}
+
+// LoadRandomness replaces the pool with the r^n values of a file saved for the same public key.
+func (gggp *GoGoGadgetPaillier) LoadRandomness(fileName string) error {
+	return gggp.pool.load(fileName, gggp.profile.ID, []*big.Int{gggp.privateKey.PublicKey.N})
+}
+
+func (gggp *GoGoGadgetPaillier) freshRN() *env.Cipher {
+
+	n := gggp.privateKey.PublicKey.N
+	r, err := rand.Int(rand.Reader, n)
+	if err != nil {
+		return nil
+	}
+	return &env.Cipher{C1: r.Exp(r, n, gggp.privateKey.PublicKey.NSquared)}
+
+}
+
+// IsZero reports whether the ciphertext is an encryption of zero, or the error of Decrypt.
+func (gggp *GoGoGadgetPaillier) IsZero(c *env.Cipher) (bool, error) {
+	plain, err := gggp.Decrypt(c)
+	if err != nil {
+		return false, err
+	}
+	return plain.Sign() == 0, nil
+}
+
+func (gggp *GoGoGadgetPaillier) Decrypt(c *env.Cipher) (*big.Int, error) {
+	if err := gggp.ValidateCipher(c); err != nil {
+		return nil, err
+	}
 	plain, err := paillier.Decrypt(gggp.privateKey, c.C1.Bytes())
 	if err != nil {
-		panic("GoGoGadgetPaillier Decrypt error: " + err.Error())
+		return nil, err
 	}
-	plainBigInt := new(big.Int).SetBytes(plain)
-	isZero := (big.NewInt(0).Cmp(plainBigInt) == 0)
-	return isZero
+	return new(big.Int).SetBytes(plain), nil
 }
 
 func (gggp *GoGoGadgetPaillier) EncryptInverse(b *big.Int) *env.Cipher {
 
+	if rn := gggp.pool.pop(); rn != nil {
+		return gggp.encryptInverseWith(b, rn)
+	}
+
 	// c = g^(-b) * r^n mod n^2
 	g := gggp.privateKey.PublicKey.G
 	n := gggp.privateKey.PublicKey.N
@@ -61,26 +144,64 @@ func (gggp *GoGoGadgetPaillier) EncryptInverse(b *big.Int) *env.Cipher {
 
 }
 
-func (gggp *GoGoGadgetPaillier) InvertCipher(inputcipher *env.Cipher) *env.Cipher {
+func (gggp *GoGoGadgetPaillier) InvertCipher(inputcipher *env.Cipher) (*env.Cipher, error) {
 
+	if err := checkUnitCiphers(gggp.privateKey.PublicKey.NSquared, inputcipher); err != nil {
+		return nil, err
+	}
 	c1 := new(big.Int).ModInverse(inputcipher.C1, gggp.privateKey.PublicKey.NSquared)
-	return &env.Cipher{C1: c1}
+	if c1 == nil {
+		return nil, ErrCipherOutOfRange
+	}
+	return &env.Cipher{C1: c1}, nil
 
 }
 
-func (gggp *GoGoGadgetPaillier) MultCiphers(cipher1, cipher2 *env.Cipher) *env.Cipher {
+func (gggp *GoGoGadgetPaillier) MultCiphers(cipher1, cipher2 *env.Cipher) (*env.Cipher, error) {
 
+	if err := checkUnitCiphers(gggp.privateKey.PublicKey.NSquared, cipher1, cipher2); err != nil {
+		return nil, err
+	}
 	cipher := paillier.AddCipher(&gggp.privateKey.PublicKey, cipher1.C1.Bytes(), cipher2.C1.Bytes())
 	cipherBigInt := new(big.Int).SetBytes(cipher)
-	return &env.Cipher{C1: cipherBigInt}
+	return &env.Cipher{C1: cipherBigInt}, nil
 
 }
 
-func (gggp *GoGoGadgetPaillier) HideCipherWithR(cipher *env.Cipher, r *big.Int) *env.Cipher {
+func (gggp *GoGoGadgetPaillier) HideCipherWithR(cipher *env.Cipher, r *big.Int) (*env.Cipher, error) {
 
+	if err := checkUnitCiphers(gggp.privateKey.PublicKey.NSquared, cipher); err != nil {
+		return nil, err
+	}
+	if new(big.Int).Mod(r, gggp.privateKey.PublicKey.N).Sign() == 0 {
+		return nil, ErrZeroBlinding
+	}
 	result := paillier.Mul(&gggp.privateKey.PublicKey, cipher.C1.Bytes(), r.Bytes())
 	resultBigInt := new(big.Int).SetBytes(result)
-	return &env.Cipher{C1: resultBigInt}
+	return &env.Cipher{C1: resultBigInt}, nil
+
+}
+
+func (gggp *GoGoGadgetPaillier) MultCipherArray(ciphers []*env.Cipher) (*env.Cipher, error) {
+
+	if err := checkUnitCiphers(gggp.privateKey.PublicKey.NSquared, ciphers...); err != nil {
+		return nil, err
+	}
+	c1s, _ := splitCiphers(ciphers)
+	return &env.Cipher{C1: productMod(c1s, gggp.privateKey.PublicKey.NSquared)}, nil
+
+}
+
+func (gggp *GoGoGadgetPaillier) MultiExpCiphers(ciphers []*env.Cipher, scalars []*big.Int) (*env.Cipher, error) {
+
+	if len(ciphers) != len(scalars) {
+		return nil, ErrCipherCountMismatch
+	}
+	if err := checkUnitCiphers(gggp.privateKey.PublicKey.NSquared, ciphers...); err != nil {
+		return nil, err
+	}
+	c1s, _ := splitCiphers(ciphers)
+	return &env.Cipher{C1: multiExpMod(c1s, scalars, gggp.privateKey.PublicKey.NSquared)}, nil
 
 }
 
@@ -90,4 +211,16 @@ func (gggp *GoGoGadgetPaillier) GetGroupOrder() *big.Int {
 
 }
 
+func (gggp *GoGoGadgetPaillier) Profile() *profiles.Profile {
+	return gggp.profile
+}
+
+// ValidateCipher checks that the ciphertext is a unit mod n^2.
+func (gggp *GoGoGadgetPaillier) ValidateCipher(c *env.Cipher) error {
+	if c == nil || c.C1 == nil {
+		return ErrMissingCipherComponent
+	}
+	return validateUnit(c.C1, gggp.privateKey.PublicKey.N, gggp.privateKey.PublicKey.NSquared)
+}
+
 // ========================== GoGoGadgetPaillier https://github.com/Roasbeef/go-go-gadget-paillier ===================
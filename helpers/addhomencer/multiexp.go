@@ -0,0 +1,255 @@
+package addhomencer
+
+import (
+	"math/big"
+	"math/bits"
+	"sync"
+
+	env "github.com/eozturk1/genomic-security-journal-code/helpers/env"
+)
+
+// ========================== Batch products and simultaneous multi-exponentiation ==========================
+// Products and products of powers of many ciphertexts are computed in Montgomery form, where a modular multiplication
+// is a word-by-word reduction instead of a long division. A product of n values needs no conversion of the inputs:
+// chaining Mont(acc, x) = acc * x * R^(-1) over the plain values gives x_1 * ... * x_n * R^(-(n-1)), which one last
+// Montgomery multiplication by R^n corrects. Products of powers use Straus' method: the squarings of the exponent
+// windows are shared by all the bases, so n exponentiations of b bits cost b squarings and about n*b/w multiplications.
+
+// multiExpWindow is the window of Straus' method; each base needs 2^multiExpWindow - 2 multiplications for its table.
+const multiExpWindow = 4
+
+// productMinLength is the length from which a product is split over ConcurrencyLevel goroutines.
+const productMinLength = 64
+
+type montgomery struct {
+	modulus *big.Int
+	n       []big.Word // modulus words, little-endian
+	n0inv   big.Word   // -n^(-1) mod 2^wordBits
+	rr      []big.Word // R^2 mod modulus, R = 2^(wordBits*len(n))
+}
+
+// newMontgomery returns the Montgomery context of an odd modulus.
+func newMontgomery(modulus *big.Int) *montgomery {
+
+	s := len(modulus.Bits())
+	m := &montgomery{modulus: modulus, n: modulus.Bits()}
+
+	// n0inv = -n^(-1) mod 2^wordBits by Newton's iteration, each step doubling the number of correct bits
+	inv := big.Word(1)
+	for i := 0; i < 7; i++ {
+		inv *= 2 - m.n[0]*inv
+	}
+	m.n0inv = -inv
+
+	rr := new(big.Int).Lsh(big.NewInt(1), uint(2*s*bits.UintSize))
+	m.rr = m.words(rr.Mod(rr, modulus))
+
+	return m
+
+}
+
+// words returns the words of x mod modulus, padded to the length of the modulus.
+func (m *montgomery) words(x *big.Int) []big.Word {
+	if x.Sign() < 0 || x.Cmp(m.modulus) >= 0 {
+		x = new(big.Int).Mod(x, m.modulus)
+	}
+	z := make([]big.Word, len(m.n))
+	copy(z, x.Bits())
+	return z
+}
+
+// mul sets z = x * y * R^(-1) mod modulus, with the multiplication and the reduction of each word of y interleaved in
+// one pass (FIOS method). z may alias x or y; t is scratch of len(n)+1 words.
+func (m *montgomery) mul(z, x, y, t []big.Word) {
+
+	s := len(m.n)
+	n, x, y, t := m.n[:s], x[:s], y[:s], t[:s+1]
+	for i := range t {
+		t[i] = 0
+	}
+
+	for i := 0; i < s; i++ {
+		// t = (t + x * y[i] + q * n) / 2^wordBits, with q such that the lowest word of the sum is zero
+		yi := uint(y[i])
+		hi1, lo1 := bits.Mul(uint(x[0]), yi)
+		lo1, c := bits.Add(lo1, uint(t[0]), 0)
+		hi1 += c
+		q := lo1 * uint(m.n0inv)
+		hi2, lo2 := bits.Mul(q, uint(n[0]))
+		_, c = bits.Add(lo2, lo1, 0)
+		hi2 += c
+		for j := 1; j < s; j++ {
+			carry1, carry2 := hi1, hi2
+			hi1, lo1 = bits.Mul(uint(x[j]), yi)
+			lo1, c = bits.Add(lo1, uint(t[j]), 0)
+			hi1 += c
+			lo1, c = bits.Add(lo1, carry1, 0)
+			hi1 += c
+			hi2, lo2 = bits.Mul(q, uint(n[j]))
+			lo2, c = bits.Add(lo2, lo1, 0)
+			hi2 += c
+			lo2, c = bits.Add(lo2, carry2, 0)
+			hi2 += c
+			t[j-1] = big.Word(lo2)
+		}
+		sum, c1 := bits.Add(uint(t[s]), hi1, 0)
+		sum, c2 := bits.Add(sum, hi2, 0)
+		t[s-1], t[s] = big.Word(sum), big.Word(c1+c2)
+	}
+
+	// t < 2n, so one subtraction at most
+	if t[s] != 0 || !lessThan(t[:s], n) {
+		var borrow uint
+		for j := 0; j < s; j++ {
+			var d uint
+			d, borrow = bits.Sub(uint(t[j]), uint(n[j]), borrow)
+			t[j] = big.Word(d)
+		}
+	}
+	copy(z, t[:s])
+
+}
+
+func lessThan(x, y []big.Word) bool {
+	for j := len(x) - 1; j >= 0; j-- {
+		if x[j] != y[j] {
+			return x[j] < y[j]
+		}
+	}
+	return false
+}
+
+// product returns values[0] * ... * values[n-1] mod modulus.
+func (m *montgomery) product(values []*big.Int) *big.Int {
+
+	if len(values) == 0 {
+		return big.NewInt(1)
+	}
+
+	t := make([]big.Word, len(m.n)+1)
+	acc := m.words(values[0])
+	for _, v := range values[1:] {
+		m.mul(acc, acc, m.words(v), t)
+	}
+
+	// acc = x_1 * ... * x_n * R^(-(n-1)), so Mont(acc, R^n) is the product
+	rn := new(big.Int).Lsh(big.NewInt(1), uint(len(m.n)*bits.UintSize))
+	rn.Exp(rn, big.NewInt(int64(len(values))), m.modulus)
+	m.mul(acc, acc, m.words(rn), t)
+
+	return new(big.Int).SetBits(acc)
+
+}
+
+// fromMont returns x * R^(-1) mod modulus as a big.Int.
+func (m *montgomery) fromMont(x, t []big.Word) *big.Int {
+	one := make([]big.Word, len(m.n))
+	one[0] = 1
+	z := make([]big.Word, len(m.n))
+	m.mul(z, x, one, t)
+	return new(big.Int).SetBits(z)
+}
+
+// multiExp returns bases[0]^exps[0] * ... * bases[n-1]^exps[n-1] mod modulus, for non-negative exponents.
+func (m *montgomery) multiExp(bases, exps []*big.Int) *big.Int {
+
+	s := len(m.n)
+	t := make([]big.Word, s+1)
+	digits := 1 << multiExpWindow
+
+	// tables[i][d] = bases[i]^d * R
+	tables := make([][][]big.Word, len(bases))
+	maxBits := 0
+	for i, b := range bases {
+		tables[i] = make([][]big.Word, digits)
+		tables[i][1] = m.words(b)
+		m.mul(tables[i][1], tables[i][1], m.rr, t)
+		for d := 2; d < digits; d++ {
+			tables[i][d] = make([]big.Word, s)
+			m.mul(tables[i][d], tables[i][d-1], tables[i][1], t)
+		}
+		if exps[i].BitLen() > maxBits {
+			maxBits = exps[i].BitLen()
+		}
+	}
+
+	// acc = R, i.e., 1 in Montgomery form
+	acc := m.words(new(big.Int).Lsh(big.NewInt(1), uint(s*bits.UintSize)))
+	for w := (maxBits+multiExpWindow-1)/multiExpWindow - 1; w >= 0; w-- {
+		for k := 0; k < multiExpWindow; k++ {
+			m.mul(acc, acc, acc, t)
+		}
+		for i, e := range exps {
+			if d := windowDigit(e.Bits(), uint(w*multiExpWindow), multiExpWindow); d != 0 {
+				m.mul(acc, acc, tables[i][d], t)
+			}
+		}
+	}
+
+	return m.fromMont(acc, t)
+
+}
+
+// productMod is montgomery.product, split over ConcurrencyLevel goroutines for long inputs.
+func productMod(values []*big.Int, modulus *big.Int) *big.Int {
+
+	var wg sync.WaitGroup
+
+	m := newMontgomery(modulus)
+	if len(values) < productMinLength {
+		return m.product(values)
+	}
+
+	partial := make([]*big.Int, ConcurrencyLevel)
+	chunk := (len(values) + ConcurrencyLevel - 1) / ConcurrencyLevel
+	wg.Add(ConcurrencyLevel)
+	for w := 0; w < ConcurrencyLevel; w++ {
+		go func(w int, wg *sync.WaitGroup) {
+			start, end := w*chunk, (w+1)*chunk
+			if end > len(values) {
+				end = len(values)
+			}
+			if start > end {
+				start = end
+			}
+			partial[w] = m.product(values[start:end])
+			wg.Done()
+		}(w, &wg)
+	}
+	wg.Wait()
+
+	return m.product(partial)
+
+}
+
+// multiExpMod returns bases[0]^exps[0] * ... * bases[n-1]^exps[n-1] mod modulus. A negative exponent is applied to the
+// inverse of its base.
+func multiExpMod(bases, exps []*big.Int, modulus *big.Int) *big.Int {
+
+	b := make([]*big.Int, len(bases))
+	e := make([]*big.Int, len(exps))
+	for i := range bases {
+		b[i], e[i] = bases[i], exps[i]
+		if e[i].Sign() < 0 {
+			b[i] = new(big.Int).ModInverse(bases[i], modulus)
+			e[i] = new(big.Int).Neg(exps[i])
+		}
+	}
+
+	return newMontgomery(modulus).multiExp(b, e)
+
+}
+
+// splitCiphers returns the C1 and the C2 components of the ciphertexts.
+func splitCiphers(ciphers []*env.Cipher) ([]*big.Int, []*big.Int) {
+
+	c1s := make([]*big.Int, len(ciphers))
+	c2s := make([]*big.Int, len(ciphers))
+	for i, c := range ciphers {
+		c1s[i], c2s[i] = c.C1, c.C2
+	}
+	return c1s, c2s
+
+}
+
+// ========================== Batch products and simultaneous multi-exponentiation ==========================
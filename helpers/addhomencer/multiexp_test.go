@@ -0,0 +1,110 @@
+package addhomencer
+
+import (
+	"crypto/rand"
+	"math/big"
+	"testing"
+)
+
+// testModuli are odd moduli of one word, of a word and a bit, and of a Damgård–Jurik size
+func testModuli(t *testing.T) []*big.Int {
+
+	moduli := []*big.Int{big.NewInt(0xfffffffb), new(big.Int).SetUint64(1<<63 + 25)}
+	for _, size := range []int{65, 1024, 3072} {
+		m, err := rand.Prime(rand.Reader, size)
+		if err != nil {
+			t.Fatal(err)
+		}
+		moduli = append(moduli, m)
+	}
+	p, _ := rand.Prime(rand.Reader, 512)
+	q, _ := rand.Prime(rand.Reader, 512)
+	return append(moduli, new(big.Int).Mul(p, q))
+
+}
+
+func randomBelow(t *testing.T, bound *big.Int) *big.Int {
+	x, err := rand.Int(rand.Reader, bound)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return x
+}
+
+func TestProductModMatchesBigInt(t *testing.T) {
+
+	for _, modulus := range testModuli(t) {
+		// below and above productMinLength, so that the parallel path runs too
+		for _, n := range []int{0, 1, 2, productMinLength - 1, productMinLength, 3*productMinLength + 7} {
+			values := make([]*big.Int, n)
+			want := big.NewInt(1)
+			for i := range values {
+				values[i] = randomBelow(t, modulus)
+				if i == 1 {
+					values[i] = new(big.Int).Add(values[i], modulus) // not reduced
+				}
+				want.Mul(want, values[i]).Mod(want, modulus)
+			}
+			if got := productMod(values, modulus); got.Cmp(want.Mod(want, modulus)) != 0 {
+				t.Fatalf("product of %d values mod %d bits: got %v, want %v", n, modulus.BitLen(), got, want)
+			}
+		}
+	}
+
+}
+
+func TestMultiExpModMatchesBigInt(t *testing.T) {
+
+	for _, modulus := range testModuli(t) {
+		for _, n := range []int{1, 2, 5, productMinLength} {
+			bases := make([]*big.Int, n)
+			exps := make([]*big.Int, n)
+			want := big.NewInt(1)
+			for i := range bases {
+				bases[i] = randomBelow(t, modulus)
+				if new(big.Int).GCD(nil, nil, bases[i], modulus).Cmp(one) != 0 {
+					bases[i].SetInt64(2)
+				}
+				switch i % 5 {
+				case 0:
+					exps[i] = big.NewInt(0)
+				case 1:
+					exps[i] = big.NewInt(1)
+				case 2:
+					exps[i] = new(big.Int).Neg(randomBelow(t, modulus))
+				default:
+					exps[i] = randomBelow(t, new(big.Int).Lsh(modulus, 3))
+				}
+				want.Mul(want, new(big.Int).Exp(bases[i], exps[i], modulus)).Mod(want, modulus)
+			}
+			if got := multiExpMod(bases, exps, modulus); got.Cmp(want) != 0 {
+				t.Fatalf("%d powers mod %d bits: got %v, want %v", n, modulus.BitLen(), got, want)
+			}
+		}
+	}
+
+}
+
+func TestMultiExpModEdgeCases(t *testing.T) {
+
+	modulus := testModuli(t)[3]
+	x := randomBelow(t, modulus)
+
+	if got := multiExpMod(nil, nil, modulus); got.Cmp(one) != 0 {
+		t.Fatalf("empty product is %v", got)
+	}
+	if got := multiExpMod([]*big.Int{x}, []*big.Int{big.NewInt(0)}, modulus); got.Cmp(one) != 0 {
+		t.Fatalf("x^0 is %v", got)
+	}
+	if got := multiExpMod([]*big.Int{big.NewInt(0)}, []*big.Int{big.NewInt(5)}, modulus); got.Sign() != 0 {
+		t.Fatalf("0^5 is %v", got)
+	}
+	if got := multiExpMod([]*big.Int{x}, []*big.Int{big.NewInt(-1)}, modulus); got.Cmp(new(big.Int).ModInverse(x, modulus)) != 0 {
+		t.Fatalf("x^(-1) is %v", got)
+	}
+	minusOne := new(big.Int).Sub(modulus, one)
+	if got := multiExpMod([]*big.Int{minusOne, x}, []*big.Int{big.NewInt(2), one}, modulus); got.Cmp(x) != 0 {
+		t.Fatalf("(-1)^2 x is %v", got)
+	}
+
+}
@@ -0,0 +1,459 @@
+package addhomencer
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"sync"
+
+	env "github.com/eozturk1/genomic-security-journal-code/helpers/env"
+)
+
+// ========================== Proofs of well-formed ciphertexts ==========================
+// The encryptor proves that a ciphertext it computed is of an agreed form, without telling which one, by an OR proof of
+// Cramer, Damgård and Schoenmakers (CRYPTO 1994) made non-interactive by Fiat–Shamir: the challenge is split among the
+// statements, and all of them but the true one are simulated. A statement is a set of equations target = prod base^w
+// over the group of AH-ElGamal, with the same witnesses w in all of its equations.
+//
+// The challenge hashes a domain, which is the kind of the proof, and a session, which the caller chooses, e.g. a nonce of
+// the setup of a tester, so that a proof verifies neither as a proof of another kind nor in another session.
+//
+// Only AH-ElGamal proves these so far: the statements below are in a group of known prime order, and Damgård–Jurik
+// would need integer responses over a group of unknown order.
+
+var (
+	ErrInvalidWellFormednessProof = errors.New("proof of well-formedness is invalid")
+	ErrNoWellFormednessProof      = errors.New("the scheme has no proof of well-formedness")
+)
+
+// WellFormednessProver is a scheme whose encryptor can prove the form of the ciphertexts it computes. The Verify methods
+// need the public key only.
+type WellFormednessProver interface {
+	// EncryptInverseWithProof returns E(-b) with a proof that b is one of the candidates, and the index of b among them
+	// has to be given.
+	EncryptInverseWithProof(session []byte, candidates []*big.Int, index int) (*env.Cipher, *ORProof, error)
+	VerifyEncryptedInverse(session []byte, c *env.Cipher, candidates []*big.Int, proof *ORProof) error
+
+	// EncryptInverseOfCommitted returns E(-b) with a proof that b is the value of the commitment G^b H^rho (see Commit).
+	EncryptInverseOfCommitted(session []byte, b, rho, commitment *big.Int) (*env.Cipher, *ORProof, error)
+	VerifyEncryptedCommitted(session []byte, c *env.Cipher, commitment *big.Int, proof *ORProof) error
+
+	// HideCiphersWithProof returns ciphers[sources[i]]^r_i E(0), or padding^r_i E(0), rerandomized, with a proof that
+	// the results are a shuffle of such powers, for units r_i, of each of the ciphers and of copies of the padding: one
+	// of the results is an encryption of zero iff one of the ciphers, or the padding, is. If exact, the padding is only
+	// rerandomized.
+	HideCiphersWithProof(session []byte, ciphers []*env.Cipher, padding *env.Cipher, exact bool, sources []int, rs []*big.Int) ([]*env.Cipher, *HidingProof, error)
+	VerifyHiddenCiphers(session []byte, results []*env.Cipher, ciphers []*env.Cipher, padding *env.Cipher, exact bool, proof *HidingProof) error
+
+	// EncryptTrivially returns E(b) with no randomness, which anyone can check.
+	EncryptTrivially(b *big.Int) *env.Cipher
+}
+
+// ORProof is the split challenge and the responses of each statement.
+type ORProof struct {
+	Challenges []*big.Int
+	Responses  [][]*big.Int
+}
+
+// linearStatement is the equations targets[e] = prod_j bases[e][j]^w_j.
+type linearStatement struct {
+	targets []*big.Int
+	bases   [][]*big.Int
+}
+
+// Domains of the OR proofs.
+const (
+	inverseDomain   = "encrypted inverse"
+	committedDomain = "encrypted committed"
+	hidingDomain    = "hidden ciphers"
+	zeroDomain      = "one zero"
+)
+
+// proveOR proves the statement at index with its witnesses. All the values are in the subgroup of order q of Z_P^*.
+func (ahelgamal *AHElGamal) proveOR(domain string, session []byte, statements []*linearStatement, index int, witnesses []*big.Int) (*ORProof, error) {
+
+	p, q := ahelgamal.Pk.P, ahelgamal.Pk.Q
+	proof := &ORProof{Challenges: make([]*big.Int, len(statements)), Responses: make([][]*big.Int, len(statements))}
+	commitments := make([][]*big.Int, len(statements))
+
+	// simulated statements: random challenge and responses, and the commitments that they verify against
+	sum := new(big.Int)
+	for i, statement := range statements {
+		if i == index {
+			continue
+		}
+		c, err := randomExponent(q)
+		if err != nil {
+			return nil, err
+		}
+		responses := make([]*big.Int, len(witnesses))
+		for j := range responses {
+			if responses[j], err = randomExponent(q); err != nil {
+				return nil, err
+			}
+		}
+		proof.Challenges[i], proof.Responses[i] = c, responses
+		commitments[i] = statement.commitments(p, q, c, responses)
+		sum.Add(sum, c)
+	}
+
+	// true statement: commitments of random nonces, and the rest of the challenge
+	nonces := make([]*big.Int, len(witnesses))
+	for j := range nonces {
+		var err error
+		if nonces[j], err = randomExponent(q); err != nil {
+			return nil, err
+		}
+	}
+	statement := statements[index]
+	commitments[index] = make([]*big.Int, len(statement.targets))
+	for e := range statement.targets {
+		commitments[index][e] = multiExpMod(statement.bases[e], nonces, p)
+	}
+	c := ahelgamal.orChallenge(domain, session, statements, commitments)
+	c.Sub(c, sum).Mod(c, q)
+	responses := make([]*big.Int, len(witnesses))
+	for j := range responses {
+		responses[j] = new(big.Int).Mul(c, witnesses[j])
+		responses[j].Add(responses[j], nonces[j]).Mod(responses[j], q)
+	}
+	proof.Challenges[index], proof.Responses[index] = c, responses
+	return proof, nil
+
+}
+
+func (ahelgamal *AHElGamal) verifyOR(domain string, session []byte, statements []*linearStatement, witnesses int, proof *ORProof) error {
+
+	p, q := ahelgamal.Pk.P, ahelgamal.Pk.Q
+	if proof == nil || len(proof.Challenges) != len(statements) || len(proof.Responses) != len(statements) {
+		return ErrInvalidWellFormednessProof
+	}
+	commitments := make([][]*big.Int, len(statements))
+	sum := new(big.Int)
+	for i, statement := range statements {
+		c, responses := proof.Challenges[i], proof.Responses[i]
+		if c == nil || !inRange(q, c) || len(responses) != witnesses {
+			return ErrInvalidWellFormednessProof
+		}
+		for _, z := range responses {
+			if z == nil || !inRange(q, z) {
+				return ErrInvalidWellFormednessProof
+			}
+		}
+		commitments[i] = statement.commitments(p, q, c, responses)
+		sum.Add(sum, c)
+	}
+	if sum.Mod(sum, q).Cmp(ahelgamal.orChallenge(domain, session, statements, commitments)) != 0 {
+		return ErrInvalidWellFormednessProof
+	}
+	return nil
+
+}
+
+// commitments returns prod_j bases[e][j]^z_j * targets[e]^(-c) for each equation.
+func (statement *linearStatement) commitments(p, q, c *big.Int, responses []*big.Int) []*big.Int {
+	minusC := new(big.Int).Sub(q, c)
+	commitments := make([]*big.Int, len(statement.targets))
+	for e, target := range statement.targets {
+		commitments[e] = multiExpMod(append(append([]*big.Int{}, statement.bases[e]...), target), append(append([]*big.Int{}, responses...), minusC), p)
+	}
+	return commitments
+}
+
+// orChallenge hashes the domain, the session, the statements and the commitments into a challenge mod q.
+func (ahelgamal *AHElGamal) orChallenge(domain string, session []byte, statements []*linearStatement, commitments [][]*big.Int) *big.Int {
+
+	h := ahelgamal.profile.NewHash()
+	var length [4]byte
+	writeBytes := func(b []byte) {
+		binary.BigEndian.PutUint32(length[:], uint32(len(b)))
+		h.Write(length[:])
+		h.Write(b)
+	}
+	write := func(v *big.Int) {
+		writeBytes(v.Bytes())
+	}
+	writeBytes([]byte(domain))
+	writeBytes(session)
+	write(ahelgamal.Pk.P)
+	write(ahelgamal.Pk.G)
+	write(ahelgamal.Pk.Y)
+	for i, statement := range statements {
+		for e, target := range statement.targets {
+			write(target)
+			for _, base := range statement.bases[e] {
+				write(base)
+			}
+			write(commitments[i][e])
+		}
+	}
+	c := new(big.Int).SetBytes(h.Sum(nil))
+	return c.Mod(c, ahelgamal.Pk.Q)
+
+}
+
+// EncryptInverseWithProof returns (G^k, Y^k G^(-b)) for b = candidates[index], with a proof that C1 = G^k and
+// C2 G^b = Y^k for one of the candidates b.
+func (ahelgamal *AHElGamal) EncryptInverseWithProof(session []byte, candidates []*big.Int, index int) (*env.Cipher, *ORProof, error) {
+
+	k, err := randomExponent(ahelgamal.Pk.Q)
+	if err != nil {
+		return nil, nil, err
+	}
+	c := ahelgamal.encryptInverseWith(candidates[index], &env.Cipher{C1: ahelgamal.expG(k), C2: ahelgamal.expY(k)})
+	proof, err := ahelgamal.proveOR(inverseDomain, session, ahelgamal.inverseStatements(c, candidates), index, []*big.Int{k})
+	if err != nil {
+		return nil, nil, err
+	}
+	return c, proof, nil
+
+}
+
+func (ahelgamal *AHElGamal) VerifyEncryptedInverse(session []byte, c *env.Cipher, candidates []*big.Int, proof *ORProof) error {
+	if err := ahelgamal.ValidateCipher(c); err != nil {
+		return err
+	}
+	return ahelgamal.verifyOR(inverseDomain, session, ahelgamal.inverseStatements(c, candidates), 1, proof)
+}
+
+func (ahelgamal *AHElGamal) inverseStatements(c *env.Cipher, candidates []*big.Int) []*linearStatement {
+	p, g, y := ahelgamal.Pk.P, ahelgamal.Pk.G, ahelgamal.Pk.Y
+	statements := make([]*linearStatement, len(candidates))
+	for i, b := range candidates {
+		c2 := ahelgamal.expG(b)
+		c2.Mul(c2, c.C2).Mod(c2, p)
+		statements[i] = &linearStatement{targets: []*big.Int{c.C1, c2}, bases: [][]*big.Int{{g}, {y}}}
+	}
+	return statements
+}
+
+// HidingProof proves that results were computed from ciphers and a padding as in HideCiphersWithProof. The results are
+// matched with slots, which are the ciphers and then as many copies of the padding as there are more results than
+// ciphers: for each result, an OR proof over the slots that it is a power of the base of one of them, and that its tag is
+// U^z for the tag base U of that slot (see tagBase), with Key = G^z. The tags are distinct, so no two results are of the
+// same slot, and there are as many results as slots, so the results are a shuffle of the slots. Under DDH, a tag does
+// not tell which U it is a power of.
+type HidingProof struct {
+	Key     *big.Int
+	Tags    []*big.Int
+	Results []*ORProof
+}
+
+// HideCiphersWithProof returns, for each i, R_i = B^r_i (G^k_i, Y^k_i) for B = ciphers[sources[i]], or B = padding for
+// a source out of the ciphers, with r_i = 1 for the padding if exact. Every cipher has to be the source of exactly one
+// result, and the order of the results is the one of sources, so that a shuffle of the sources hides which result is
+// which.
+//
+// Each statement is about witnesses (r, k, s, k', z) such that R = B^r (G^k, Y^k) and B = R^s (G^k', Y^k'), with the tag
+// of the result: the second power, s = r^(-1) and k' = -k s, shows that r is a unit, so that R is an encryption of zero
+// iff B is.
+func (ahelgamal *AHElGamal) HideCiphersWithProof(session []byte, ciphers []*env.Cipher, padding *env.Cipher, exact bool, sources []int, rs []*big.Int) ([]*env.Cipher, *HidingProof, error) {
+
+	p, q := ahelgamal.Pk.P, ahelgamal.Pk.Q
+	if len(rs) != len(sources) {
+		return nil, nil, errors.New("there should be a blinding exponent for each result")
+	}
+	if len(sources) < len(ciphers) {
+		return nil, nil, errors.New("there should be a result for each cipher")
+	}
+	z, err := randomExponent(q)
+	if err != nil {
+		return nil, nil, err
+	}
+	bases := ahelgamal.tagBases(session, len(sources))
+	proof := &HidingProof{Key: ahelgamal.expG(z), Tags: make([]*big.Int, len(sources)), Results: make([]*ORProof, len(sources))}
+
+	results := make([]*env.Cipher, len(sources))
+	witnesses := make([][]*big.Int, len(sources))
+	slots := make([]int, len(sources))
+	covered := make([]bool, len(ciphers))
+	paddings := len(ciphers)
+	for i, source := range sources {
+		b, r := padding, new(big.Int).Mod(rs[i], q)
+		if source >= 0 && source < len(ciphers) {
+			if covered[source] {
+				return nil, nil, errors.New("a cipher should be the source of one result only")
+			}
+			b, slots[i], covered[source] = ciphers[source], source, true
+		} else {
+			slots[i] = paddings
+			paddings++
+			if exact {
+				r.SetInt64(1)
+			}
+		}
+		if r.Sign() == 0 {
+			return nil, nil, errors.New("blinding exponent is zero mod q")
+		}
+		k, err := randomExponent(q)
+		if err != nil {
+			return nil, nil, err
+		}
+		results[i] = &env.Cipher{
+			C1: new(big.Int).Mod(new(big.Int).Mul(new(big.Int).Exp(b.C1, r, p), ahelgamal.expG(k)), p),
+			C2: new(big.Int).Mod(new(big.Int).Mul(new(big.Int).Exp(b.C2, r, p), ahelgamal.expY(k)), p),
+		}
+		s := new(big.Int).ModInverse(r, q)
+		k2 := new(big.Int).Mul(k, s)
+		k2.Sub(q, k2.Mod(k2, q)).Mod(k2, q)
+		witnesses[i] = []*big.Int{r, k, s, k2, z}
+	}
+	for _, ok := range covered {
+		if !ok {
+			return nil, nil, errors.New("every cipher should be the source of a result")
+		}
+	}
+	for i := range results {
+		proof.Tags[i] = new(big.Int).Exp(bases[slots[i]], z, p)
+	}
+
+	errs := make([]error, len(results))
+	var wg sync.WaitGroup
+	wg.Add(len(results))
+	for i := range results {
+		go func(i int, wg *sync.WaitGroup) {
+			statements := ahelgamal.resultStatements(results[i], proof.Tags[i], proof.Key, bases, ciphers, padding, exact)
+			proof.Results[i], errs[i] = ahelgamal.proveOR(hidingDomain, session, statements, slots[i], witnesses[i])
+			wg.Done()
+		}(i, &wg)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return results, proof, nil
+
+}
+
+func (ahelgamal *AHElGamal) VerifyHiddenCiphers(session []byte, results []*env.Cipher, ciphers []*env.Cipher, padding *env.Cipher, exact bool, proof *HidingProof) error {
+
+	if proof == nil || len(results) < len(ciphers) || len(proof.Tags) != len(results) || len(proof.Results) != len(results) {
+		return ErrInvalidWellFormednessProof
+	}
+	for _, c := range append(append([]*env.Cipher{padding}, results...), ciphers...) {
+		if err := ahelgamal.ValidateCipher(c); err != nil {
+			return err
+		}
+	}
+	tags := make(map[string]bool, len(proof.Tags))
+	for _, tag := range append([]*big.Int{proof.Key}, proof.Tags...) {
+		if tag == nil || !ahelgamal.inSubgroup(tag) {
+			return ErrInvalidWellFormednessProof
+		}
+	}
+	for _, tag := range proof.Tags {
+		if tags[string(tag.Bytes())] {
+			return ErrInvalidWellFormednessProof
+		}
+		tags[string(tag.Bytes())] = true
+	}
+
+	bases := ahelgamal.tagBases(session, len(results))
+	return verifyAll(len(results), func(i int) error {
+		statements := ahelgamal.resultStatements(results[i], proof.Tags[i], proof.Key, bases, ciphers, padding, exact)
+		return ahelgamal.verifyOR(hidingDomain, session, statements, 5, proof.Results[i])
+	})
+
+}
+
+func (ahelgamal *AHElGamal) EncryptTrivially(b *big.Int) *env.Cipher {
+	return &env.Cipher{C1: big.NewInt(1), C2: ahelgamal.expG(b)}
+}
+
+// resultStatements are the statements that R is a power of the base of one of the slots, the ciphers and then copies of
+// the padding, and that its tag is of that slot.
+func (ahelgamal *AHElGamal) resultStatements(c *env.Cipher, tag, key *big.Int, bases []*big.Int, ciphers []*env.Cipher, padding *env.Cipher, exact bool) []*linearStatement {
+	statements := make([]*linearStatement, len(bases))
+	for j := range bases {
+		var statement *linearStatement
+		if j < len(ciphers) {
+			statement = ahelgamal.hiddenStatement(c, ciphers[j])
+		} else if exact {
+			statement = ahelgamal.rerandomizedStatement(c, padding)
+		} else {
+			statement = ahelgamal.hiddenStatement(c, padding)
+		}
+		statements[j] = ahelgamal.taggedStatement(statement, tag, key, bases[j])
+	}
+	return statements
+}
+
+// taggedStatement is the statement, with a last witness z such that tag = U^z and key = G^z.
+func (ahelgamal *AHElGamal) taggedStatement(statement *linearStatement, tag, key, u *big.Int) *linearStatement {
+	tagged := &linearStatement{targets: append(append([]*big.Int{}, statement.targets...), tag, key)}
+	for _, bases := range statement.bases {
+		tagged.bases = append(tagged.bases, append(append([]*big.Int{}, bases...), one))
+	}
+	none := make([]*big.Int, len(statement.bases[0]))
+	for j := range none {
+		none[j] = one
+	}
+	tagged.bases = append(tagged.bases, append(append([]*big.Int{}, none...), u), append(append([]*big.Int{}, none...), ahelgamal.Pk.G))
+	return tagged
+}
+
+// tagBases returns the tag bases of the given number of slots in the session, hashed into the group so that nobody
+// knows their logarithms.
+func (ahelgamal *AHElGamal) tagBases(session []byte, slots int) []*big.Int {
+	bases := make([]*big.Int, slots)
+	var index [4]byte
+	for j := range bases {
+		binary.BigEndian.PutUint32(index[:], uint32(j))
+		bases[j] = hashToGroup(ahelgamal.profile, "shuffle tag base", session, index[:])
+	}
+	return bases
+}
+
+// verifyAll runs verify over [0, n) concurrently, and returns one of the errors.
+func verifyAll(n int, verify func(i int) error) error {
+
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func(i int, wg *sync.WaitGroup) {
+			errs[i] = verify(i)
+			wg.Done()
+		}(i, &wg)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+
+}
+
+// hiddenStatement is, for witnesses (r, k, s, k'): R1 = B1^r G^k, R2 = B2^r Y^k, B1 = R1^s G^k' and B2 = R2^s Y^k'.
+func (ahelgamal *AHElGamal) hiddenStatement(c *env.Cipher, b *env.Cipher) *linearStatement {
+	g, y := ahelgamal.Pk.G, ahelgamal.Pk.Y
+	return &linearStatement{
+		targets: []*big.Int{c.C1, c.C2, b.C1, b.C2},
+		bases: [][]*big.Int{
+			{b.C1, g, one, one},
+			{b.C2, y, one, one},
+			{one, one, c.C1, g},
+			{one, one, c.C2, y},
+		},
+	}
+}
+
+// rerandomizedStatement is hiddenStatement with r = 1: R1 B1^(-1) = G^k and R2 B2^(-1) = Y^k. It keeps the four
+// witnesses, so that it can be in an OR with hiddenStatement.
+func (ahelgamal *AHElGamal) rerandomizedStatement(c *env.Cipher, b *env.Cipher) *linearStatement {
+	p, g, y := ahelgamal.Pk.P, ahelgamal.Pk.G, ahelgamal.Pk.Y
+	c1 := new(big.Int).ModInverse(b.C1, p)
+	c1.Mul(c1, c.C1).Mod(c1, p)
+	c2 := new(big.Int).ModInverse(b.C2, p)
+	c2.Mul(c2, c.C2).Mod(c2, p)
+	return &linearStatement{
+		targets: []*big.Int{c1, c2},
+		bases:   [][]*big.Int{{one, g, one, one}, {one, y, one, one}},
+	}
+}
+
+// ========================== Proofs of well-formed ciphertexts ==========================
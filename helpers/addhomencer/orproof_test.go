@@ -0,0 +1,187 @@
+package addhomencer
+
+import (
+	"math/big"
+	"testing"
+
+	env "github.com/eozturk1/genomic-security-journal-code/helpers/env"
+	"github.com/eozturk1/genomic-security-journal-code/helpers/profiles"
+)
+
+func testElGamal() *AHElGamal {
+
+	ahelgamal := &AHElGamal{}
+	ahelgamal.SetupProfile(profiles.Default)
+	return ahelgamal
+
+}
+
+// isZero is IsZero for ciphertexts that are known to be valid.
+func isZero(t *testing.T, ahelgamal *AHElGamal, c *env.Cipher) bool {
+
+	zero, err := ahelgamal.IsZero(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return zero
+
+}
+
+// session is the session of the proofs of the tests.
+var session = []byte("session of the tests")
+
+func TestEncryptInverseWithProofRoundTrip(t *testing.T) {
+
+	ahelgamal := testElGamal()
+	candidates := []*big.Int{big.NewInt(11), big.NewInt(22), big.NewInt(33)}
+	for index, b := range candidates {
+		c, proof, err := ahelgamal.EncryptInverseWithProof(session, candidates, index)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ahelgamal.VerifyEncryptedInverse(session, c, candidates, proof); err != nil {
+			t.Fatalf("candidate %d: %v", index, err)
+		}
+		sum, err := ahelgamal.MultCiphers(c, ahelgamal.Encrypt(b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !isZero(t, ahelgamal, sum) {
+			t.Fatalf("candidate %d: the ciphertext is not E(-b)", index)
+		}
+	}
+
+}
+
+func TestVerifyEncryptedInverseRejectsForgedProofs(t *testing.T) {
+
+	ahelgamal := testElGamal()
+	candidates := []*big.Int{big.NewInt(11), big.NewInt(22), big.NewInt(33)}
+	c, proof, err := ahelgamal.EncryptInverseWithProof(session, candidates, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	others := []*big.Int{big.NewInt(11), big.NewInt(33), big.NewInt(44)}
+	if err := ahelgamal.VerifyEncryptedInverse(session, c, others, proof); err != ErrInvalidWellFormednessProof {
+		t.Errorf("the proof is accepted for candidates without b: %v", err)
+	}
+	if err := ahelgamal.VerifyEncryptedInverse(session, ahelgamal.EncryptInverse(big.NewInt(99)), candidates, proof); err != ErrInvalidWellFormednessProof {
+		t.Errorf("the proof is accepted for another ciphertext: %v", err)
+	}
+
+	if err := ahelgamal.VerifyEncryptedInverse([]byte("another session"), c, candidates, proof); err != ErrInvalidWellFormednessProof {
+		t.Errorf("the proof is accepted in another session: %v", err)
+	}
+	tampered := &ORProof{Challenges: append([]*big.Int{}, proof.Challenges...), Responses: proof.Responses}
+	tampered.Challenges[0] = new(big.Int).Add(tampered.Challenges[0], one)
+	if err := ahelgamal.VerifyEncryptedInverse(session, c, candidates, tampered); err != ErrInvalidWellFormednessProof {
+		t.Errorf("a tampered challenge is accepted: %v", err)
+	}
+	for name, malformed := range map[string]*ORProof{
+		"no proof":          nil,
+		"missing statement": {Challenges: proof.Challenges[1:], Responses: proof.Responses[1:]},
+		"missing response":  {Challenges: proof.Challenges, Responses: [][]*big.Int{nil, proof.Responses[1], proof.Responses[2]}},
+		"challenge out of range": {Challenges: []*big.Int{ahelgamal.Pk.Q, proof.Challenges[1], proof.Challenges[2]},
+			Responses: proof.Responses},
+	} {
+		if err := ahelgamal.VerifyEncryptedInverse(session, c, candidates, malformed); err != ErrInvalidWellFormednessProof {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+
+}
+
+func TestHideCiphersWithProofRoundTrip(t *testing.T) {
+
+	ahelgamal := testElGamal()
+	padding := ahelgamal.EncryptTrivially(big.NewInt(1))
+	for _, exact := range []bool{true, false} {
+		for _, zeroAt := range []int{-1, 1} {
+			ciphers := make([]*env.Cipher, 3)
+			for j := range ciphers {
+				if j == zeroAt {
+					ciphers[j] = ahelgamal.Encrypt(big.NewInt(0))
+				} else {
+					ciphers[j] = ahelgamal.Encrypt(big.NewInt(int64(5 + j)))
+				}
+			}
+			sources := []int{2, -1, 0, 1, -1}
+			rs := make([]*big.Int, len(sources))
+			for i := range rs {
+				rs[i], _ = RandomBlinding(ahelgamal)
+			}
+			results, proof, err := ahelgamal.HideCiphersWithProof(session, ciphers, padding, exact, sources, rs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := ahelgamal.VerifyHiddenCiphers(session, results, ciphers, padding, exact, proof); err != nil {
+				t.Fatalf("exact %v, zero at %d: %v", exact, zeroAt, err)
+			}
+			for i, source := range sources {
+				if isZero(t, ahelgamal, results[i]) != (source == zeroAt && zeroAt >= 0) {
+					t.Errorf("exact %v, zero at %d: result %d of source %d", exact, zeroAt, i, source)
+				}
+			}
+		}
+	}
+
+}
+
+func TestVerifyHiddenCiphersRejectsForgedResults(t *testing.T) {
+
+	ahelgamal := testElGamal()
+	padding := ahelgamal.EncryptTrivially(big.NewInt(1))
+	ciphers := []*env.Cipher{ahelgamal.Encrypt(big.NewInt(5)), ahelgamal.Encrypt(big.NewInt(6))}
+	sources := []int{1, 0, -1}
+	rs := make([]*big.Int, len(sources))
+	for i := range rs {
+		rs[i], _ = RandomBlinding(ahelgamal)
+	}
+	results, proof, err := ahelgamal.HideCiphersWithProof(session, ciphers, padding, true, sources, rs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a fresh E(0) in place of a result would make the test match
+	forged := append([]*env.Cipher{}, results...)
+	forged[2] = ahelgamal.Encrypt(big.NewInt(0))
+	if err := ahelgamal.VerifyHiddenCiphers(session, forged, ciphers, padding, true, proof); err != ErrInvalidWellFormednessProof {
+		t.Errorf("a fresh E(0) is accepted as a result: %v", err)
+	}
+	// the statements of an exact padding are not the ones of a powered padding
+	if err := ahelgamal.VerifyHiddenCiphers(session, results, ciphers, padding, false, proof); err != ErrInvalidWellFormednessProof {
+		t.Errorf("the proof of an exact padding is accepted for a powered one: %v", err)
+	}
+	// a result of the same slot as another one would drop a window from the test
+	twice := &HidingProof{Key: proof.Key, Tags: append([]*big.Int{}, proof.Tags...), Results: append([]*ORProof{}, proof.Results...)}
+	forged = append([]*env.Cipher{}, results...)
+	forged[1], twice.Tags[1], twice.Results[1] = forged[0], twice.Tags[0], twice.Results[0]
+	if err := ahelgamal.VerifyHiddenCiphers(session, forged, ciphers, padding, true, twice); err != ErrInvalidWellFormednessProof {
+		t.Errorf("two results of the same cipher are accepted: %v", err)
+	}
+	dropped := &HidingProof{Key: proof.Key, Tags: proof.Tags[:2], Results: proof.Results[:2]}
+	if err := ahelgamal.VerifyHiddenCiphers(session, results[:2], ciphers, padding, true, dropped); err != ErrInvalidWellFormednessProof {
+		t.Errorf("the proof is accepted for a subset of the results: %v", err)
+	}
+	if err := ahelgamal.VerifyHiddenCiphers(session, results, ciphers[:1], padding, true, proof); err != ErrInvalidWellFormednessProof {
+		t.Errorf("the proof is accepted for a subset of the ciphers: %v", err)
+	}
+	if err := ahelgamal.VerifyHiddenCiphers([]byte("another session"), results, ciphers, padding, true, proof); err != ErrInvalidWellFormednessProof {
+		t.Errorf("the proof is accepted in another session: %v", err)
+	}
+
+	if _, _, err := ahelgamal.HideCiphersWithProof(session, ciphers, padding, true, []int{1, -1}, rs[:2]); err == nil {
+		t.Error("a cipher that is the source of no result is hidden")
+	}
+	if _, _, err := ahelgamal.HideCiphersWithProof(session, ciphers, padding, true, []int{1, 1, 0}, rs); err == nil {
+		t.Error("a cipher that is the source of two results is hidden")
+	}
+	if _, _, err := ahelgamal.HideCiphersWithProof(session, ciphers, padding, true, sources, []*big.Int{one, one, new(big.Int)}); err != nil {
+		t.Errorf("the exponent of an exact padding is not ignored: %v", err)
+	}
+	if _, _, err := ahelgamal.HideCiphersWithProof(session, ciphers, padding, true, sources, []*big.Int{one, ahelgamal.Pk.Q, one}); err == nil {
+		t.Error("an exponent of zero mod q is accepted")
+	}
+
+}
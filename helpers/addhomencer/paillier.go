@@ -0,0 +1,238 @@
+package addhomencer
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/gob"
+	"errors"
+	"math/big"
+
+	"github.com/eozturk1/genomic-security-journal-code/helpers/profiles"
+)
+
+// checkKeyProfile checks that the key was generated under a known profile and has the size of that profile.
+func checkKeyProfile(encoding paillierKeyEncoding) error {
+	profile, err := profiles.ByID(encoding.Profile)
+	if err != nil {
+		return err
+	}
+	if encoding.N.BitLen() != profile.PaillierKeyLen {
+		return profiles.ErrProfileMismatch
+	}
+	return nil
+}
+
+// ========================== Paillier, the case s = 1 of Damgård–Jurik ==========================
+// E(m) = (1 + m*N) * r^N mod N^2, since g^m = (1 + N)^m = 1 + m*N mod N^2.
+// Paillier is DamgardJurik with S fixed to 1, so that both share the encryption, the homomorphic operations and the pool
+// of r^N values, which can be filled in an offline phase (see PrecomputeRandomness) so that each online encryption
+// costs two multiplications mod N^2. Decryption is done mod p^2 and q^2 and recombined with the CRT, which is about four
+// times faster than working mod N^2; Damgård–Jurik takes this path for s = 1 too. The keys below are those of both.
+
+var one = big.NewInt(1)
+
+var ErrNoPrivateKey = errors.New("the scheme has no private key")
+
+type PaillierPublicKey struct {
+	N        *big.Int
+	NSquared *big.Int
+	Profile  string // ID of the security profile the key was generated for
+}
+
+type PaillierPrivateKey struct {
+	PaillierPublicKey
+	P, Q *big.Int
+
+	// CRT values, computed from P and Q by precompute()
+	pSquared, qSquared *big.Int
+	hp, hq             *big.Int // hp = L_p(g^(p-1) mod p^2)^(-1) mod p, hq likewise
+	pInvQ              *big.Int // p^(-1) mod q
+}
+
+type Paillier struct {
+	DamgardJurik
+}
+
+func (pl *Paillier) Setup() {
+	pl.SetupProfile(profiles.Default)
+}
+
+// SetupProfile generates a key of the size of the profile.
+func (pl *Paillier) SetupProfile(profile *profiles.Profile) {
+	pl.S = 1
+	pl.DamgardJurik.SetupProfile(profile)
+}
+
+// SetPrivateKey loads a (deserialized) private key into the scheme. Pending precomputed randomness belongs to the old key
+// and is dropped.
+func (pl *Paillier) SetPrivateKey(sk *PaillierPrivateKey) {
+	pl.S = 1
+	pl.DamgardJurik.SetPrivateKey(sk)
+}
+
+// SetPublicKey loads a public key only, e.g., on the side that encrypts but never decrypts.
+func (pl *Paillier) SetPublicKey(pk *PaillierPublicKey) {
+	pl.S = 1
+	pl.DamgardJurik.SetPublicKey(pk)
+}
+
+// GeneratePaillierKey generates a key with an N of profile.PaillierKeyLen bits.
+func GeneratePaillierKey(profile *profiles.Profile) (*PaillierPrivateKey, error) {
+
+	bits := profile.PaillierKeyLen
+	for {
+		p, err := rand.Prime(rand.Reader, bits/2)
+		if err != nil {
+			return nil, err
+		}
+		q, err := rand.Prime(rand.Reader, bits-bits/2)
+		if err != nil {
+			return nil, err
+		}
+		if p.Cmp(q) == 0 {
+			continue
+		}
+
+		n := new(big.Int).Mul(p, q)
+		if n.BitLen() != bits {
+			continue
+		}
+
+		sk := &PaillierPrivateKey{
+			PaillierPublicKey: PaillierPublicKey{N: n, NSquared: new(big.Int).Mul(n, n), Profile: profile.ID},
+			P:                 p,
+			Q:                 q,
+		}
+		sk.precompute()
+		return sk, nil
+	}
+
+}
+
+// profile returns the profile of the key. Keys come from GeneratePaillierKey or UnmarshalBinary, which both set a known
+// profile.
+func (pk *PaillierPublicKey) profile() *profiles.Profile {
+	profile, err := profiles.ByID(pk.Profile)
+	if err != nil {
+		panic("Paillier key error: " + err.Error())
+	}
+	return profile
+}
+
+// validateUnit checks that 0 < c < modulus and gcd(c, N) = 1, for a modulus that is a power of N.
+func validateUnit(c, n, modulus *big.Int) error {
+	if c.Sign() <= 0 || c.Cmp(modulus) >= 0 || new(big.Int).GCD(nil, nil, c, n).Cmp(one) != 0 {
+		return ErrCipherOutOfRange
+	}
+	return nil
+}
+
+func (sk *PaillierPrivateKey) decryptCRT(c *big.Int) *big.Int {
+
+	mp := decryptModPrime(c, sk.P, sk.pSquared, sk.hp)
+	mq := decryptModPrime(c, sk.Q, sk.qSquared, sk.hq)
+
+	// m = mp + ((mq - mp) * p^(-1) mod q) * p
+	m := new(big.Int).Sub(mq, mp)
+	m.Mul(m, sk.pInvQ)
+	m.Mod(m, sk.Q)
+	m.Mul(m, sk.P)
+	return m.Add(m, mp)
+
+}
+
+func (sk *PaillierPrivateKey) precompute() {
+
+	sk.pSquared = new(big.Int).Mul(sk.P, sk.P)
+	sk.qSquared = new(big.Int).Mul(sk.Q, sk.Q)
+	sk.hp = crtH(sk.N, sk.P, sk.pSquared)
+	sk.hq = crtH(sk.N, sk.Q, sk.qSquared)
+	sk.pInvQ = new(big.Int).ModInverse(sk.P, sk.Q)
+
+}
+
+func crtH(n, p, pSquared *big.Int) *big.Int {
+	// L_p(g^(p-1) mod p^2)^(-1) mod p, with g = N+1
+	g := new(big.Int).Add(n, one)
+	h := g.Exp(g, new(big.Int).Sub(p, one), pSquared)
+	h = lFunction(h, p)
+	return h.ModInverse(h, p)
+}
+
+func decryptModPrime(c, p, pSquared, hp *big.Int) *big.Int {
+	// m_p = L_p(c^(p-1) mod p^2) * hp mod p
+	m := new(big.Int).Exp(c, new(big.Int).Sub(p, one), pSquared)
+	m = lFunction(m, p)
+	m.Mul(m, hp)
+	return m.Mod(m, p)
+}
+
+func lFunction(x, p *big.Int) *big.Int {
+	// L(x) = (x - 1) / p
+	l := new(big.Int).Sub(x, one)
+	return l.Div(l, p)
+}
+
+// ========================== key serialization ==========================
+
+type paillierKeyEncoding struct {
+	Profile string
+	N, P, Q *big.Int
+}
+
+func (pk *PaillierPublicKey) MarshalBinary() ([]byte, error) {
+	var buffer bytes.Buffer
+	err := gob.NewEncoder(&buffer).Encode(paillierKeyEncoding{Profile: pk.Profile, N: pk.N})
+	return buffer.Bytes(), err
+}
+
+func (pk *PaillierPublicKey) UnmarshalBinary(data []byte) error {
+
+	var encoding paillierKeyEncoding
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&encoding); err != nil {
+		return err
+	}
+	if encoding.N == nil || encoding.N.Sign() <= 0 {
+		return errors.New("invalid Paillier public key")
+	}
+	if err := checkKeyProfile(encoding); err != nil {
+		return err
+	}
+
+	pk.N = encoding.N
+	pk.NSquared = new(big.Int).Mul(pk.N, pk.N)
+	pk.Profile = encoding.Profile
+	return nil
+
+}
+
+func (sk *PaillierPrivateKey) MarshalBinary() ([]byte, error) {
+	var buffer bytes.Buffer
+	err := gob.NewEncoder(&buffer).Encode(paillierKeyEncoding{Profile: sk.Profile, N: sk.N, P: sk.P, Q: sk.Q})
+	return buffer.Bytes(), err
+}
+
+func (sk *PaillierPrivateKey) UnmarshalBinary(data []byte) error {
+
+	var encoding paillierKeyEncoding
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&encoding); err != nil {
+		return err
+	}
+	if encoding.N == nil || encoding.P == nil || encoding.Q == nil || new(big.Int).Mul(encoding.P, encoding.Q).Cmp(encoding.N) != 0 {
+		return errors.New("invalid Paillier private key")
+	}
+	if err := checkKeyProfile(encoding); err != nil {
+		return err
+	}
+
+	sk.N = encoding.N
+	sk.NSquared = new(big.Int).Mul(sk.N, sk.N)
+	sk.Profile = encoding.Profile
+	sk.P = encoding.P
+	sk.Q = encoding.Q
+	sk.precompute()
+	return nil
+
+}
+
+// ========================== Paillier, the case s = 1 of Damgård–Jurik ==========================
@@ -0,0 +1,117 @@
+package addhomencer
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/eozturk1/genomic-security-journal-code/helpers/profiles"
+)
+
+func TestPaillierIsDamgardJurikWithSOne(t *testing.T) {
+
+	pl := Paillier{}
+	pl.SetupProfile(profiles.Default)
+	if pl.S != 1 {
+		t.Fatalf("S = %d, want 1", pl.S)
+	}
+	if pl.GetGroupOrder().Cmp(pl.Pk.N) != 0 {
+		t.Fatal("plaintext group is not Z_N")
+	}
+
+	dj := DamgardJurik{S: 1}
+	dj.SetPrivateKey(pl.Sk)
+	for _, m := range []int64{0, 1, 42, -7} {
+		c := pl.Encrypt(big.NewInt(m))
+		if c.C1.Cmp(pl.Pk.NSquared) >= 0 {
+			t.Fatal("ciphertext is not mod N^2")
+		}
+		got, err := dj.Decrypt(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := new(big.Int).Mod(big.NewInt(m), pl.Pk.N)
+		if got.Cmp(want) != 0 {
+			t.Fatalf("Damgård–Jurik decrypts E(%d) to %v", m, got)
+		}
+	}
+
+	// g = 1 + N: E(m) r^(-N) = 1 + m N
+	pl.PrecomputeRandomness(1)
+	rn := pl.pool.values[0]
+	c := pl.Encrypt(big.NewInt(5))
+	c.C1.Mul(c.C1, new(big.Int).ModInverse(rn.C1, pl.Pk.NSquared)).Mod(c.C1, pl.Pk.NSquared)
+	want := new(big.Int).Mul(big.NewInt(5), pl.Pk.N)
+	if c.C1.Cmp(want.Add(want, one)) != 0 {
+		t.Fatal("E(m) is not (1 + m N) r^N")
+	}
+
+}
+
+func TestPaillierSetPublicKeyKeepsSOne(t *testing.T) {
+
+	pl := Paillier{}
+	pl.SetupProfile(profiles.Default)
+
+	data, err := pl.Pk.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pk PaillierPublicKey
+	if err := pk.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	encryptor := Paillier{}
+	encryptor.SetPublicKey(&pk)
+	if encryptor.S != 1 || encryptor.Sk != nil {
+		t.Fatal("public key is not loaded as Paillier")
+	}
+	c, err := encryptor.MultCiphers(encryptor.Encrypt(big.NewInt(3)), encryptor.EncryptInverse(big.NewInt(3)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := encryptor.Decrypt(c); err != ErrNoPrivateKey {
+		t.Fatal("decryption without the private key")
+	}
+	zero, err := pl.Decrypt(c)
+	if err != nil || zero.Sign() != 0 {
+		t.Fatal("E(3) E(-3) is not E(0)")
+	}
+
+}
+
+func TestArtifactsOfAnotherProfileAreRejected(t *testing.T) {
+
+	pl := Paillier{}
+	pl.SetupProfile(profiles.Default)
+
+	// a key of the size of sec112 that claims another profile
+	for id, want := range map[string]error{profiles.Profile128.ID: profiles.ErrProfileMismatch, "sec256": profiles.ErrUnknownProfile} {
+		data, err := (&PaillierPublicKey{N: pl.Pk.N, Profile: id}).MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var pk PaillierPublicKey
+		if err := pk.UnmarshalBinary(data); err != want {
+			t.Fatalf("public key of %s: %v, want %v", id, err, want)
+		}
+		data, err = (&PaillierPrivateKey{PaillierPublicKey: PaillierPublicKey{N: pl.Sk.N, Profile: id}, P: pl.Sk.P, Q: pl.Sk.Q}).MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var sk PaillierPrivateKey
+		if err := sk.UnmarshalBinary(data); err != want {
+			t.Fatalf("private key of %s: %v, want %v", id, err, want)
+		}
+	}
+
+	// a ciphertext encoded under another profile
+	data := pl.MarshalCipher(pl.Encrypt(big.NewInt(7)))
+	if _, err := pl.UnmarshalCipher(data); err != nil {
+		t.Fatal(err)
+	}
+	copy(data[1:], profiles.Profile128.ID)
+	if _, err := pl.UnmarshalCipher(data); err != profiles.ErrProfileMismatch {
+		t.Fatalf("ciphertext of another profile: %v", err)
+	}
+
+}
@@ -0,0 +1,223 @@
+package addhomencer
+
+import (
+	"encoding/gob"
+	"errors"
+	"math/big"
+	"os"
+	"sync"
+
+	env "github.com/eozturk1/genomic-security-journal-code/helpers/env"
+	"github.com/eozturk1/genomic-security-journal-code/helpers/profiles"
+)
+
+// ========================== Offline randomness and batch encryption ==========================
+// The randomness of an encryption does not depend on the message: (G^k, Y^k) for AH-ElGamal, r^N for Paillier and
+// r^(N^s) for Damgård–Jurik. It can be computed in an offline phase (PrecomputeRandomness), saved with the key it belongs
+// to and loaded again later, so that online encryptions only combine it with the message. Batch encryptions take their
+// randomness from the pool at once and compute what is missing with ConcurrencyLevel goroutines.
+// Randomness used twice would reveal the difference of the messages, so saving moves the values from the pool to the
+// file, and loading marks the file as consumed, which a second load refuses.
+
+var (
+	ErrRandomnessPoolMismatch = errors.New("randomness pool was computed for another key")
+	ErrRandomnessPoolConsumed = errors.New("randomness pool was already loaded")
+)
+
+// randomnessPool holds precomputed encryption randomness, each value in a Cipher of the shape of the scheme's randomness
+// (C1 only, or C1 and C2 for AH-ElGamal). Every value is handed out once.
+type randomnessPool struct {
+	values []*env.Cipher
+	mutex  sync.Mutex
+}
+
+type randomnessPoolEncoding struct {
+	Profile  string     // ID of the security profile
+	Key      []*big.Int // public values identifying the key
+	Values   []*env.Cipher
+	Consumed bool // set, with no values, once the pool is loaded
+}
+
+// fill adds count values from gen, computed by ConcurrencyLevel goroutines.
+func (pool *randomnessPool) fill(count int, gen func() *env.Cipher) {
+
+	values := generateRandomness(count, gen)
+
+	pool.mutex.Lock()
+	for _, v := range values {
+		if v != nil {
+			pool.values = append(pool.values, v)
+		}
+	}
+	pool.mutex.Unlock()
+
+}
+
+// pop removes a value from the pool, or returns nil when the pool is empty.
+func (pool *randomnessPool) pop() *env.Cipher {
+
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+
+	n := len(pool.values)
+	if n == 0 {
+		return nil
+	}
+	v := pool.values[n-1]
+	pool.values[n-1] = nil
+	pool.values = pool.values[:n-1]
+	return v
+
+}
+
+// next pops a value, or computes one with gen when the pool is empty.
+func (pool *randomnessPool) next(gen func() *env.Cipher) *env.Cipher {
+	if v := pool.pop(); v != nil {
+		return v
+	}
+	return gen()
+}
+
+// take returns count values: as many as the pool holds, and the others computed with gen by ConcurrencyLevel goroutines.
+func (pool *randomnessPool) take(count int, gen func() *env.Cipher) []*env.Cipher {
+
+	pool.mutex.Lock()
+	n := len(pool.values)
+	if n > count {
+		n = count
+	}
+	values := make([]*env.Cipher, count)
+	copy(values, pool.values[len(pool.values)-n:])
+	for i := len(pool.values) - n; i < len(pool.values); i++ {
+		pool.values[i] = nil
+	}
+	pool.values = pool.values[:len(pool.values)-n]
+	pool.mutex.Unlock()
+
+	copy(values[n:], generateRandomness(count-n, gen))
+	return values
+
+}
+
+func (pool *randomnessPool) drop() {
+	pool.mutex.Lock()
+	pool.values = nil
+	pool.mutex.Unlock()
+}
+
+// save moves the values of the pool, with the key they belong to, to a file: the pool is empty afterwards, so that its
+// values are only used again by loading the file.
+func (pool *randomnessPool) save(fileName string, profile string, key []*big.Int) error {
+
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+
+	if err := writeRandomness(fileName, randomnessPoolEncoding{Profile: profile, Key: key, Values: pool.values}); err != nil {
+		return err
+	}
+	pool.values = nil
+	return nil
+
+}
+
+// load replaces the values of the pool with the ones of a file saved for the same profile and key, and marks the file
+// as consumed, so that it cannot be loaded again.
+func (pool *randomnessPool) load(fileName string, profile string, key []*big.Int) error {
+
+	file, err := os.Open(fileName)
+	if err != nil {
+		return err
+	}
+	var encoding randomnessPoolEncoding
+	err = gob.NewDecoder(file).Decode(&encoding)
+	file.Close()
+	if err != nil {
+		return err
+	}
+	if encoding.Profile != profile {
+		return profiles.ErrProfileMismatch
+	}
+	if len(encoding.Key) != len(key) {
+		return ErrRandomnessPoolMismatch
+	}
+	for i := range key {
+		if encoding.Key[i] == nil || encoding.Key[i].Cmp(key[i]) != 0 {
+			return ErrRandomnessPoolMismatch
+		}
+	}
+	if encoding.Consumed {
+		return ErrRandomnessPoolConsumed
+	}
+	if err := writeRandomness(fileName, randomnessPoolEncoding{Profile: profile, Key: key, Consumed: true}); err != nil {
+		return err
+	}
+
+	pool.mutex.Lock()
+	pool.values = encoding.Values
+	pool.mutex.Unlock()
+
+	return nil
+
+}
+
+// writeRandomness writes the encoding of a pool to a file.
+func writeRandomness(fileName string, encoding randomnessPoolEncoding) error {
+
+	file, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	if err := gob.NewEncoder(file).Encode(encoding); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+
+}
+
+// generateRandomness returns count values from gen, computed by ConcurrencyLevel goroutines.
+func generateRandomness(count int, gen func() *env.Cipher) []*env.Cipher {
+
+	var wg sync.WaitGroup
+
+	values := make([]*env.Cipher, count)
+	wg.Add(ConcurrencyLevel)
+	for w := 0; w < ConcurrencyLevel; w++ {
+		go func(w int, wg *sync.WaitGroup) {
+			for i := w; i < count; i += ConcurrencyLevel {
+				values[i] = gen()
+			}
+			wg.Done()
+		}(w, &wg)
+	}
+	wg.Wait()
+
+	return values
+
+}
+
+// encryptBatch encrypts bs[i] with randomness[i], by ConcurrencyLevel goroutines. Messages with no randomness are left
+// unencrypted (nil), as Encrypt returns nil when randomness cannot be drawn.
+func encryptBatch(bs []*big.Int, randomness []*env.Cipher, encrypt func(b *big.Int, randomness *env.Cipher) *env.Cipher) []*env.Cipher {
+
+	var wg sync.WaitGroup
+
+	ciphers := make([]*env.Cipher, len(bs))
+	wg.Add(ConcurrencyLevel)
+	for w := 0; w < ConcurrencyLevel; w++ {
+		go func(w int, wg *sync.WaitGroup) {
+			for i := w; i < len(bs); i += ConcurrencyLevel {
+				if randomness[i] != nil {
+					ciphers[i] = encrypt(bs[i], randomness[i])
+				}
+			}
+			wg.Done()
+		}(w, &wg)
+	}
+	wg.Wait()
+
+	return ciphers
+
+}
+
+// ========================== Offline randomness and batch encryption ==========================
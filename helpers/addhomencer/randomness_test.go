@@ -0,0 +1,150 @@
+package addhomencer
+
+import (
+	"math/big"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/eozturk1/genomic-security-journal-code/helpers/profiles"
+)
+
+func TestRandomnessSaveLoadRoundTrip(t *testing.T) {
+
+	fileName := filepath.Join(t.TempDir(), "randomness")
+
+	ahelgamal := AHElGamal{}
+	ahelgamal.SetupProfile(profiles.Default)
+	ahelgamal.PrecomputeRandomness(8)
+	saved := append(ahelgamal.pool.values[:0:0], ahelgamal.pool.values...)
+	if err := ahelgamal.SaveRandomness(fileName); err != nil {
+		t.Fatal(err)
+	}
+
+	ahelgamal.pool.drop()
+	if err := ahelgamal.LoadRandomness(fileName); err != nil {
+		t.Fatal(err)
+	}
+	if len(ahelgamal.pool.values) != len(saved) {
+		t.Fatalf("%d values loaded, %d saved", len(ahelgamal.pool.values), len(saved))
+	}
+	for i, v := range ahelgamal.pool.values {
+		if v.C1.Cmp(saved[i].C1) != 0 || v.C2.Cmp(saved[i].C2) != 0 {
+			t.Fatalf("value %d changed in the round trip", i)
+		}
+	}
+	for _, c := range ahelgamal.EncryptBatch([]*big.Int{big.NewInt(0), big.NewInt(0)}) {
+		if zero, err := ahelgamal.IsZero(c); err != nil || !zero {
+			t.Fatal("encryption with loaded randomness does not decrypt")
+		}
+	}
+
+	// the pool of another key, or of another profile, is refused
+	other := AHElGamal{}
+	other.SetupProfile(profiles.Default)
+	if err := other.LoadRandomness(fileName); err != ErrRandomnessPoolMismatch {
+		t.Fatalf("pool of another key: %v", err)
+	}
+	if err := other.pool.load(fileName, profiles.Profile192.ID, []*big.Int{ahelgamal.Pk.G, ahelgamal.Pk.P, ahelgamal.Pk.Y}); err != profiles.ErrProfileMismatch {
+		t.Fatalf("pool of another profile: %v", err)
+	}
+	if err := other.LoadRandomness(filepath.Join(t.TempDir(), "missing")); !os.IsNotExist(err) {
+		t.Fatalf("missing file: %v", err)
+	}
+
+}
+
+func TestRandomnessIsNotReusedAfterSaveAndLoad(t *testing.T) {
+
+	fileName := filepath.Join(t.TempDir(), "randomness")
+
+	ahelgamal := AHElGamal{}
+	ahelgamal.SetupProfile(profiles.Default)
+	ahelgamal.PrecomputeRandomness(4)
+	if err := ahelgamal.SaveRandomness(fileName); err != nil {
+		t.Fatal(err)
+	}
+	if len(ahelgamal.pool.values) != 0 {
+		t.Fatalf("%d values stay in the pool after it is saved", len(ahelgamal.pool.values))
+	}
+	if err := ahelgamal.LoadRandomness(fileName); err != nil {
+		t.Fatal(err)
+	}
+	if len(ahelgamal.pool.values) != 4 {
+		t.Fatalf("%d values loaded, 4 saved", len(ahelgamal.pool.values))
+	}
+	if err := ahelgamal.LoadRandomness(fileName); err != ErrRandomnessPoolConsumed {
+		t.Fatalf("second load of the pool: %v", err)
+	}
+
+	// every encryption of the saved and loaded pool, and of the next ones, has its own randomness
+	seen := make(map[string]bool)
+	for _, c := range ahelgamal.EncryptBatch([]*big.Int{big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0)}) {
+		if seen[c.C1.String()] {
+			t.Fatal("randomness is used twice")
+		}
+		seen[c.C1.String()] = true
+	}
+
+}
+
+func TestRandomnessIsUsedOnce(t *testing.T) {
+
+	dj := DamgardJurik{S: 1}
+	dj.SetupProfile(profiles.Default)
+	const precomputed = 48
+	dj.PrecomputeRandomness(precomputed)
+	pooled := make(map[string]bool)
+	for _, v := range dj.pool.values {
+		pooled[v.C1.String()] = true
+	}
+	if len(pooled) != precomputed {
+		t.Fatal("precomputed values repeat")
+	}
+
+	// E(0) = r^N, so the ciphertexts are the randomness values; draw more than the pool holds, concurrently
+	var mutex sync.Mutex
+	var used []*big.Int
+	var wg sync.WaitGroup
+	wg.Add(4)
+	for w := 0; w < 4; w++ {
+		go func(w int, wg *sync.WaitGroup) {
+			var ciphers []*big.Int
+			zeros := make([]*big.Int, 8)
+			for i := range zeros {
+				zeros[i] = big.NewInt(0)
+			}
+			for _, c := range dj.EncryptBatch(zeros) {
+				ciphers = append(ciphers, c.C1)
+			}
+			for i := 0; i < 8; i++ {
+				ciphers = append(ciphers, dj.Encrypt(big.NewInt(0)).C1)
+			}
+			mutex.Lock()
+			used = append(used, ciphers...)
+			mutex.Unlock()
+			wg.Done()
+		}(w, &wg)
+	}
+	wg.Wait()
+
+	if len(dj.pool.values) != 0 {
+		t.Fatalf("%d values left in the pool", len(dj.pool.values))
+	}
+	seen := make(map[string]bool)
+	fromPool := 0
+	for _, c := range used {
+		if seen[c.String()] {
+			t.Fatal("randomness used twice")
+		}
+		seen[c.String()] = true
+		if pooled[c.String()] {
+			fromPool++
+		}
+	}
+	if fromPool != precomputed {
+		t.Fatalf("%d of %d precomputed values used", fromPool, precomputed)
+	}
+
+}
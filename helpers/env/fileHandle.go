@@ -23,6 +23,18 @@ func GenFiles(fileA, fileTm, fileTnm string, n, s, e, ns, ne uint32) {
 
 }
 
+// GenMarkerFiles generates the files of a tester's marker of 'T' in [s, e] only, e.g., for the markers of the queries
+// over several ranges, which have to be disjoint.
+func GenMarkerFiles(fileT string, s, e uint32) {
+
+	path := "../../tmpFiles/"
+	os.Mkdir("../../tmpFiles", 0755)
+
+	GenerateGenomeInFile(path+fileT+".txt", 0, s, e, false)
+	GenerateGenomeInFile(path+fileT+"_snp.txt", 0, s, e, true)
+
+}
+
 func EraseFiles(fileA, fileTm, fileTnm string) {
 
 	path := "../../tmpFiles/"
@@ -0,0 +1,82 @@
+package env
+
+import (
+	"hash"
+	"math/big"
+)
+
+// Packed encoding of the whole genome: instead of one hashed base per plaintext, a block of consecutive positions is
+// encoded in one plaintext as P = sum_j code(base_j) * 2^(SlotBits*j). Codes are in [1, 5], so the difference of two
+// codes is in [-4, 4], which fits a slot with a margin: a packed difference sum_j d_j * 2^(SlotBits*j) with |d_j| < 2^SlotBits
+// is zero if and only if every d_j is zero, i.e., if and only if all positions of the block match.
+// Only S-SPH-PSM (SecureSPHPSM.MainPacked) uses packing: the other protocols test ranges with per-position commitments.
+
+const PackedSlotBits = 3
+
+const packedTag = "pck"
+
+type PackingLayout struct {
+	SlotBits uint
+	Slots    uint32 // number of positions per block
+}
+
+// NewPackingLayout returns the layout with as many slots as fit below the plaintext order, so that packed values and
+// their differences never wrap around.
+func NewPackingLayout(plaintextOrder *big.Int) PackingLayout {
+	return PackingLayout{SlotBits: PackedSlotBits, Slots: uint32(plaintextOrder.BitLen()-1) / PackedSlotBits}
+}
+
+func BaseCode(letter uint8) int64 {
+	switch letter {
+	case 'A':
+		return 1
+	case 'C':
+		return 2
+	case 'G':
+		return 3
+	case 'T':
+		return 4
+	default:
+		return 5
+	}
+}
+
+// BlockOf returns the block holding the position and its slot in the block. Positions start from 1.
+func (layout PackingLayout) BlockOf(position uint32) (uint32, uint) {
+	return (position - 1) / layout.Slots, uint((position - 1) % layout.Slots)
+}
+
+// FirstPosition returns the position in slot 0 of the block.
+func (layout PackingLayout) FirstPosition(block uint32) uint32 {
+	return block*layout.Slots + 1
+}
+
+// Pack encodes the bases into their blocks. Slots of positions with no base are left zero.
+func (layout PackingLayout) Pack(baseArray []*Base) map[uint32]*big.Int {
+
+	blocks := make(map[uint32]*big.Int)
+	for _, base := range baseArray {
+		block, slot := layout.BlockOf(base.Position)
+		if blocks[block] == nil {
+			blocks[block] = new(big.Int)
+		}
+		code := big.NewInt(BaseCode(base.Letter))
+		blocks[block].Add(blocks[block], code.Lsh(code, slot*layout.SlotBits))
+	}
+
+	return blocks
+
+}
+
+func HashBlockAndCipher(h hash.Hash, block uint32, cipher *Cipher) []byte {
+	// Output h(tag || block || cipher), tagged so that a block signature is never valid for a position
+
+	hashingValue := append([]byte(packedTag), Uint32ToBytes(block)...)
+	hashingValue = append(hashingValue, cipher.C1.Bytes()...)
+	if cipher.C2 != nil {
+		hashingValue = append(hashingValue, cipher.C2.Bytes()...)
+	}
+
+	return h.Sum(hashingValue)
+
+}
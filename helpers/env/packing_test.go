@@ -0,0 +1,54 @@
+package env
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestPackingLayoutFitsPlaintexts(t *testing.T) {
+
+	order := new(big.Int).Lsh(big.NewInt(1), 2048)
+	layout := NewPackingLayout(order)
+	if layout.Slots == 0 || uint(layout.Slots)*layout.SlotBits >= uint(order.BitLen()) {
+		t.Fatalf("%d slots do not fit below the order", layout.Slots)
+	}
+
+	for _, position := range []uint32{1, layout.Slots, layout.Slots + 1, 3*layout.Slots + 2} {
+		block, slot := layout.BlockOf(position)
+		if layout.FirstPosition(block)+uint32(slot) != position {
+			t.Fatalf("position %d is in block %d, slot %d", position, block, slot)
+		}
+	}
+
+}
+
+func TestPackedDifferenceIsZeroOnlyOnMatch(t *testing.T) {
+
+	layout := PackingLayout{SlotBits: PackedSlotBits, Slots: 4}
+	genome := func(letters string) []*Base {
+		bases := make([]*Base, len(letters))
+		for i := range letters {
+			bases[i] = &Base{Position: uint32(i + 1), Letter: letters[i]}
+		}
+		return bases
+	}
+
+	alice := layout.Pack(genome("ACGTNACG"))
+	for _, tc := range []struct {
+		letters string
+		equal   []bool
+	}{
+		{"ACGTNACG", []bool{true, true}},
+		{"ACGTNACT", []bool{true, false}},
+		{"TCGANACG", []bool{false, true}},
+	} {
+		tester := layout.Pack(genome(tc.letters))
+		for block, equal := range tc.equal {
+			difference := new(big.Int).Sub(alice[uint32(block)], tester[uint32(block)])
+			if (difference.Sign() == 0) != equal {
+				t.Fatalf("%s: difference of block %d is %v", tc.letters, block, difference)
+			}
+		}
+	}
+
+}
@@ -0,0 +1,23 @@
+package profiles
+
+// ========================== AH-ElGamal groups ==========================
+// The 3072- and 7680-bit groups were generated as Schnorr groups: q a random prime of 256 (384) bits, p = kq+1 prime
+// for a random even k, and g = 2^((p-1)/q) mod p, rejected if it is 1. Validate checks every property the protocols
+// rely on.
+
+// This is the 2048-bit MODP group with 224-bit prime order subgroup from RFC 5114, section 2.2:
+const rfc5114PrimeHex = "AD107E1E9123A9D0D660FAA79559C51FA20D64E5683B9FD1B54B1597B61D0A75E6FA141DF95A56DBAF9A3C407BA1DF15EB3D688A309C180E1DE6B85A1274A0A66D3F8152AD6AC2129037C9EDEFDA4DF8D91E8FEF55B7394B7AD5B7D0B6C12207C9F98D11ED34DBF6C6BA0B2C8BBC27BE6A00E0A0B9C49708B3BF8A317091883681286130BC8985DB1602E714415D9330278273C7DE31EFDC7310F7121FD5A07415987D9ADC0A486DCDF93ACC44328387315D75E198C641A480CD86A1B9E587E8BE60E69CC928B2B9C52172E413042E9B23F10B0E16E79763C9B53DCF4BA80A29E3FB73C16B8E75B97EF363E2FFA31F71CF9DE5384E71B81C0AC4DFFE0C10E64F"
+const rfc5114SubgroupOrderHex = "801C0D34C58D93FE997177101F80535A4738CEBCBF389A99B36371EB"
+const rfc5114GeneratorHex = "AC4032EF4F2D9AE39DF30B5C8FFDAC506CDEBE7B89998CAF74866A08CFE4FFE3A6824A4E10B9A6F0DD921F01A70C4AFAAB739D7700C29F52C57DB17C620A8652BE5E9001A8D66AD7C17669101999024AF4D027275AC1348BB8A762D0521BC98AE247150422EA1ED409939D54DA7460CDB5F6C6B250717CBEF180EB34118E98D119529A45D6F834566E3025E316A330EFBB77A86F0C1AB15B051AE3D428C8F8ACB70A8137150B8EEB10E183EDD19963DDD9E263E4770589EF6AA21E7F5F2FF381B539CCE3409D13CD566AFBB48D6C019181E1BCFE94B30269EDFE72FE9B6AA4BD7B5A0F1C71CFFF4C19C418E1F6EC017981BC087F2A7065B384B890D3191F2BFA"
+
+// 3072-bit group with 256-bit prime order subgroup:
+const group3072PrimeHex = "C9E4E1D1F82EA164CF569A1C6B8574861C842D8C43B8D739EBD84A4E07747CF2A4FF4AB07E4CBE76271E303207B2A57268223AAAF4F8F98C14D6A7DD142075E98404CEBD201DD3C859D028593344D46B5C2D04ACE7F655CAB85DAF77000B5E061AE6F78D4F7BB447F92C45CA6A59C8BBF8E036BEC33B892C331B28998653F4A3D59DC0E4755059E43BE0E8284E88A2435B0292A52434FCA18BBE14DE72807E690E7C55F37765CE2982360BDE7512C6D7F7AE234EC941FA05A6CC1279E81510FF8C48D3EFEA90FF34CB30FED62A63FF725DE12A5345D0A59ADC0453AA7DEDE7EB8725D819595EFE440D300C6D3E6B6EBFB5114F6B1DFE4F2EA7EE620D5AABA16F1F91A7B3F0A24065DB6F0AA37A579EAEFD33CFAD94AC7D522A78E860C001FDB2976CFD551B82730A140BBDF68C29D7AEF067E9BFD7421C2A0D5508052CFAAFAC6461214ED53A0E387E258C1C70750C7E5A8E32D45B833FC712D6AD2F9938AF84C83F48481210BE2279128D992E1DC838F3F1C4D4FBA206D59CD4858AC8DAB4AF"
+const group3072SubgroupOrderHex = "FCC131B30D47766146C0EF5126245F11EFFAF978411441BCEACA819D025028BB"
+const group3072GeneratorHex = "2B35BBD443A1A9C216B0A2EBFBF0F934C9F16A989D4F22851760929140EBA577E635AC0102E31694E7B424CA3A2437232A028864F69CBBB6545CEA0DE4D5BF99215096224AAA39BEDF3607A5F932E7948C5D0F551BAC071D0185F79F6E28FA31B9D80EC7073686CE70AD46F712C87BE6CE3FD7E50BFDF17EF79E32FE15AB8B28608D9D05274A4BE032214313005A9F2FB0ED6AEB02ACFCFB836DDC03AB6DA0243572FE5880B5BD2CBE62B1CEA2FE9F937FB27B58F1C8A211569762D45BA16F1F2364B76E92CB31CC5A8ED21A060DCD33BD313B397755B24BAD1AC9CB48F134C24DBB4159D9726F91CE4B90FC21E5449CDECBFCB27DEB84A9E17EBEB6F656761D1BB424E3069179B4BA328B9FB42B785E8E7A12AD39706658F18ACAC1835A34D88D6A57C2EBD94B3A1B43F2AD0A52625F8906F88388F5C48D860ABB31682B76CB687FA4067900803A61EEF1C830DC2EF8B4EC3C0D4A94075D9F37E95DB1E7C36BBE8E7FF9F988288F37B7AD27C9AB45FD59EB1FFA689123DAD3AB7F46FC5253BE"
+
+// 7680-bit group with 384-bit prime order subgroup:
+const group7680PrimeHex = "BAA591E1862BD001D1D484C1D9B110C5267E86DB207B856B633086F9D53659D086C27EDDF478219E47580774B0C02F729F3B2D8BC9FC3116BFEA881DE045FA3F21665495924EE80FD7D19E5608B8598CA6BE02C71BF1827F68DCF9F6FB46351BAEB6181E3132021C8CE5A9040494F290AB3E53BB81706BA200243A6043688194F7A37B4DC3697E0B4411375938B66245CEDB099C0537D194E7B2A47FA9698DF5A515A676216D21974F30A6C621409858193A75F0B55B46F58F8122724FCDC8C5681AA0254E0D18C95702887963A693AFD1313D273F8CA3F5FE23B224A799EA792CE4EB0FFEFCBF799A39F25EF57DE228AF7874A32ED50C4C997CF5C3E19F868E65E9F028B3262700EAF1C9C0C65EDB13BFB56359FBB0F7CB9C5257C5A805F3108CF7215006B8BE4E77F12FC166B33036917D7339C18FC98AE02330D239EDEBF437F73214A9B1F1218982D65154FA52736A775026BCE8E00A185EC21626047C5118166F9B58317988951929B2CDF1E4C8D3C516FD58BC24EA776F3AEF45771BB0AE8B45C0ED1FBA9066F36808B3BEE837D3187EA0AA6FA321125AA72CDECD9FC13F61A112129170917F674106740757F0C8B9B9CDA2356A08A39BC0C34871CFB5AB12B6F2D3A7963C5179966C36B99871ACC48A3E2B0CF6FE15CA81DDF67A756A5BA8291F51307EE8EDF235B8F0D2AD1573F38982584EC58BE4CF8EC4AD2DBA805035D99393117ED8C34414223089178A84C31F68183657908A1730F270AC1703EE3853DCB6CE2FCF19E03704FC45CFE6F4360E85FFADB7397F2EA6018ECF00E79468BF7A0484C8ACC26C51FC6ACF5E15378581360EA60602922454F4E424D84A1391D46157CD47FA86FE756F89170AA37D00E36BBB172CFE6E2ADD6224AC700CCFCCA2B776D657C18CC78839001F890846098D44F42FF839C9326E8D3FAC84141B8FE19254DDF18D896512978F5D2CB8B052B2913CFFA8AE0CDB45793BC514ED5F295B699DD1318141973E4657BF8D19A90EB830815D3528360B8D2135DFDD3755CC283ACDBB60BD4F35879A4AB6BF7FEF3CDF65FC34358CA5C59E890F86A6C6FE60A83A0E5F2F216654E9B42D238EC64F1D516468D4B5F538CB12579452FBC589BCF5AD7D28B76E1F67D245F324D92110FD65C711958F3B395BB8CC706BAB4FBCE8152A7C386994D0C1B287A83DB674F90300FDA061AC9E85EBC6A5C4F9E01E7F9D8F32F32CFCC3D33A80B9DBA51D566E99D464417EE8AB26CB263F007C7BA4A54215191E954F28B7FA3CE9B4A04F21D2098BB3612AFE661F9BFF3E16048334212371AD14EBCCD531710F8608CF4B9ED96C96049CB657C9F80EEE5707AD695B"
+const group7680SubgroupOrderHex = "DB48CA7AF9B842827BB457B54BAFF960832ED73E6203C8A38E46D67970BAA8CDFEF964D8417AE31E0811178C25B02D3B"
+const group7680GeneratorHex = "561B6CEEB5C2168B81F57EB079D024A2F3D451016968157B059C5BF5D67A7CB2850126464128B7D29CDB8B5EB6F0024B223AEBA290FCB4094384198FB9BEEF756F0D36A0566E06BEEB142942B7A9BF46FE5B0D76082A0DFD67DBBC9140245F93C0E11D29C1237BF931F502BFB82B92E64F840A009DD4E0B43B6DEE58156566E049716C77EB7C4BE3A630798E47D44D33AE0087C3ADA901AA23A9AF5B4B5D1D69E5BA15F831F9BE91FED5895380E3C95E72E93E4649418D0F8571AE8316122029CA43644A6057A3CE42F204EA3A05E3FF81477EE6FEC10E6944FF3FD1C7C85A5A21FE05639B7DC88CD36EEF81CE73EDA72A7B362DFABA81B756AB21B7099FD02DF7CB259F0C909381AA15A532C18D3C1E63EDBC9C72322242C191690ACF81190160CA8FCB808F07A90DB807FEE3DAF3669EB9429680E2AD89AB7D7D3AC97E7D29FF400A13D7109C3A5DA1F616C751FC37CBE60F441E832AABEC6450F337D2BD47002C2C17DF47DCAED9712715D5250877322B609D4F57A479EF17F773E0BFE2E5A204975499429BF5D99A717DD037721842F1C73AA359D5595B0FE02C4994262F4A0497B8EF45B155A73EA8FF76C1D51B17CE06E77FDCAAE53A39D6722F380C5CEF658856FF74D899FC7D7E2C853328E47D1E67122A3CDA9D8DB1E042406AD9557B8624C92134727F9BA79C8035D7AF64358BE88B36286E3604C794184D2EA470A8D11964033C6E6F6B741F0E6177A5D22660E6DC5D3A2B7B93DEAA2E7CA473E3C4BDDE0D1B0D85282EFC4061304DFC747125A10918D0B0C520705C3448CA1AD89809BA2220859B1EA99810DE27514E36BCA2D09D92A2297F8649FDB2058003D68DB47BB3D865AA87DCB0B8E24A7ED7E589B2AC5EC8BBBE3F28B7BAF346EEE6669971172B1475BDE6CD4D8F05E98DAD489B6409648666DEC186C61EB88BD72ED6C50BA488DDC1BA043BFCFC2024E7C4118A0784B6AEFD6DF74B4A02F3AD6E1A3380B78EC785D9259D5B23F50B514BBC3FF64050D4F9F2F1E8C92BDDB68644A4D7F86FC73B4604D206CDDD326E600C93222AE7621570895EF7A8654947A872C3513D770BE74C27FE528C8A39D3A0A85085717D9D2A6123172A9565D93424EE97573494F4A5C58CC9C1F92897FCAC49A15B1DFB885EFD5C7476EB21D72059CC8583F834F7B18432512A9DEE2AE7FB4C4C784F0F866880A3EF8391ACB3F183C2C7CC4A96816183B9A984198EC926F6F2CAB32A3EF27207174347005F5BAD88C40CE3BC59F26C8C269D1CB33617C7D68070D00C128684D4A26850199B0828EDAD91FD8881C8119D186C17DDA38A3A0ACC29FF93265EC3711A5A73BED2BB340EBD41D7"
+
+// ========================== AH-ElGamal groups ==========================
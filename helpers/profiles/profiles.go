@@ -0,0 +1,189 @@
+package profiles
+
+import (
+	"crypto/elliptic"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"hash"
+	"math/big"
+
+	"github.com/ing-bank/zkrp/crypto/p256"
+)
+
+// ========================== Security profiles ==========================
+// A profile fixes every cryptographic parameter of the protocols for one security level, so that the parameters of the
+// encryption schemes, the signatures and the range proofs cannot be chosen inconsistently:
+//
+//	ID      Paillier/DJ  AH-ElGamal group (P/q)  ECDSA  hash     commitments           range proofs
+//	sec112  2048         2048/224 (RFC 5114)     P-256  SHA-256  secp256k1, sound 112  2^32, CCS08 optimal u
+//	sec128  3072         3072/256                P-256  SHA-256  secp256k1, sound 128  2^32, CCS08 optimal u
+//	sec192  7680         7680/384                P-384  SHA-384  secp256k1, sound 128  2^32, CCS08 optimal u
+//
+// SecurityBits is the security of the confidentiality of the genomes, which has to last as long as the data, and
+// SoundnessBits the one of the binding of the Pedersen commitments and of the proofs over them, which only has to hold
+// during a run of the protocols: a cheating party has to break it before the verifier answers. The commitments are
+// perfectly hiding, so their curve does not bound the confidentiality. secp256k1 is the only curve of the zkrp library,
+// so every profile commits over it and sec192 is sound at 128 bits only; Validate rejects any other CommitmentCurve. The
+// CCS08 range proofs also rely on the bn256 pairing, now estimated at about 100 bits, below SoundnessBits in every
+// profile; Bulletproofs do not.
+// Artifacts that are exchanged or stored (keys, ciphertext encodings, randomness pools) carry the profile ID, and loading
+// them under another profile fails with ErrProfileMismatch.
+
+var (
+	ErrUnknownProfile   = errors.New("unknown security profile")
+	ErrProfileMismatch  = errors.New("artifact was produced under another security profile")
+	ErrUnsupportedCurve = errors.New("commitment curve is not implemented by the range proofs")
+)
+
+type Profile struct {
+	ID            string
+	SecurityBits  int
+	SoundnessBits int
+
+	// additively homomorphic encryption
+	PaillierKeyLen int // bits of N, for Paillier and Damgård–Jurik
+	ElGamal        *ElGamalGroup
+
+	// signatures of the sequencing lab and hashing of (position, base) and (position, ciphertext)
+	SignatureCurve elliptic.Curve
+	NewHash        func() hash.Hash
+
+	// Pedersen commitments to positions, and range proofs over them
+	CommitmentCurve elliptic.Curve
+	RangeBits       int   // Bulletproofs prove x in [0, 2^RangeBits), RangeBits a power of 2 up to 64
+	CCS08Base       int64 // u of CCS08 range proofs, which prove x in [0, u^l); 0 for ccs08.OptimalBase
+}
+
+// ElGamalGroup is the subgroup of prime order Q of Z_P^*, generated by G.
+type ElGamalGroup struct {
+	P, G, Q *big.Int
+}
+
+var (
+	Profile112 = &Profile{
+		ID:              "sec112",
+		SecurityBits:    112,
+		SoundnessBits:   112,
+		PaillierKeyLen:  2048,
+		ElGamal:         newElGamalGroup(rfc5114PrimeHex, rfc5114GeneratorHex, rfc5114SubgroupOrderHex),
+		SignatureCurve:  elliptic.P256(),
+		NewHash:         sha256.New,
+		CommitmentCurve: p256.CURVE,
+		RangeBits:       32,
+		CCS08Base:       0,
+	}
+
+	Profile128 = &Profile{
+		ID:              "sec128",
+		SecurityBits:    128,
+		SoundnessBits:   128,
+		PaillierKeyLen:  3072,
+		ElGamal:         newElGamalGroup(group3072PrimeHex, group3072GeneratorHex, group3072SubgroupOrderHex),
+		SignatureCurve:  elliptic.P256(),
+		NewHash:         sha256.New,
+		CommitmentCurve: p256.CURVE,
+		RangeBits:       32,
+		CCS08Base:       0,
+	}
+
+	Profile192 = &Profile{
+		ID:              "sec192",
+		SecurityBits:    192,
+		SoundnessBits:   128,
+		PaillierKeyLen:  7680,
+		ElGamal:         newElGamalGroup(group7680PrimeHex, group7680GeneratorHex, group7680SubgroupOrderHex),
+		SignatureCurve:  elliptic.P384(),
+		NewHash:         sha512.New384,
+		CommitmentCurve: p256.CURVE,
+		RangeBits:       32,
+		CCS08Base:       0,
+	}
+
+	// Default is the profile of the results in the paper
+	Default = Profile112
+)
+
+var all = []*Profile{Profile112, Profile128, Profile192}
+
+// ByID returns the profile with the given ID.
+func ByID(id string) (*Profile, error) {
+	for _, profile := range all {
+		if profile.ID == id {
+			return profile, nil
+		}
+	}
+	return nil, ErrUnknownProfile
+}
+
+// Check returns ErrProfileMismatch unless the ID, read from an artifact, is the one of the profile.
+func (profile *Profile) Check(id string) error {
+	if id != profile.ID {
+		return ErrProfileMismatch
+	}
+	return nil
+}
+
+// Validate checks the profile: every parameter is at least as strong as the profile claims, the commitments are over the
+// curve of the range proofs, and the AH-ElGamal group is valid. Like ElGamalGroup.Validate, it is meant for tests.
+func (profile *Profile) Validate() error {
+
+	if profile.SoundnessBits <= 0 || profile.SoundnessBits > profile.SecurityBits {
+		return errors.New("soundness of the profile is not in (0, SecurityBits]")
+	}
+	if profile.ElGamal.Q.BitLen() < 2*profile.SecurityBits || profile.SignatureCurve.Params().N.BitLen() < 2*profile.SecurityBits {
+		return errors.New("group order or signature curve is too small for the profile")
+	}
+	if 8*profile.NewHash().Size() < 2*profile.SecurityBits {
+		return errors.New("hash of the profile is too short")
+	}
+	if profile.PaillierKeyLen < paillierKeyLen[profile.SecurityBits] || profile.ElGamal.P.BitLen() < paillierKeyLen[profile.SecurityBits] {
+		return errors.New("modulus is too small for the profile")
+	}
+	if profile.CommitmentCurve != elliptic.Curve(p256.CURVE) {
+		return ErrUnsupportedCurve
+	}
+	if profile.CommitmentCurve.Params().N.BitLen() < 2*profile.SoundnessBits {
+		return errors.New("commitment curve is too small for the soundness of the profile")
+	}
+	if profile.RangeBits <= 0 || profile.RangeBits > 64 || profile.RangeBits&(profile.RangeBits-1) != 0 {
+		return errors.New("range of the range proofs is not a power of 2 up to 64")
+	}
+	return profile.ElGamal.Validate()
+
+}
+
+// paillierKeyLen is the modulus size of each security level, from NIST SP 800-57.
+var paillierKeyLen = map[int]int{112: 2048, 128: 3072, 192: 7680, 256: 15360}
+
+// Validate checks that the group is a prime-order subgroup: P and Q prime, Q | P-1, and G of order Q. It costs a few
+// primality tests on P, so it is meant for tests rather than for every Setup.
+func (group *ElGamalGroup) Validate() error {
+
+	one := big.NewInt(1)
+	if !group.P.ProbablyPrime(20) || !group.Q.ProbablyPrime(20) {
+		return errors.New("group modulus or order is not prime")
+	}
+	if new(big.Int).Mod(new(big.Int).Sub(group.P, one), group.Q).Sign() != 0 {
+		return errors.New("group order does not divide P-1")
+	}
+	if group.G.Cmp(one) <= 0 || group.G.Cmp(group.P) >= 0 || new(big.Int).Exp(group.G, group.Q, group.P).Cmp(one) != 0 {
+		return errors.New("generator is not of order Q")
+	}
+	return nil
+
+}
+
+func newElGamalGroup(primeHex, generatorHex, subgroupOrderHex string) *ElGamalGroup {
+	return &ElGamalGroup{P: fromHex(primeHex), G: fromHex(generatorHex), Q: fromHex(subgroupOrderHex)}
+}
+
+func fromHex(hex string) *big.Int {
+	n, ok := new(big.Int).SetString(hex, 16)
+	if !ok {
+		panic("failed to parse hex number")
+	}
+	return n
+}
+
+// ========================== Security profiles ==========================
@@ -0,0 +1,64 @@
+package profiles
+
+import (
+	"crypto/elliptic"
+	"testing"
+)
+
+func TestProfilesAreValid(t *testing.T) {
+
+	for _, profile := range all {
+		if err := profile.Validate(); err != nil {
+			t.Fatalf("%s: %v", profile.ID, err)
+		}
+	}
+
+}
+
+func TestValidateRejectsWeakProfiles(t *testing.T) {
+
+	weaken := map[string]func(p *Profile){
+		"soundness above security": func(p *Profile) { p.SoundnessBits = p.SecurityBits + 1 },
+		"no soundness":             func(p *Profile) { p.SoundnessBits = 0 },
+		"signature curve":          func(p *Profile) { p.SecurityBits, p.SoundnessBits = 192, 128 },
+		"modulus":                  func(p *Profile) { p.PaillierKeyLen = 1024 },
+		"commitment curve":         func(p *Profile) { p.CommitmentCurve = elliptic.P256() },
+		"range":                    func(p *Profile) { p.RangeBits = 48 },
+		"group": func(p *Profile) {
+			p.ElGamal = &ElGamalGroup{P: Profile112.ElGamal.P, G: Profile112.ElGamal.G, Q: Profile128.ElGamal.Q}
+		},
+	}
+	for name, weaken := range weaken {
+		profile := *Profile112
+		weaken(&profile)
+		if err := profile.Validate(); err == nil {
+			t.Fatalf("%s: weak profile is valid", name)
+		}
+	}
+	profile := *Profile112
+	profile.CommitmentCurve = elliptic.P256()
+	if err := profile.Validate(); err != ErrUnsupportedCurve {
+		t.Fatalf("commitments over P-256: %v, want ErrUnsupportedCurve", err)
+	}
+
+}
+
+func TestByIDAndCheck(t *testing.T) {
+
+	for _, profile := range all {
+		got, err := ByID(profile.ID)
+		if err != nil || got != profile {
+			t.Fatalf("ByID(%q) = %v, %v", profile.ID, got, err)
+		}
+		if err := profile.Check(profile.ID); err != nil {
+			t.Fatalf("%s: %v", profile.ID, err)
+		}
+	}
+	if _, err := ByID("sec256"); err != ErrUnknownProfile {
+		t.Fatalf("ByID of an unknown profile: %v", err)
+	}
+	if err := Profile112.Check(Profile192.ID); err != ErrProfileMismatch {
+		t.Fatalf("Check of another profile: %v", err)
+	}
+
+}
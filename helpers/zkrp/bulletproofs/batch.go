@@ -0,0 +1,363 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+/*
+This file contains the batch verification of range proofs. The equations (65)
+and (67) of a range proof and the final equation of an inner product argument,
+on its own or in a range proof, are all of the form sum_i e_i . P_i = 0. They are multiplied by random weights
+and the equations of all the proofs are added up, so that a whole batch is
+checked with one multi-scalar multiplication. The generators Gg and Hh, G and H
+are shared by all the proofs, so their terms are merged. A batch of invalid
+proofs passes with probability at most 1/ORDER.
+
+The inner product argument is expanded as in Section 6.2 of the paper: the
+folded generators g' and h' are the products of Gg and Hh with the challenges
+of the rounds, and the argument is checked against P = Commit . U^t'.
+*/
+
+package bulletproofs
+
+import (
+    "crypto/rand"
+    "errors"
+    "math/big"
+    "math/bits"
+    "sort"
+
+    "github.com/ing-bank/zkrp/crypto/p256"
+    . "github.com/ing-bank/zkrp/util"
+    "github.com/ing-bank/zkrp/util/bn"
+)
+
+/*
+BatchVerify returns true if and only if all the proofs are valid for the
+parameters. Otherwise it also returns the indexes of the invalid proofs.
+*/
+func BatchVerify(proofs []*BulletProof, params BulletProofSetupParams) (bool, []int) {
+    return batchVerify(len(proofs), func(i int, terms *batchTerms) error {
+        proof := proofs[i]
+        if proof == nil {
+            return errors.New("nil proof")
+        }
+        return terms.addRangeProof([]*p256.P256{proof.V}, proof.A, proof.S, proof.T1, proof.T2, proof.Taux, proof.Mu,
+            proof.Tprime, proof.InnerProductProof, proof.Commit, params, NewTranscript(rangeProofDomain))
+    })
+}
+
+/*
+BatchVerifyBPRP returns true if and only if all the generic range proofs are
+valid for the parameters. Otherwise it also returns the indexes of the invalid
+proofs.
+*/
+func BatchVerifyBPRP(proofs []*ProofBPRP, params *bprp) (bool, []int) {
+    return batchVerify(len(proofs), func(i int, terms *batchTerms) error {
+        proof := proofs[i]
+        if proof == nil {
+            return errors.New("nil proof")
+        }
+        err := terms.addGenericLink(proof.P1.V, proof.P2.V, genericOffset(params.A, params.B, params.N))
+        if err != nil {
+            return err
+        }
+        err = terms.addRangeProof([]*p256.P256{proof.P1.V}, proof.P1.A, proof.P1.S, proof.P1.T1, proof.P1.T2,
+            proof.P1.Taux, proof.P1.Mu, proof.P1.Tprime, proof.P1.InnerProductProof, proof.P1.Commit, params.BP1,
+            genericTranscript(params.A, params.B, params.N, 1))
+        if err != nil {
+            return err
+        }
+        return terms.addRangeProof([]*p256.P256{proof.P2.V}, proof.P2.A, proof.P2.S, proof.P2.T1, proof.P2.T2,
+            proof.P2.Taux, proof.P2.Mu, proof.P2.Tprime, proof.P2.InnerProductProof, proof.P2.Commit, params.BP2,
+            genericTranscript(params.A, params.B, params.N, 2))
+    })
+}
+
+/*
+BatchVerifyAggregated returns true if and only if all the aggregated proofs are
+valid for the parameters. Otherwise it also returns the indexes of the invalid
+proofs.
+*/
+func BatchVerifyAggregated(proofs []*AggregatedBulletProof, params BulletProofSetupParams) (bool, []int) {
+    return batchVerify(len(proofs), func(i int, terms *batchTerms) error {
+        proof := proofs[i]
+        if proof == nil {
+            return errors.New("nil proof")
+        }
+        return terms.addRangeProof(proof.V, proof.A, proof.S, proof.T1, proof.T2, proof.Taux, proof.Mu, proof.Tprime,
+            proof.InnerProductProof, proof.Commit, params, NewTranscript(rangeProofDomain))
+    })
+}
+
+/*
+BatchVerifyGeneric returns true if and only if all the proofs computed by
+ProveGenericAggregated are valid for the intervals of the parameters. Otherwise
+it also returns the indexes of the invalid proofs.
+*/
+func BatchVerifyGeneric(proofs []*AggregatedBulletProof, params *bprpAggregated) (bool, []int) {
+    return batchVerify(len(proofs), func(i int, terms *batchTerms) error {
+        proof := proofs[i]
+        if proof == nil {
+            return errors.New("nil proof")
+        }
+        if int64(len(proof.V)) != params.values() {
+            return errors.New("number of commitments does not match the number of intervals")
+        }
+        for j := range params.A {
+            err := terms.addGenericLink(proof.V[2*j], proof.V[2*j+1], genericOffset(params.A[j], params.B[j], params.N))
+            if err != nil {
+                return err
+            }
+        }
+        return terms.addRangeProof(proof.V, proof.A, proof.S, proof.T1, proof.T2, proof.Taux, proof.Mu, proof.Tprime,
+            proof.InnerProductProof, proof.Commit, params.BP, genericAggregatedTranscript(params.A, params.B, params.N))
+    })
+}
+
+/*
+BatchVerifyInnerProduct returns true if and only if all the inner product
+arguments are valid, proofs[i] for params[i], which holds the commitment P and
+the inner product c of the statement. Otherwise it also returns the indexes of
+the invalid proofs.
+*/
+func BatchVerifyInnerProduct(proofs []*InnerProductProof, params []InnerProductParams) (bool, []int) {
+    return batchVerify(len(proofs), func(i int, terms *batchTerms) error {
+        proof := proofs[i]
+        if proof == nil || i >= len(params) {
+            return errors.New("nil proof")
+        }
+        p := params[i]
+        logn := len(proof.Ls)
+        if int64(1)<<uint(logn) != p.N || len(proof.Rs) != logn || int64(len(p.Gg)) < p.N || int64(len(p.Hh)) < p.N {
+            return errors.New("proof does not match the parameters")
+        }
+        points := append([]*p256.P256{p.P, p.Uu}, proof.Ls...)
+        if !completePoints(append(points, proof.Rs...)...) || !completeScalars(p.Cc, proof.A, proof.B) {
+            return errors.New("proof is missing elements")
+        }
+        w, _ := rand.Int(rand.Reader, ORDER)
+        terms.addInnerProduct(w, p.P, p.Cc, p.Uu, *proof, p.Gg[:p.N], p.Hh[:p.N], nil, innerProductTranscript(p, p.P))
+        return nil
+    })
+}
+
+/*
+batchVerify adds the equations of the n proofs to one batch. Malformed proofs
+are left out of it and reported at once. When the batch fails, every proof is
+checked on its own to find the invalid ones.
+*/
+func batchVerify(n int, add func(i int, terms *batchTerms) error) (bool, []int) {
+    var (
+        failed []int
+        valid  []int
+    )
+    terms := newBatchTerms()
+    for i := 0; i < n; i++ {
+        single := newBatchTerms()
+        if add(i, single) != nil {
+            failed = append(failed, i)
+            continue
+        }
+        terms.merge(single)
+        valid = append(valid, i)
+    }
+    if !terms.isZero() {
+        for _, i := range valid {
+            single := newBatchTerms()
+            add(i, single)
+            if !single.isZero() {
+                failed = append(failed, i)
+            }
+        }
+        sort.Ints(failed)
+    }
+    return len(failed) == 0, failed
+}
+
+/*
+batchTerms is a sum of terms e . P in which equal points are merged.
+*/
+type batchTerms struct {
+    index   map[string]int
+    points  []*p256.P256
+    scalars []*big.Int
+}
+
+func newBatchTerms() *batchTerms {
+    return &batchTerms{index: make(map[string]int)}
+}
+
+func (terms *batchTerms) add(point *p256.P256, scalar *big.Int) {
+    key := point.X.Text(16) + "," + point.Y.Text(16)
+    i, ok := terms.index[key]
+    if !ok {
+        i = len(terms.points)
+        terms.index[key] = i
+        terms.points = append(terms.points, point)
+        terms.scalars = append(terms.scalars, new(big.Int))
+    }
+    terms.scalars[i] = bn.Mod(bn.Add(terms.scalars[i], scalar), ORDER)
+}
+
+func (terms *batchTerms) merge(other *batchTerms) {
+    for i, point := range other.points {
+        terms.add(point, other.scalars[i])
+    }
+}
+
+func (terms *batchTerms) isZero() bool {
+    return p256.MultiMult(terms.points, terms.scalars).IsZero()
+}
+
+/*
+addGenericLink adds the equation V1 = V2 . g^offset of a generic range proof,
+with a random weight.
+*/
+func (terms *batchTerms) addGenericLink(V1, V2 *p256.P256, offset *big.Int) error {
+    if !completePoints(V1, V2) {
+        return errors.New("proof is missing points")
+    }
+    w, _ := rand.Int(rand.Reader, ORDER)
+    terms.add(V1, w)
+    terms.add(V2, bn.Sub(ORDER, w))
+    terms.add(new(p256.P256).ScalarBaseMult(new(big.Int).SetInt64(1)), bn.Sub(ORDER, bn.Multiply(w, offset)))
+    return nil
+}
+
+/*
+addRangeProof adds the equations of a range proof of the m values committed in
+V, with one random weight per equation. A single range proof is the case m = 1.
+The challenges are derived from the transcript t, which holds the statement.
+*/
+func (terms *batchTerms) addRangeProof(V []*p256.P256, A, S, T1, T2 *p256.P256, taux, mu, tprime *big.Int,
+    ipp InnerProductProof, commit *p256.P256, params BulletProofSetupParams, t *Transcript) error {
+
+    m := int64(len(V))
+    nm := params.N * m
+    logn := len(ipp.Ls)
+    if m == 0 || !IsPowerOfTwo(m) || !IsPowerOfTwo(params.N) || nm > int64(len(params.Gg)) ||
+        nm > int64(len(params.Hh)) || int64(1)<<uint(logn) != nm || len(ipp.Rs) != logn {
+        return errors.New("proof does not match the parameters")
+    }
+    points := append([]*p256.P256{A, S, T1, T2, commit, params.H}, V...)
+    points = append(points, ipp.Ls...)
+    points = append(points, ipp.Rs...)
+    if !completePoints(points...) || !completeScalars(taux, mu, tprime, ipp.A, ipp.B) {
+        return errors.New("proof is missing elements")
+    }
+    w1, _ := rand.Int(rand.Reader, ORDER)
+    w2, _ := rand.Int(rand.Reader, ORDER)
+    w3, _ := rand.Int(rand.Reader, ORDER)
+
+    appendRangeStatement(t, params, V)
+    t.AppendP256("A", A)
+    t.AppendP256("S", S)
+    y := t.Challenge("y", ORDER)
+    z := t.Challenge("z", ORDER)
+    t.AppendP256("T1", T1)
+    t.AppendP256("T2", T2)
+    x := t.Challenge("x", ORDER)
+    x2 := bn.Mod(bn.Multiply(x, x), ORDER)
+    g := new(p256.P256).ScalarBaseMult(new(big.Int).SetInt64(1))
+
+    // (65): g^(t'-delta) . h^taux . prod_j V_j^(-z^(1+j)) . T1^(-x) . T2^(-x^2) = 0
+    terms.add(g, bn.Multiply(w1, bn.Sub(tprime, params.aggregatedDelta(y, z, m))))
+    terms.add(params.H, bn.Multiply(w1, taux))
+    zj := bn.Mod(bn.Multiply(z, z), ORDER)
+    for j := int64(0); j < m; j++ {
+        terms.add(V[j], bn.Multiply(w1, bn.Sub(ORDER, zj)))
+        zj = bn.Mod(bn.Multiply(zj, z), ORDER)
+    }
+    terms.add(T1, bn.Multiply(w1, bn.Sub(ORDER, x)))
+    terms.add(T2, bn.Multiply(w1, bn.Sub(ORDER, x2)))
+
+    // (67): A . S^x . g^(-z) . h'^(z.y^nm + z^(1+j).2^n) . h^(-mu) . Commit^(-1) = 0, where h'_k = Hh_k^(y^-k), so
+    // that the exponent of Hh_k is z + z^(1+j).2^i.y^-k for k = j.n + i
+    terms.add(A, w2)
+    terms.add(S, bn.Multiply(w2, x))
+    terms.add(params.H, bn.Multiply(w2, bn.Sub(ORDER, mu)))
+    terms.add(commit, bn.Sub(ORDER, w2))
+    yinv := powerOf(bn.ModInverse(y, ORDER), nm)
+    twos := params.aggregatedTwos(z, m)
+    for k := int64(0); k < nm; k++ {
+        terms.add(params.Gg[k], bn.Multiply(w2, bn.Sub(ORDER, z)))
+        terms.add(params.Hh[k], bn.Multiply(w2, bn.Add(z, bn.Multiply(twos[k], yinv[k]))))
+    }
+
+    // Inner product of l and r, which are committed in Commit over Gg and h', with h'_k = Hh_k^(y^-k)
+    appendRangeResponse(t, taux, mu, tprime, commit)
+    _, Uu, _, _ := generators(0)
+    terms.addInnerProduct(w3, commit, tprime, Uu, ipp, params.Gg[:nm], params.Hh[:nm], yinv, t)
+    return nil
+}
+
+/*
+addInnerProduct adds the final equation of the inner product argument ipp that
+P = g^a.h^b with <a,b> = c, with the weight w:
+P . U^c . prod_i L_i^(x_i^2) . R_i^(x_i^-2) = g'^a . h'^b . U^(a.b), with U = Uu^x
+for the first challenge x of the argument, g' = prod_k g_k^(s_k) and
+h' = prod_k h_k^(hScale_k/s_k). hScale is nil for h itself. The transcript t
+already holds the statement.
+*/
+func (terms *batchTerms) addInnerProduct(w *big.Int, P *p256.P256, c *big.Int, Uu *p256.P256, ipp InnerProductProof, g, h []*p256.P256,
+    hScale []*big.Int, t *Transcript) {
+
+    n := int64(len(g))
+    logn := len(ipp.Ls)
+    wu := bn.Mod(bn.Multiply(w, t.Challenge("x", ORDER)), ORDER)
+    terms.add(P, w)
+    terms.add(Uu, bn.Multiply(wu, bn.Sub(c, bn.Multiply(ipp.A, ipp.B))))
+    xs := make([]*big.Int, logn)
+    s0 := new(big.Int).SetInt64(1)
+    for i := 0; i < logn; i++ {
+        t.AppendP256("L", ipp.Ls[i])
+        t.AppendP256("R", ipp.Rs[i])
+        xi := t.Challenge("x", ORDER)
+        xiinv := bn.ModInverse(xi, ORDER)
+        xs[i] = bn.Mod(bn.Multiply(xi, xi), ORDER)
+        terms.add(ipp.Ls[i], bn.Multiply(w, xs[i]))
+        terms.add(ipp.Rs[i], bn.Multiply(w, bn.Mod(bn.Multiply(xiinv, xiinv), ORDER)))
+        s0 = bn.Mod(bn.Multiply(s0, xiinv), ORDER)
+    }
+    s := foldingExponents(s0, xs, n)
+    wa := bn.Mod(bn.Multiply(w, ipp.A), ORDER)
+    wb := bn.Mod(bn.Multiply(w, ipp.B), ORDER)
+    for k := int64(0); k < n; k++ {
+        // 1/s_k = s_(n-1-k), since the bits of n-1-k are the complement of the ones of k
+        terms.add(g[k], bn.Sub(ORDER, bn.Multiply(wa, s[k])))
+        hk := bn.Multiply(wb, s[n-1-k])
+        if hScale != nil {
+            hk = bn.Multiply(hk, hScale[k])
+        }
+        terms.add(h[k], bn.Sub(ORDER, hk))
+    }
+}
+
+/*
+foldingExponents returns s_k = prod_i x_i^(+-1) for k in [0, n), where x_i is the
+challenge of the i-th round and the sign is the bit log2(n)-1-i of k: the first
+round folds the left half of the generators with x^-1 and the right half with x.
+xs holds the squares of the challenges and s0 = prod_i x_i^-1.
+*/
+func foldingExponents(s0 *big.Int, xs []*big.Int, n int64) []*big.Int {
+    logn := len(xs)
+    s := make([]*big.Int, n)
+    s[0] = s0
+    for k := int64(1); k < n; k++ {
+        j := bits.Len64(uint64(k)) - 1
+        s[k] = bn.Mod(bn.Multiply(s[k-int64(1)<<uint(j)], xs[logn-1-j]), ORDER)
+    }
+    return s
+}
@@ -0,0 +1,150 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+package bulletproofs
+
+import (
+    "math/big"
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+)
+
+func TestBatchVerify(t *testing.T) {
+    params, _ := SetupBits(32)
+    var proofs []*BulletProof
+    for _, secret := range []int64{0, 18, 4294967295} {
+        proof, _ := Prove(big.NewInt(secret), params)
+        proofs = append(proofs, &proof)
+    }
+    ok, failed := BatchVerify(proofs, params)
+    assert.True(t, ok, "valid proofs should verify successfully")
+    assert.Empty(t, failed)
+
+    // a proof out of range, and a proof whose inner product argument was tampered with
+    outOfRange, _ := Prove(big.NewInt(4294967296), params)
+    tampered, _ := Prove(big.NewInt(7), params)
+    tampered.InnerProductProof.A = new(big.Int).Add(tampered.InnerProductProof.A, big.NewInt(1))
+    proofs = []*BulletProof{proofs[0], &outOfRange, proofs[1], &tampered}
+    ok, failed = BatchVerify(proofs, params)
+    assert.False(t, ok, "invalid proofs should fail verification")
+    assert.Equal(t, []int{1, 3}, failed)
+}
+
+func TestBatchVerifyMalformed(t *testing.T) {
+    params, _ := SetupBits(32)
+    proof, _ := Prove(big.NewInt(5), params)
+    truncated, _ := Prove(big.NewInt(5), params)
+    truncated.InnerProductProof.Ls = truncated.InnerProductProof.Ls[1:]
+    missing, _ := Prove(big.NewInt(5), params)
+    missing.T2 = nil
+    ok, failed := BatchVerify([]*BulletProof{&truncated, &proof, nil, &missing}, params)
+    assert.False(t, ok, "malformed proofs should fail verification")
+    assert.Equal(t, []int{0, 2, 3}, failed)
+}
+
+func TestBatchVerifyBPRP(t *testing.T) {
+    params, _ := SetupGeneric(18, 200)
+    var proofs []*ProofBPRP
+    for _, secret := range []int64{18, 40, 199, 200} {
+        proof, _ := ProveGeneric(big.NewInt(secret), params)
+        proofs = append(proofs, &proof)
+    }
+    ok, failed := BatchVerifyBPRP(proofs, params)
+    assert.False(t, ok, "a secret out of range should fail verification")
+    assert.Equal(t, []int{3}, failed)
+    ok, failed = BatchVerifyBPRP(proofs[:3], params)
+    assert.True(t, ok, "valid proofs should verify successfully")
+    assert.Empty(t, failed)
+}
+
+func TestBatchVerifyAggregated(t *testing.T) {
+    params, _ := SetupAggregated(32, 2)
+    var proofs []*AggregatedBulletProof
+    for _, secrets := range [][]int64{{18, 200}, {0, 4294967296}, {4294967295, 7}} {
+        proof, _ := ProveAggregated([]*big.Int{big.NewInt(secrets[0]), big.NewInt(secrets[1])}, params)
+        proofs = append(proofs, &proof)
+    }
+    ok, failed := BatchVerifyAggregated(proofs, params)
+    assert.False(t, ok, "a secret out of range should fail verification")
+    assert.Equal(t, []int{1}, failed)
+}
+
+func TestBatchVerifyGeneric(t *testing.T) {
+    params, _ := SetupGenericAggregated([]int64{0, 2001}, []int64{1000, 3200000010})
+    var proofs []*AggregatedBulletProof
+    for _, secrets := range [][]int64{{999, 2001}, {0, 3200000009}, {1000, 2001}} {
+        proof, _ := ProveGenericAggregated([]*big.Int{big.NewInt(secrets[0]), big.NewInt(secrets[1])}, params)
+        proofs = append(proofs, &proof)
+    }
+    ok, failed := BatchVerifyGeneric(proofs, params)
+    assert.False(t, ok, "a secret out of range should fail verification")
+    assert.Equal(t, []int{2}, failed)
+
+    // generic proofs are bound to their intervals
+    ok, _ = BatchVerifyAggregated(proofs[:2], params.BP)
+    assert.False(t, ok, "generic proofs should not verify as plain aggregated proofs")
+
+    // the commitments must be the ones the proof was made for
+    proofs[1].V[0], proofs[1].V[1] = proofs[1].V[1], proofs[1].V[0]
+    ok, failed = BatchVerifyGeneric(proofs[:2], params)
+    assert.False(t, ok, "swapped commitments should fail verification")
+    assert.Equal(t, []int{1}, failed)
+}
+
+func TestBatchVerifyInnerProduct(t *testing.T) {
+    var (
+        proofs []*InnerProductProof
+        params []InnerProductParams
+    )
+    for _, values := range [][]int64{{2, -1, 10, 6, 1, 2, 10, 7}, {0, 0, 0, 1, 5, 5, 5, 5}, {3, 1, 4, 1, 5, 9, 2, 6}} {
+        a := []*big.Int{big.NewInt(values[0]), big.NewInt(values[1]), big.NewInt(values[2]), big.NewInt(values[3])}
+        b := []*big.Int{big.NewInt(values[4]), big.NewInt(values[5]), big.NewInt(values[6]), big.NewInt(values[7])}
+        c := new(big.Int)
+        for i := range a {
+            c.Add(c, new(big.Int).Mul(a[i], b[i]))
+        }
+        p, _ := setupInnerProduct(nil, nil, nil, c, 4)
+        p.P = commitInnerProduct(p.Gg, p.Hh, a, b)
+        proof, _ := proveInnerProduct(a, b, p.P, p)
+        proofs = append(proofs, &proof)
+        params = append(params, p)
+    }
+    ok, failed := BatchVerifyInnerProduct(proofs, params)
+    assert.True(t, ok, "valid proofs should verify successfully")
+    assert.Empty(t, failed)
+
+    // a proof of another inner product, a tampered proof and a missing one
+    params[0].Cc = new(big.Int).Add(params[0].Cc, big.NewInt(1))
+    tampered := *proofs[2]
+    tampered.B = new(big.Int).Add(tampered.B, big.NewInt(1))
+    ok, failed = BatchVerifyInnerProduct([]*InnerProductProof{proofs[0], proofs[1], &tampered, nil}, params[:3])
+    assert.False(t, ok, "invalid proofs should fail verification")
+    assert.Equal(t, []int{0, 2, 3}, failed)
+}
+
+func BenchmarkBatchVerify(b *testing.B) {
+    params, _ := SetupBits(32)
+    proofs := make([]*BulletProof, 8)
+    for i := range proofs {
+        proof, _ := Prove(big.NewInt(int64(i)), params)
+        proofs[i] = &proof
+    }
+    b.ResetTimer()
+    for i := 0; i < b.N; i++ {
+        BatchVerify(proofs, params)
+    }
+}
@@ -18,17 +18,14 @@
 package bulletproofs
 
 import (
-    "crypto/sha256"
     "errors"
     "math/big"
 
     "github.com/ing-bank/zkrp/crypto/p256"
+    . "github.com/ing-bank/zkrp/util"
     "github.com/ing-bank/zkrp/util/bn"
-    "github.com/ing-bank/zkrp/util/byteconversion"
 )
 
-var SEEDU = "BulletproofsDoesNotNeedTrustedSetupU"
-
 /*
 InnerProductParams contains elliptic curve generators used to compute Pedersen
 commitments.
@@ -56,7 +53,6 @@ type InnerProductProof struct {
     Hh     *p256.P256
     A      *big.Int
     B      *big.Int
-    Params InnerProductParams
 }
 
 /*
@@ -71,29 +67,29 @@ func setupInnerProduct(H *p256.P256, g, h []*p256.P256, c *big.Int, N int64) (In
     } else {
         params.N = N
     }
+    // the generators of the setup are used unless others are given
+    count := params.N
+    if g != nil && h != nil {
+        count = 0
+    }
+    H0, U, Gg, Hh := generators(count)
     if H == nil {
-        params.H, _ = p256.MapToGroup(SEEDH)
+        params.H = H0
     } else {
         params.H = H
     }
     if g == nil {
-        params.Gg = make([]*p256.P256, params.N)
-        for i := int64(0); i < params.N; i++ {
-            params.Gg[i], _ = p256.MapToGroup(SEEDH + "g" + string(i))
-        }
+        params.Gg = Gg
     } else {
         params.Gg = g
     }
     if h == nil {
-        params.Hh = make([]*p256.P256, params.N)
-        for i := int64(0); i < params.N; i++ {
-            params.Hh[i], _ = p256.MapToGroup(SEEDH + "h" + string(i))
-        }
+        params.Hh = Hh
     } else {
         params.Hh = h
     }
     params.Cc = c
-    params.Uu, _ = p256.MapToGroup(SEEDU)
+    params.Uu = U
     params.P = new(p256.P256).SetInfinity()
 
     return params, nil
@@ -103,6 +99,14 @@ func setupInnerProduct(H *p256.P256, g, h []*p256.P256, c *big.Int, N int64) (In
 proveInnerProduct calculates the Zero Knowledge Proof for the Inner Product argument.
 */
 func proveInnerProduct(a, b []*big.Int, P *p256.P256, params InnerProductParams) (InnerProductProof, error) {
+    return proveInnerProductTranscript(a, b, P, params, innerProductTranscript(params, P))
+}
+
+/*
+proveInnerProductTranscript is proveInnerProduct with the challenges derived from
+a transcript that already binds the statement (g, h, P, c).
+*/
+func proveInnerProductTranscript(a, b []*big.Int, P *p256.P256, params InnerProductParams, t *Transcript) (InnerProductProof, error) {
     var (
         proof InnerProductProof
         n, m  int64
@@ -118,23 +122,21 @@ func proveInnerProduct(a, b []*big.Int, P *p256.P256, params InnerProductParams)
     }
 
     // Fiat-Shamir:
-    // x = Hash(g,h,P,c)
-    x, _ := hashIP(params.Gg, params.Hh, P, params.Cc, params.N)
+    // x = Hash(transcript)
+    x := t.Challenge("x", ORDER)
     // Pprime = P.u^(x.c)
     ux := new(p256.P256).ScalarMult(params.Uu, x)
     uxc := new(p256.P256).ScalarMult(ux, params.Cc)
     PP := new(p256.P256).Multiply(P, uxc)
     // Execute Protocol 2 recursively
-    proof = computeBipRecursive(a, b, params.Gg, params.Hh, ux, PP, n, Ls, Rs)
-    proof.Params = params
-    proof.Params.P = PP
+    proof = computeBipRecursive(a, b, params.Gg, params.Hh, ux, PP, n, Ls, Rs, t)
     return proof, nil
 }
 
 /*
 computeBipRecursive is the main recursive function that will be used to compute the inner product argument.
 */
-func computeBipRecursive(a, b []*big.Int, g, h []*p256.P256, u, P *p256.P256, n int64, Ls, Rs []*p256.P256) InnerProductProof {
+func computeBipRecursive(a, b []*big.Int, g, h []*p256.P256, u, P *p256.P256, n int64, Ls, Rs []*p256.P256, t *Transcript) InnerProductProof {
     var (
         proof                            InnerProductProof
         cL, cR, x, xinv, x2, x2inv       *big.Int
@@ -177,7 +179,9 @@ func computeBipRecursive(a, b []*big.Int, g, h []*p256.P256, u, P *p256.P256, n
         R.Multiply(R, new(p256.P256).ScalarMult(u, cR))
 
         // Fiat-Shamir:                                                       // (26)
-        x, _, _ = HashBP(L, R)
+        t.AppendP256("L", L)
+        t.AppendP256("R", R)
+        x = t.Challenge("x", ORDER)
         xinv = bn.ModInverse(x, ORDER)
 
         // Compute g' = g[:n']^(x^-1) * g[n':]^(x)                            // (29)
@@ -208,30 +212,56 @@ func computeBipRecursive(a, b []*big.Int, g, h []*p256.P256, u, P *p256.P256, n
         Ls = append(Ls, L)
         Rs = append(Rs, R)
         // recursion computeBipRecursive(g',h',u,P'; a', b')                  // (35)
-        proof = computeBipRecursive(aprime, bprime, gprime, hprime, u, Pprime, nprime, Ls, Rs)
+        proof = computeBipRecursive(aprime, bprime, gprime, hprime, u, Pprime, nprime, Ls, Rs, t)
     }
     proof.N = n
     return proof
 }
 
 /*
-Verify is responsible for the verification of the Inner Product Proof.
+Verify is responsible for the verification of the Inner Product Proof. The
+parameters are the ones the proof was computed with, and params.P is the
+commitment g^a.h^b whose inner product <a,b> = params.Cc is proven.
+*/
+func (proof InnerProductProof) Verify(params InnerProductParams) (bool, error) {
+    if params.P == nil || int64(len(params.Gg)) < params.N || int64(len(params.Hh)) < params.N {
+        return false, errors.New("proof does not match the parameters")
+    }
+    return proof.verify(params, innerProductTranscript(params, params.P))
+}
+
+/*
+verify is Verify with the challenges derived from a transcript that already
+binds the statement (g, h, P, c).
 */
-func (proof InnerProductProof) Verify() (bool, error) {
+func (proof InnerProductProof) verify(params InnerProductParams, t *Transcript) (bool, error) {
 
     logn := len(proof.Ls)
     var (
         x, xinv, x2, x2inv                   *big.Int
         ngprime, nhprime, ngprime2, nhprime2 []*p256.P256
     )
+    if int64(1)<<uint(logn) != params.N || len(proof.Rs) != logn || int64(len(params.Gg)) < params.N ||
+        int64(len(params.Hh)) < params.N || params.P == nil {
+        return false, errors.New("proof does not match the parameters")
+    }
+    for i := range proof.Ls {
+        if proof.Ls[i] == nil || proof.Rs[i] == nil || proof.Ls[i].X == nil || proof.Rs[i].X == nil {
+            return false, errors.New("proof is missing points")
+        }
+    }
 
-    gprime := proof.Params.Gg
-    hprime := proof.Params.Hh
-    Pprime := proof.Params.P
-    nprime := proof.N
+    gprime := params.Gg[:params.N]
+    hprime := params.Hh[:params.N]
+    // P' = P.u^c, where u = Uu^x for the first challenge x
+    ux := new(p256.P256).ScalarMult(params.Uu, t.Challenge("x", ORDER))
+    Pprime := new(p256.P256).Multiply(params.P, new(p256.P256).ScalarMult(ux, params.Cc))
+    nprime := params.N
     for i := int64(0); i < int64(logn); i++ {
         nprime = nprime / 2                        // (20)
-        x, _, _ = HashBP(proof.Ls[i], proof.Rs[i]) // (26)
+        t.AppendP256("L", proof.Ls[i])              // (26)
+        t.AppendP256("R", proof.Rs[i])
+        x = t.Challenge("x", ORDER)
         xinv = bn.ModInverse(x, ORDER)
         // Compute g' = g[:n']^(x^-1) * g[n':]^(x)                            // (29)
         ngprime = vectorScalarExp(gprime[:nprime], xinv)
@@ -255,7 +285,7 @@ func (proof InnerProductProof) Verify() (bool, error) {
     rhs := new(p256.P256).ScalarMult(gprime[0], proof.A)
     hb := new(p256.P256).ScalarMult(hprime[0], proof.B)
     rhs.Multiply(rhs, hb)
-    rhs.Multiply(rhs, new(p256.P256).ScalarMult(proof.U, ab))
+    rhs.Multiply(rhs, new(p256.P256).ScalarMult(ux, ab))
     // Compute inverse of left hand side
     nP := Pprime.Neg(Pprime)
     nP.Multiply(nP, rhs)
@@ -265,26 +295,6 @@ func (proof InnerProductProof) Verify() (bool, error) {
     return c, nil
 }
 
-/*
-hashIP is responsible for the computing a Zp element given elements from GT and G1.
-*/
-func hashIP(g, h []*p256.P256, P *p256.P256, c *big.Int, n int64) (*big.Int, error) {
-    digest := sha256.New()
-    digest.Write([]byte(P.String()))
-
-    for i := int64(0); i < n; i++ {
-        digest.Write([]byte(g[i].String()))
-        digest.Write([]byte(h[i].String()))
-    }
-
-    digest.Write([]byte(c.String()))
-    output := digest.Sum(nil)
-    tmp := output[0:]
-    result, err := byteconversion.FromByteArray(tmp)
-
-    return result, err
-}
-
 /*
 commitInnerProduct is responsible for calculating g^a.h^b.
 */
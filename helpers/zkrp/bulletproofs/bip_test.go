@@ -47,7 +47,8 @@ func TestInnerProduct(t *testing.T) {
     commit := commitInnerProduct(innerProductParams.Gg, innerProductParams.Hh, a, b)
 
     proof, _ := proveInnerProduct(a, b, commit, innerProductParams)
-    ok, _ := proof.Verify()
+    innerProductParams.P = commit
+    ok, _ := proof.Verify(innerProductParams)
     if ok != true {
         t.Errorf("Assert failure: expected true, actual: %t", ok)
     }
@@ -21,8 +21,8 @@ import (
     "crypto/rand"
     "errors"
     "fmt"
-    "math"
     "math/big"
+    "math/bits"
 
     "github.com/ing-bank/zkrp/crypto/p256"
     . "github.com/ing-bank/zkrp/util"
@@ -64,35 +64,43 @@ type BulletProof struct {
     Tprime            *big.Int
     InnerProductProof InnerProductProof
     Commit            *p256.P256
-    Params            BulletProofSetupParams
 }
 
 /*
 SetupInnerProduct is responsible for computing the common parameters.
-Only works for ranges to 0 to 2^n, where n is a power of 2 and n <= 32
-TODO: allow n > 32 (need uint64 for that).
+Only works for ranges to 0 to 2^n, where n is a power of 2 and n <= 32.
+Use SetupBits for ranges up to 2^64, whose end does not fit in an int64.
 */
 func Setup(b int64) (BulletProofSetupParams, error) {
     if !IsPowerOfTwo(b) {
         return BulletProofSetupParams{}, errors.New("range end is not a power of 2")
     }
-
-    params := BulletProofSetupParams{}
-    params.G = new(p256.P256).ScalarBaseMult(new(big.Int).SetInt64(1))
-    params.H, _ = p256.MapToGroup(SEEDH)
-    params.N = int64(math.Log2(float64(b)))
-    if !IsPowerOfTwo(params.N) {
-        return BulletProofSetupParams{}, fmt.Errorf("range end is a power of 2, but it's exponent should also be. Exponent: %d", params.N)
+    n := int64(bits.Len64(uint64(b)) - 1)
+    if !IsPowerOfTwo(n) {
+        return BulletProofSetupParams{}, fmt.Errorf("range end is a power of 2, but it's exponent should also be. Exponent: %d", n)
     }
-    if params.N > 32 {
+    if n > 32 {
         return BulletProofSetupParams{}, errors.New("range end can not be greater than 2**32")
     }
-    params.Gg = make([]*p256.P256, params.N)
-    params.Hh = make([]*p256.P256, params.N)
-    for i := int64(0); i < params.N; i++ {
-        params.Gg[i], _ = p256.MapToGroup(SEEDH + "g" + string(i))
-        params.Hh[i], _ = p256.MapToGroup(SEEDH + "h" + string(i))
+    return SetupBits(n)
+}
+
+/*
+SetupBits computes the common parameters for the range 0 to 2^n, where n is a
+power of 2 and n <= MAX_RANGE_BITS.
+*/
+func SetupBits(n int64) (BulletProofSetupParams, error) {
+    if !IsPowerOfTwo(n) {
+        return BulletProofSetupParams{}, fmt.Errorf("bit-length of the range should be a power of 2: %d", n)
     }
+    if n > MAX_RANGE_BITS {
+        return BulletProofSetupParams{}, fmt.Errorf("bit-length of the range can not be greater than %d", MAX_RANGE_BITS)
+    }
+
+    params := BulletProofSetupParams{}
+    params.G = new(p256.P256).ScalarBaseMult(new(big.Int).SetInt64(1))
+    params.N = n
+    params.H, _, params.Gg, params.Hh = generators(params.N)
     return params, nil
 }
 
@@ -102,6 +110,15 @@ eprint version of Bulletproofs papers:
 https://eprint.iacr.org/2017/1066.pdf
 */
 func Prove(secret *big.Int, params BulletProofSetupParams) (BulletProof, error) {
+    gamma, _ := rand.Int(rand.Reader, ORDER)
+    return prove(secret, gamma, params, NewTranscript(rangeProofDomain))
+}
+
+/*
+prove computes the rangeproof of the commitment to secret with randomness gamma,
+continuing the transcript t.
+*/
+func prove(secret, gamma *big.Int, params BulletProofSetupParams, t *Transcript) (BulletProof, error) {
     var (
         proof BulletProof
     )
@@ -110,8 +127,8 @@ func Prove(secret *big.Int, params BulletProofSetupParams) (BulletProof, error)
     // ////////////////////////////////////////////////////////////////////////////
 
     // commitment to v and gamma
-    gamma, _ := rand.Int(rand.Reader, ORDER)
     V, _ := CommitG1(secret, gamma, params.H)
+    appendRangeStatement(t, params, []*p256.P256{V})
 
     // aL, aR and commitment: (A, alpha)
     aL, _ := Decompose(secret, 2, params.N)                                    // (41)
@@ -126,7 +143,10 @@ func Prove(secret *big.Int, params BulletProofSetupParams) (BulletProof, error)
     S := commitVectorBig(sL, sR, rho, params.H, params.Gg, params.Hh, params.N) // (47)
 
     // Fiat-Shamir heuristic to compute challenges y and z, corresponds to    (49)
-    y, z, _ := HashBP(A, S)
+    t.AppendP256("A", A)
+    t.AppendP256("S", S)
+    y := t.Challenge("y", ORDER)
+    z := t.Challenge("z", ORDER)
 
     // ////////////////////////////////////////////////////////////////////////////
     // Second phase: page 20
@@ -179,7 +199,9 @@ func Prove(secret *big.Int, params BulletProofSetupParams) (BulletProof, error)
     T2, _ := CommitG1(t2, tau2, params.H) // (53)
 
     // Fiat-Shamir heuristic to compute 'random' challenge x
-    x, _, _ := HashBP(T1, T2)
+    t.AppendP256("T1", T1)
+    t.AppendP256("T2", T2)
+    x := t.Challenge("x", ORDER)
 
     // ////////////////////////////////////////////////////////////////////////////
     // Third phase                                                              //
@@ -221,7 +243,8 @@ func Prove(secret *big.Int, params BulletProofSetupParams) (BulletProof, error)
         return proof, setupErr
     }
     commit := commitInnerProduct(params.Gg, hprime, bl, br)
-    proofip, _ := proveInnerProduct(bl, br, commit, params.InnerProductParams)
+    appendRangeResponse(t, taux, mu, tprime, commit)
+    proofip, _ := proveInnerProductTranscript(bl, br, commit, params.InnerProductParams, t)
 
     proof.V = V
     proof.A = A
@@ -233,19 +256,37 @@ func Prove(secret *big.Int, params BulletProofSetupParams) (BulletProof, error)
     proof.Tprime = tprime
     proof.InnerProductProof = proofip
     proof.Commit = commit
-    proof.Params = params
 
     return proof, nil
 }
 
 /*
-Verify returns true if and only if the proof is valid.
+Verify returns true if and only if the proof is valid for the parameters it was
+computed with.
 */
-func (proof *BulletProof) Verify() (bool, error) {
-    params := proof.Params
+func (proof *BulletProof) Verify(params BulletProofSetupParams) (bool, error) {
+    return proof.verify(params, NewTranscript(rangeProofDomain))
+}
+
+/*
+verify is Verify continuing the transcript t.
+*/
+func (proof *BulletProof) verify(params BulletProofSetupParams, t *Transcript) (bool, error) {
+    if int64(len(params.Gg)) < params.N || int64(len(params.Hh)) < params.N {
+        return false, errors.New("setup parameters are incomplete")
+    }
+    if !proof.complete() {
+        return false, errors.New("proof is missing elements")
+    }
     // Recover x, y, z using Fiat-Shamir heuristic
-    x, _, _ := HashBP(proof.T1, proof.T2)
-    y, z, _ := HashBP(proof.A, proof.S)
+    appendRangeStatement(t, params, []*p256.P256{proof.V})
+    t.AppendP256("A", proof.A)
+    t.AppendP256("S", proof.S)
+    y := t.Challenge("y", ORDER)
+    z := t.Challenge("z", ORDER)
+    t.AppendP256("T1", proof.T1)
+    t.AppendP256("T2", proof.T2)
+    x := t.Challenge("x", ORDER)
 
     // Switch generators                                                   // (64)
     hprime := updateGenerators(params.Hh, y, params.N)
@@ -326,13 +367,42 @@ func (proof *BulletProof) Verify() (bool, error) {
     c67 := rP.IsZero()
 
     // Verify Inner Product Proof ################################################
-    ok, _ := proof.InnerProductProof.Verify()
+    ipParams, _ := setupInnerProduct(params.H, params.Gg[:params.N], hprime, proof.Tprime, params.N)
+    ipParams.P = proof.Commit
+    appendRangeResponse(t, proof.Taux, proof.Mu, proof.Tprime, proof.Commit)
+    ok, _ := proof.InnerProductProof.verify(ipParams, t)
 
     result := c65 && c67 && ok
 
     return result, nil
 }
 
+/*
+complete returns true if and only if no element of the proof is missing.
+*/
+func (proof *BulletProof) complete() bool {
+    return completePoints(proof.V, proof.A, proof.S, proof.T1, proof.T2, proof.Commit) &&
+        completeScalars(proof.Taux, proof.Mu, proof.Tprime, proof.InnerProductProof.A, proof.InnerProductProof.B)
+}
+
+func completePoints(points ...*p256.P256) bool {
+    for _, point := range points {
+        if point == nil || point.X == nil || point.Y == nil {
+            return false
+        }
+    }
+    return true
+}
+
+func completeScalars(scalars ...*big.Int) bool {
+    for _, scalar := range scalars {
+        if scalar == nil {
+            return false
+        }
+    }
+    return true
+}
+
 /*
 SampleRandomVector generates a vector composed by random big numbers.
 */
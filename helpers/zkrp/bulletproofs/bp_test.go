@@ -76,6 +76,44 @@ func TestXWithinRange(t *testing.T) {
     }
 }
 
+func TestXWithin64BitRange(t *testing.T) {
+    params := setupBits(t, 64)
+    x := new(big.Int).SetUint64(math.MaxUint64)
+    if proveAndVerifyRange(x, params) != true {
+        t.Errorf("x within 64-bit range should verify successfully")
+    }
+    x = new(big.Int).SetUint64(1<<63 + 5)
+    if proveAndVerifyRange(x, params) != true {
+        t.Errorf("x within 64-bit range should verify successfully")
+    }
+}
+
+func TestXEqualTo64BitRangeEnd(t *testing.T) {
+    params := setupBits(t, 64)
+    x := new(big.Int).Lsh(big.NewInt(1), 64)
+    if proveAndVerifyRange(x, params) == true {
+        t.Errorf("x equal to range end should not verify")
+    }
+}
+
+func TestSetupBitsInvalid(t *testing.T) {
+    if _, err := SetupBits(128); err == nil {
+        t.Errorf("bit-length greater than MAX_RANGE_BITS should fail")
+    }
+    if _, err := SetupBits(48); err == nil {
+        t.Errorf("bit-length not a power of 2 should fail")
+    }
+}
+
+func setupBits(t *testing.T, n int64) BulletProofSetupParams {
+    params, err := SetupBits(n)
+    if err != nil {
+        t.Errorf("Invalid bit-length: %s", err)
+        t.FailNow()
+    }
+    return params
+}
+
 func setupRange(t *testing.T, rangeEnd int64) BulletProofSetupParams {
     params, err := Setup(rangeEnd)
     if err != nil {
@@ -87,7 +125,7 @@ func setupRange(t *testing.T, rangeEnd int64) BulletProofSetupParams {
 
 func proveAndVerifyRange(x *big.Int, params BulletProofSetupParams) bool {
     proof, _ := Prove(x, params)
-    ok, _ := proof.Verify()
+    ok, _ := proof.Verify(params)
     return ok
 }
 
@@ -109,7 +147,7 @@ func TestJsonEncodeDecode(t *testing.T) {
 
     assert.Equal(t, proof, decodedProof, "should be equal")
 
-    ok, err := decodedProof.Verify()
+    ok, err := decodedProof.Verify(params)
     if err != nil {
         t.Fatal("verify error:", err)
     }
@@ -0,0 +1,327 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+/*
+This file contains the aggregated range proof of Section 4.3 of the Bulletproofs
+paper, which proves that m committed values are all in [0, 2^n) with a single
+proof. The inner product argument is over vectors of n.m elements, so the proof
+only grows by 2.log2(m) elements compared to a single range proof, and one
+verification replaces m of them.
+*/
+
+package bulletproofs
+
+import (
+    "crypto/rand"
+    "errors"
+    "fmt"
+    "math/big"
+
+    "github.com/ing-bank/zkrp/crypto/p256"
+    . "github.com/ing-bank/zkrp/util"
+    "github.com/ing-bank/zkrp/util/bn"
+)
+
+/*
+MAX_AGGREGATED is the maximum number of values of an aggregated proof.
+*/
+var MAX_AGGREGATED int64 = 16
+
+/*
+AggregatedBulletProof contains the elements that are necessary for the
+verification of an aggregated range proof. V contains the commitments to the
+values, in the order in which they were proven.
+*/
+type AggregatedBulletProof struct {
+    V                 []*p256.P256
+    A                 *p256.P256
+    S                 *p256.P256
+    T1                *p256.P256
+    T2                *p256.P256
+    Taux              *big.Int
+    Mu                *big.Int
+    Tprime            *big.Int
+    InnerProductProof InnerProductProof
+    Commit            *p256.P256
+}
+
+/*
+SetupAggregated computes the common parameters for proving that m values are in
+the range 0 to 2^n, where n and m are powers of 2, n <= MAX_RANGE_BITS and
+m <= MAX_AGGREGATED. The n first generators are the ones of SetupBits(n).
+*/
+func SetupAggregated(n, m int64) (BulletProofSetupParams, error) {
+    if !IsPowerOfTwo(m) {
+        return BulletProofSetupParams{}, fmt.Errorf("number of aggregated values should be a power of 2: %d", m)
+    }
+    if m > MAX_AGGREGATED {
+        return BulletProofSetupParams{}, fmt.Errorf("number of aggregated values can not be greater than %d", MAX_AGGREGATED)
+    }
+    params, err := SetupBits(n)
+    if err != nil {
+        return params, err
+    }
+    params.H, _, params.Gg, params.Hh = generators(n * m)
+    return params, nil
+}
+
+/*
+ProveAggregated computes the aggregated rangeproof of the secrets. The number of
+secrets has to be a power of 2, and at most the number of values the parameters
+were set up for.
+*/
+func ProveAggregated(secrets []*big.Int, params BulletProofSetupParams) (AggregatedBulletProof, error) {
+    gammas := make([]*big.Int, len(secrets))
+    for j := range gammas {
+        gammas[j], _ = rand.Int(rand.Reader, ORDER)
+    }
+    return proveAggregated(secrets, gammas, params, NewTranscript(rangeProofDomain))
+}
+
+/*
+proveAggregated computes the aggregated rangeproof of the commitments to secrets
+with randomness gammas, continuing the transcript t.
+*/
+func proveAggregated(secrets, gammas []*big.Int, params BulletProofSetupParams, t *Transcript) (AggregatedBulletProof, error) {
+    var (
+        proof AggregatedBulletProof
+    )
+    m := int64(len(secrets))
+    if !IsPowerOfTwo(m) {
+        return proof, fmt.Errorf("number of aggregated values should be a power of 2: %d", m)
+    }
+    nm := params.N * m
+    if nm > int64(len(params.Gg)) {
+        return proof, errors.New("parameters were set up for less aggregated values")
+    }
+    Gg, Hh := params.Gg[:nm], params.Hh[:nm]
+
+    // commitments to v_j and gamma_j, and the bits of all the values
+    V := make([]*p256.P256, m)
+    aL := make([]int64, 0, nm)
+    for j, secret := range secrets {
+        V[j], _ = CommitG1(secret, gammas[j], params.H)
+        bits, _ := Decompose(secret, 2, params.N)
+        aL = append(aL, bits...)
+    }
+    aR, _ := computeAR(aL)
+    alpha, _ := rand.Int(rand.Reader, ORDER)
+    A := commitVector(aL, aR, alpha, params.H, Gg, Hh, nm)
+
+    sL := sampleRandomVector(nm)
+    sR := sampleRandomVector(nm)
+    rho, _ := rand.Int(rand.Reader, ORDER)
+    S := commitVectorBig(sL, sR, rho, params.H, Gg, Hh, nm)
+
+    appendRangeStatement(t, params, V)
+    t.AppendP256("A", A)
+    t.AppendP256("S", S)
+    y := t.Challenge("y", ORDER)
+    z := t.Challenge("z", ORDER)
+
+    // l(X) = aL - z.1^nm + sL.X
+    // r(X) = y^nm . (aR + z.1^nm + sR.X) + sum_j z^(1+j) . (0^((j-1)n) || 2^n || 0^((m-j)n))
+    vz, _ := VectorCopy(z, nm)
+    vy := powerOf(y, nm)
+    z2n := params.aggregatedTwos(z, m)
+
+    naL, _ := VectorConvertToBig(aL, nm)
+    aLmvz, _ := VectorSub(naL, vz)
+    naR, _ := VectorConvertToBig(aR, nm)
+    aRzn, _ := VectorAdd(naR, vz)
+    ynaRzn, _ := VectorMul(vy, aRzn)
+    r0, _ := VectorAdd(ynaRzn, z2n)
+    ynsR, _ := VectorMul(vy, sR)
+
+    // t1 = < l0, r1 > + < l1, r0 >, t2 = < l1, r1 >
+    sp1, _ := ScalarProduct(aLmvz, ynsR)
+    sp2, _ := ScalarProduct(sL, r0)
+    t1 := bn.Mod(bn.Add(sp1, sp2), ORDER)
+    t2, _ := ScalarProduct(sL, ynsR)
+    t2 = bn.Mod(t2, ORDER)
+
+    tau1, _ := rand.Int(rand.Reader, ORDER)
+    tau2, _ := rand.Int(rand.Reader, ORDER)
+    T1, _ := CommitG1(t1, tau1, params.H)
+    T2, _ := CommitG1(t2, tau2, params.H)
+
+    t.AppendP256("T1", T1)
+    t.AppendP256("T2", T2)
+    x := t.Challenge("x", ORDER)
+
+    // bl = l(x), br = r(x), t' = < bl, br >
+    sLx, _ := VectorScalarMul(sL, x)
+    bl, _ := VectorAdd(aLmvz, sLx)
+    sRx, _ := VectorScalarMul(sR, x)
+    aRzn, _ = VectorAdd(aRzn, sRx)
+    ynaRzn, _ = VectorMul(vy, aRzn)
+    br, _ := VectorAdd(ynaRzn, z2n)
+    tprime, _ := ScalarProduct(bl, br)
+
+    // taux = tau2 . x^2 + tau1 . x + sum_j z^(1+j) . gamma_j
+    taux := bn.Multiply(tau2, bn.Multiply(x, x))
+    taux = bn.Add(taux, bn.Multiply(tau1, x))
+    zj := bn.Mod(bn.Multiply(z, z), ORDER)
+    for j := int64(0); j < m; j++ {
+        taux = bn.Add(taux, bn.Multiply(zj, gammas[j]))
+        zj = bn.Mod(bn.Multiply(zj, z), ORDER)
+    }
+    taux = bn.Mod(taux, ORDER)
+
+    // mu = alpha + rho . x
+    mu := bn.Mod(bn.Add(alpha, bn.Multiply(rho, x)), ORDER)
+
+    hprime := updateGenerators(Hh, y, nm)
+    ipParams, setupErr := setupInnerProduct(params.H, Gg, hprime, tprime, nm)
+    if setupErr != nil {
+        return proof, setupErr
+    }
+    commit := commitInnerProduct(Gg, hprime, bl, br)
+    appendRangeResponse(t, taux, mu, tprime, commit)
+    proofip, _ := proveInnerProductTranscript(bl, br, commit, ipParams, t)
+
+    proof.V = V
+    proof.A = A
+    proof.S = S
+    proof.T1 = T1
+    proof.T2 = T2
+    proof.Taux = taux
+    proof.Mu = mu
+    proof.Tprime = tprime
+    proof.InnerProductProof = proofip
+    proof.Commit = commit
+
+    return proof, nil
+}
+
+/*
+Verify returns true if and only if the proof is valid, i.e., if all the values
+committed in V are in [0, 2^n).
+*/
+func (proof *AggregatedBulletProof) Verify(params BulletProofSetupParams) (bool, error) {
+    return proof.verify(params, NewTranscript(rangeProofDomain))
+}
+
+/*
+verify is Verify continuing the transcript t.
+*/
+func (proof *AggregatedBulletProof) verify(params BulletProofSetupParams, t *Transcript) (bool, error) {
+    m := int64(len(proof.V))
+    if !IsPowerOfTwo(m) || params.N*m > int64(len(params.Gg)) || params.N*m > int64(len(params.Hh)) {
+        return false, errors.New("number of commitments does not match the parameters")
+    }
+    if !completePoints(proof.V...) || !completePoints(proof.A, proof.S, proof.T1, proof.T2, proof.Commit) ||
+        !completeScalars(proof.Taux, proof.Mu, proof.Tprime, proof.InnerProductProof.A, proof.InnerProductProof.B) {
+        return false, errors.New("proof is missing elements")
+    }
+    nm := params.N * m
+    Gg, Hh := params.Gg[:nm], params.Hh[:nm]
+
+    appendRangeStatement(t, params, proof.V)
+    t.AppendP256("A", proof.A)
+    t.AppendP256("S", proof.S)
+    y := t.Challenge("y", ORDER)
+    z := t.Challenge("z", ORDER)
+    t.AppendP256("T1", proof.T1)
+    t.AppendP256("T2", proof.T2)
+    x := t.Challenge("x", ORDER)
+
+    hprime := updateGenerators(Hh, y, nm)
+
+    // g^t' . h^taux = prod_j V_j^(z^(1+j)) . g^delta(y,z) . T1^x . T2^(x^2)
+    lhs, _ := CommitG1(proof.Tprime, proof.Taux, params.H)
+
+    x2 := bn.Mod(bn.Multiply(x, x), ORDER)
+    rhs := new(p256.P256).ScalarBaseMult(params.aggregatedDelta(y, z, m))
+    zj := bn.Mod(bn.Multiply(z, z), ORDER)
+    for j := int64(0); j < m; j++ {
+        rhs.Multiply(rhs, new(p256.P256).ScalarMult(proof.V[j], zj))
+        zj = bn.Mod(bn.Multiply(zj, z), ORDER)
+    }
+    rhs.Multiply(rhs, new(p256.P256).ScalarMult(proof.T1, x))
+    rhs.Multiply(rhs, new(p256.P256).ScalarMult(proof.T2, x2))
+
+    lhs.Neg(lhs)
+    rhs.Multiply(rhs, lhs)
+    c65 := rhs.IsZero()
+
+    // P = A . S^x . g^(-z) . h'^(z.y^nm + sum_j z^(1+j) . 2^n_j) = h^mu . Commit
+    lP := new(p256.P256).Add(proof.A, new(p256.P256).ScalarMult(proof.S, x))
+    vmz, _ := VectorCopy(bn.Sub(ORDER, z), nm)
+    gpmz, _ := VectorExp(Gg, vmz)
+    lP.Add(lP, gpmz)
+
+    vz, _ := VectorCopy(z, nm)
+    zyn, _ := VectorMul(powerOf(y, nm), vz)
+    exps, _ := VectorAdd(zyn, params.aggregatedTwos(z, m))
+    hprimeexp, _ := VectorExp(hprime, exps)
+    lP.Add(lP, hprimeexp)
+
+    rP := new(p256.P256).ScalarMult(params.H, proof.Mu)
+    rP.Multiply(rP, proof.Commit)
+    lP = lP.Neg(lP)
+    rP.Add(rP, lP)
+    c67 := rP.IsZero()
+
+    ipParams, _ := setupInnerProduct(params.H, Gg, hprime, proof.Tprime, nm)
+    ipParams.P = proof.Commit
+    appendRangeResponse(t, proof.Taux, proof.Mu, proof.Tprime, proof.Commit)
+    ok, _ := proof.InnerProductProof.verify(ipParams, t)
+
+    result := c65 && c67 && ok
+
+    return result, nil
+}
+
+/*
+aggregatedTwos returns the vector of n.m elements whose j-th block of n elements
+is z^(1+j) . 2^n, for j = 1 to m.
+*/
+func (params *BulletProofSetupParams) aggregatedTwos(z *big.Int, m int64) []*big.Int {
+    p2n := powerOf(new(big.Int).SetInt64(2), params.N)
+    result := make([]*big.Int, 0, params.N*m)
+    zj := bn.Mod(bn.Multiply(z, z), ORDER)
+    for j := int64(0); j < m; j++ {
+        block, _ := VectorScalarMul(p2n, zj)
+        result = append(result, block...)
+        zj = bn.Mod(bn.Multiply(zj, z), ORDER)
+    }
+    return result
+}
+
+/*
+delta(y,z) = (z-z^2) . < 1^nm, y^nm > - sum_j z^(2+j) . < 1^n, 2^n >
+*/
+func (params *BulletProofSetupParams) aggregatedDelta(y, z *big.Int, m int64) *big.Int {
+    nm := params.N * m
+    z2 := bn.Mod(bn.Multiply(z, z), ORDER)
+
+    // < 1^nm, y^nm >
+    v1, _ := VectorCopy(new(big.Int).SetInt64(1), nm)
+    sp1y, _ := ScalarProduct(v1, powerOf(y, nm))
+
+    // < 1^n, 2^n > = 2^n - 1
+    sp12 := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), uint(params.N)), big.NewInt(1))
+
+    result := bn.Mod(bn.Sub(z, z2), ORDER)
+    result = bn.Mod(bn.Multiply(result, sp1y), ORDER)
+    zj := bn.Mod(bn.Multiply(z2, z), ORDER)
+    for j := int64(0); j < m; j++ {
+        result = bn.Sub(result, bn.Multiply(zj, sp12))
+        zj = bn.Mod(bn.Multiply(zj, z), ORDER)
+    }
+    return bn.Mod(result, ORDER)
+}
@@ -0,0 +1,154 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+package bulletproofs
+
+import (
+    "encoding/json"
+    "math/big"
+    "testing"
+
+    "github.com/ing-bank/zkrp/crypto/p256"
+    "github.com/ing-bank/zkrp/util"
+    "github.com/stretchr/testify/assert"
+)
+
+func TestAggregatedWithinRange(t *testing.T) {
+    secrets := []*big.Int{big.NewInt(0), big.NewInt(3), big.NewInt(4294967295), big.NewInt(1234567)}
+    if setupProveVerifyAggregated(t, 32, secrets) != true {
+        t.Errorf("secrets within range should verify successfully")
+    }
+}
+
+func TestAggregatedOneOutOfRange(t *testing.T) {
+    secrets := []*big.Int{big.NewInt(5), big.NewInt(4294967296)}
+    if setupProveVerifyAggregated(t, 32, secrets) == true {
+        t.Errorf("a secret out of range should fail verification")
+    }
+    secrets = []*big.Int{big.NewInt(-1), big.NewInt(5)}
+    if setupProveVerifyAggregated(t, 32, secrets) == true {
+        t.Errorf("a secret out of range should fail verification")
+    }
+}
+
+func TestAggregated64Bit(t *testing.T) {
+    secrets := []*big.Int{new(big.Int).SetUint64(1<<64 - 1), big.NewInt(7)}
+    if setupProveVerifyAggregated(t, 64, secrets) != true {
+        t.Errorf("secrets within 64-bit range should verify successfully")
+    }
+}
+
+func TestAggregatedInvalidNumberOfValues(t *testing.T) {
+    if _, err := SetupAggregated(32, 3); err == nil {
+        t.Errorf("number of values not a power of 2 should fail")
+    }
+    params, _ := SetupAggregated(32, 2)
+    if _, err := ProveAggregated([]*big.Int{big.NewInt(1), big.NewInt(2), big.NewInt(3), big.NewInt(4)}, params); err == nil {
+        t.Errorf("more values than set up for should fail")
+    }
+}
+
+func TestGenericAggregated(t *testing.T) {
+    // the two boundaries of a query: lower in [0, 1000), upper in [2001, 3200000010)
+    a := []int64{0, 2001}
+    b := []int64{1000, 3200000010}
+    params, errSetup := SetupGenericAggregated(a, b)
+    if errSetup != nil {
+        t.Errorf(errSetup.Error())
+        t.FailNow()
+    }
+    for _, c := range []struct {
+        l, h int64
+        ok   bool
+    }{{999, 2001, true}, {0, 3200000009, true}, {1000, 2001, false}, {999, 2000, false}, {999, 3200000010, false}} {
+        proof, _ := ProveGenericAggregated([]*big.Int{big.NewInt(c.l), big.NewInt(c.h)}, params)
+        ok, _ := proof.VerifyGeneric(params)
+        if ok != c.ok {
+            t.Errorf("secrets (%d, %d): expected %t, actual: %t", c.l, c.h, c.ok, ok)
+        }
+    }
+}
+
+func TestGenericAggregatedCommitted(t *testing.T) {
+    a := []int64{0, 2001}
+    b := []int64{1000, 3200000010}
+    params, _ := SetupGenericAggregated(a, b)
+    secrets := []*big.Int{big.NewInt(999), big.NewInt(2001)}
+    randomness := []*big.Int{big.NewInt(12345), big.NewInt(67890)}
+    var C [2]*p256.P256
+    for j := range C {
+        C[j], _ = util.CommitG1(secrets[j], randomness[j], params.BP.H)
+    }
+    proof, _ := ProveGenericAggregatedCommitted(secrets, randomness, params)
+    if ok, _ := proof.VerifyGeneric(params); !ok {
+        t.Errorf("Assert failure: the proof should verify")
+    }
+    for j := range C {
+        if !proof.CommitsTo(j, C[j], params) {
+            t.Errorf("Assert failure: the proof should be of the secret of commitment %d", j)
+        }
+    }
+    if proof.CommitsTo(0, C[1], params) || proof.CommitsTo(1, C[0], params) || proof.CommitsTo(2, C[0], params) {
+        t.Errorf("Assert failure: the proof should not be of another commitment")
+    }
+    // fresh randomness, as in ProveGenericAggregated, is not bound to the commitments
+    fresh, _ := ProveGenericAggregated(secrets, params)
+    if fresh.CommitsTo(0, C[0], params) {
+        t.Errorf("Assert failure: a proof with fresh randomness should not be of the commitment")
+    }
+}
+
+func TestJsonEncodeDecodeAggregated(t *testing.T) {
+    params, _ := SetupAggregated(32, 2)
+    proof, _ := ProveAggregated([]*big.Int{big.NewInt(18), big.NewInt(200)}, params)
+    jsonEncoded, err := json.Marshal(proof)
+    if err != nil {
+        t.Fatal("encode error:", err)
+    }
+
+    var decodedProof AggregatedBulletProof
+    err = json.Unmarshal(jsonEncoded, &decodedProof)
+    if err != nil {
+        t.Fatal("decode error:", err)
+    }
+
+    assert.Equal(t, proof, decodedProof, "should be equal")
+
+    ok, err := decodedProof.Verify(params)
+    if err != nil {
+        t.Fatal("verify error:", err)
+    }
+    assert.True(t, ok, "should verify")
+}
+
+func setupProveVerifyAggregated(t *testing.T, n int64, secrets []*big.Int) bool {
+    params, errSetup := SetupAggregated(n, int64(len(secrets)))
+    if errSetup != nil {
+        t.Errorf(errSetup.Error())
+        t.FailNow()
+    }
+    proof, errProve := ProveAggregated(secrets, params)
+    if errProve != nil {
+        t.Errorf(errProve.Error())
+        t.FailNow()
+    }
+    ok, errVerify := proof.Verify(params)
+    if errVerify != nil {
+        t.Errorf(errVerify.Error())
+        t.FailNow()
+    }
+    return ok
+}
@@ -18,7 +18,12 @@
 package bulletproofs
 
 import (
+    "crypto/rand"
+    "errors"
+    "fmt"
     "math/big"
+
+    "github.com/ing-bank/zkrp/crypto/p256"
 )
 
 /*
@@ -28,6 +33,7 @@ generic Range Proofs, for any interval [A, B).
 type bprp struct {
     A   int64
     B   int64
+    N   int64
     BP1 BulletProofSetupParams
     BP2 BulletProofSetupParams
 }
@@ -42,18 +48,25 @@ type ProofBPRP struct {
 
 /*
 SetupGeneric is responsible for calling the Setup algorithm for each
-BulletProof.
+BulletProof. Both BulletProofs are over [0, 2^N), with N = MAX_RANGE_END_EXPONENT
+when B - A <= 2^MAX_RANGE_END_EXPONENT and N = MAX_RANGE_BITS otherwise, since
+the proof is only complete for secrets in [A, B) if B - A <= 2^N.
 */
 func SetupGeneric(a, b int64) (*bprp, error) {
     params := new(bprp)
     params.A = a
     params.B = b
+    params.N = int64(MAX_RANGE_END_EXPONENT)
+    width := new(big.Int).Sub(big.NewInt(b), big.NewInt(a))
+    if width.Cmp(new(big.Int).Lsh(big.NewInt(1), uint(params.N))) > 0 {
+        params.N = MAX_RANGE_BITS
+    }
     var errBp1, errBp2 error
-    params.BP1, errBp1 = Setup(MAX_RANGE_END)
+    params.BP1, errBp1 = SetupBits(params.N)
     if errBp1 != nil {
         return nil, errBp1
     }
-    params.BP2, errBp2 = Setup(MAX_RANGE_END)
+    params.BP2, errBp2 = SetupBits(params.N)
     if errBp2 != nil {
         return nil, errBp2
     }
@@ -69,20 +82,23 @@ https://infoscience.epfl.ch/record/128718/files/CCS08.pdf
 func ProveGeneric(secret *big.Int, params *bprp) (ProofBPRP, error) {
     var proof ProofBPRP
 
+    // both commitments use the same randomness, see genericOffset
+    gamma, _ := rand.Int(rand.Reader, ORDER)
+
     // x - b + 2^N
-    p2 := new(big.Int).SetInt64(MAX_RANGE_END)
+    p2 := new(big.Int).Lsh(big.NewInt(1), uint(params.N))
     xb := new(big.Int).Sub(secret, new(big.Int).SetInt64(params.B))
     xb.Add(xb, p2)
 
     var err1 error
-    proof.P1, err1 = Prove(xb, params.BP1)
+    proof.P1, err1 = prove(xb, gamma, params.BP1, genericTranscript(params.A, params.B, params.N, 1))
     if err1 != nil {
         return proof, err1
     }
 
     xa := new(big.Int).Sub(secret, new(big.Int).SetInt64(params.A))
     var err2 error
-    proof.P2, err2 = Prove(xa, params.BP2)
+    proof.P2, err2 = prove(xa, gamma, params.BP2, genericTranscript(params.A, params.B, params.N, 2))
     if err2 != nil {
         return proof, err2
     }
@@ -91,17 +107,188 @@ func ProveGeneric(secret *big.Int, params *bprp) (ProofBPRP, error) {
 }
 
 /*
-Verify call the Verification algorithm for each BulletProof argument.
+Verify call the Verification algorithm for each BulletProof argument, and
+checks that both commit to the same secret.
 */
-func (proof ProofBPRP) Verify() (bool, error) {
-    ok1, err1 := proof.P1.Verify()
+func (proof ProofBPRP) Verify(params *bprp) (bool, error) {
+    if !genericLinked(proof.P1.V, proof.P2.V, genericOffset(params.A, params.B, params.N)) {
+        return false, errors.New("commitments are not to the same secret")
+    }
+    ok1, err1 := proof.P1.verify(params.BP1, genericTranscript(params.A, params.B, params.N, 1))
     if !ok1 {
         return false, err1
     }
-    ok2, err2 := proof.P2.Verify()
+    ok2, err2 := proof.P2.verify(params.BP2, genericTranscript(params.A, params.B, params.N, 2))
     if !ok2 {
         return false, err2
     }
 
     return ok1 && ok2, nil
 }
+
+/*
+bprpAggregated contains the intervals [A[j], B[j]) of several generic range
+proofs, which are proven together in one aggregated BulletProof over [0, 2^N).
+*/
+type bprpAggregated struct {
+    A  []int64
+    B  []int64
+    N  int64
+    BP BulletProofSetupParams
+}
+
+/*
+SetupGenericAggregated is responsible for calling the SetupAggregated algorithm
+for the intervals [a[j], b[j]). Each interval takes 2 values of the aggregated
+BulletProof, as in ProveGeneric, and N is chosen as in SetupGeneric for the
+widest interval.
+*/
+func SetupGenericAggregated(a, b []int64) (*bprpAggregated, error) {
+    n, err := genericAggregatedBits(a, b)
+    if err != nil {
+        return nil, err
+    }
+    params, err := SetupAggregated(n, aggregatedValues(len(a)))
+    if err != nil {
+        return nil, err
+    }
+    return NewGenericAggregated(a, b, params)
+}
+
+/*
+NewGenericAggregated returns the generic range proofs of the intervals [a[j], b[j])
+over parameters that were set up, or loaded, beforehand. The range of the
+parameters has to cover the widest interval, and the parameters have to be set
+up for at least 2 values per interval.
+*/
+func NewGenericAggregated(a, b []int64, bp BulletProofSetupParams) (*bprpAggregated, error) {
+    n, err := genericAggregatedBits(a, b)
+    if err != nil {
+        return nil, err
+    }
+    if bp.N < n {
+        return nil, fmt.Errorf("intervals need a range of %d bits, the parameters are set up for %d", n, bp.N)
+    }
+    if bp.N*aggregatedValues(len(a)) > int64(len(bp.Gg)) || bp.N*aggregatedValues(len(a)) > int64(len(bp.Hh)) {
+        return nil, errors.New("parameters are not set up for as many values")
+    }
+    params := new(bprpAggregated)
+    params.A = a
+    params.B = b
+    params.N = bp.N
+    params.BP = bp
+    return params, nil
+}
+
+/*
+genericAggregatedBits returns the bit-length N of the range of the aggregated
+BulletProof of the intervals [a[j], b[j]).
+*/
+func genericAggregatedBits(a, b []int64) (int64, error) {
+    if len(a) == 0 || len(a) != len(b) {
+        return 0, errors.New("interval bounds should be non-empty and of the same length")
+    }
+    n := int64(MAX_RANGE_END_EXPONENT)
+    for j := range a {
+        width := new(big.Int).Sub(big.NewInt(b[j]), big.NewInt(a[j]))
+        if width.Cmp(new(big.Int).Lsh(big.NewInt(1), uint(n))) > 0 {
+            n = MAX_RANGE_BITS
+        }
+    }
+    return n, nil
+}
+
+/*
+ProveGenericAggregated proves that secrets[j] is in [A[j], B[j]) for every j,
+with one aggregated BulletProof over x[j] - B[j] + 2^N and x[j] - A[j]. The
+proof is padded with zeros to a power of 2 values.
+*/
+func ProveGenericAggregated(secrets []*big.Int, params *bprpAggregated) (AggregatedBulletProof, error) {
+    randomness := make([]*big.Int, len(secrets))
+    for j := range randomness {
+        randomness[j], _ = rand.Int(rand.Reader, ORDER)
+    }
+    return ProveGenericAggregatedCommitted(secrets, randomness, params)
+}
+
+/*
+ProveGenericAggregatedCommitted is ProveGenericAggregated for secrets that are
+already committed as g^secrets[j].H^randomness[j], with the H of the parameters:
+V[2j+1] is then the commitment of secrets[j] times g^-A[j], which the verifier
+checks with CommitsTo.
+*/
+func ProveGenericAggregatedCommitted(secrets, randomness []*big.Int, params *bprpAggregated) (AggregatedBulletProof, error) {
+    if len(secrets) != len(params.A) || len(randomness) != len(secrets) {
+        return AggregatedBulletProof{}, errors.New("number of secrets does not match the number of intervals")
+    }
+    p2 := new(big.Int).Lsh(big.NewInt(1), uint(params.N))
+    values := make([]*big.Int, params.values())
+    gammas := make([]*big.Int, params.values())
+    for j, secret := range secrets {
+        xb := new(big.Int).Sub(secret, new(big.Int).SetInt64(params.B[j]))
+        values[2*j] = xb.Add(xb, p2)
+        values[2*j+1] = new(big.Int).Sub(secret, new(big.Int).SetInt64(params.A[j]))
+        // both commitments of an interval use the same randomness, see genericOffset
+        gammas[2*j] = new(big.Int).Mod(randomness[j], ORDER)
+        gammas[2*j+1] = gammas[2*j]
+    }
+    for i := 2 * len(secrets); i < len(values); i++ {
+        values[i] = new(big.Int)
+        gammas[i], _ = rand.Int(rand.Reader, ORDER)
+    }
+    return proveAggregated(values, gammas, params.BP, genericAggregatedTranscript(params.A, params.B, params.N))
+}
+
+/*
+VerifyGeneric returns true if and only if the proof, computed by
+ProveGenericAggregated, is valid for the intervals of the parameters, i.e., if
+the secret of V[2j] and V[2j+1] is in [A[j], B[j]) for every j.
+*/
+func (proof *AggregatedBulletProof) VerifyGeneric(params *bprpAggregated) (bool, error) {
+    if int64(len(proof.V)) != params.values() {
+        return false, errors.New("number of commitments does not match the number of intervals")
+    }
+    for j := range params.A {
+        if !genericLinked(proof.V[2*j], proof.V[2*j+1], genericOffset(params.A[j], params.B[j], params.N)) {
+            return false, errors.New("commitments are not to the same secret")
+        }
+    }
+    return proof.verify(params.BP, genericAggregatedTranscript(params.A, params.B, params.N))
+}
+
+/*
+CommitsTo returns true if and only if the secret of the j-th interval of the
+proof is the one committed by C, i.e., if V[2j+1] = C.g^-A[j], as for a proof
+computed by ProveGenericAggregatedCommitted with the randomness of C.
+*/
+func (proof *AggregatedBulletProof) CommitsTo(j int, C *p256.P256, params *bprpAggregated) bool {
+    if j < 0 || j >= len(params.A) || 2*j+1 >= len(proof.V) || C == nil || C.IsZero() {
+        return false
+    }
+    V := proof.V[2*j+1]
+    if V == nil || V.IsZero() {
+        return false
+    }
+    shift := new(big.Int).Mod(new(big.Int).Neg(big.NewInt(params.A[j])), ORDER)
+    expected := new(p256.P256).Multiply(C, new(p256.P256).ScalarBaseMult(shift))
+    return !expected.IsZero() && expected.X.Cmp(V.X) == 0 && expected.Y.Cmp(V.Y) == 0
+}
+
+/*
+values returns the number of values of the aggregated BulletProof.
+*/
+func (params *bprpAggregated) values() int64 {
+    return aggregatedValues(len(params.A))
+}
+
+/*
+aggregatedValues returns the number of values of the aggregated BulletProof of
+the given number of intervals: 2 for each interval, rounded up to a power of 2.
+*/
+func aggregatedValues(intervals int) int64 {
+    m := int64(1)
+    for m < int64(2*intervals) {
+        m *= 2
+    }
+    return m
+}
@@ -55,6 +55,27 @@ func TestXEqualToRangeEndGeneric(t *testing.T) {
     }
 }
 
+func TestXWithinWideGenericRange(t *testing.T) {
+    // [2^40, 2^62) is wider than 2^32, so both BulletProofs are 64-bit
+    a, b := int64(1)<<40, int64(1)<<62
+    params, errSetup := SetupGeneric(a, b)
+    if errSetup != nil {
+        t.Errorf(errSetup.Error())
+        t.FailNow()
+    }
+    assert.Equal(t, MAX_RANGE_BITS, params.N, "should use 64-bit BulletProofs")
+    for _, c := range []struct {
+        secret int64
+        ok     bool
+    }{{a, true}, {b - 1, true}, {a - 1, false}, {b, false}} {
+        proof, _ := ProveGeneric(new(big.Int).SetInt64(c.secret), params)
+        ok, _ := proof.Verify(params)
+        if ok != c.ok {
+            t.Errorf("secret %d: expected %t, actual: %t", c.secret, c.ok, ok)
+        }
+    }
+}
+
 func setupProveVerify18To200(t *testing.T, secret int) bool {
     params, errSetup := SetupGeneric(18, 200)
     if errSetup != nil {
@@ -67,7 +88,7 @@ func setupProveVerify18To200(t *testing.T, secret int) bool {
         t.Errorf(errProve.Error())
         t.FailNow()
     }
-    ok, errVerify := proof.Verify()
+    ok, errVerify := proof.Verify(params)
     if errVerify != nil {
         t.Errorf(errVerify.Error())
         t.FailNow()
@@ -110,7 +131,7 @@ func TestJsonEncodeDecodeBPRP(t *testing.T) {
     assert.Equal(t, proof, decodedProof, "should be equal")
 
     // Verify the proof
-    ok, errVerify := decodedProof.Verify()
+    ok, errVerify := decodedProof.Verify(params)
     if errVerify != nil {
         t.Errorf(errVerify.Error())
         t.FailNow()
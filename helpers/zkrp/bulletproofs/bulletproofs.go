@@ -32,3 +32,4 @@ var ORDER = p256.CURVE.N
 var SEEDH = "BulletproofsDoesNotNeedTrustedSetupH"
 var MAX_RANGE_END int64 = 4294967296 // 2**32
 var MAX_RANGE_END_EXPONENT = 32      // 2**32
+var MAX_RANGE_BITS int64 = 64        // SetupBits supports ranges up to 2**64
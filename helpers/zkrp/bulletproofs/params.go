@@ -0,0 +1,166 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+/*
+This file contains the caching and the serialization of the setup parameters.
+The generators are derived deterministically from SEEDH and SEEDU with
+MapToGroup, which costs a square root per point, so they are derived once per
+process and shared by every setup: the parameters of SetupBits(n) and of
+SetupAggregated(n, m) are prefixes of the same sequences. Parameters can also
+be computed once, serialized with MarshalBinary and loaded by the prover and
+the verifier with UnmarshalBinary, which checks that every generator is the one
+derived from the seeds, so that a file cannot bring generators with a known
+discrete logarithm relation. Proofs do not embed their parameters.
+*/
+
+package bulletproofs
+
+import (
+    "encoding/binary"
+    "errors"
+    "math/big"
+    "sync"
+
+    "github.com/ing-bank/zkrp/crypto/p256"
+)
+
+var SEEDU = "BulletproofsDoesNotNeedTrustedSetupU"
+
+/*
+generatorCache holds the generators derived so far. The points are shared by
+all the parameters, so they must never be modified.
+*/
+var generatorCache struct {
+    mutex  sync.Mutex
+    H, U   *p256.P256
+    Gg, Hh []*p256.P256
+}
+
+/*
+generators returns H, U and the count first generators of Gg and Hh, deriving
+the ones that are not cached yet.
+*/
+func generators(count int64) (H, U *p256.P256, Gg, Hh []*p256.P256) {
+    cache := &generatorCache
+    cache.mutex.Lock()
+    defer cache.mutex.Unlock()
+
+    if cache.H == nil {
+        cache.H, _ = p256.MapToGroup(SEEDH)
+        cache.U, _ = p256.MapToGroup(SEEDU)
+    }
+    for i := int64(len(cache.Gg)); i < count; i++ {
+        g, _ := p256.MapToGroup(generatorSeed("g", i))
+        h, _ := p256.MapToGroup(generatorSeed("h", i))
+        cache.Gg = append(cache.Gg, g)
+        cache.Hh = append(cache.Hh, h)
+    }
+    Gg = append([]*p256.P256(nil), cache.Gg[:count]...)
+    Hh = append([]*p256.P256(nil), cache.Hh[:count]...)
+    return cache.H, cache.U, Gg, Hh
+}
+
+/*
+generatorSeed returns the seed of the i-th generator of Gg ("g") or Hh ("h").
+*/
+func generatorSeed(name string, i int64) string {
+    return SEEDH + name + string(rune(i))
+}
+
+/*
+pointSize is the size of an encoded point: X and Y, 32 bytes each.
+*/
+const pointSize = 64
+
+/*
+MarshalBinary encodes N, H and the generators Gg and Hh. G is the base point of
+the curve and is not encoded.
+*/
+func (params BulletProofSetupParams) MarshalBinary() ([]byte, error) {
+    if params.H == nil || len(params.Gg) != len(params.Hh) {
+        return nil, errors.New("setup parameters are incomplete")
+    }
+    data := make([]byte, 16, 16+pointSize*(1+2*len(params.Gg)))
+    binary.BigEndian.PutUint64(data[0:8], uint64(params.N))
+    binary.BigEndian.PutUint64(data[8:16], uint64(len(params.Gg)))
+    data = appendPoint(data, params.H)
+    for i := range params.Gg {
+        data = appendPoint(data, params.Gg[i])
+        data = appendPoint(data, params.Hh[i])
+    }
+    return data, nil
+}
+
+/*
+UnmarshalBinary decodes parameters encoded by MarshalBinary. It fails if the
+number of generators is not N times a power of 2, or if a point is not the one
+derived from SEEDH (see p256.IsMappedToGroup).
+*/
+func (params *BulletProofSetupParams) UnmarshalBinary(data []byte) error {
+    if len(data) < 16 {
+        return errors.New("setup parameters are truncated")
+    }
+    n := int64(binary.BigEndian.Uint64(data[0:8]))
+    count := int64(binary.BigEndian.Uint64(data[8:16]))
+    if n <= 0 || n > MAX_RANGE_BITS || !IsPowerOfTwo(n) || count%n != 0 || !IsPowerOfTwo(count/n) ||
+        count/n > MAX_AGGREGATED {
+        return errors.New("invalid number of generators")
+    }
+    if int64(len(data)) != 16+pointSize*(1+2*count) {
+        return errors.New("setup parameters are truncated")
+    }
+
+    var err error
+    data = data[16:]
+    decoded := BulletProofSetupParams{N: n, Gg: make([]*p256.P256, count), Hh: make([]*p256.P256, count)}
+    decoded.G = new(p256.P256).ScalarBaseMult(new(big.Int).SetInt64(1))
+    if decoded.H, data, err = readPoint(data, SEEDH); err != nil {
+        return err
+    }
+    for i := int64(0); i < count; i++ {
+        if decoded.Gg[i], data, err = readPoint(data, generatorSeed("g", i)); err != nil {
+            return err
+        }
+        if decoded.Hh[i], data, err = readPoint(data, generatorSeed("h", i)); err != nil {
+            return err
+        }
+    }
+    *params = decoded
+    return nil
+}
+
+func appendPoint(data []byte, point *p256.P256) []byte {
+    var buffer [pointSize]byte
+    x, y := point.X.Bytes(), point.Y.Bytes()
+    copy(buffer[pointSize/2-len(x):pointSize/2], x)
+    copy(buffer[pointSize-len(y):], y)
+    return append(data, buffer[:]...)
+}
+
+/*
+readPoint decodes the generator derived from seed.
+*/
+func readPoint(data []byte, seed string) (*p256.P256, []byte, error) {
+    point := &p256.P256{
+        X: new(big.Int).SetBytes(data[:pointSize/2]),
+        Y: new(big.Int).SetBytes(data[pointSize/2 : pointSize]),
+    }
+    if !p256.IsMappedToGroup(seed, point) {
+        return nil, nil, errors.New("generator is not the one derived from the seed")
+    }
+    return point, data[pointSize:], nil
+}
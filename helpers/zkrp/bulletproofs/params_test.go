@@ -0,0 +1,113 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+package bulletproofs
+
+import (
+    "math/big"
+    "testing"
+
+    "github.com/ing-bank/zkrp/crypto/p256"
+    "github.com/stretchr/testify/assert"
+)
+
+func TestSetupDeterministic(t *testing.T) {
+    params1, _ := SetupBits(32)
+    params2, _ := SetupBits(32)
+    assert.Equal(t, params1, params2, "setups of the same range should be equal")
+
+    // the generators of a single range proof are the first ones of an aggregated one
+    aggregated, _ := SetupAggregated(32, 4)
+    assert.Equal(t, params1.H, aggregated.H)
+    assert.Equal(t, params1.Gg, aggregated.Gg[:32])
+    assert.Equal(t, params1.Hh, aggregated.Hh[:32])
+    assert.Equal(t, 128, len(aggregated.Gg))
+}
+
+func TestSetupParamsMarshalUnmarshal(t *testing.T) {
+    params, _ := SetupAggregated(32, 2)
+    data, err := params.MarshalBinary()
+    if err != nil {
+        t.Fatal("encode error:", err)
+    }
+
+    // the parameters are stored or sent to the prover and the verifier here
+
+    var decoded BulletProofSetupParams
+    if err := decoded.UnmarshalBinary(data); err != nil {
+        t.Fatal("decode error:", err)
+    }
+    assert.Equal(t, params, decoded, "should be equal")
+
+    proof, _ := ProveAggregated([]*big.Int{big.NewInt(18), big.NewInt(200)}, params)
+    ok, _ := proof.Verify(decoded)
+    assert.True(t, ok, "should verify with the decoded parameters")
+}
+
+func TestSetupParamsUnmarshalInvalid(t *testing.T) {
+    params, _ := SetupBits(32)
+    data, _ := params.MarshalBinary()
+    var decoded BulletProofSetupParams
+
+    assert.Error(t, decoded.UnmarshalBinary(data[:len(data)-1]), "truncated parameters should fail")
+
+    // a generator that is not on the curve
+    invalid := append([]byte(nil), data...)
+    invalid[len(invalid)-1] ^= 1
+    assert.Error(t, decoded.UnmarshalBinary(invalid), "a point off the curve should fail")
+
+    // generators that are on the curve but not derived from the seeds: a known multiple of G, and two swapped ones
+    substituted := params
+    substituted.Gg = append([]*p256.P256(nil), params.Gg...)
+    substituted.Gg[5] = new(p256.P256).ScalarBaseMult(big.NewInt(5))
+    data, _ = substituted.MarshalBinary()
+    assert.Error(t, decoded.UnmarshalBinary(data), "a generator with a known logarithm should fail")
+    substituted.Gg[5], substituted.Hh = params.Hh[5], append([]*p256.P256(nil), params.Hh...)
+    substituted.Hh[5] = params.Gg[5]
+    data, _ = substituted.MarshalBinary()
+    assert.Error(t, decoded.UnmarshalBinary(data), "swapped generators should fail")
+
+    // 3 values of 32 bits
+    aggregated, _ := SetupAggregated(32, 4)
+    aggregated.Gg, aggregated.Hh = aggregated.Gg[:96], aggregated.Hh[:96]
+    data, _ = aggregated.MarshalBinary()
+    assert.Error(t, decoded.UnmarshalBinary(data), "a number of values not a power of 2 should fail")
+}
+
+func TestVerifyWithOtherParams(t *testing.T) {
+    params, _ := SetupBits(32)
+    other, _ := SetupBits(64)
+    proof, _ := Prove(big.NewInt(18), params)
+    ok, _ := proof.Verify(other)
+    assert.False(t, ok, "a proof should not verify with other parameters")
+}
+
+func TestNewGenericAggregated(t *testing.T) {
+    bp, _ := SetupAggregated(32, 4)
+    params, err := NewGenericAggregated([]int64{0, 2001}, []int64{1000, 3200000010}, bp)
+    if err != nil {
+        t.Fatal(err)
+    }
+    proof, _ := ProveGenericAggregated([]*big.Int{big.NewInt(999), big.NewInt(2001)}, params)
+    ok, _ := proof.VerifyGeneric(params)
+    assert.True(t, ok, "should verify")
+
+    // an interval wider than 2^32, and more intervals than the parameters are set up for
+    _, err = NewGenericAggregated([]int64{0}, []int64{1 << 33}, bp)
+    assert.Error(t, err)
+    _, err = NewGenericAggregated([]int64{0, 0, 0}, []int64{1, 1, 1}, bp)
+    assert.Error(t, err)
+}
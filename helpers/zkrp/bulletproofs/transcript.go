@@ -0,0 +1,130 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+/*
+This file contains the Fiat-Shamir transcripts of the range proofs. Every proof
+absorbs, before its first challenge, its domain, the statement, the setup
+parameters and the commitments V, and then every message of the prover before
+the challenge that follows it. A proof is thus bound to the statement it was
+computed for: a proof of a generic range proof for [A, B) does not verify for
+another interval, nor for other parameters.
+*/
+
+package bulletproofs
+
+import (
+    "math/big"
+
+    "github.com/ing-bank/zkrp/crypto/p256"
+    . "github.com/ing-bank/zkrp/util"
+)
+
+const (
+    rangeProofDomain                  = "zkrp/bulletproofs/range-proof"
+    genericRangeProofDomain           = "zkrp/bulletproofs/generic-range-proof"
+    genericAggregatedRangeProofDomain = "zkrp/bulletproofs/generic-aggregated-range-proof"
+    innerProductDomain                = "zkrp/bulletproofs/inner-product"
+)
+
+/*
+appendRangeStatement absorbs the parameters of a proof of m values and the
+commitments V to the values.
+*/
+func appendRangeStatement(t *Transcript, params BulletProofSetupParams, V []*p256.P256) {
+    nm := params.N * int64(len(V))
+    t.AppendInt64("n", params.N)
+    t.AppendInt64("m", int64(len(V)))
+    t.AppendP256("H", params.H)
+    t.AppendP256s("Gg", params.Gg[:nm])
+    t.AppendP256s("Hh", params.Hh[:nm])
+    t.AppendP256s("V", V)
+}
+
+/*
+appendRangeResponse absorbs the last messages of the prover before the inner
+product argument.
+*/
+func appendRangeResponse(t *Transcript, taux, mu, tprime *big.Int, commit *p256.P256) {
+    t.AppendBigInt("taux", taux)
+    t.AppendBigInt("mu", mu)
+    t.AppendBigInt("t", tprime)
+    t.AppendP256("P", commit)
+}
+
+/*
+genericTranscript returns the transcript of one of the 2 BulletProofs, part 1
+or 2, of a generic range proof for [a, b) over [0, 2^n).
+*/
+func genericTranscript(a, b, n int64, part int64) *Transcript {
+    t := NewTranscript(genericRangeProofDomain)
+    t.AppendInt64("A", a)
+    t.AppendInt64("B", b)
+    t.AppendInt64("N", n)
+    t.AppendInt64("part", part)
+    return t
+}
+
+/*
+genericAggregatedTranscript returns the transcript of an aggregated generic
+range proof for the intervals [a[j], b[j]) over [0, 2^n).
+*/
+func genericAggregatedTranscript(a, b []int64, n int64) *Transcript {
+    t := NewTranscript(genericAggregatedRangeProofDomain)
+    t.AppendInt64("intervals", int64(len(a)))
+    for j := range a {
+        t.AppendInt64("A", a[j])
+        t.AppendInt64("B", b[j])
+    }
+    t.AppendInt64("N", n)
+    return t
+}
+
+/*
+innerProductTranscript returns the transcript of a standalone inner product
+proof of <a,b> = params.Cc for the commitment P = g^a.h^b.
+*/
+func innerProductTranscript(params InnerProductParams, P *p256.P256) *Transcript {
+    t := NewTranscript(innerProductDomain)
+    t.AppendInt64("n", params.N)
+    t.AppendP256s("Gg", params.Gg[:params.N])
+    t.AppendP256s("Hh", params.Hh[:params.N])
+    t.AppendP256("P", P)
+    t.AppendBigInt("c", params.Cc)
+    return t
+}
+
+/*
+genericOffset returns 2^n - b + a. The 2 BulletProofs of a generic range proof
+for [a, b) commit to x - b + 2^n and x - a with the same randomness, so that
+their commitments differ by g^genericOffset, which binds both to the same x.
+*/
+func genericOffset(a, b, n int64) *big.Int {
+    offset := new(big.Int).Lsh(big.NewInt(1), uint(n))
+    offset.Sub(offset, big.NewInt(b))
+    return offset.Add(offset, big.NewInt(a))
+}
+
+/*
+genericLinked returns true if and only if V1 = V2 . g^offset.
+*/
+func genericLinked(V1, V2 *p256.P256, offset *big.Int) bool {
+    if V1 == nil || V2 == nil || V1.X == nil || V2.X == nil {
+        return false
+    }
+    expected := new(p256.P256).Multiply(V2, new(p256.P256).ScalarBaseMult(offset))
+    return expected.X.Cmp(V1.X) == 0 && expected.Y.Cmp(V1.Y) == 0
+}
@@ -0,0 +1,94 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package bulletproofs
+
+import (
+    "math/big"
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+)
+
+func TestGenericProofOtherInterval(t *testing.T) {
+    params, _ := SetupGeneric(18, 200)
+    other, _ := SetupGeneric(19, 200)
+    proof, _ := ProveGeneric(big.NewInt(40), params)
+    ok, _ := proof.Verify(other)
+    assert.False(t, ok, "a proof for [18, 200) should not verify for [19, 200)")
+
+    // the same setup parameters, another lower bound
+    other.BP1, other.BP2 = params.BP1, params.BP2
+    other.A = 0
+    ok, _ = proof.Verify(other)
+    assert.False(t, ok, "a proof for [18, 200) should not verify for [0, 200)")
+}
+
+func TestGenericProofMixedHalves(t *testing.T) {
+    params, _ := SetupGeneric(18, 200)
+    proof1, _ := ProveGeneric(big.NewInt(40), params)
+    proof2, _ := ProveGeneric(big.NewInt(40), params)
+    proof1.P2 = proof2.P2
+    ok, _ := proof1.Verify(params)
+    assert.False(t, ok, "halves of different proofs should not verify together")
+
+    // a value below the interval: the first half is valid for 10, the second one for 40
+    low, _ := ProveGeneric(big.NewInt(10), params)
+    low.P2 = proof2.P2
+    ok, _ = low.Verify(params)
+    assert.False(t, ok, "halves committing to different values should not verify together")
+}
+
+func TestGenericAggregatedOtherIntervals(t *testing.T) {
+    params, _ := SetupGenericAggregated([]int64{0, 2001}, []int64{1000, 3200000010})
+    proof, _ := ProveGenericAggregated([]*big.Int{big.NewInt(999), big.NewInt(2001)}, params)
+    ok, _ := proof.VerifyGeneric(params)
+    assert.True(t, ok, "should verify")
+
+    other, _ := NewGenericAggregated([]int64{0, 2000}, []int64{1000, 3200000010}, params.BP)
+    ok, _ = proof.VerifyGeneric(other)
+    assert.False(t, ok, "a proof should not verify for other intervals")
+
+    ok, _ = proof.Verify(params.BP)
+    assert.False(t, ok, "a generic proof should not verify as a plain aggregated proof")
+}
+
+func TestRangeProofAsGenericHalf(t *testing.T) {
+    params, _ := SetupGeneric(18, 200)
+    proof, _ := ProveGeneric(big.NewInt(40), params)
+    ok, _ := proof.P1.Verify(params.BP1)
+    assert.False(t, ok, "a half of a generic proof should not verify as a plain range proof")
+
+    plain, _ := Prove(big.NewInt(40), params.BP1)
+    proof.P1 = plain
+    ok, _ = proof.Verify(params)
+    assert.False(t, ok, "a plain range proof should not verify as a half of a generic proof")
+}
+
+func TestInnerProductOtherStatement(t *testing.T) {
+    c := new(big.Int).SetInt64(142)
+    params, _ := setupInnerProduct(nil, nil, nil, c, 4)
+    a := []*big.Int{big.NewInt(2), big.NewInt(-1), big.NewInt(10), big.NewInt(6)}
+    b := []*big.Int{big.NewInt(1), big.NewInt(2), big.NewInt(10), big.NewInt(7)}
+    commit := commitInnerProduct(params.Gg, params.Hh, a, b)
+    proof, _ := proveInnerProduct(a, b, commit, params)
+    params.P = commit
+
+    params.Cc = new(big.Int).SetInt64(143)
+    ok, _ := proof.Verify(params)
+    assert.False(t, ok, "a proof for c = 142 should not verify for c = 143")
+}
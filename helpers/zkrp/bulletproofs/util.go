@@ -18,8 +18,6 @@
 package bulletproofs
 
 import (
-    "bytes"
-    "crypto/sha256"
     "errors"
     "math/big"
 
@@ -48,37 +46,6 @@ func powerOf(x *big.Int, n int64) []*big.Int {
     return result
 }
 
-/*
-Hash is responsible for the computing a Zp element given elements from GT and G1.
-*/
-func HashBP(A, S *p256.P256) (*big.Int, *big.Int, error) {
-
-    digest1 := sha256.New()
-    var buffer bytes.Buffer
-    buffer.WriteString(A.X.String())
-    buffer.WriteString(A.Y.String())
-    buffer.WriteString(S.X.String())
-    buffer.WriteString(S.Y.String())
-    digest1.Write(buffer.Bytes())
-    output1 := digest1.Sum(nil)
-    tmp1 := output1[0:]
-    result1 := new(big.Int).SetBytes(tmp1)
-
-    digest2 := sha256.New()
-    var buffer2 bytes.Buffer
-    buffer2.WriteString(A.X.String())
-    buffer2.WriteString(A.Y.String())
-    buffer2.WriteString(S.X.String())
-    buffer2.WriteString(S.Y.String())
-    buffer2.WriteString(result1.String())
-    digest2.Write(buffer.Bytes())
-    output2 := digest2.Sum(nil)
-    tmp2 := output2[0:]
-    result2 := new(big.Int).SetBytes(tmp2)
-
-    return result1, result2, nil
-}
-
 /*
 VectorExp computes Prod_i^n{a[i]^b[i]}.
 */
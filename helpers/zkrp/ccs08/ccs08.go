@@ -28,8 +28,8 @@ import (
     "bytes"
     "crypto/rand"
     "errors"
-    "math"
     "math/big"
+    "sort"
     "strconv"
 
     "github.com/ing-bank/zkrp/crypto/bbsignatures"
@@ -94,6 +94,58 @@ type proofUL struct {
     c, m, zr       *big.Int
 }
 
+const (
+    setDomain        = "zkrp/ccs08/set-membership"
+    ulDomain         = "zkrp/ccs08/range-proof-ul"
+    rangeProofDomain = "zkrp/ccs08/range-proof"
+)
+
+/*
+appendSetStatement absorbs the public key, H and the signatures of the elements
+of the set, sorted by element.
+*/
+func appendSetStatement(t *Transcript, p *paramsSet) {
+    elements := make([]int64, 0, len(p.signatures))
+    for element := range p.signatures {
+        elements = append(elements, element)
+    }
+    sort.Slice(elements, func(i, j int) bool { return elements[i] < elements[j] })
+    t.AppendG1("Pubk", p.kp.Pubk)
+    t.AppendG2("H", p.H)
+    t.AppendInt64("elements", int64(len(elements)))
+    for _, element := range elements {
+        t.AppendInt64("element", element)
+        t.AppendG2("signature", p.signatures[element])
+    }
+}
+
+/*
+appendULStatement absorbs u, l, the public key, H and the signatures of the
+digits 0, ..., u-1.
+*/
+func appendULStatement(t *Transcript, p *paramsUL) {
+    t.AppendInt64("u", p.u)
+    t.AppendInt64("l", p.l)
+    t.AppendG1("Pubk", p.kp.Pubk)
+    t.AppendG2("H", p.H)
+    for i := int64(0); i < p.u; i++ {
+        t.AppendG2("signature", p.signatures[strconv.FormatInt(i, 10)])
+    }
+}
+
+/*
+appendULCommitments absorbs the messages of the prover of a proof for [0,u^l).
+*/
+func appendULCommitments(t *Transcript, proof_out *proofUL) {
+    t.AppendG2("C", proof_out.C)
+    t.AppendInt64("digits", int64(len(proof_out.V)))
+    for i := range proof_out.V {
+        t.AppendG2("V", proof_out.V[i])
+        t.AppendGT("a", proof_out.a[i])
+    }
+    t.AppendG2("D", proof_out.D)
+}
+
 /*
 SetupSet generates the signature for the elements in the set.
 */
@@ -117,8 +169,7 @@ func SetupSet(s []int64) (paramsSet, error) {
 
 /*
 SetupUL generates the signature for the interval [0,u^l).
-The value of u should be roughly b/log(b), but we can choose smaller values in
-order to get smaller parameters, at the cost of having worse performance.
+See OptimalBase for the choice of u and l.
 */
 func SetupUL(u, l int64) (paramsUL, error) {
     var (
@@ -179,8 +230,13 @@ func ProveSet(x int64, r *big.Int, p paramsSet) (proofSet, error) {
     // so that it is possible to delegate the commitment computation to an external party.
     proof_out.C, _ = Commit(new(big.Int).SetInt64(x), r, p.H)
     // Fiat-Shamir heuristic
-    proof_out.c, _ = HashSet(proof_out.a, proof_out.D)
-    proof_out.c = bn.Mod(proof_out.c, bn256.Order)
+    t := NewTranscript(setDomain)
+    appendSetStatement(t, &p)
+    t.AppendG2("C", proof_out.C)
+    t.AppendG2("V", proof_out.V)
+    t.AppendGT("a", proof_out.a)
+    t.AppendG2("D", proof_out.D)
+    proof_out.c = t.Challenge("c", bn256.Order)
 
     proof_out.zr = bn.Sub(proof_out.m, bn.Multiply(r, proof_out.c))
     proof_out.zr = bn.Mod(proof_out.zr, bn256.Order)
@@ -195,11 +251,22 @@ func ProveSet(x int64, r *big.Int, p paramsSet) (proofSet, error) {
 ProveUL method is used to produce the ZKRP proof that secret x belongs to the interval [0,U^L].
 */
 func ProveUL(x, r *big.Int, p paramsUL) (proofUL, error) {
+    return proveUL(x, r, p, NewTranscript(ulDomain))
+}
+
+/*
+proveUL is ProveUL with the challenge derived from the transcript t.
+*/
+func proveUL(x, r *big.Int, p paramsUL, t *Transcript) (proofUL, error) {
     var (
         i         int64
         v         []*big.Int
         proof_out proofUL
     )
+    ul := new(big.Int).Exp(new(big.Int).SetInt64(p.u), new(big.Int).SetInt64(p.l), nil)
+    if x.Sign() < 0 || x.Cmp(ul) >= 0 {
+        return proof_out, errors.New("Could not generate proof. Element does not belong to the interval.")
+    }
     decx, _ := Decompose(x, p.u, p.l)
 
     // Initialize variables
@@ -243,8 +310,9 @@ func ProveUL(x, r *big.Int, p paramsUL) (proofUL, error) {
     // so that it is possible to delegate the commitment computation to an external party.
     proof_out.C, _ = Commit(x, r, p.H)
     // Fiat-Shamir heuristic
-    proof_out.c, _ = Hash(proof_out.a, proof_out.D)
-    proof_out.c = bn.Mod(proof_out.c, bn256.Order)
+    appendULStatement(t, &p)
+    appendULCommitments(t, &proof_out)
+    proof_out.c = t.Challenge("c", bn256.Order)
 
     proof_out.zr = bn.Sub(proof_out.m, bn.Multiply(r, proof_out.c))
     proof_out.zr = bn.Mod(proof_out.zr, bn256.Order)
@@ -266,6 +334,20 @@ func VerifySet(proof_out *proofSet, p *paramsSet) (bool, error) {
         r1, r2 bool
         p1, p2 *bn256.GT
     )
+    if proof_out.C == nil || proof_out.V == nil || proof_out.D == nil || proof_out.a == nil || proof_out.c == nil ||
+        proof_out.zr == nil || proof_out.zsig == nil || proof_out.zv == nil {
+        return false, errors.New("proof is missing elements")
+    }
+    t := NewTranscript(setDomain)
+    appendSetStatement(t, p)
+    t.AppendG2("C", proof_out.C)
+    t.AppendG2("V", proof_out.V)
+    t.AppendGT("a", proof_out.a)
+    t.AppendG2("D", proof_out.D)
+    if t.Challenge("c", bn256.Order).Cmp(proof_out.c) != 0 {
+        return false, nil
+    }
+
     // D == C^c.h^ zr.g^zsig ?
     D = new(bn256.G2).ScalarMult(proof_out.C, proof_out.c)
     D.Add(D, new(bn256.G2).ScalarMult(p.H, proof_out.zr))
@@ -296,12 +378,35 @@ func VerifySet(proof_out *proofSet, p *paramsSet) (bool, error) {
 VerifyUL is used to validate the ZKRP proof. It returns true iff the proof is valid.
 */
 func VerifyUL(proof_out *proofUL, p *paramsUL) (bool, error) {
+    return verifyUL(proof_out, p, NewTranscript(ulDomain))
+}
+
+/*
+verifyUL is VerifyUL with the challenge derived from the transcript t.
+*/
+func verifyUL(proof_out *proofUL, p *paramsUL, t *Transcript) (bool, error) {
     var (
         i      int64
         D      *bn256.G2
         r1, r2 bool
         p1, p2 *bn256.GT
     )
+    if proof_out.C == nil || proof_out.D == nil || proof_out.c == nil || proof_out.zr == nil ||
+        int64(len(proof_out.V)) != p.l || int64(len(proof_out.a)) != p.l ||
+        int64(len(proof_out.zsig)) != p.l || int64(len(proof_out.zv)) != p.l {
+        return false, errors.New("proof is missing elements")
+    }
+    for i = 0; i < p.l; i++ {
+        if proof_out.V[i] == nil || proof_out.a[i] == nil || proof_out.zsig[i] == nil || proof_out.zv[i] == nil {
+            return false, errors.New("proof is missing elements")
+        }
+    }
+    appendULStatement(t, p)
+    appendULCommitments(t, proof_out)
+    if t.Challenge("c", bn256.Order).Cmp(proof_out.c) != 0 {
+        return false, nil
+    }
+
     // D == C^c.h^ zr.g^zsig ?
     D = new(bn256.G2).ScalarMult(proof_out.C, proof_out.c)
     D.Add(D, new(bn256.G2).ScalarMult(p.H, proof_out.zr))
@@ -358,59 +463,75 @@ type ccs08 struct {
 }
 
 /*
-SetupInnerProduct receives integers a and b, and configures the parameters for the rangeproof scheme.
+Setup receives integers a and b, and configures the parameters for the rangeproof scheme,
+with the base u given by OptimalBase for the width of the interval.
 */
 func (zkrp *ccs08) Setup(a, b int64) error {
-    // Compute optimal values for u and l
-    var (
-        u, l int64
-        logb float64
-        p    *params
-    )
     if a > b {
         zkrp.p = nil
         return errors.New("a must be less than or equal to b")
     }
-    p = new(params)
-    logb = math.Log(float64(b))
-    if logb != 0 {
-        // u = b / int64(logb)
-        u = 57
-        if u != 0 {
-            l = 0
-            for i := b; i > 0; i = i / u {
-                l = l + 1
-            }
-            params_out, e := SetupUL(u, l)
-            p.p = &params_out
-            p.a = a
-            p.b = b
-            zkrp.p = p
-            return e
-        } else {
-            zkrp.p = nil
-            return errors.New("u is zero")
-        }
-    } else {
+    u, _ := OptimalBase(b - a)
+    return zkrp.SetupWithBase(a, b, u)
+}
+
+/*
+SetupWithBase is Setup with the base u of the digits of the secret given by the caller.
+*/
+func (zkrp *ccs08) SetupWithBase(a, b, u int64) error {
+    if a > b {
         zkrp.p = nil
-        return errors.New("log(b) is zero")
+        return errors.New("a must be less than or equal to b")
     }
+    if u < 2 {
+        zkrp.p = nil
+        return errors.New("u must be greater than 1")
+    }
+    params_out, e := SetupUL(u, digits(u, b-a))
+    if e != nil {
+        zkrp.p = nil
+        return e
+    }
+    return zkrp.SetupWithParams(a, b, params_out)
+}
+
+/*
+SetupWithParams configures the rangeproof scheme for [a, b) with the signatures of a setup
+ceremony, e.g., parameters published by the verifier. The number of digits l is the one
+of the interval, whatever the width the parameters were generated for, so that the prover
+and the verifier derive the same l.
+*/
+func (zkrp *ccs08) SetupWithParams(a, b int64, p paramsUL) error {
+    if a > b {
+        zkrp.p = nil
+        return errors.New("a must be less than or equal to b")
+    }
+    if p.u < 2 || int64(len(p.signatures)) < p.u || p.kp.Pubk == nil || p.H == nil {
+        zkrp.p = nil
+        return errors.New("invalid setup parameters")
+    }
+    p.l = digits(p.u, b-a)
+    zkrp.p = &params{p: &p, a: a, b: b}
+    return nil
 }
 
 /*
 Prove method is responsible for generating the zero knowledge proof.
 */
 func (zkrp *ccs08) Prove() error {
+    if zkrp.p == nil {
+        return errors.New("range proof is not set up")
+    }
     ul := new(big.Int).Exp(new(big.Int).SetInt64(zkrp.p.p.u), new(big.Int).SetInt64(zkrp.p.p.l), nil)
 
     // x - b + ul
     xb := new(big.Int).Sub(zkrp.x, new(big.Int).SetInt64(zkrp.p.b))
     xb.Add(xb, ul)
-    first, _ := ProveUL(xb, zkrp.r, *zkrp.p.p)
+    first, _ := proveUL(xb, zkrp.r, *zkrp.p.p, zkrp.p.transcript(1))
 
     // x - a
     xa := new(big.Int).Sub(zkrp.x, new(big.Int).SetInt64(zkrp.p.a))
-    second, _ := ProveUL(xa, zkrp.r, *zkrp.p.p)
+    second, _ := proveUL(xa, zkrp.r, *zkrp.p.p, zkrp.p.transcript(2))
 
     zkrp.proof_out.p1 = first
     zkrp.proof_out.p2 = second
@@ -421,7 +542,34 @@ func (zkrp *ccs08) Prove() error {
 Verify is responsible for validating the proof.
 */
 func (zkrp *ccs08) Verify() (bool, error) {
-    first, _ := VerifyUL(&zkrp.proof_out.p1, zkrp.p.p)
-    second, _ := VerifyUL(&zkrp.proof_out.p2, zkrp.p.p)
+    if zkrp.p == nil {
+        return false, errors.New("range proof is not set up")
+    }
+    // Both parts commit with the same r, so that C1 = C2.g^(u^l - b + a) binds them to the same x.
+    p1, p2 := &zkrp.proof_out.p1, &zkrp.proof_out.p2
+    if p1.C == nil || p2.C == nil {
+        return false, errors.New("proof is missing elements")
+    }
+    ul := new(big.Int).Exp(new(big.Int).SetInt64(zkrp.p.p.u), new(big.Int).SetInt64(zkrp.p.p.l), nil)
+    offset := new(big.Int).Sub(ul, new(big.Int).SetInt64(zkrp.p.b))
+    offset = bn.Mod(offset.Add(offset, new(big.Int).SetInt64(zkrp.p.a)), bn256.Order)
+    C1 := new(bn256.G2).Add(p2.C, new(bn256.G2).ScalarBaseMult(offset))
+    if !bytes.Equal(C1.Marshal(), p1.C.Marshal()) {
+        return false, nil
+    }
+    first, _ := verifyUL(p1, zkrp.p.p, zkrp.p.transcript(1))
+    second, _ := verifyUL(p2, zkrp.p.p, zkrp.p.transcript(2))
     return first && second, nil
 }
+
+/*
+transcript returns the transcript of one of the 2 proofs, part 1 or 2, of a
+range proof for [a, b].
+*/
+func (p *params) transcript(part int64) *Transcript {
+    t := NewTranscript(rangeProofDomain)
+    t.AppendInt64("a", p.a)
+    t.AppendInt64("b", p.b)
+    t.AppendInt64("part", part)
+    return t
+}
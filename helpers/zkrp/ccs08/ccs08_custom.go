@@ -1,24 +1,78 @@
 package ccs08
 
 import (
+	"crypto/rand"
+	"errors"
 	"fmt"
 	"math/big"
-	"crypto/rand"
 
 	"github.com/ing-bank/zkrp/crypto/bn256"
-
 )
 
 type CCS08Custom struct {
 	proof ccs08
 }
 
+// CCS08Params is the output of the setup ceremony of the range proofs: the signatures of the digits 0, ..., u-1 under
+// the key of the verifier. The verifier runs NewCCS08Params once, keeps the private key and publishes MarshalBinary;
+// provers load it with UnmarshalBinary.
+type CCS08Params struct {
+	p paramsUL
+}
+
+// NewCCS08Params runs the setup ceremony for intervals of up to width values. If u is 0, the base is the one of
+// OptimalBase.
+func NewCCS08Params(width, u int64) (*CCS08Params, error) {
+	l := int64(0)
+	if u == 0 {
+		u, l = OptimalBase(width)
+	} else if u < 2 {
+		return nil, errors.New("u must be greater than 1")
+	} else {
+		l = digits(u, width)
+	}
+	p, err := SetupUL(u, l)
+	if err != nil {
+		return nil, err
+	}
+	return &CCS08Params{p: p}, nil
+}
+
+// Base returns u.
+func (params *CCS08Params) Base() int64 {
+	return params.p.u
+}
+
+// MarshalBinary encodes the public part of the parameters.
+func (params *CCS08Params) MarshalBinary() ([]byte, error) {
+	return params.p.MarshalBinary()
+}
+
+// UnmarshalBinary decodes parameters encoded by MarshalBinary and checks their signatures.
+func (params *CCS08Params) UnmarshalBinary(data []byte) error {
+	return params.p.UnmarshalBinary(data)
+}
+
 func (custom *CCS08Custom) Setup(a, b int64) {
 
 	custom.proof.Setup(a, b)
 
 }
 
+func (custom *CCS08Custom) SetupWithBase(a, b, u int64) {
+
+	custom.proof.SetupWithBase(a, b, u)
+
+}
+
+// SetupWithParams sets up the proof of x in [a, b) with the published parameters of a setup ceremony. The prover and
+// the verifier must use the same parameters.
+func (custom *CCS08Custom) SetupWithParams(a, b int64, params *CCS08Params) error {
+
+	return custom.proof.SetupWithParams(a, b, params.p)
+
+}
+
 func (custom *CCS08Custom) Prove(secret *big.Int) {
 
 	custom.proof.x = secret
@@ -40,3 +94,37 @@ func (custom *CCS08Custom) Verify() bool {
 
 }
 
+// MarshalBinary exports the proof, without the parameters: the verifier imports it into a CCS08Custom set up with its
+// own parameters and interval.
+func (custom *CCS08Custom) MarshalBinary() ([]byte, error) {
+
+	first, err := custom.proof.proof_out.p1.marshal()
+	if err != nil {
+		return nil, err
+	}
+	second, err := custom.proof.proof_out.p2.marshal()
+	if err != nil {
+		return nil, err
+	}
+	return append(first, second...), nil
+
+}
+
+// UnmarshalBinary imports a proof exported by MarshalBinary.
+func (custom *CCS08Custom) UnmarshalBinary(data []byte) error {
+
+	first, data, err := unmarshalProofUL(data)
+	if err != nil {
+		return err
+	}
+	second, data, err := unmarshalProofUL(data)
+	if err != nil {
+		return err
+	}
+	if len(data) != 0 {
+		return errors.New("trailing data after proof")
+	}
+	custom.proof.proof_out = proof{p1: first, p2: second}
+	return nil
+
+}
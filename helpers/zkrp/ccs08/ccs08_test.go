@@ -23,6 +23,7 @@ import (
     "testing"
 
     "github.com/ing-bank/zkrp/crypto/bn256"
+    "github.com/ing-bank/zkrp/crypto/p256"
     . "github.com/ing-bank/zkrp/util"
     "github.com/ing-bank/zkrp/util/bn"
     "github.com/ing-bank/zkrp/util/intconversion"
@@ -163,3 +164,309 @@ func TestZKRP(t *testing.T) {
         t.Errorf("Assert failure: expected true, actual: %t", result)
     }
 }
+
+/*
+Tests the ZK Range Proof (CCS08) protocol with a base other than the default one.
+*/
+func TestZKRPWithBase(t *testing.T) {
+    var (
+        result bool
+        zkrp   ccs08
+    )
+    e := zkrp.SetupWithBase(347184000, 599644800, 32)
+    if e != nil {
+        t.Errorf("Error while setting up ZKRP: %s", e.Error())
+    }
+    zkrp.x = new(big.Int).SetInt64(419835123)
+    zkrp.r, _ = rand.Int(rand.Reader, bn256.Order)
+    e = zkrp.Prove()
+    if e != nil {
+        t.Errorf("Error while proving ZKRP: %s", e.Error())
+    }
+    result, _ = zkrp.Verify()
+    if result != true {
+        t.Errorf("Assert failure: expected true, actual: %t", result)
+    }
+    e = zkrp.SetupWithBase(0, 599644800, 1)
+    if e == nil {
+        t.Errorf("Assert failure: expected an error for base 1")
+    }
+}
+
+/*
+Tests that a ZK Range Proof (CCS08) does not verify for another interval, nor
+with the parts of another proof.
+*/
+func TestZKRPOtherStatement(t *testing.T) {
+    var (
+        result bool
+        zkrp   ccs08
+    )
+    zkrp.SetupWithBase(347184000, 599644800, 32)
+    zkrp.x = new(big.Int).SetInt64(419835123)
+    zkrp.r, _ = rand.Int(rand.Reader, bn256.Order)
+    zkrp.Prove()
+    p := *zkrp.p
+
+    zkrp.p = &params{p: p.p, a: p.a + 1, b: p.b}
+    result, _ = zkrp.Verify()
+    if result != false {
+        t.Errorf("Assert failure: a proof should not verify for another lower bound")
+    }
+    zkrp.p = &params{p: p.p, a: p.a, b: p.b + 1}
+    result, _ = zkrp.Verify()
+    if result != false {
+        t.Errorf("Assert failure: a proof should not verify for another upper bound")
+    }
+    zkrp.p = &p
+
+    // the first part proven for another secret
+    first := zkrp.proof_out
+    zkrp.x = new(big.Int).SetInt64(419835124)
+    zkrp.r, _ = rand.Int(rand.Reader, bn256.Order)
+    zkrp.Prove()
+    zkrp.proof_out.p1 = first.p1
+    result, _ = zkrp.Verify()
+    if result != false {
+        t.Errorf("Assert failure: parts of different proofs should not verify together")
+    }
+
+    // the parts swapped
+    zkrp.proof_out.p1, zkrp.proof_out.p2 = first.p2, first.p1
+    result, _ = zkrp.Verify()
+    if result != false {
+        t.Errorf("Assert failure: swapped parts should not verify")
+    }
+}
+
+/*
+Tests that a ZK Set Membership proof does not verify for another set.
+*/
+func TestZKSetOtherSet(t *testing.T) {
+    p, _ := SetupSet([]int64{12, 42, 61, 71})
+    other := p
+    other.signatures = map[int64]*bn256.G2{12: p.signatures[12], 42: p.signatures[42]}
+    r, _ := rand.Int(rand.Reader, bn256.Order)
+    proof_out, _ := ProveSet(12, r, p)
+    result, _ := VerifySet(&proof_out, &other)
+    if result != false {
+        t.Errorf("Assert failure: a proof should not verify for another set")
+    }
+}
+
+/*
+Tests that OptimalBase covers the width with the fewest signatures plus digits.
+*/
+func TestOptimalBase(t *testing.T) {
+    for _, c := range []struct{ width, u, l int64 }{{1, 2, 1}, {10, 4, 2}, {1000, 4, 5}, {3200000010, 9, 10}} {
+        u, l := OptimalBase(c.width)
+        if u != c.u || l != c.l {
+            t.Errorf("Assert failure: width %d: expected (%d, %d), actual: (%d, %d)", c.width, c.u, c.l, u, l)
+        }
+    }
+    for u := int64(2); u < 20; u++ {
+        l := digits(u, 3200000010)
+        if new(big.Int).Exp(big.NewInt(u), big.NewInt(l), nil).Cmp(big.NewInt(3200000010)) < 0 || u+l < 19 {
+            t.Errorf("Assert failure: base %d with %d digits", u, l)
+        }
+    }
+}
+
+/*
+Tests that the verifier's parameters can be published and loaded, without the private key.
+*/
+func TestParamsMarshalUnmarshal(t *testing.T) {
+    params, _ := NewCCS08Params(1000, 0)
+    data, err := params.MarshalBinary()
+    if err != nil {
+        t.Fatal(err)
+    }
+    var loaded CCS08Params
+    if err := loaded.UnmarshalBinary(data); err != nil {
+        t.Fatal(err)
+    }
+    if loaded.p.kp.Privk != nil || loaded.Base() != params.Base() {
+        t.Errorf("Assert failure: loaded parameters differ")
+    }
+
+    // a signature that is not the one of its digit
+    tampered := append([]byte{}, data...)
+    copy(tampered[len(data)-g2Size:], data[len(data)-2*g2Size:len(data)-g2Size])
+    if loaded.UnmarshalBinary(tampered) == nil {
+        t.Errorf("Assert failure: an invalid signature should fail")
+    }
+    if loaded.UnmarshalBinary(data[:len(data)-1]) == nil {
+        t.Errorf("Assert failure: truncated parameters should fail")
+    }
+}
+
+/*
+Tests that a proof computed with the published parameters verifies once exported and
+imported by the verifier, and only for its interval.
+*/
+func TestCCS08CustomExportImport(t *testing.T) {
+    verifierParams, _ := NewCCS08Params(3200000010, 0)
+    published, _ := verifierParams.MarshalBinary()
+    var proverParams CCS08Params
+    if err := proverParams.UnmarshalBinary(published); err != nil {
+        t.Fatal(err)
+    }
+
+    var prover CCS08Custom
+    prover.SetupWithParams(1001, 3200000010, &proverParams)
+    prover.Prove(big.NewInt(419835123))
+    data, err := prover.MarshalBinary()
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    var verifier CCS08Custom
+    verifier.SetupWithParams(1001, 3200000010, verifierParams)
+    if err := verifier.UnmarshalBinary(data); err != nil {
+        t.Fatal(err)
+    }
+    if !verifier.Verify() {
+        t.Errorf("Assert failure: an imported proof should verify")
+    }
+    verifier.SetupWithParams(1000, 3200000010, verifierParams)
+    if verifier.Verify() {
+        t.Errorf("Assert failure: an imported proof should not verify for another interval")
+    }
+    if verifier.UnmarshalBinary(data[:len(data)-1]) == nil {
+        t.Errorf("Assert failure: a truncated proof should fail")
+    }
+}
+
+/*
+Tests the membership proofs of positions committed over secp256k1.
+*/
+func TestMembershipProof(t *testing.T) {
+    digits, _ := NewCCS08Params(1000, 0)
+    params, _ := NewCCS08SetParams([]int64{12, 42, 61, 71}, digits)
+    published, _ := params.MarshalBinary()
+    var loaded CCS08SetParams
+    if err := loaded.UnmarshalBinary(published); err != nil {
+        t.Fatal(err)
+    }
+    h, _ := p256.MapToGroup("Testing membership proofs:")
+    r, _ := rand.Int(rand.Reader, p256.CURVE.N)
+    C, _ := CommitG1(big.NewInt(42), r, h)
+
+    proof, err := ProveMembership(big.NewInt(42), r, h, &loaded)
+    if err != nil {
+        t.Fatal(err)
+    }
+    data, _ := proof.MarshalBinary()
+    var imported MembershipProof
+    if err := imported.UnmarshalBinary(data); err != nil {
+        t.Fatal(err)
+    }
+    if !imported.Verify(C, h, params) {
+        t.Errorf("Assert failure: a member should verify")
+    }
+    other, _ := CommitG1(big.NewInt(61), r, h)
+    if imported.Verify(other, h, params) {
+        t.Errorf("Assert failure: a proof should not verify for another commitment")
+    }
+    if _, err := ProveMembership(big.NewInt(43), r, h, &loaded); err == nil {
+        t.Errorf("Assert failure: a non-member should not be proven")
+    }
+}
+
+/*
+Tests the gap proofs of positions committed over secp256k1.
+*/
+func TestGapProof(t *testing.T) {
+    digits, _ := NewCCS08Params(1000, 0)
+    params, _ := NewCCS08SetParams([]int64{12, 42, 43, 71}, digits)
+    h, _ := p256.MapToGroup("Testing gap proofs:")
+    r, _ := rand.Int(rand.Reader, p256.CURVE.N)
+    C, _ := CommitG1(big.NewInt(50), r, h)
+
+    proof, err := ProveGap(big.NewInt(50), r, h, params)
+    if err != nil {
+        t.Fatal(err)
+    }
+    data, _ := proof.MarshalBinary()
+    var imported GapProof
+    if err := imported.UnmarshalBinary(data); err != nil {
+        t.Fatal(err)
+    }
+    if !imported.Verify(C, h, params) {
+        t.Errorf("Assert failure: a position in a gap should verify")
+    }
+    member, _ := CommitG1(big.NewInt(42), r, h)
+    if imported.Verify(member, h, params) {
+        t.Errorf("Assert failure: a proof should not verify for another commitment")
+    }
+    for _, x := range []int64{42, 11, 72} {
+        if _, err := ProveGap(big.NewInt(x), r, h, params); err == nil {
+            t.Errorf("Assert failure: %d should not be proven in a gap", x)
+        }
+    }
+    // a gap proof computed for a value outside its gap
+    rG2, _ := rand.Int(rand.Reader, bn256.Order)
+    if _, err := proveGap(big.NewInt(71), rG2, 43, 71, params.gaps, params.digits); err == nil {
+        t.Errorf("Assert failure: an element should not be proven in the gap below it")
+    }
+    // bounds that would make the gap codes ambiguous
+    if _, err := proveGap(big.NewInt(50), rG2, 42-1, 43+1<<32, params.gaps, params.digits); err == nil {
+        t.Errorf("Assert failure: a gap with an upper bound of more than 32 bits should be refused")
+    }
+}
+
+/*
+Tests that the setup refuses the parameters for which the gap codes are not
+injective.
+*/
+func TestSetParamsBounds(t *testing.T) {
+    if _, err := NewCCS08SetParams([]int64{12, 1 << 32}, &CCS08Params{}); err == nil {
+        t.Errorf("Assert failure: an element of more than 32 bits should be refused")
+    }
+    wide, _ := NewCCS08Params(1<<32+1, 0)
+    if _, err := NewCCS08SetParams([]int64{12, 42}, wide); err == nil {
+        t.Errorf("Assert failure: digits over more than 2^32 values should be refused")
+    }
+    // the genome size of the labs
+    digits, _ := NewCCS08Params(3300000000, 0)
+    params, err := NewCCS08SetParams([]int64{12, 42}, digits)
+    if err != nil {
+        t.Fatal(err)
+    }
+    published, _ := params.MarshalBinary()
+    var loaded CCS08SetParams
+    if err := loaded.UnmarshalBinary(published); err != nil {
+        t.Errorf("Assert failure: the parameters should load, got %v", err)
+    }
+}
+
+/*
+Tests the interval proofs of positions committed over secp256k1.
+*/
+func TestIntervalProof(t *testing.T) {
+    digits, _ := NewCCS08Params(1000, 0)
+    params, _ := NewCCS08SetParams([]int64{12, 42}, digits)
+    h, _ := p256.MapToGroup("Testing interval proofs:")
+    r, _ := rand.Int(rand.Reader, p256.CURVE.N)
+    for _, c := range []struct{ x, a, b int64 }{{0, 0, 12}, {43, 43, 1000}, {999, 43, 1000}} {
+        C, _ := CommitG1(big.NewInt(c.x), r, h)
+        proof, err := ProveInterval(big.NewInt(c.x), r, h, c.a, c.b, params)
+        if err != nil {
+            t.Fatal(err)
+        }
+        data, _ := proof.MarshalBinary()
+        var imported IntervalProof
+        if err := imported.UnmarshalBinary(data); err != nil {
+            t.Fatal(err)
+        }
+        if !imported.Verify(C, h, c.a, c.b, params) {
+            t.Errorf("Assert failure: %d should verify in [%d, %d)", c.x, c.a, c.b)
+        }
+        if imported.Verify(C, h, c.a+1, c.b, params) {
+            t.Errorf("Assert failure: a proof should not verify for another interval")
+        }
+    }
+    if _, err := ProveInterval(big.NewInt(12), r, h, 0, 12, params); err == nil {
+        t.Errorf("Assert failure: 12 should not be proven in [0, 12)")
+    }
+}
@@ -0,0 +1,442 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+/*
+This file contains the proofs that a position committed by a Pedersen commitment
+over secp256k1, i.e., the commitments of the bulletproofs package, belongs to a
+set signed by the verifier (membership), or lies strictly between two
+consecutive elements of the set (gap), without revealing which ones.
+
+Both are CCS08 proofs over commitments in G2, linked to the secp256k1 commitment
+by a proof of equality of the committed values across the two groups. Since the
+groups have different orders, the link proves the equality of integers: it is
+sound for values of at most linkValueBits bits, which the membership or the
+range proofs of the G2 commitment guarantee.
+*/
+
+package ccs08
+
+import (
+    "bytes"
+    "encoding/binary"
+    "crypto/rand"
+    "errors"
+    "math/big"
+    "sort"
+
+    "github.com/ing-bank/zkrp/crypto/bbsignatures"
+    "github.com/ing-bank/zkrp/crypto/bn256"
+    "github.com/ing-bank/zkrp/crypto/p256"
+    . "github.com/ing-bank/zkrp/util"
+    "github.com/ing-bank/zkrp/util/bn"
+)
+
+const (
+    linkDomain = "zkrp/ccs08/p256-link"
+    gapDomain  = "zkrp/ccs08/gap"
+
+    // the link hides values of linkValueBits bits with linkSlackBits of statistical slack
+    linkValueBits     = 32
+    linkChallengeBits = 128
+    linkSlackBits     = 80
+
+    p256Size = 64
+)
+
+/*
+proofLink proves that C1 = g^x.h1^r1 over secp256k1 and C2 = g2^x.H^r2 in G2
+commit to the same integer x.
+*/
+type proofLink struct {
+    T1        *p256.P256
+    T2        *bn256.G2
+    z, z1, z2 *big.Int
+}
+
+/*
+proofGap proves that the value x committed in G2 lies in [L+1, U-1] for a gap
+(L, U) signed by the verifier: e = L.2^32 + U is in the set of the gaps, with
+C(e) = CL^(2^32).CU, and U, x-L-1 and U-x-1 are in [0, u^l).
+*/
+type proofGap struct {
+    CL, CU             *bn256.G2
+    e                  proofSet
+    upper, left, right proofUL
+}
+
+/*
+linkChallenge returns the challenge of the link, of linkChallengeBits bits.
+*/
+func linkChallenge(h1 *p256.P256, H *bn256.G2, C1 *p256.P256, C2 *bn256.G2, T1 *p256.P256, T2 *bn256.G2) *big.Int {
+    t := NewTranscript(linkDomain)
+    t.AppendP256("h", h1)
+    t.AppendG2("H", H)
+    t.AppendP256("C1", C1)
+    t.AppendG2("C2", C2)
+    t.AppendP256("T1", T1)
+    t.AppendG2("T2", T2)
+    return t.Challenge("c", new(big.Int).Lsh(big.NewInt(1), linkChallengeBits))
+}
+
+/*
+proveLink computes the proof that C1 = g^x.h1^r1 and C2 = g2^x.H^r2 commit to
+the same x, for 0 <= x < 2^linkValueBits. The response z = k + c.x is computed
+over the integers, with k of linkSlackBits bits more than c.x, so that it hides
+x statistically and is less than the order of both groups.
+*/
+func proveLink(x, r1 *big.Int, h1 *p256.P256, r2 *big.Int, H *bn256.G2) (proofLink, error) {
+    var proof_out proofLink
+    if x.Sign() < 0 || x.BitLen() > linkValueBits {
+        return proof_out, errors.New("value out of the range of the link")
+    }
+    k, _ := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), linkValueBits+linkChallengeBits+linkSlackBits))
+    k1, _ := rand.Int(rand.Reader, p256.CURVE.N)
+    k2, _ := rand.Int(rand.Reader, bn256.Order)
+    C1, _ := CommitG1(x, r1, h1)
+    C2, _ := Commit(x, r2, H)
+    proof_out.T1, _ = CommitG1(k, k1, h1)
+    proof_out.T2, _ = Commit(k, k2, H)
+    c := linkChallenge(h1, H, C1, C2, proof_out.T1, proof_out.T2)
+    proof_out.z = new(big.Int).Add(k, new(big.Int).Mul(c, x))
+    proof_out.z1 = bn.Mod(bn.Add(k1, bn.Multiply(c, r1)), p256.CURVE.N)
+    proof_out.z2 = bn.Mod(bn.Add(k2, bn.Multiply(c, r2)), bn256.Order)
+    return proof_out, nil
+}
+
+/*
+verify returns true if and only if the proof links C1 and C2.
+*/
+func (proof_out *proofLink) verify(h1 *p256.P256, H *bn256.G2, C1 *p256.P256, C2 *bn256.G2) bool {
+    if proof_out.T1 == nil || proof_out.T2 == nil || proof_out.z == nil || proof_out.z1 == nil || proof_out.z2 == nil ||
+        C1 == nil || C2 == nil {
+        return false
+    }
+    if proof_out.z.Sign() < 0 || proof_out.z.BitLen() > linkValueBits+linkChallengeBits+linkSlackBits+1 ||
+        proof_out.z1.Cmp(p256.CURVE.N) >= 0 || proof_out.z2.Cmp(bn256.Order) >= 0 {
+        return false
+    }
+    c := linkChallenge(h1, H, C1, C2, proof_out.T1, proof_out.T2)
+
+    // g^z.h1^z1 == T1.C1^c
+    lhs1 := new(p256.P256).Multiply(new(p256.P256).ScalarBaseMult(proof_out.z), new(p256.P256).ScalarMult(h1, proof_out.z1))
+    rhs1 := new(p256.P256).Multiply(proof_out.T1, new(p256.P256).ScalarMult(C1, c))
+    if lhs1.IsZero() != rhs1.IsZero() || (!lhs1.IsZero() && (lhs1.X.Cmp(rhs1.X) != 0 || lhs1.Y.Cmp(rhs1.Y) != 0)) {
+        return false
+    }
+
+    // g2^z.H^z2 == T2.C2^c
+    lhs2, _ := Commit(proof_out.z, proof_out.z2, H)
+    rhs2 := new(bn256.G2).Add(proof_out.T2, new(bn256.G2).ScalarMult(C2, c))
+    return bytes.Equal(lhs2.Marshal(), rhs2.Marshal())
+}
+
+/*
+gapCode encodes the gap (L, U) as an element of the signed set of the gaps. It
+is injective for bounds of at most linkValueBits bits, which the setup checks
+(see CCS08SetParams.checkBounds).
+*/
+func gapCode(L, U int64) int64 {
+    return L<<32 | U
+}
+
+/*
+gapTranscript returns the transcript of one of the range proofs of a gap proof.
+*/
+func gapTranscript(part int64) *Transcript {
+    t := NewTranscript(gapDomain)
+    t.AppendInt64("part", part)
+    return t
+}
+
+/*
+proveGap computes the proof that x, committed as g2^x.H^r, lies in the gap
+(L, U), with the signatures of the gaps and the parameters of the digits.
+*/
+func proveGap(x, r *big.Int, L, U int64, gaps paramsSet, digits paramsUL) (proofGap, error) {
+    var proof_out proofGap
+    if L < 0 || U <= L || U >= 1<<linkValueBits {
+        return proof_out, errors.New("gap out of the range of positions")
+    }
+    rL, _ := rand.Int(rand.Reader, bn256.Order)
+    rU, _ := rand.Int(rand.Reader, bn256.Order)
+    proof_out.CL, _ = Commit(big.NewInt(L), rL, gaps.H)
+    proof_out.CU, _ = Commit(big.NewInt(U), rU, gaps.H)
+
+    re := bn.Mod(bn.Add(bn.Multiply(rL, new(big.Int).Lsh(big.NewInt(1), 32)), rU), bn256.Order)
+    var err error
+    if proof_out.e, err = ProveSet(gapCode(L, U), re, gaps); err != nil {
+        return proof_out, err
+    }
+    if proof_out.upper, err = proveUL(big.NewInt(U), rU, digits, gapTranscript(1)); err != nil {
+        return proof_out, err
+    }
+    left := new(big.Int).Sub(x, big.NewInt(L+1))
+    if proof_out.left, err = proveUL(left, bn.Mod(bn.Sub(r, rL), bn256.Order), digits, gapTranscript(2)); err != nil {
+        return proof_out, err
+    }
+    right := new(big.Int).Sub(big.NewInt(U-1), x)
+    if proof_out.right, err = proveUL(right, bn.Mod(bn.Sub(rU, r), bn256.Order), digits, gapTranscript(3)); err != nil {
+        return proof_out, err
+    }
+    return proof_out, nil
+}
+
+/*
+verify returns true if and only if the proof shows that C commits to a value in
+one of the gaps.
+*/
+func (proof_out *proofGap) verify(C *bn256.G2, gaps *paramsSet, digits *paramsUL) bool {
+    if C == nil || proof_out.CL == nil || proof_out.CU == nil || proof_out.e.C == nil || proof_out.upper.C == nil ||
+        proof_out.left.C == nil || proof_out.right.C == nil {
+        return false
+    }
+    g2 := new(bn256.G2).ScalarBaseMult(big.NewInt(1))
+    equal := func(a, b *bn256.G2) bool { return bytes.Equal(a.Marshal(), b.Marshal()) }
+
+    // C(e) = CL^(2^32).CU, C(U) = CU, C(x-L-1) = C.CL^-1.g2^-1 and C(U-x-1) = CU.C^-1.g2^-1
+    Ce := new(bn256.G2).Add(new(bn256.G2).ScalarMult(proof_out.CL, new(big.Int).Lsh(big.NewInt(1), 32)), proof_out.CU)
+    Cleft := new(bn256.G2).Add(C, new(bn256.G2).Neg(new(bn256.G2).Add(proof_out.CL, g2)))
+    Cright := new(bn256.G2).Add(proof_out.CU, new(bn256.G2).Neg(new(bn256.G2).Add(C, g2)))
+    if !equal(Ce, proof_out.e.C) || !equal(proof_out.CU, proof_out.upper.C) ||
+        !equal(Cleft, proof_out.left.C) || !equal(Cright, proof_out.right.C) {
+        return false
+    }
+    if ok, _ := VerifySet(&proof_out.e, gaps); !ok {
+        return false
+    }
+    for part, proof := range []*proofUL{&proof_out.upper, &proof_out.left, &proof_out.right} {
+        if ok, _ := verifyUL(proof, digits, gapTranscript(int64(part+1))); !ok {
+            return false
+        }
+    }
+    return true
+}
+
+/*
+MarshalBinary encodes the public part of the parameters: the public key of the
+verifier, H, and the elements of the set with their signatures, sorted.
+*/
+func (p paramsSet) MarshalBinary() ([]byte, error) {
+    if p.kp.Pubk == nil || p.H == nil {
+        return nil, errors.New("invalid setup parameters")
+    }
+    elements := make([]int64, 0, len(p.signatures))
+    for element := range p.signatures {
+        elements = append(elements, element)
+    }
+    sort.Slice(elements, func(i, j int) bool { return elements[i] < elements[j] })
+    data := make([]byte, 8, 8+g1Size+g2Size+len(elements)*(8+g2Size))
+    binary.BigEndian.PutUint64(data, uint64(len(elements)))
+    data = append(data, p.kp.Pubk.Marshal()...)
+    data = append(data, p.H.Marshal()...)
+    for _, element := range elements {
+        var buf [8]byte
+        binary.BigEndian.PutUint64(buf[:], uint64(element))
+        data = append(data, buf[:]...)
+        data = append(data, p.signatures[element].Marshal()...)
+    }
+    return data, nil
+}
+
+/*
+UnmarshalBinary decodes parameters encoded by MarshalBinary, and checks every
+signature against the public key.
+*/
+func (p *paramsSet) UnmarshalBinary(data []byte) error {
+    if len(data) < 8+g1Size+g2Size {
+        return errors.New("setup parameters too short")
+    }
+    count := binary.BigEndian.Uint64(data)
+    if count > uint64(len(data))/(8+g2Size) || uint64(len(data)) != 8+g1Size+g2Size+count*(8+g2Size) {
+        return errors.New("invalid length of setup parameters")
+    }
+    data = data[8:]
+    pubk, ok := new(bn256.G1).Unmarshal(data[:g1Size])
+    if !ok {
+        return errors.New("invalid public key")
+    }
+    H, ok := new(bn256.G2).Unmarshal(data[g1Size : g1Size+g2Size])
+    if !ok {
+        return errors.New("invalid H")
+    }
+    data = data[g1Size+g2Size:]
+    signatures := make(map[int64]*bn256.G2, count)
+    for i := uint64(0); i < count; i++ {
+        element := int64(binary.BigEndian.Uint64(data))
+        signature, ok := new(bn256.G2).Unmarshal(data[8 : 8+g2Size])
+        if !ok {
+            return errors.New("invalid signature")
+        }
+        if valid, _ := bbsignatures.Verify(signature, big.NewInt(element), pubk); !valid {
+            return errors.New("invalid signature")
+        }
+        signatures[element] = signature
+        data = data[8+g2Size:]
+    }
+    p.kp = bbsignatures.Keypair{Pubk: pubk}
+    p.H = H
+    p.signatures = signatures
+    return nil
+}
+
+/*
+marshal encodes the elements of a set membership proof that the verifier needs:
+C, D, V, a, c, zr, zsig and zv.
+*/
+func (proof_out *proofSet) marshal() ([]byte, error) {
+    if proof_out.C == nil || proof_out.D == nil || proof_out.V == nil || proof_out.a == nil || proof_out.c == nil ||
+        proof_out.zr == nil || proof_out.zsig == nil || proof_out.zv == nil {
+        return nil, errors.New("proof is missing elements")
+    }
+    data := make([]byte, 0, 3*g2Size+gtSize+4*scalarSize)
+    data = append(data, proof_out.C.Marshal()...)
+    data = append(data, proof_out.D.Marshal()...)
+    data = append(data, proof_out.V.Marshal()...)
+    data = append(data, proof_out.a.Marshal()...)
+    for _, n := range []*big.Int{proof_out.c, proof_out.zr, proof_out.zsig, proof_out.zv} {
+        data = appendScalar(data, n)
+    }
+    return data, nil
+}
+
+/*
+unmarshalProofSet decodes a proof encoded by marshal at the start of data, and
+returns the rest of data.
+*/
+func unmarshalProofSet(data []byte) (proofSet, []byte, error) {
+    var proof_out proofSet
+    if len(data) < 3*g2Size+gtSize+4*scalarSize {
+        return proof_out, nil, errors.New("proof too short")
+    }
+    var ok bool
+    for _, point := range []**bn256.G2{&proof_out.C, &proof_out.D, &proof_out.V} {
+        if *point, ok = new(bn256.G2).Unmarshal(data[:g2Size]); !ok {
+            return proof_out, nil, errors.New("invalid commitment")
+        }
+        data = data[g2Size:]
+    }
+    proof_out.a, _ = new(bn256.GT).Unmarshal(data[:gtSize])
+    data = data[gtSize:]
+    for _, n := range []**big.Int{&proof_out.c, &proof_out.zr, &proof_out.zsig, &proof_out.zv} {
+        if *n, data, ok = readScalar(data); !ok {
+            return proof_out, nil, errors.New("invalid scalar")
+        }
+    }
+    return proof_out, data, nil
+}
+
+/*
+marshal encodes the link: T1, T2, z in 32 bytes, z1 and z2.
+*/
+func (proof_out *proofLink) marshal() ([]byte, error) {
+    if proof_out.T1 == nil || proof_out.T1.IsZero() || proof_out.T2 == nil || proof_out.z == nil ||
+        proof_out.z1 == nil || proof_out.z2 == nil || proof_out.z.BitLen() > 8*scalarSize {
+        return nil, errors.New("proof is missing elements")
+    }
+    data := make([]byte, p256Size, p256Size+g2Size+3*scalarSize)
+    x, y := proof_out.T1.X.Bytes(), proof_out.T1.Y.Bytes()
+    copy(data[p256Size/2-len(x):p256Size/2], x)
+    copy(data[p256Size-len(y):], y)
+    data = append(data, proof_out.T2.Marshal()...)
+    for _, n := range []*big.Int{proof_out.z, proof_out.z1, proof_out.z2} {
+        data = appendScalar(data, n)
+    }
+    return data, nil
+}
+
+/*
+unmarshalProofLink decodes a link encoded by marshal at the start of data, and
+returns the rest of data.
+*/
+func unmarshalProofLink(data []byte) (proofLink, []byte, error) {
+    var proof_out proofLink
+    if len(data) < p256Size+g2Size+3*scalarSize {
+        return proof_out, nil, errors.New("proof too short")
+    }
+    proof_out.T1 = &p256.P256{X: new(big.Int).SetBytes(data[:p256Size/2]), Y: new(big.Int).SetBytes(data[p256Size/2 : p256Size])}
+    if proof_out.T1.X.Cmp(p256.CURVE.P) >= 0 || proof_out.T1.Y.Cmp(p256.CURVE.P) >= 0 || proof_out.T1.IsZero() ||
+        !proof_out.T1.IsOnCurve() {
+        return proof_out, nil, errors.New("point is not on the curve")
+    }
+    var ok bool
+    if proof_out.T2, ok = new(bn256.G2).Unmarshal(data[p256Size : p256Size+g2Size]); !ok {
+        return proof_out, nil, errors.New("invalid commitment")
+    }
+    data = data[p256Size+g2Size:]
+    proof_out.z = new(big.Int).SetBytes(data[:scalarSize])
+    data = data[scalarSize:]
+    if proof_out.z1 = new(big.Int).SetBytes(data[:scalarSize]); proof_out.z1.Cmp(p256.CURVE.N) >= 0 {
+        return proof_out, nil, errors.New("invalid scalar")
+    }
+    data = data[scalarSize:]
+    if proof_out.z2, data, ok = readScalar(data); !ok {
+        return proof_out, nil, errors.New("invalid scalar")
+    }
+    return proof_out, data, nil
+}
+
+/*
+marshal encodes a gap proof: CL, CU, the set membership proof of the gap and the
+3 range proofs.
+*/
+func (proof_out *proofGap) marshal() ([]byte, error) {
+    if proof_out.CL == nil || proof_out.CU == nil {
+        return nil, errors.New("proof is missing elements")
+    }
+    data := append(proof_out.CL.Marshal(), proof_out.CU.Marshal()...)
+    e, err := proof_out.e.marshal()
+    if err != nil {
+        return nil, err
+    }
+    data = append(data, e...)
+    for _, proof := range []*proofUL{&proof_out.upper, &proof_out.left, &proof_out.right} {
+        encoded, err := proof.marshal()
+        if err != nil {
+            return nil, err
+        }
+        data = append(data, encoded...)
+    }
+    return data, nil
+}
+
+/*
+unmarshalProofGap decodes a gap proof encoded by marshal at the start of data,
+and returns the rest of data.
+*/
+func unmarshalProofGap(data []byte) (proofGap, []byte, error) {
+    var proof_out proofGap
+    if len(data) < 2*g2Size {
+        return proof_out, nil, errors.New("proof too short")
+    }
+    var ok bool
+    if proof_out.CL, ok = new(bn256.G2).Unmarshal(data[:g2Size]); !ok {
+        return proof_out, nil, errors.New("invalid commitment")
+    }
+    if proof_out.CU, ok = new(bn256.G2).Unmarshal(data[g2Size : 2*g2Size]); !ok {
+        return proof_out, nil, errors.New("invalid commitment")
+    }
+    var err error
+    if proof_out.e, data, err = unmarshalProofSet(data[2*g2Size:]); err != nil {
+        return proof_out, nil, err
+    }
+    for _, proof := range []*proofUL{&proof_out.upper, &proof_out.left, &proof_out.right} {
+        if *proof, data, err = unmarshalProofUL(data); err != nil {
+            return proof_out, nil, err
+        }
+    }
+    return proof_out, data, nil
+}
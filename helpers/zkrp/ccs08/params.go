@@ -0,0 +1,266 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+/*
+This file contains the choice of the base u and the encodings of the parameters
+and of the proofs, so that the verifier can run the setup once and publish its
+output, and the prover can send its proofs.
+*/
+
+package ccs08
+
+import (
+    "encoding/binary"
+    "errors"
+    "math"
+    "math/big"
+    "strconv"
+
+    "github.com/ing-bank/zkrp/crypto/bbsignatures"
+    "github.com/ing-bank/zkrp/crypto/bn256"
+)
+
+const (
+    g1Size     = 64
+    g2Size     = 128
+    gtSize     = 384
+    scalarSize = 32
+)
+
+/*
+digits returns the number of digits l in base u such that u^l >= width, and at
+least 1.
+*/
+func digits(u, width int64) int64 {
+    l := int64(1)
+    for p := u; p < width; p *= u {
+        l++
+        if p > math.MaxInt64/u {
+            break
+        }
+    }
+    return l
+}
+
+/*
+OptimalBase returns the base u and the number of digits l for intervals of width
+values, i.e., u^l >= width. The setup publishes u signatures and a proof holds l
+commitments to digits, so u and l minimize u + l. This is the choice
+u = log(b)/log(log(b)) of the paper, computed exactly; ties go to the smaller l,
+since the verifier computes pairings per digit.
+*/
+func OptimalBase(width int64) (int64, int64) {
+    bestU, bestL := int64(2), digits(2, width)
+    for u := int64(3); u <= bestU+bestL; u++ {
+        l := digits(u, width)
+        if u+l < bestU+bestL || (u+l == bestU+bestL && l < bestL) {
+            bestU, bestL = u, l
+        }
+    }
+    return bestU, bestL
+}
+
+/*
+MarshalBinary encodes the public output of the setup: u, l, the public key of
+the verifier, H and the signatures of the digits 0, ..., u-1. The private key is
+not encoded.
+*/
+func (p paramsUL) MarshalBinary() ([]byte, error) {
+    if p.u < 2 || p.l < 1 || p.kp.Pubk == nil || p.H == nil {
+        return nil, errors.New("invalid setup parameters")
+    }
+    data := make([]byte, 16, 16+g1Size+g2Size*(1+p.u))
+    binary.BigEndian.PutUint64(data[0:8], uint64(p.u))
+    binary.BigEndian.PutUint64(data[8:16], uint64(p.l))
+    data = append(data, p.kp.Pubk.Marshal()...)
+    data = append(data, p.H.Marshal()...)
+    for i := int64(0); i < p.u; i++ {
+        signature, ok := p.signatures[strconv.FormatInt(i, 10)]
+        if !ok {
+            return nil, errors.New("missing signature of digit " + strconv.FormatInt(i, 10))
+        }
+        data = append(data, signature.Marshal()...)
+    }
+    return data, nil
+}
+
+/*
+UnmarshalBinary decodes parameters encoded by MarshalBinary. Every signature is
+checked against the public key, so that the prover does not accept parameters
+it could not prove with.
+*/
+func (p *paramsUL) UnmarshalBinary(data []byte) error {
+    if len(data) < 16+g1Size+g2Size {
+        return errors.New("setup parameters too short")
+    }
+    u := binary.BigEndian.Uint64(data[0:8])
+    l := binary.BigEndian.Uint64(data[8:16])
+    if u < 2 || l < 1 || l > 64 || u > uint64(len(data)-16-g1Size-g2Size)/g2Size {
+        return errors.New("invalid setup parameters")
+    }
+    if uint64(len(data)) != 16+g1Size+g2Size*(1+u) {
+        return errors.New("invalid length of setup parameters")
+    }
+    data = data[16:]
+    pubk, ok := new(bn256.G1).Unmarshal(data[:g1Size])
+    if !ok {
+        return errors.New("invalid public key")
+    }
+    data = data[g1Size:]
+    H, ok := new(bn256.G2).Unmarshal(data[:g2Size])
+    if !ok {
+        return errors.New("invalid H")
+    }
+    data = data[g2Size:]
+    signatures := make(map[string]*bn256.G2)
+    for i := int64(0); i < int64(u); i++ {
+        signature, ok := new(bn256.G2).Unmarshal(data[i*g2Size : (i+1)*g2Size])
+        if !ok {
+            return errors.New("invalid signature of digit " + strconv.FormatInt(i, 10))
+        }
+        if valid, _ := bbsignatures.Verify(signature, big.NewInt(i), pubk); !valid {
+            return errors.New("invalid signature of digit " + strconv.FormatInt(i, 10))
+        }
+        signatures[strconv.FormatInt(i, 10)] = signature
+    }
+    p.u, p.l = int64(u), int64(l)
+    p.kp = bbsignatures.Keypair{Pubk: pubk}
+    p.H = H
+    p.signatures = signatures
+    return nil
+}
+
+/*
+marshal encodes the elements of the proof that the verifier needs, i.e., not the
+randomness s, t and m of the prover: l, C, D, then V, a, zsig and zv of every
+digit, then c and zr.
+*/
+func (proof_out *proofUL) marshal() ([]byte, error) {
+    l := len(proof_out.V)
+    if proof_out.C == nil || proof_out.D == nil || proof_out.c == nil || proof_out.zr == nil ||
+        len(proof_out.a) != l || len(proof_out.zsig) != l || len(proof_out.zv) != l {
+        return nil, errors.New("proof is missing elements")
+    }
+    data := make([]byte, 8, 8+2*g2Size+l*(g2Size+gtSize+2*scalarSize)+2*scalarSize)
+    binary.BigEndian.PutUint64(data, uint64(l))
+    data = append(data, proof_out.C.Marshal()...)
+    data = append(data, proof_out.D.Marshal()...)
+    for i := 0; i < l; i++ {
+        if proof_out.V[i] == nil || proof_out.a[i] == nil || proof_out.zsig[i] == nil || proof_out.zv[i] == nil {
+            return nil, errors.New("proof is missing elements")
+        }
+        data = append(data, proof_out.V[i].Marshal()...)
+        data = append(data, proof_out.a[i].Marshal()...)
+        data = appendScalar(data, proof_out.zsig[i])
+        data = appendScalar(data, proof_out.zv[i])
+    }
+    data = appendScalar(data, proof_out.c)
+    data = appendScalar(data, proof_out.zr)
+    return data, nil
+}
+
+/*
+unmarshalProofUL decodes a proof encoded by marshal at the start of data, and
+returns the rest of data.
+*/
+func unmarshalProofUL(data []byte) (proofUL, []byte, error) {
+    var proof_out proofUL
+    if len(data) < 8 {
+        return proof_out, nil, errors.New("proof too short")
+    }
+    l := binary.BigEndian.Uint64(data)
+    data = data[8:]
+    if l < 1 || l > 64 ||
+        uint64(len(data)) < 2*g2Size+l*(g2Size+gtSize+2*scalarSize)+2*scalarSize {
+        return proof_out, nil, errors.New("invalid length of proof")
+    }
+    var ok bool
+    if proof_out.C, ok = new(bn256.G2).Unmarshal(data[:g2Size]); !ok {
+        return proof_out, nil, errors.New("invalid commitment")
+    }
+    if proof_out.D, ok = new(bn256.G2).Unmarshal(data[g2Size : 2*g2Size]); !ok {
+        return proof_out, nil, errors.New("invalid commitment")
+    }
+    data = data[2*g2Size:]
+    proof_out.V = make([]*bn256.G2, l)
+    proof_out.a = make([]*bn256.GT, l)
+    proof_out.zsig = make([]*big.Int, l)
+    proof_out.zv = make([]*big.Int, l)
+    for i := uint64(0); i < l; i++ {
+        if proof_out.V[i], ok = new(bn256.G2).Unmarshal(data[:g2Size]); !ok {
+            return proof_out, nil, errors.New("invalid commitment to a digit")
+        }
+        proof_out.a[i], _ = new(bn256.GT).Unmarshal(data[g2Size : g2Size+gtSize])
+        data = data[g2Size+gtSize:]
+        if proof_out.zsig[i], data, ok = readScalar(data); !ok {
+            return proof_out, nil, errors.New("invalid scalar")
+        }
+        if proof_out.zv[i], data, ok = readScalar(data); !ok {
+            return proof_out, nil, errors.New("invalid scalar")
+        }
+    }
+    if proof_out.c, data, ok = readScalar(data); !ok {
+        return proof_out, nil, errors.New("invalid scalar")
+    }
+    if proof_out.zr, data, ok = readScalar(data); !ok {
+        return proof_out, nil, errors.New("invalid scalar")
+    }
+    return proof_out, data, nil
+}
+
+/*
+appendScalar appends n in 32 bytes.
+*/
+func appendScalar(data []byte, n *big.Int) []byte {
+    var buf [scalarSize]byte
+    b := n.Bytes()
+    copy(buf[scalarSize-len(b):], b)
+    return append(data, buf[:]...)
+}
+
+/*
+readScalar reads a scalar less than the order of the group from the start of
+data, and returns the rest of data.
+*/
+func readScalar(data []byte) (*big.Int, []byte, bool) {
+    n := new(big.Int).SetBytes(data[:scalarSize])
+    return n, data[scalarSize:], n.Cmp(bn256.Order) < 0
+}
+
+/*
+appendLength appends the length of encoded in 8 bytes, and encoded.
+*/
+func appendLength(data, encoded []byte) []byte {
+    var length [8]byte
+    binary.BigEndian.PutUint64(length[:], uint64(len(encoded)))
+    return append(append(data, length[:]...), encoded...)
+}
+
+/*
+readLength reads an encoding written by appendLength from the start of data, and
+returns the rest of data.
+*/
+func readLength(data []byte) ([]byte, []byte, error) {
+    if len(data) < 8 {
+        return nil, nil, errors.New("encoding too short")
+    }
+    length := binary.BigEndian.Uint64(data)
+    if length > uint64(len(data)-8) {
+        return nil, nil, errors.New("encoding too short")
+    }
+    return data[8 : 8+length], data[8+length:], nil
+}
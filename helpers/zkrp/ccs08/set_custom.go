@@ -0,0 +1,417 @@
+package ccs08
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"sort"
+
+	"github.com/ing-bank/zkrp/crypto/bn256"
+	"github.com/ing-bank/zkrp/crypto/p256"
+	"github.com/ing-bank/zkrp/util"
+)
+
+// CCS08SetParams is the output of the setup ceremony of set membership and gap proofs for a set S of positions: the
+// signatures of the elements of S, and of the gaps (s_j, s_j+1) between consecutive elements that contain positions,
+// under the key of the verifier, and the parameters of the digits of the range proofs of the gaps. The verifier runs
+// NewCCS08SetParams once and publishes MarshalBinary; provers load it with UnmarshalBinary.
+type CCS08SetParams struct {
+	members paramsSet
+	gaps    paramsSet
+	digits  paramsUL
+}
+
+// NewCCS08SetParams runs the setup ceremony for the set, with the digits of params, which must cover the positions.
+func NewCCS08SetParams(set []int64, params *CCS08Params) (*CCS08SetParams, error) {
+
+	elements := append([]int64{}, set...)
+	sort.Slice(elements, func(i, j int) bool { return elements[i] < elements[j] })
+	var gaps []int64
+	for j := range elements {
+		if elements[j] < 0 || elements[j] >= 1<<linkValueBits {
+			return nil, errors.New("element out of the range of positions")
+		}
+		if j > 0 && elements[j] == elements[j-1] {
+			return nil, errors.New("duplicate element")
+		}
+		if j > 0 && elements[j]-elements[j-1] > 1 {
+			gaps = append(gaps, gapCode(elements[j-1], elements[j]))
+		}
+	}
+	members, err := SetupSet(elements)
+	if err != nil {
+		return nil, err
+	}
+	gapParams, err := SetupSet(gaps)
+	if err != nil {
+		return nil, err
+	}
+	loaded := &CCS08SetParams{members: members, gaps: gapParams, digits: params.p}
+	if err := loaded.checkBounds(); err != nil {
+		return nil, err
+	}
+	return loaded, nil
+
+}
+
+// checkBounds returns an error unless the gap codes L<<32 | U of the set are sound: the elements, and so the bounds of
+// the gaps, are of at most linkValueBits bits, and the range proofs of the gaps prove values in [0, u^l) with
+// u^l <= 2^linkValueBits, so that no other pair of bounds in range encodes the same gap.
+func (params *CCS08SetParams) checkBounds() error {
+
+	bound := new(big.Int).Lsh(big.NewInt(1), linkValueBits)
+	width := new(big.Int).Exp(big.NewInt(params.digits.u), big.NewInt(params.digits.l), nil)
+	if width.Cmp(bound) > 0 {
+		return errors.New("digits of the range proofs exceed the range of positions")
+	}
+	for element := range params.members.signatures {
+		if element < 0 || element >= 1<<linkValueBits {
+			return errors.New("element out of the range of positions")
+		}
+	}
+	return nil
+
+}
+
+// Elements returns the elements of the set, sorted.
+func (params *CCS08SetParams) Elements() []int64 {
+
+	elements := make([]int64, 0, len(params.members.signatures))
+	for element := range params.members.signatures {
+		elements = append(elements, element)
+	}
+	sort.Slice(elements, func(i, j int) bool { return elements[i] < elements[j] })
+	return elements
+
+}
+
+// Gap returns the gap (L, U) of the set that contains x, i.e., L < x < U are consecutive elements, and false if x is an
+// element or lies outside the set.
+func (params *CCS08SetParams) Gap(x int64) (int64, int64, bool) {
+
+	elements := params.Elements()
+	j := sort.Search(len(elements), func(j int) bool { return elements[j] >= x })
+	if j == 0 || j == len(elements) || elements[j] == x {
+		return 0, 0, false
+	}
+	return elements[j-1], elements[j], true
+
+}
+
+// MarshalBinary encodes the public part of the parameters.
+func (params *CCS08SetParams) MarshalBinary() ([]byte, error) {
+
+	var data []byte
+	for _, marshal := range []func() ([]byte, error){params.members.MarshalBinary, params.gaps.MarshalBinary, params.digits.MarshalBinary} {
+		encoded, err := marshal()
+		if err != nil {
+			return nil, err
+		}
+		data = appendLength(data, encoded)
+	}
+	return data, nil
+
+}
+
+// UnmarshalBinary decodes parameters encoded by MarshalBinary and checks their signatures.
+func (params *CCS08SetParams) UnmarshalBinary(data []byte) error {
+
+	var loaded CCS08SetParams
+	for _, unmarshal := range []func([]byte) error{loaded.members.UnmarshalBinary, loaded.gaps.UnmarshalBinary, loaded.digits.UnmarshalBinary} {
+		encoded, rest, err := readLength(data)
+		if err != nil {
+			return err
+		}
+		if err := unmarshal(encoded); err != nil {
+			return err
+		}
+		data = rest
+	}
+	if len(data) != 0 {
+		return errors.New("trailing data after setup parameters")
+	}
+	if err := loaded.checkBounds(); err != nil {
+		return err
+	}
+	*params = loaded
+	return nil
+
+}
+
+// MembershipProof proves that the position committed by a Pedersen commitment g^x.h^r over secp256k1 is an element of
+// the set, without revealing which one.
+type MembershipProof struct {
+	set  proofSet
+	link proofLink
+}
+
+// ProveMembership computes the membership proof of x, committed as g^x.h^r.
+func ProveMembership(x, r *big.Int, h *p256.P256, params *CCS08SetParams) (*MembershipProof, error) {
+
+	if !x.IsInt64() {
+		return nil, errors.New("element does not belong to the set")
+	}
+	r2, _ := rand.Int(rand.Reader, bn256.Order)
+	set, err := ProveSet(x.Int64(), r2, params.members)
+	if err != nil {
+		return nil, err
+	}
+	link, err := proveLink(x, r, h, r2, params.members.H)
+	if err != nil {
+		return nil, err
+	}
+	return &MembershipProof{set: set, link: link}, nil
+
+}
+
+// Verify returns true if and only if the position committed by C = g^x.h^r is an element of the set.
+func (proof *MembershipProof) Verify(C, h *p256.P256, params *CCS08SetParams) bool {
+
+	if !proof.link.verify(h, params.members.H, C, proof.set.C) {
+		return false
+	}
+	ok, _ := VerifySet(&proof.set, &params.members)
+	return ok
+
+}
+
+// MarshalBinary exports the proof.
+func (proof *MembershipProof) MarshalBinary() ([]byte, error) {
+
+	set, err := proof.set.marshal()
+	if err != nil {
+		return nil, err
+	}
+	link, err := proof.link.marshal()
+	if err != nil {
+		return nil, err
+	}
+	return append(set, link...), nil
+
+}
+
+// UnmarshalBinary imports a proof exported by MarshalBinary.
+func (proof *MembershipProof) UnmarshalBinary(data []byte) error {
+
+	set, data, err := unmarshalProofSet(data)
+	if err != nil {
+		return err
+	}
+	link, data, err := unmarshalProofLink(data)
+	if err != nil {
+		return err
+	}
+	if len(data) != 0 {
+		return errors.New("trailing data after proof")
+	}
+	proof.set, proof.link = set, link
+	return nil
+
+}
+
+// GapProof proves that the position committed by a Pedersen commitment g^x.h^r over secp256k1 lies strictly between
+// two consecutive elements of the set, without revealing which ones.
+type GapProof struct {
+	C    *bn256.G2
+	gap  proofGap
+	link proofLink
+}
+
+// ProveGap computes the gap proof of x, committed as g^x.h^r.
+func ProveGap(x, r *big.Int, h *p256.P256, params *CCS08SetParams) (*GapProof, error) {
+
+	if !x.IsInt64() {
+		return nil, errors.New("element does not lie in a gap of the set")
+	}
+	L, U, ok := params.Gap(x.Int64())
+	if !ok {
+		return nil, errors.New("element does not lie in a gap of the set")
+	}
+	r2, _ := rand.Int(rand.Reader, bn256.Order)
+	C, _ := util.Commit(x, r2, params.gaps.H)
+	gap, err := proveGap(x, r2, L, U, params.gaps, params.digits)
+	if err != nil {
+		return nil, err
+	}
+	link, err := proveLink(x, r, h, r2, params.gaps.H)
+	if err != nil {
+		return nil, err
+	}
+	return &GapProof{C: C, gap: gap, link: link}, nil
+
+}
+
+// Verify returns true if and only if the position committed by C = g^x.h^r lies in a gap of the set.
+func (proof *GapProof) Verify(C, h *p256.P256, params *CCS08SetParams) bool {
+
+	return proof.link.verify(h, params.gaps.H, C, proof.C) && proof.gap.verify(proof.C, &params.gaps, &params.digits)
+
+}
+
+// MarshalBinary exports the proof.
+func (proof *GapProof) MarshalBinary() ([]byte, error) {
+
+	if proof.C == nil {
+		return nil, errors.New("proof is missing elements")
+	}
+	gap, err := proof.gap.marshal()
+	if err != nil {
+		return nil, err
+	}
+	link, err := proof.link.marshal()
+	if err != nil {
+		return nil, err
+	}
+	data := append(proof.C.Marshal(), gap...)
+	return append(data, link...), nil
+
+}
+
+// UnmarshalBinary imports a proof exported by MarshalBinary.
+func (proof *GapProof) UnmarshalBinary(data []byte) error {
+
+	if len(data) < g2Size {
+		return errors.New("proof too short")
+	}
+	C, ok := new(bn256.G2).Unmarshal(data[:g2Size])
+	if !ok {
+		return errors.New("invalid commitment")
+	}
+	gap, data, err := unmarshalProofGap(data[g2Size:])
+	if err != nil {
+		return err
+	}
+	link, data, err := unmarshalProofLink(data)
+	if err != nil {
+		return err
+	}
+	if len(data) != 0 {
+		return errors.New("trailing data after proof")
+	}
+	proof.C, proof.gap, proof.link = C, gap, link
+	return nil
+
+}
+
+// Intervals returns parameters for interval proofs (see ProveInterval) with the digits of params and no set, for range
+// proofs that are bound to a Pedersen commitment over secp256k1.
+func (params *CCS08Params) Intervals() *CCS08SetParams {
+	return &CCS08SetParams{digits: params.p}
+}
+
+// IntervalProof proves that the position committed by a Pedersen commitment g^x.h^r over secp256k1 lies in [a, b),
+// e.g., that the boundaries of a set query lie below and above the set.
+type IntervalProof struct {
+	C     *bn256.G2
+	proof proof
+	link  proofLink
+}
+
+// interval returns the range proof of [a, b) with the digits of the parameters.
+func (params *CCS08SetParams) interval(a, b int64) (*ccs08, error) {
+
+	zkrp := new(ccs08)
+	if err := zkrp.SetupWithParams(a, b, params.digits); err != nil {
+		return nil, err
+	}
+	return zkrp, nil
+
+}
+
+// ProveInterval computes the proof that x, committed as g^x.h^r, lies in [a, b).
+func ProveInterval(x, r *big.Int, h *p256.P256, a, b int64, params *CCS08SetParams) (*IntervalProof, error) {
+
+	zkrp, err := params.interval(a, b)
+	if err != nil {
+		return nil, err
+	}
+	zkrp.x = x
+	zkrp.r, _ = rand.Int(rand.Reader, bn256.Order)
+	if err := zkrp.Prove(); err != nil {
+		return nil, err
+	}
+	if zkrp.proof_out.p1.C == nil || zkrp.proof_out.p2.C == nil {
+		return nil, errors.New("Could not generate proof. Element does not belong to the interval.")
+	}
+	C, _ := util.Commit(x, zkrp.r, params.digits.H)
+	link, err := proveLink(x, r, h, zkrp.r, params.digits.H)
+	if err != nil {
+		return nil, err
+	}
+	return &IntervalProof{C: C, proof: zkrp.proof_out, link: link}, nil
+
+}
+
+// Verify returns true if and only if the position committed by C = g^x.h^r lies in [a, b).
+func (interval *IntervalProof) Verify(C, h *p256.P256, a, b int64, params *CCS08SetParams) bool {
+
+	zkrp, err := params.interval(a, b)
+	if err != nil || interval.C == nil || interval.proof.p2.C == nil {
+		return false
+	}
+	// the second part proves x - a, committed as C.g2^-a
+	Ca := new(bn256.G2).Add(interval.C, new(bn256.G2).Neg(new(bn256.G2).ScalarBaseMult(big.NewInt(a))))
+	if a == 0 {
+		Ca = interval.C
+	}
+	if !bytes.Equal(Ca.Marshal(), interval.proof.p2.C.Marshal()) {
+		return false
+	}
+	zkrp.proof_out = interval.proof
+	ok, _ := zkrp.Verify()
+	return ok && interval.link.verify(h, params.digits.H, C, interval.C)
+
+}
+
+// MarshalBinary exports the proof.
+func (interval *IntervalProof) MarshalBinary() ([]byte, error) {
+
+	if interval.C == nil {
+		return nil, errors.New("proof is missing elements")
+	}
+	data := interval.C.Marshal()
+	for _, part := range []*proofUL{&interval.proof.p1, &interval.proof.p2} {
+		encoded, err := part.marshal()
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, encoded...)
+	}
+	link, err := interval.link.marshal()
+	if err != nil {
+		return nil, err
+	}
+	return append(data, link...), nil
+
+}
+
+// UnmarshalBinary imports a proof exported by MarshalBinary.
+func (interval *IntervalProof) UnmarshalBinary(data []byte) error {
+
+	if len(data) < g2Size {
+		return errors.New("proof too short")
+	}
+	C, ok := new(bn256.G2).Unmarshal(data[:g2Size])
+	if !ok {
+		return errors.New("invalid commitment")
+	}
+	first, data, err := unmarshalProofUL(data[g2Size:])
+	if err != nil {
+		return err
+	}
+	second, data, err := unmarshalProofUL(data)
+	if err != nil {
+		return err
+	}
+	link, data, err := unmarshalProofLink(data)
+	if err != nil {
+		return err
+	}
+	if len(data) != 0 {
+		return errors.New("trailing data after proof")
+	}
+	interval.C, interval.proof, interval.link = C, proof{p1: first, p2: second}, link
+	return nil
+
+}
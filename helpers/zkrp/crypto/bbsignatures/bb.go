@@ -76,10 +76,10 @@ func Sign(m *big.Int, privk *big.Int) (*bn256.G2, error) {
 }
 
 /*
-verify receives as input the digital signature, the message and the public key. It outputs
+Verify receives as input the digital signature, the message and the public key. It outputs
 true if and only if the signature is valid.
 */
-func verify(signature *bn256.G2, m *big.Int, pubk *bn256.G1) (bool, error) {
+func Verify(signature *bn256.G2, m *big.Int, pubk *bn256.G1) (bool, error) {
     // e(y.g^m, sig) = e(g1,g2)
     var (
         gm     *bn256.G1
@@ -25,7 +25,7 @@ import (
 func TestKeyGen(t *testing.T) {
     kp, _ := Keygen()
     signature, _ := Sign(big.NewInt(42), kp.Privk)
-    res, _ := verify(signature, big.NewInt(42), kp.Pubk)
+    res, _ := Verify(signature, big.NewInt(42), kp.Pubk)
     if res != true {
         t.Errorf("Assert failure: expected true, actual: %t", res)
         t.Fail()
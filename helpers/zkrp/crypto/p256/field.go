@@ -0,0 +1,157 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package p256
+
+import (
+    "encoding/binary"
+    "math/big"
+    "math/bits"
+)
+
+/*
+fieldElement is an element of the field of CURVE.P, as four 64-bit limbs, least
+significant first, always reduced below CURVE.P. The arithmetic is that of
+math/bits on fixed-size words, which is much faster than math/big for point
+additions. It is not constant time, like the rest of this package.
+*/
+type fieldElement [4]uint64
+
+/*
+CURVE.P = 2^256 - fieldC, so that hi.2^256 + lo = hi.fieldC + lo mod CURVE.P.
+*/
+const fieldC = 0x1000003D1 // 2^32 + 977
+
+/*
+setBig sets z to x, for x in [0, CURVE.P).
+*/
+func (z *fieldElement) setBig(x *big.Int) *fieldElement {
+    var b [32]byte
+    x.FillBytes(b[:])
+    for i := 0; i < 4; i++ {
+        z[i] = binary.BigEndian.Uint64(b[24-8*i:])
+    }
+    return z
+}
+
+func (z *fieldElement) big() *big.Int {
+    var b [32]byte
+    for i := 0; i < 4; i++ {
+        binary.BigEndian.PutUint64(b[24-8*i:], z[i])
+    }
+    return new(big.Int).SetBytes(b[:])
+}
+
+func (z *fieldElement) isZero() bool {
+    return z[0]|z[1]|z[2]|z[3] == 0
+}
+
+/*
+reduce sets z to r + carry.2^256 mod CURVE.P, for r < 2^256 and a carry of a few
+bits.
+*/
+func (z *fieldElement) reduce(r *[4]uint64, carry uint64) *fieldElement {
+    var c uint64
+    for carry != 0 {
+        hi, lo := bits.Mul64(carry, fieldC)
+        r[0], c = bits.Add64(r[0], lo, 0)
+        r[1], c = bits.Add64(r[1], hi, c)
+        r[2], c = bits.Add64(r[2], 0, c)
+        r[3], c = bits.Add64(r[3], 0, c)
+        carry = c
+    }
+    // r - CURVE.P = r + fieldC - 2^256, so r >= CURVE.P iff r + fieldC carries
+    var s [4]uint64
+    s[0], c = bits.Add64(r[0], fieldC, 0)
+    s[1], c = bits.Add64(r[1], 0, c)
+    s[2], c = bits.Add64(r[2], 0, c)
+    s[3], c = bits.Add64(r[3], 0, c)
+    if c != 0 {
+        *z = s
+    } else {
+        *z = *r
+    }
+    return z
+}
+
+func (z *fieldElement) add(a, b *fieldElement) *fieldElement {
+    var (
+        r [4]uint64
+        c uint64
+    )
+    r[0], c = bits.Add64(a[0], b[0], 0)
+    r[1], c = bits.Add64(a[1], b[1], c)
+    r[2], c = bits.Add64(a[2], b[2], c)
+    r[3], c = bits.Add64(a[3], b[3], c)
+    return z.reduce(&r, c)
+}
+
+func (z *fieldElement) sub(a, b *fieldElement) *fieldElement {
+    var (
+        r [4]uint64
+        c uint64
+    )
+    r[0], c = bits.Sub64(a[0], b[0], 0)
+    r[1], c = bits.Sub64(a[1], b[1], c)
+    r[2], c = bits.Sub64(a[2], b[2], c)
+    r[3], c = bits.Sub64(a[3], b[3], c)
+    if c != 0 {
+        // a - b + 2^256 - fieldC = a - b + CURVE.P, which does not borrow
+        r[0], c = bits.Sub64(r[0], fieldC, 0)
+        r[1], c = bits.Sub64(r[1], 0, c)
+        r[2], c = bits.Sub64(r[2], 0, c)
+        r[3], _ = bits.Sub64(r[3], 0, c)
+    }
+    *z = r
+    return z
+}
+
+func (z *fieldElement) mul(a, b *fieldElement) *fieldElement {
+    var t [8]uint64
+    for i := 0; i < 4; i++ {
+        var carry uint64
+        for j := 0; j < 4; j++ {
+            hi, lo := bits.Mul64(a[i], b[j])
+            var c uint64
+            lo, c = bits.Add64(lo, t[i+j], 0)
+            hi += c
+            lo, c = bits.Add64(lo, carry, 0)
+            hi += c
+            t[i+j], carry = lo, hi
+        }
+        t[i+4] = carry
+    }
+    // t = hi.2^256 + lo = hi.fieldC + lo
+    var (
+        r     [4]uint64
+        carry uint64
+    )
+    for i := 0; i < 4; i++ {
+        hi, lo := bits.Mul64(t[4+i], fieldC)
+        var c uint64
+        lo, c = bits.Add64(lo, t[i], 0)
+        hi += c
+        lo, c = bits.Add64(lo, carry, 0)
+        hi += c
+        r[i], carry = lo, hi
+    }
+    return z.reduce(&r, carry)
+}
+
+func (z *fieldElement) square(a *fieldElement) *fieldElement {
+    return z.mul(a, a)
+}
@@ -0,0 +1,52 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package p256
+
+import (
+    "crypto/rand"
+    "math/big"
+    "testing"
+)
+
+func TestFieldArithmetic(t *testing.T) {
+    one := new(big.Int).SetInt64(1)
+    values := []*big.Int{
+        new(big.Int), one, new(big.Int).SetInt64(fieldC), new(big.Int).Sub(CURVE.P, one),
+        new(big.Int).Sub(CURVE.P, new(big.Int).SetInt64(fieldC)), new(big.Int).Lsh(one, 255),
+    }
+    for i := 0; i < 20; i++ {
+        x, _ := rand.Int(rand.Reader, CURVE.P)
+        values = append(values, x)
+    }
+    check := func(name string, x, y *big.Int, actual *fieldElement, expected *big.Int) {
+        expected.Mod(expected, CURVE.P)
+        if actual.big().Cmp(expected) != 0 {
+            t.Fatalf("%s of %x and %x: expected %x, actual %x", name, x, y, expected, actual.big())
+        }
+    }
+    for _, x := range values {
+        for _, y := range values {
+            var a, b, z fieldElement
+            a.setBig(x)
+            b.setBig(y)
+            check("sum", x, y, z.add(&a, &b), new(big.Int).Add(x, y))
+            check("difference", x, y, z.sub(&a, &b), new(big.Int).Sub(x, y))
+            check("product", x, y, z.mul(&a, &b), new(big.Int).Mul(x, y))
+        }
+    }
+}
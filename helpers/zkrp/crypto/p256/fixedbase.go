@@ -0,0 +1,241 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package p256
+
+import (
+    "math/big"
+
+    "github.com/ing-bank/zkrp/util/bn"
+)
+
+/*
+FixedBaseWindow is the default window size, in bits, of fixed-base tables.
+*/
+const FixedBaseWindow = 8
+
+/*
+FixedBaseTable holds the multiples (j * 2^(w*i)) * B of a fixed base point B, for every
+window i of a scalar and every digit j in [1, 2^w). A scalar multiplication by B then
+costs one point addition per window of the scalar and no doubling. The additions are
+done in Jacobian coordinates, so that only one inversion is needed per multiplication.
+*/
+type FixedBaseTable struct {
+    window uint
+    points [][]*P256
+}
+
+/*
+NewFixedBaseTable builds the table of the given base point, for scalars mod CURVE.N.
+*/
+func NewFixedBaseTable(base *P256, window uint) *FixedBaseTable {
+    var (
+        windows int
+        table   *FixedBaseTable
+    )
+    windows = (CURVE.N.BitLen() + int(window) - 1) / int(window)
+    table = &FixedBaseTable{window: window, points: make([][]*P256, windows)}
+    digits := 1 << window
+    first := &P256{X: base.X, Y: base.Y}
+    for i := 0; i < windows; i++ {
+        table.points[i] = make([]*P256, digits)
+        table.points[i][1] = first
+        for j := 2; j < digits; j++ {
+            table.points[i][j] = new(P256).Multiply(table.points[i][j-1], first)
+        }
+        // first point of the next window: 2^w * first = (2^(w-1) * first) doubled
+        first = new(P256).Double(table.points[i][digits/2])
+    }
+    return table
+}
+
+/*
+ScalarMult returns n * B, where B is the base point of the table.
+*/
+func (table *FixedBaseTable) ScalarMult(n *big.Int) *P256 {
+    return FixedBaseMultiMult([]*FixedBaseTable{table}, []*big.Int{n})
+}
+
+/*
+FixedBaseMultiMult returns the sum of scalars[i] * B_i, where B_i is the base point of
+tables[i]. All the terms are accumulated in Jacobian coordinates.
+*/
+func FixedBaseMultiMult(tables []*FixedBaseTable, scalars []*big.Int) *P256 {
+    var (
+        acc jacobianPoint
+    )
+    for t, table := range tables {
+        n := bn.Mod(scalars[t], CURVE.N)
+        mask := uint(1)<<table.window - 1
+        for i := 0; i < len(table.points) && n.Sign() != 0; i++ {
+            digit := uint(n.Uint64()) & mask
+            n.Rsh(n, table.window)
+            if digit != 0 {
+                acc.addAffine(table.points[i][digit])
+            }
+        }
+    }
+    return acc.affine()
+}
+
+/*
+jacobianPoint is (x/z^2, y/z^3) in affine coordinates. The zero value is the point at
+infinity.
+*/
+type jacobianPoint struct {
+    x, y, z fieldElement
+    finite  bool
+}
+
+/*
+addAffine adds the affine point a to p with the mixed addition formulas madd-2007-bl
+(http://hyperelliptic.org/EFD/g1p/auto-shortw-jacobian-0.html#addition-madd-2007-bl).
+*/
+func (p *jacobianPoint) addAffine(a *P256) {
+    if !a.IsZero() {
+        p.addMixed(newAffinePoint(a))
+    }
+}
+
+/*
+newAffinePoint returns the finite point a with z = 1, for addMixed.
+*/
+func newAffinePoint(a *P256) *jacobianPoint {
+    q := &jacobianPoint{finite: true}
+    q.x.setBig(a.X)
+    q.y.setBig(a.Y)
+    q.z[0] = 1
+    return q
+}
+
+/*
+addMixed is addAffine for a point q with z = 1.
+*/
+func (p *jacobianPoint) addMixed(q *jacobianPoint) {
+    if !p.finite {
+        *p = *q
+        return
+    }
+    var z1z1, u2, s2, h, hh, i, j, r, v, t fieldElement
+    z1z1.square(&p.z)                        // Z1Z1 = Z1^2
+    u2.mul(&q.x, &z1z1)                      // U2 = X2*Z1Z1
+    s2.mul(&q.y, &p.z).mul(&s2, &z1z1)       // S2 = Y2*Z1*Z1Z1
+    h.sub(&u2, &p.x)                         // H = U2-X1
+    r.sub(&s2, &p.y)                         // r = 2*(S2-Y1)
+    if h.isZero() {
+        p.addEqualX(&r)
+        return
+    }
+    r.add(&r, &r)
+    hh.square(&h)                            // HH = H^2
+    i.add(&hh, &hh).add(&i, &i)              // I = 4*HH
+    j.mul(&h, &i)                            // J = H*I
+    v.mul(&p.x, &i)                          // V = X1*I
+    t.mul(&p.y, &j).add(&t, &t)              // 2*Y1*J
+    p.z.add(&p.z, &h).square(&p.z)           // Z3 = (Z1+H)^2-Z1Z1-HH
+    p.z.sub(&p.z, &z1z1).sub(&p.z, &hh)
+    p.x.square(&r).sub(&p.x, &j)             // X3 = r^2-J-2*V
+    p.x.sub(&p.x, &v).sub(&p.x, &v)
+    p.y.sub(&v, &p.x).mul(&p.y, &r)          // Y3 = r*(V-X3)-2*Y1*J
+    p.y.sub(&p.y, &t)
+}
+
+/*
+add adds q to p with the formulas add-2007-bl
+(http://hyperelliptic.org/EFD/g1p/auto-shortw-jacobian-0.html#addition-add-2007-bl).
+*/
+func (p *jacobianPoint) add(q *jacobianPoint) {
+    if !q.finite {
+        return
+    }
+    if !p.finite {
+        *p = *q
+        return
+    }
+    var z1z1, z2z2, u1, u2, s1, s2, h, i, j, r, v, t fieldElement
+    z1z1.square(&p.z)                        // Z1Z1 = Z1^2
+    z2z2.square(&q.z)                        // Z2Z2 = Z2^2
+    u1.mul(&p.x, &z2z2)                      // U1 = X1*Z2Z2
+    u2.mul(&q.x, &z1z1)                      // U2 = X2*Z1Z1
+    s1.mul(&p.y, &q.z).mul(&s1, &z2z2)       // S1 = Y1*Z2*Z2Z2
+    s2.mul(&q.y, &p.z).mul(&s2, &z1z1)       // S2 = Y2*Z1*Z1Z1
+    h.sub(&u2, &u1)                          // H = U2-U1
+    r.sub(&s2, &s1)                          // r = 2*(S2-S1)
+    if h.isZero() {
+        p.addEqualX(&r)
+        return
+    }
+    r.add(&r, &r)
+    i.add(&h, &h).square(&i)                 // I = (2*H)^2
+    j.mul(&h, &i)                            // J = H*I
+    v.mul(&u1, &i)                           // V = U1*I
+    t.mul(&s1, &j).add(&t, &t)               // 2*S1*J
+    p.z.add(&p.z, &q.z).square(&p.z)         // Z3 = ((Z1+Z2)^2-Z1Z1-Z2Z2)*H
+    p.z.sub(&p.z, &z1z1).sub(&p.z, &z2z2).mul(&p.z, &h)
+    p.x.square(&r).sub(&p.x, &j)             // X3 = r^2-J-2*V
+    p.x.sub(&p.x, &v).sub(&p.x, &v)
+    p.y.sub(&v, &p.x).mul(&p.y, &r)          // Y3 = r*(V-X3)-2*S1*J
+    p.y.sub(&p.y, &t)
+}
+
+/*
+addEqualX adds to p a point with the same affine x, given the difference r of the
+scaled y of the points: the sum is 2p if they are equal, the point at infinity if
+they are opposite.
+*/
+func (p *jacobianPoint) addEqualX(r *fieldElement) {
+    if r.isZero() {
+        p.double()
+    } else {
+        p.finite = false
+    }
+}
+
+/*
+double doubles p with the formulas dbl-2009-l
+(http://hyperelliptic.org/EFD/g1p/auto-shortw-jacobian-0.html#doubling-dbl-2009-l),
+since the curve has a = 0.
+*/
+func (p *jacobianPoint) double() {
+    if !p.finite {
+        return
+    }
+    var a, b, c, d, e, f fieldElement
+    a.square(&p.x)                           // A = X1^2
+    b.square(&p.y)                           // B = Y1^2
+    c.square(&b)                             // C = B^2
+    d.add(&p.x, &b).square(&d)               // D = 2*((X1+B)^2-A-C)
+    d.sub(&d, &a).sub(&d, &c).add(&d, &d)
+    e.add(&a, &a).add(&e, &a)                // E = 3*A
+    f.square(&e)                             // F = E^2
+    p.z.mul(&p.y, &p.z).add(&p.z, &p.z)      // Z3 = 2*Y1*Z1
+    p.x.sub(&f, &d).sub(&p.x, &d)            // X3 = F-2*D
+    c.add(&c, &c).add(&c, &c).add(&c, &c)    // Y3 = E*(D-X3)-8*C
+    p.y.sub(&d, &p.x).mul(&p.y, &e).sub(&p.y, &c)
+}
+
+func (p *jacobianPoint) affine() *P256 {
+    if !p.finite {
+        return new(P256).SetInfinity()
+    }
+    var zinv, zinv2, x, y fieldElement
+    zinv.setBig(new(big.Int).ModInverse(p.z.big(), CURVE.P))
+    zinv2.square(&zinv)
+    x.mul(&p.x, &zinv2)
+    y.mul(&p.y, &zinv2).mul(&y, &zinv)
+    return &P256{X: x.big(), Y: y.big()}
+}
@@ -0,0 +1,76 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package p256
+
+import (
+    "crypto/rand"
+    "math/big"
+    "testing"
+)
+
+func TestFixedBaseScalarMult(t *testing.T) {
+    base, _ := MapToGroup("Testing fixed-base tables:")
+    table := NewFixedBaseTable(base, FixedBaseWindow)
+    scalars := []*big.Int{
+        new(big.Int).SetInt64(1),
+        new(big.Int).SetInt64(255),
+        new(big.Int).SetInt64(256),
+        new(big.Int).Sub(CURVE.N, new(big.Int).SetInt64(1)),
+    }
+    for i := 0; i < 10; i++ {
+        n, _ := rand.Int(rand.Reader, CURVE.N)
+        scalars = append(scalars, n)
+    }
+    for _, n := range scalars {
+        expected := new(P256).ScalarMult(base, n)
+        actual := table.ScalarMult(n)
+        if actual.X.Cmp(expected.X) != 0 || actual.Y.Cmp(expected.Y) != 0 {
+            t.Errorf("Assert failure: expected %s, actual: %s", expected, actual)
+        }
+    }
+    res := table.ScalarMult(CURVE.N).IsZero()
+    if res != true {
+        t.Errorf("Assert failure: expected true, actual: %t", res)
+    }
+}
+
+func TestFixedBaseMultiMult(t *testing.T) {
+    g := new(P256).ScalarBaseMult(new(big.Int).SetInt64(1))
+    tables := []*FixedBaseTable{NewFixedBaseTable(g, FixedBaseWindow), NewFixedBaseTable(g, FixedBaseWindow)}
+    // x.G + (N-x).G is the point at infinity, and x.G + x.G needs a doubling
+    x, _ := rand.Int(rand.Reader, CURVE.N)
+    res := FixedBaseMultiMult(tables, []*big.Int{x, new(big.Int).Sub(CURVE.N, x)}).IsZero()
+    if res != true {
+        t.Errorf("Assert failure: expected true, actual: %t", res)
+    }
+    expected := new(P256).ScalarBaseMult(new(big.Int).Lsh(x, 1))
+    actual := FixedBaseMultiMult(tables, []*big.Int{x, x})
+    if actual.X.Cmp(expected.X) != 0 || actual.Y.Cmp(expected.Y) != 0 {
+        t.Errorf("Assert failure: expected %s, actual: %s", expected, actual)
+    }
+}
+
+func BenchmarkFixedBaseScalarMult(b *testing.B) {
+    table := NewFixedBaseTable(new(P256).ScalarBaseMult(new(big.Int).SetInt64(1)), FixedBaseWindow)
+    a := make([]byte, 32)
+    b.ResetTimer()
+    for i := 0; i < b.N; i++ {
+        rand.Read(a)
+        _ = table.ScalarMult(new(big.Int).SetBytes(a))
+    }
+}
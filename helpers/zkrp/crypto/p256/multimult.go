@@ -0,0 +1,105 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+package p256
+
+import (
+    "math/big"
+    "math/bits"
+
+    "github.com/ing-bank/zkrp/util/bn"
+)
+
+/*
+MultiMult returns the sum of scalars[i] * points[i], with the bucket method of
+Pippenger: the scalars are cut into windows of c bits and, for each window, every
+point is added to the bucket of its digit, and the buckets are summed with weights
+1..2^c-1 by running sums. A sum of n terms then costs about (n + 2^(c+1)) . 256/c
+additions instead of n scalar multiplications, all in Jacobian coordinates so that
+only one inversion is needed for the whole sum.
+*/
+func MultiMult(points []*P256, scalars []*big.Int) *P256 {
+    var (
+        acc     jacobianPoint
+        affine  []*jacobianPoint
+        digits  [][]byte
+    )
+    for i, point := range points {
+        n := bn.Mod(scalars[i], CURVE.N)
+        if point.IsZero() || n.Sign() == 0 {
+            continue
+        }
+        affine = append(affine, newAffinePoint(point))
+        digits = append(digits, n.FillBytes(make([]byte, 32)))
+    }
+    if len(affine) == 0 {
+        return acc.affine()
+    }
+
+    c := multiMultWindow(len(affine))
+    buckets := make([]jacobianPoint, 1<<uint(c))
+    for offset := ((CURVE.N.BitLen()+c-1)/c - 1) * c; offset >= 0; offset -= c {
+        for i := 0; i < c; i++ {
+            acc.double()
+        }
+        for i := range buckets {
+            buckets[i].finite = false
+        }
+        for i, point := range affine {
+            if digit := windowDigit(digits[i], offset, c); digit != 0 {
+                buckets[digit].addMixed(point)
+            }
+        }
+        // sum_d d . B_d = sum_d (B_(2^c-1) + ... + B_d)
+        var running, sum jacobianPoint
+        for d := len(buckets) - 1; d > 0; d-- {
+            running.add(&buckets[d])
+            sum.add(&running)
+        }
+        acc.add(&sum)
+    }
+    return acc.affine()
+}
+
+/*
+multiMultWindow returns the window size of a sum of n terms, about log2(n) - 2, which
+minimizes (n + 2^(c+1)) / c.
+*/
+func multiMultWindow(n int) int {
+    c := bits.Len(uint(n)) - 3
+    if c < 2 {
+        return 2
+    }
+    if c > 16 {
+        return 16
+    }
+    return c
+}
+
+/*
+windowDigit returns the c bits of the 256-bit big-endian scalar b from the bit
+offset, counted from the least significant bit.
+*/
+func windowDigit(b []byte, offset, c int) int {
+    digit := 0
+    for k := offset + c - 1; k >= offset; k-- {
+        digit <<= 1
+        if k < 256 {
+            digit |= int(b[31-k/8]>>(uint(k)%8)) & 1
+        }
+    }
+    return digit
+}
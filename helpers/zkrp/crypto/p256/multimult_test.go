@@ -0,0 +1,100 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+package p256
+
+import (
+    "crypto/rand"
+    "math/big"
+    "strconv"
+    "testing"
+)
+
+func TestMultiMult(t *testing.T) {
+    var (
+        points  []*P256
+        scalars []*big.Int
+    )
+    expected := new(P256).SetInfinity()
+    for i := 0; i < 20; i++ {
+        point, _ := MapToGroup("Testing multi-scalar multiplication:" + strconv.Itoa(i))
+        n, _ := rand.Int(rand.Reader, CURVE.N)
+        if i == 3 {
+            n = new(big.Int).SetInt64(0)
+        }
+        points = append(points, point)
+        scalars = append(scalars, n)
+        expected.Multiply(expected, new(P256).ScalarMult(point, n))
+    }
+    actual := MultiMult(points, scalars)
+    if actual.X.Cmp(expected.X) != 0 || actual.Y.Cmp(expected.Y) != 0 {
+        t.Errorf("Assert failure: expected %s, actual: %s", expected, actual)
+    }
+}
+
+func TestMultiMultSizes(t *testing.T) {
+    // the window of the bucket method grows with the number of terms
+    for _, n := range []int{1, 2, 3, 31, 130} {
+        var (
+            points  []*P256
+            scalars []*big.Int
+        )
+        expected := new(P256).SetInfinity()
+        for i := 0; i < n; i++ {
+            point, _ := MapToGroup("Testing multi-scalar multiplication sizes:" + strconv.Itoa(i))
+            m, _ := rand.Int(rand.Reader, CURVE.N)
+            points = append(points, point)
+            scalars = append(scalars, m)
+            expected.Multiply(expected, new(P256).ScalarMult(point, m))
+        }
+        actual := MultiMult(points, scalars)
+        if actual.X.Cmp(expected.X) != 0 || actual.Y.Cmp(expected.Y) != 0 {
+            t.Errorf("Assert failure for %d terms: expected %s, actual: %s", n, expected, actual)
+        }
+    }
+}
+
+func TestMultiMultCancellation(t *testing.T) {
+    g := new(P256).ScalarBaseMult(new(big.Int).SetInt64(1))
+    // x.G + (N-x).G is the point at infinity, and x.G + x.G needs a doubling
+    x, _ := rand.Int(rand.Reader, CURVE.N)
+    res := MultiMult([]*P256{g, g}, []*big.Int{x, new(big.Int).Sub(CURVE.N, x)}).IsZero()
+    if res != true {
+        t.Errorf("Assert failure: expected true, actual: %t", res)
+    }
+    expected := new(P256).ScalarBaseMult(new(big.Int).Lsh(x, 1))
+    actual := MultiMult([]*P256{g, g}, []*big.Int{x, x})
+    if actual.X.Cmp(expected.X) != 0 || actual.Y.Cmp(expected.Y) != 0 {
+        t.Errorf("Assert failure: expected %s, actual: %s", expected, actual)
+    }
+    res = MultiMult(nil, nil).IsZero()
+    if res != true {
+        t.Errorf("Assert failure: expected true, actual: %t", res)
+    }
+}
+
+func BenchmarkMultiMult(b *testing.B) {
+    points := make([]*P256, 64)
+    scalars := make([]*big.Int, 64)
+    for i := range points {
+        points[i], _ = MapToGroup("Benchmarking multi-scalar multiplication:" + strconv.Itoa(i))
+        scalars[i], _ = rand.Int(rand.Reader, CURVE.N)
+    }
+    b.ResetTimer()
+    for i := 0; i < b.N; i++ {
+        _ = MultiMult(points, scalars)
+    }
+}
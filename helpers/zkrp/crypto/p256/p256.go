@@ -217,6 +217,38 @@ func MapToGroup(m string) (*P256, error) {
     return nil, errors.New("Failed to Hash-to-point.")
 }
 
+/*
+IsMappedToGroup returns true if and only if p is MapToGroup(m). It computes the
+same hashes as MapToGroup, but replaces its square roots by Jacobi symbols, which
+are much cheaper: the candidates before p.X must have no square root, and p.Y
+must be the root that ModSqrt returns, fx^((P+1)/4), which is a square, while -y
+is not one since P = 3 mod 4.
+*/
+func IsMappedToGroup(m string, p *P256) bool {
+    var (
+        i      int
+        buffer bytes.Buffer
+    )
+    if p == nil || p.IsZero() || p.X.Cmp(CURVE.P) >= 0 || p.Y.Cmp(CURVE.P) >= 0 || !p.IsOnCurve() {
+        return false
+    }
+    for i = 0; i < 256; i++ {
+        buffer.Reset()
+        buffer.WriteString(strconv.Itoa(i))
+        buffer.WriteString(m)
+        x, _ := HashToInt(buffer)
+        x = bn.Mod(x, CURVE.P)
+        if x.Cmp(p.X) == 0 {
+            return big.Jacobi(p.Y, CURVE.P) == 1
+        }
+        fx, _ := F(x)
+        if big.Jacobi(fx, CURVE.P) != -1 {
+            return false
+        }
+    }
+    return false
+}
+
 /*
 F receives a big integer x as input and return x^3 + 7 mod ORDER.
 */
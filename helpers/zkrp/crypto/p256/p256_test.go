@@ -20,6 +20,7 @@ package p256
 import (
     "crypto/rand"
     "math/big"
+    "strconv"
     "testing"
 )
 
@@ -83,6 +84,25 @@ func TestMapToGroup(t *testing.T) {
     p.ScalarMult(p, curve.N)
 }
 
+func TestIsMappedToGroup(t *testing.T) {
+    for i := 0; i < 20; i++ {
+        m := "Testing Hash-to-point check:" + strconv.Itoa(i)
+        p, _ := MapToGroup(m)
+        if !IsMappedToGroup(m, p) {
+            t.Errorf("Assert failure: MapToGroup(%q) is not recognized", m)
+        }
+        if IsMappedToGroup(m+"x", p) {
+            t.Errorf("Assert failure: MapToGroup(%q) is recognized for another message", m)
+        }
+        if IsMappedToGroup(m, &P256{X: p.X, Y: new(big.Int).Sub(CURVE.P, p.Y)}) {
+            t.Errorf("Assert failure: -MapToGroup(%q) is recognized", m)
+        }
+    }
+    if IsMappedToGroup("", new(P256).ScalarBaseMult(new(big.Int).SetInt64(1))) || IsMappedToGroup("", new(P256)) {
+        t.Errorf("Assert failure: points not mapped from the message are recognized")
+    }
+}
+
 func BenchmarkScalarMultP256(b *testing.B) {
     a := make([]byte, 32)
     b.ResetTimer()
@@ -0,0 +1,162 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+package util
+
+import (
+    "crypto/sha256"
+    "encoding/binary"
+    "math/big"
+
+    "github.com/ing-bank/zkrp/crypto/bn256"
+    "github.com/ing-bank/zkrp/crypto/p256"
+)
+
+/*
+Transcript is the Fiat-Shamir transcript of a proof. The prover and the verifier
+append the same public inputs in the same order, i.e., the statement, the setup
+parameters and the messages of the prover, and challenges are derived from all
+that was appended before them. Every input is absorbed with its label and its
+length in a canonical encoding, and the transcript starts with a domain
+separator, so that two different statements or protocols never give the same
+challenges. The state is a chained SHA-256 digest.
+*/
+type Transcript struct {
+    state [sha256.Size]byte
+}
+
+/*
+NewTranscript returns a transcript for the protocol named by domain.
+*/
+func NewTranscript(domain string) *Transcript {
+    t := new(Transcript)
+    t.AppendMessage("domain-separator", []byte(domain))
+    return t
+}
+
+/*
+Clone returns a copy of the transcript, so that sub-proofs of a statement can
+continue from the same state independently.
+*/
+func (t *Transcript) Clone() *Transcript {
+    clone := *t
+    return &clone
+}
+
+/*
+AppendMessage absorbs the data under the label: state = H(state, label, data),
+each of label and data prefixed by its length.
+*/
+func (t *Transcript) AppendMessage(label string, data []byte) {
+    var length [8]byte
+    digest := sha256.New()
+    digest.Write(t.state[:])
+    binary.BigEndian.PutUint64(length[:], uint64(len(label)))
+    digest.Write(length[:])
+    digest.Write([]byte(label))
+    binary.BigEndian.PutUint64(length[:], uint64(len(data)))
+    digest.Write(length[:])
+    digest.Write(data)
+    copy(t.state[:], digest.Sum(nil))
+}
+
+/*
+AppendInt64 absorbs a 64-bit integer in 8 bytes.
+*/
+func (t *Transcript) AppendInt64(label string, n int64) {
+    var data [8]byte
+    binary.BigEndian.PutUint64(data[:], uint64(n))
+    t.AppendMessage(label, data[:])
+}
+
+/*
+AppendBigInt absorbs the sign and the magnitude of n.
+*/
+func (t *Transcript) AppendBigInt(label string, n *big.Int) {
+    data := append([]byte{byte(n.Sign() + 1)}, n.Bytes()...)
+    t.AppendMessage(label, data)
+}
+
+/*
+AppendP256 absorbs a point as X and Y in 32 bytes each. The point at infinity
+is absorbed as an empty message.
+*/
+func (t *Transcript) AppendP256(label string, point *p256.P256) {
+    if point == nil || point.IsZero() {
+        t.AppendMessage(label, nil)
+        return
+    }
+    var data [64]byte
+    x, y := point.X.Bytes(), point.Y.Bytes()
+    copy(data[32-len(x):32], x)
+    copy(data[64-len(y):], y)
+    t.AppendMessage(label, data[:])
+}
+
+/*
+AppendP256s absorbs the number of points and the points.
+*/
+func (t *Transcript) AppendP256s(label string, points []*p256.P256) {
+    t.AppendInt64(label, int64(len(points)))
+    for _, point := range points {
+        t.AppendP256(label, point)
+    }
+}
+
+/*
+AppendG1 absorbs a point of G1 in its marshalled form.
+*/
+func (t *Transcript) AppendG1(label string, point *bn256.G1) {
+    t.AppendMessage(label, point.Marshal())
+}
+
+/*
+AppendG2 absorbs a point of G2 in its marshalled form.
+*/
+func (t *Transcript) AppendG2(label string, point *bn256.G2) {
+    t.AppendMessage(label, point.Marshal())
+}
+
+/*
+AppendGT absorbs an element of GT in its marshalled form.
+*/
+func (t *Transcript) AppendGT(label string, a *bn256.GT) {
+    t.AppendMessage(label, a.Marshal())
+}
+
+/*
+Challenge returns a non-zero challenge mod order derived from the state, and
+absorbs it. 512 bits are reduced mod order, so that the challenge is uniform up
+to a negligible bias.
+*/
+func (t *Transcript) Challenge(label string, order *big.Int) *big.Int {
+    for counter := uint64(0); ; counter++ {
+        var wide []byte
+        for block := byte(0); block < 2; block++ {
+            var data [9]byte
+            binary.BigEndian.PutUint64(data[:8], counter)
+            data[8] = block
+            fork := t.Clone()
+            fork.AppendMessage(label, data[:])
+            wide = append(wide, fork.state[:]...)
+        }
+        c := new(big.Int).Mod(new(big.Int).SetBytes(wide), order)
+        if c.Sign() != 0 {
+            t.AppendBigInt(label, c)
+            return c
+        }
+    }
+}
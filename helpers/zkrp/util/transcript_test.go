@@ -0,0 +1,76 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+package util
+
+import (
+    "math/big"
+    "testing"
+
+    "github.com/ing-bank/zkrp/crypto/p256"
+)
+
+func TestTranscriptDeterministic(t *testing.T) {
+    t1, t2 := NewTranscript("test"), NewTranscript("test")
+    t1.AppendInt64("n", 32)
+    t2.AppendInt64("n", 32)
+    c1, c2 := t1.Challenge("x", p256.CURVE.N), t2.Challenge("x", p256.CURVE.N)
+    if c1.Cmp(c2) != 0 {
+        t.Errorf("Assert failure: expected %s, actual: %s", c1, c2)
+    }
+    if c1.Sign() == 0 || c1.Cmp(p256.CURVE.N) >= 0 {
+        t.Errorf("Assert failure: challenge %s out of range", c1)
+    }
+    // a challenge is absorbed, so the next one differs
+    if t1.Challenge("x", p256.CURVE.N).Cmp(c1) == 0 {
+        t.Errorf("Assert failure: consecutive challenges should differ")
+    }
+}
+
+func TestTranscriptSeparation(t *testing.T) {
+    challenge := func(domain, label string, data []byte) *big.Int {
+        t := NewTranscript(domain)
+        t.AppendMessage(label, data)
+        return t.Challenge("x", p256.CURVE.N)
+    }
+    c := challenge("test", "ab", []byte("c"))
+    for _, other := range []*big.Int{
+        challenge("other", "ab", []byte("c")),
+        challenge("test", "a", []byte("bc")),
+        challenge("test", "ab", []byte("d")),
+    } {
+        if c.Cmp(other) == 0 {
+            t.Errorf("Assert failure: different inputs should give different challenges")
+        }
+    }
+}
+
+func TestTranscriptClone(t *testing.T) {
+    t1 := NewTranscript("test")
+    t1.AppendBigInt("a", big.NewInt(-1))
+    t2 := t1.Clone()
+    t2.AppendBigInt("b", big.NewInt(1))
+    t1.AppendBigInt("b", big.NewInt(1))
+    if t1.Challenge("x", p256.CURVE.N).Cmp(t2.Challenge("x", p256.CURVE.N)) != 0 {
+        t.Errorf("Assert failure: a clone should continue from the same state")
+    }
+    t3 := NewTranscript("test")
+    t3.AppendBigInt("a", big.NewInt(1))
+    t3.AppendBigInt("b", big.NewInt(1))
+    if t1.Challenge("y", p256.CURVE.N).Cmp(t3.Challenge("y", p256.CURVE.N)) == 0 {
+        t.Errorf("Assert failure: the sign of an integer should be absorbed")
+    }
+}
@@ -18,13 +18,11 @@
 package util
 
 import (
-    "crypto/sha256"
     "math/big"
 
     "github.com/ing-bank/zkrp/crypto/bn256"
     "github.com/ing-bank/zkrp/crypto/p256"
     "github.com/ing-bank/zkrp/util/bn"
-    "github.com/ing-bank/zkrp/util/byteconversion"
 )
 
 // Constants that are going to be used frequently, then we just need to compute them once.
@@ -75,27 +73,31 @@ func CommitG1(x, r *big.Int, h *p256.P256) (*p256.P256, error) {
 }
 
 /*
-HashSet is responsible for the computing a Zp element given elements from GT and G2.
+CommitTables holds fixed-base tables for the generator and for h, so that Pedersen
+commitments with a fixed h do not need full scalar multiplications.
 */
-func HashSet(a *bn256.GT, D *bn256.G2) (*big.Int, error) {
-    digest := sha256.New()
-    digest.Write([]byte(a.String()))
-    digest.Write([]byte(D.String()))
-    output := digest.Sum(nil)
-    tmp := output[0:]
-    return byteconversion.FromByteArray(tmp)
+type CommitTables struct {
+    G *p256.FixedBaseTable
+    H *p256.FixedBaseTable
 }
 
 /*
-Hash is responsible for the computing a Zp element given elements from GT and G2.
+NewCommitTables builds the tables of the generator and of h. Building them costs about
+as much as a few hundred commitments, so they should be built once per setup.
 */
-func Hash(a []*bn256.GT, D *bn256.G2) (*big.Int, error) {
-    digest := sha256.New()
-    for i := range a {
-        digest.Write([]byte(a[i].String()))
+func NewCommitTables(h *p256.P256) *CommitTables {
+    g := new(p256.P256).ScalarBaseMult(new(big.Int).SetInt64(1))
+    return &CommitTables{
+        G: p256.NewFixedBaseTable(g, p256.FixedBaseWindow),
+        H: p256.NewFixedBaseTable(h, p256.FixedBaseWindow),
     }
-    digest.Write([]byte(D.String()))
-    output := digest.Sum(nil)
-    tmp := output[0:]
-    return byteconversion.FromByteArray(tmp)
+}
+
+/*
+CommitG1 is the same as the CommitG1 function with the h of the tables, i.e., it outputs
+g^x.h^r.
+*/
+func (tables *CommitTables) CommitG1(x, r *big.Int) (*p256.P256, error) {
+    C := p256.FixedBaseMultiMult([]*p256.FixedBaseTable{tables.G, tables.H}, []*big.Int{x, r})
+    return C, nil
 }
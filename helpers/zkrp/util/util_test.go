@@ -16,3 +16,43 @@
  */
 
 package util
+
+import (
+    "crypto/rand"
+    "math/big"
+    "testing"
+
+    "github.com/ing-bank/zkrp/crypto/p256"
+)
+
+func TestCommitTables(t *testing.T) {
+    h, _ := p256.MapToGroup("Testing commitment tables:")
+    tables := NewCommitTables(h)
+    for i := 0; i < 10; i++ {
+        x := new(big.Int).SetInt64(int64(i * 1000))
+        r, _ := rand.Int(rand.Reader, p256.CURVE.N)
+        expected, _ := CommitG1(x, r, h)
+        actual, _ := tables.CommitG1(x, r)
+        if actual.X.Cmp(expected.X) != 0 || actual.Y.Cmp(expected.Y) != 0 {
+            t.Errorf("Assert failure: expected %s, actual: %s", expected, actual)
+        }
+    }
+}
+
+func BenchmarkCommitG1(b *testing.B) {
+    h, _ := p256.MapToGroup("Testing commitment tables:")
+    r, _ := rand.Int(rand.Reader, p256.CURVE.N)
+    for i := 0; i < b.N; i++ {
+        _, _ = CommitG1(new(big.Int).SetInt64(int64(i)), r, h)
+    }
+}
+
+func BenchmarkCommitTables(b *testing.B) {
+    h, _ := p256.MapToGroup("Testing commitment tables:")
+    tables := NewCommitTables(h)
+    r, _ := rand.Int(rand.Reader, p256.CURVE.N)
+    b.ResetTimer()
+    for i := 0; i < b.N; i++ {
+        _, _ = tables.CommitG1(new(big.Int).SetInt64(int64(i)), r)
+    }
+}
@@ -9,15 +9,18 @@ import (
 	"sync"
 	"time"
 
+	"github.com/eozturk1/genomic-security-journal-code/entities/policy"
 	sl "github.com/eozturk1/genomic-security-journal-code/entities/sequencinglab"
 	t "github.com/eozturk1/genomic-security-journal-code/entities/tester"
 	env "github.com/eozturk1/genomic-security-journal-code/helpers/env"
 
 	"github.com/ing-bank/zkrp/crypto/p256"
-	"github.com/ing-bank/zkrp/util"
 )
 
-func Main(w *bufio.Writer, lab *sl.SequencingLab, tester *t.Tester, alice_genome, tester_genome []*env.Base, withOpt bool) bool {
+// Main runs the protocol, with the results of the tester disclosed to Alice as mode allows (see t.ResultMode). If
+// alicePolicy is not nil, Alice answers only a query that it allows. She sends all of her variants, so the results are
+// bound to the queried range only in WellFormed mode, where the marker is proven to be in it.
+func Main(w *bufio.Writer, lab *sl.SequencingLab, tester *t.Tester, alice_genome, tester_genome []*env.Base, withOpt bool, mode t.ResultMode, alicePolicy *policy.Policy) bool {
 
 	var wg sync.WaitGroup
 
@@ -34,7 +37,7 @@ func Main(w *bufio.Writer, lab *sl.SequencingLab, tester *t.Tester, alice_genome
 	wg.Add(len(positions))
 	for i := uint32(0); i < uint32(len(positions)); i++ {
 		go func(i uint32, wg *sync.WaitGroup) {
-			commitments[i], _ = util.CommitG1(big.NewInt(int64(positions[i])), salts[i], lab.BPparams.H)
+			commitments[i], _ = lab.CommitTables.CommitG1(big.NewInt(int64(positions[i])), salts[i])
 			wg.Done()
 		}(i, &wg)
 	}
@@ -44,10 +47,34 @@ func Main(w *bufio.Writer, lab *sl.SequencingLab, tester *t.Tester, alice_genome
 	fmt.Fprintln(w, timecheck.Microseconds())
 
 	timestart = time.Now()
-	tester.Setup(lab, tester_genome, 0)
+	if tester.Certificate != nil {
+		if err := tester.SetupCertified(lab, tester_genome, 0); err != nil {
+			fmt.Println("tester cannot prove its certified marker, so ABORT!")
+			return false
+		}
+	} else if mode&t.WellFormed != 0 {
+		if err := tester.SetupWellFormed(lab, tester_genome, 0); err != nil {
+			fmt.Println("tester cannot prove its marker, so ABORT!")
+			return false
+		}
+	} else {
+		tester.Setup(lab, tester_genome, 0)
+	}
+	tester.ResultMode = mode
+	tester.PrecomputeTesting(numberOfMutations)
 	timecheck = time.Since(timestart)
 	fmt.Println("Tester offline phase is done")
 	fmt.Fprintln(w, timecheck.Microseconds())
+	if alicePolicy != nil {
+		querySig, err := tester.SignQuery() // the tester sends its query signed, Alice only verifies it
+		if err != nil && err != t.ErrNoIdentity {
+			fmt.Println("tester cannot sign its query (", err, "), so ABORT!")
+			return false
+		}
+		if !t.CheckQuery(lab, alicePolicy, tester, querySig, len(aliceCiphers), mode&t.WellFormed != 0) {
+			return false
+		}
+	}
 
 	/* Online Phase */
 	timestart = time.Now()
@@ -58,9 +85,20 @@ func Main(w *bufio.Writer, lab *sl.SequencingLab, tester *t.Tester, alice_genome
 	fmt.Println("Tester online phase is done")
 	fmt.Fprintln(w, timecheck.Microseconds())
 
+	if mode&t.WellFormed != 0 && !t.CheckWellFormed(lab, tester, alicePolicy != nil && alicePolicy.Regulator != nil, aliceCiphers, resultCipherArray) {
+		return false
+	}
+	if mode&t.TesterLearns != 0 {
+		return t.VerdictForTester(w, lab, tester, resultCipherArray)
+	}
+
 	timestart = time.Now()
 	for i := 0; i < len(resultCipherArray); i++ {
-		isZero := lab.Ahe.IsZero(resultCipherArray[i])
+		isZero, err := lab.Ahe.IsZero(resultCipherArray[i])
+		if err != nil {
+			fmt.Println("result of the tester is invalid (", err, "), so ABORT!")
+			return false
+		}
 		if isZero {
 			timecheck = time.Since(timestart)
 			fmt.Println("Alice online phase is done")
@@ -4,13 +4,17 @@ package EfficientAndSecureSPHPSM
 
 import (
 	"bufio"
+	"crypto/ecdsa"
 	"fmt"
 	"log"
 
+	"github.com/eozturk1/genomic-security-journal-code/entities/policy"
+	"github.com/eozturk1/genomic-security-journal-code/entities/regulator"
 	sl "github.com/eozturk1/genomic-security-journal-code/entities/sequencinglab"
 	t "github.com/eozturk1/genomic-security-journal-code/entities/tester"
 	ahe "github.com/eozturk1/genomic-security-journal-code/helpers/addhomencer"
 	env "github.com/eozturk1/genomic-security-journal-code/helpers/env"
+	"github.com/eozturk1/genomic-security-journal-code/helpers/profiles"
 )
 
 func TestElGamalExactMatching(w *bufio.Writer, fileA, fileTm string, withOpt bool) {
@@ -19,15 +23,15 @@ func TestElGamalExactMatching(w *bufio.Writer, fileA, fileTm string, withOpt boo
 	tester_genome := env.ReadGenomeFromFile(fileTm)
 
 	scheme := ahe.AHElGamal{}
-	scheme.Setup()
+	scheme.SetupProfile(profiles.Default)
 
 	lab := sl.SequencingLab{}
-	lab.Setup(&scheme)
+	lab.Setup(&scheme, profiles.Default)
 
 	tester := t.Tester{}
 
 	fmt.Println("sae protocol, matching test with ElGamal starts!")
-	result := Main(w, &lab, &tester, alice_genome, tester_genome, withOpt)
+	result := Main(w, &lab, &tester, alice_genome, tester_genome, withOpt, t.PerWindow, nil)
 	if !result {
 		log.Fatal("Exact matching test: Failed\n")
 	}
@@ -40,15 +44,15 @@ func TestElGamalNoMatching(w *bufio.Writer, fileA, fileTnm string, withOpt bool)
 	tester_genome := env.ReadGenomeFromFile(fileTnm)
 
 	scheme := ahe.AHElGamal{}
-	scheme.Setup()
+	scheme.SetupProfile(profiles.Default)
 
 	lab := sl.SequencingLab{}
-	lab.Setup(&scheme)
+	lab.Setup(&scheme, profiles.Default)
 
 	tester := t.Tester{}
 
 	fmt.Println("sae protocol, no matching test with ElGamal starts!")
-	result := Main(w, &lab, &tester, alice_genome, tester_genome, withOpt)
+	result := Main(w, &lab, &tester, alice_genome, tester_genome, withOpt, t.PerWindow, nil)
 	if result {
 		log.Fatal("No matching test: Failed\n")
 	}
@@ -64,15 +68,15 @@ func TestPaillierExactMatching(w *bufio.Writer, fileA, fileTm string, withOpt bo
 	tester_genome := env.ReadGenomeFromFile(fileTm)
 
 	scheme := ahe.GoGoGadgetPaillier{}
-	scheme.Setup()
+	scheme.SetupProfile(profiles.Default)
 
 	lab := sl.SequencingLab{}
-	lab.Setup(&scheme)
+	lab.Setup(&scheme, profiles.Default)
 
 	tester := t.Tester{}
 
 	fmt.Println("sae protocol, matching test with Paillier starts!")
-	result := Main(w, &lab, &tester, alice_genome, tester_genome, withOpt)
+	result := Main(w, &lab, &tester, alice_genome, tester_genome, withOpt, t.PerWindow, nil)
 	if !result {
 		log.Fatal("Exact matching test: Failed\n")
 	}
@@ -86,18 +90,195 @@ func TestPaillierNoMatching(w *bufio.Writer, fileA, fileTnm string, withOpt bool
 	tester_genome := env.ReadGenomeFromFile(fileTnm)
 
 	scheme := ahe.GoGoGadgetPaillier{}
-	scheme.Setup()
+	scheme.SetupProfile(profiles.Default)
 
 	lab := sl.SequencingLab{}
-	lab.Setup(&scheme)
+	lab.Setup(&scheme, profiles.Default)
 
 	tester := t.Tester{}
 
 	fmt.Println("sae protocol, no matching test with Paillier starts!")
-	result := Main(w, &lab, &tester, alice_genome, tester_genome, withOpt)
+	result := Main(w, &lab, &tester, alice_genome, tester_genome, withOpt, t.PerWindow, nil)
 	if result {
 		log.Fatal("No matching test: Failed\n")
 	}
 	fmt.Println("sae protocol, no matching test with Paillier finished!")
 
 }
+
+//---------------
+
+func TestDamgardJurikExactMatching(w *bufio.Writer, fileA, fileTm string, withOpt bool, s int) {
+
+	alice_genome := env.ReadGenomeFromFile(fileA)
+	tester_genome := env.ReadGenomeFromFile(fileTm)
+
+	scheme := ahe.DamgardJurik{S: s}
+	scheme.SetupProfile(profiles.Default)
+
+	lab := sl.SequencingLab{}
+	lab.Setup(&scheme, profiles.Default)
+
+	tester := t.Tester{}
+
+	fmt.Println("sae protocol, matching test with Damgard-Jurik starts!")
+	result := Main(w, &lab, &tester, alice_genome, tester_genome, withOpt, t.PerWindow, nil)
+	if !result {
+		log.Fatal("Exact matching test: Failed\n")
+	}
+	fmt.Println("sae protocol, matching test with Damgard-Jurik finished!")
+
+}
+
+func TestDamgardJurikNoMatching(w *bufio.Writer, fileA, fileTnm string, withOpt bool, s int) {
+
+	alice_genome := env.ReadGenomeFromFile(fileA)
+	tester_genome := env.ReadGenomeFromFile(fileTnm)
+
+	scheme := ahe.DamgardJurik{S: s}
+	scheme.SetupProfile(profiles.Default)
+
+	lab := sl.SequencingLab{}
+	lab.Setup(&scheme, profiles.Default)
+
+	tester := t.Tester{}
+
+	fmt.Println("sae protocol, no matching test with Damgard-Jurik starts!")
+	result := Main(w, &lab, &tester, alice_genome, tester_genome, withOpt, t.PerWindow, nil)
+	if result {
+		log.Fatal("No matching test: Failed\n")
+	}
+	fmt.Println("sae protocol, no matching test with Damgard-Jurik finished!")
+
+}
+
+func TestDamgardJurikSingleBit(w *bufio.Writer, fileA, fileT string, withOpt bool, s int, expected bool) {
+
+	alice_genome := env.ReadGenomeFromFile(fileA)
+	tester_genome := env.ReadGenomeFromFile(fileT)
+
+	scheme := ahe.DamgardJurik{S: s}
+	scheme.SetupProfile(profiles.Default)
+
+	lab := sl.SequencingLab{}
+	lab.Setup(&scheme, profiles.Default)
+
+	tester := t.Tester{}
+
+	fmt.Println("sae protocol, single-bit test with Damgard-Jurik starts!")
+	result := Main(w, &lab, &tester, alice_genome, tester_genome, withOpt, t.SingleBit, nil)
+	if result != expected {
+		log.Fatal("Single-bit test: Failed\n")
+	}
+	fmt.Println("sae protocol, single-bit test with Damgard-Jurik finished!")
+
+}
+
+func TestElGamalTesterLearns(w *bufio.Writer, fileA, fileT string, withOpt bool, expected bool) {
+
+	alice_genome := env.ReadGenomeFromFile(fileA)
+	tester_genome := env.ReadGenomeFromFile(fileT)
+
+	scheme := ahe.AHElGamal{}
+	scheme.SetupProfile(profiles.Default)
+
+	lab := sl.SequencingLab{}
+	lab.Setup(&scheme, profiles.Default)
+
+	tester := t.Tester{}
+
+	fmt.Println("sae protocol, tester-learns test with ElGamal starts!")
+	result := Main(w, &lab, &tester, alice_genome, tester_genome, withOpt, t.TesterLearns, nil)
+	if result != expected {
+		log.Fatal("Tester-learns test: Failed\n")
+	}
+	fmt.Println("sae protocol, tester-learns test with ElGamal finished!")
+
+}
+
+func TestElGamalWellFormed(w *bufio.Writer, fileA, fileT string, withOpt bool, expected bool) {
+
+	alice_genome := env.ReadGenomeFromFile(fileA)
+	tester_genome := env.ReadGenomeFromFile(fileT)
+
+	scheme := ahe.AHElGamal{}
+	scheme.SetupProfile(profiles.Default)
+
+	lab := sl.SequencingLab{}
+	lab.Setup(&scheme, profiles.Default)
+
+	tester := t.Tester{}
+
+	fmt.Println("sae protocol, well-formed test with ElGamal starts!")
+	result := Main(w, &lab, &tester, alice_genome, tester_genome, withOpt, t.WellFormed, nil)
+	if result != expected {
+		log.Fatal("Well-formed test: Failed\n")
+	}
+	fmt.Println("sae protocol, well-formed test with ElGamal finished!")
+
+}
+
+// TestElGamalCertified runs a test that Alice accepts only if the marker is certified by her regulator. If certified,
+// the regulator certifies the marker of the tester, and the results of the test are proven too.
+func TestElGamalCertified(w *bufio.Writer, fileA, fileT string, withOpt bool, certified bool, expected bool) {
+
+	alice_genome := env.ReadGenomeFromFile(fileA)
+	tester_genome := env.ReadGenomeFromFile(fileT)
+
+	scheme := ahe.AHElGamal{}
+	scheme.SetupProfile(profiles.Default)
+
+	lab := sl.SequencingLab{}
+	lab.Setup(&scheme, profiles.Default)
+
+	reg := regulator.Regulator{}
+	reg.Setup(profiles.Default)
+
+	tester := t.Tester{}
+	if certified {
+		// the regulator approves the marker for the range that the tester queries
+		rangeStart, rangeEnd := tester_genome[0].Position, tester_genome[len(tester_genome)-1].Position
+		cert, openings, err := reg.Certify(&lab, tester_genome, rangeStart, rangeEnd, "clinical test")
+		if err != nil {
+			log.Fatal(err)
+		}
+		tester.SetCertificate(cert, openings)
+	}
+
+	fmt.Println("sae protocol, certified test with ElGamal starts!")
+	result := Main(w, &lab, &tester, alice_genome, tester_genome, withOpt, t.WellFormed, &policy.Policy{Regulator: reg.VerifyingKey})
+	if result != expected {
+		log.Fatal("Certified test: Failed\n")
+	}
+	fmt.Println("sae protocol, certified test with ElGamal finished!")
+
+}
+
+// TestElGamalPolicy runs a test in the mode by the tester with the given ID and key, which Alice answers only if her
+// policy allows it, and prints the decision of the policy. A nil key leaves the query unsigned.
+func TestElGamalPolicy(w *bufio.Writer, fileA, fileT string, withOpt bool, mode t.ResultMode, testerID string, testerKey *ecdsa.PrivateKey, alicePolicy *policy.Policy, expected bool) {
+
+	alice_genome := env.ReadGenomeFromFile(fileA)
+	tester_genome := env.ReadGenomeFromFile(fileT)
+
+	scheme := ahe.AHElGamal{}
+	scheme.SetupProfile(profiles.Default)
+
+	lab := sl.SequencingLab{}
+	lab.Setup(&scheme, profiles.Default)
+
+	tester := t.Tester{}
+	tester.SetIdentity(testerID, testerKey)
+
+	fmt.Println("sae protocol, policy test with ElGamal starts!")
+	result := Main(w, &lab, &tester, alice_genome, tester_genome, withOpt, mode, alicePolicy)
+	decisions := alicePolicy.Decisions()
+	decision := decisions[len(decisions)-1]
+	fmt.Printf("policy decision on %s (authenticated %v) %v (%d variants): allowed %v, %v\n", decision.Query.Tester,
+		decision.Authenticated, decision.Query.Ranges, decision.Query.Variants, decision.Allowed(), decision.Reason)
+	if result != expected {
+		log.Fatal("Policy test: Failed\n")
+	}
+	fmt.Println("sae protocol, policy test with ElGamal finished!")
+
+}
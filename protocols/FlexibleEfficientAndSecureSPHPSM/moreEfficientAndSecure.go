@@ -4,11 +4,13 @@ package FlexibleEfficientAndSecureSPHPSM
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"math/big"
 	"sync"
 	"time"
 
+	"github.com/eozturk1/genomic-security-journal-code/entities/policy"
 	sl "github.com/eozturk1/genomic-security-journal-code/entities/sequencinglab"
 	t "github.com/eozturk1/genomic-security-journal-code/entities/tester"
 	env "github.com/eozturk1/genomic-security-journal-code/helpers/env"
@@ -16,38 +18,40 @@ import (
 	bp "github.com/ing-bank/zkrp/bulletproofs"
 	"github.com/ing-bank/zkrp/ccs08"
 	"github.com/ing-bank/zkrp/crypto/p256"
-	"github.com/ing-bank/zkrp/util"
 )
 
-func Main(w *bufio.Writer, lab *sl.SequencingLab, tester *t.Tester, alice_genome, tester_genome []*env.Base, secParam uint32, withOpt bool, rangeProof int) bool {
+func Main(w *bufio.Writer, lab *sl.SequencingLab, tester *t.Tester, alice_genome, tester_genome []*env.Base, secParam uint32, withOpt bool, rangeProof int, mode t.ResultMode, alicePolicy *policy.Policy) bool {
 	// rangeProof - 0: BulletProofs, 1: CCS08
-
-	var wg sync.WaitGroup
+	// mode - what the results of the tester disclose to Alice (see t.ResultMode)
+	// alicePolicy - if not nil, Alice answers only a query that it allows
 
 	/* Offline Phase */
-	timestart := time.Now()
-	positions, aliceCiphers, salts, aliceSigs := lab.SequenceSNPSetRange(alice_genome)
-	timecheck := time.Since(timestart)
-	fmt.Println("SL offline phase is done")
-	fmt.Fprintln(w, timecheck.Microseconds())
+	positions, aliceCiphers, salts, aliceSigs, commitments := commitAndSign(w, lab, alice_genome)
 
-	timestart = time.Now()
-	commitments := make([]*p256.P256, len(positions))
-	wg.Add(len(positions))
-	for i := uint32(0); i < uint32(len(positions)); i++ {
-		go func(i uint32, wg *sync.WaitGroup) {
-			commitments[i], _ = util.CommitG1(big.NewInt(int64(positions[i])), salts[i], lab.BPparams.H)
-			wg.Done()
-		}(i, &wg)
+	timestart := time.Now()
+	if tester.Certificate != nil {
+		if err := tester.SetupCertified(lab, tester_genome, secParam); err != nil {
+			fmt.Println("tester cannot prove its certified marker, so ABORT!")
+			return false
+		}
+	} else if mode&t.WellFormed != 0 {
+		if err := tester.SetupWellFormed(lab, tester_genome, secParam); err != nil {
+			fmt.Println("tester cannot prove its marker, so ABORT!")
+			return false
+		}
+	} else {
+		tester.Setup(lab, tester_genome, secParam)
 	}
-	wg.Wait()
-	timecheck = time.Since(timestart)
-	fmt.Println("Alice offline phase is done")
-	fmt.Fprintln(w, timecheck.Microseconds())
-
-	timestart = time.Now()
-	tester.Setup(lab, tester_genome, secParam)
-	timecheck = time.Since(timestart)
+	tester.ResultMode = mode
+	tester.PrecomputeTesting(len(aliceCiphers) - 2) // at most, the range of the tester may cover fewer
+	var publishedCCS08Params []byte
+	if rangeProof == 1 { // the setup ceremony, run once by the tester
+		if err := tester.SetupCCS08(lab); err != nil {
+			panic(err)
+		}
+		publishedCCS08Params, _ = tester.CCS08Params.MarshalBinary()
+	}
+	timecheck := time.Since(timestart)
 	fmt.Println("Tester offline phase is done")
 	fmt.Fprintln(w, timecheck.Microseconds())
 
@@ -71,85 +75,563 @@ func Main(w *bufio.Writer, lab *sl.SequencingLab, tester *t.Tester, alice_genome
 	slicedCipher := aliceCiphers[startIndexm1:endIndexp2]
 	slicedComm := commitments[startIndexm1:endIndexp2]
 	slicedSig := aliceSigs[startIndexm1:endIndexp1]
+	if alicePolicy != nil && !allowed(lab, alicePolicy, tester, len(slicedCipher)) {
+		return false
+	}
 
 	if rangeProof == 0 { // bulletproof
 
-		// generate lower bound proof
-		params, _ := bp.SetupGeneric(0, int64(tester.RangeStart)) // to show the lower bound position < RangeStart
-		l, _ := bp.ProveGeneric(big.NewInt(int64(positions[startIndexm1])), params)
-
-		// generate upper bound proof
-		params2, _ := bp.SetupGeneric(int64(tester.RangeEnd+1), int64(lab.GetMaxHumanGenomeSize()+10)) // to show the upper bound position >= RangeEnd + 1
-		h, _ := bp.ProveGeneric(big.NewInt(int64(positions[endIndexp1])), params2)
+		// generate one proof for both bounds: the lower bound position < RangeStart and the upper bound position >= RangeEnd + 1,
+		// with the salts of their signed commitments
+		intervalsA, intervalsB := tester.RangeProofIntervals()
+		params, _ := bp.NewGenericAggregated(intervalsA, intervalsB, lab.BPparams)
+		proof, err := bp.ProveGenericAggregatedCommitted(
+			[]*big.Int{big.NewInt(int64(positions[startIndexm1])), big.NewInt(int64(positions[endIndexp1]))},
+			[]*big.Int{salts[startIndexm1], salts[endIndexp1]}, params)
+		if err != nil {
+			fmt.Println("Boundary positions are not outside of the range, so ABORT!")
+			return false
+		}
 
 		timecheck = time.Since(timestart)
 		fmt.Println("Alice preprocessing in online phase is done")
 		fmt.Fprintln(w, timecheck.Microseconds())
 
 		timestart = time.Now()
-		resultCipherArray := tester.TestingSNPRange(slicedComm, slicedCipher, slicedSig, &l, &h, withOpt)
+		resultCipherArray := tester.TestingSNPRange(slicedComm, slicedCipher, slicedSig, &proof, withOpt)
 		timecheck = time.Since(timestart)
 		fmt.Println("Tester online phase is done")
 		fmt.Fprintln(w, timecheck.Microseconds())
-
-		timestart = time.Now()
-		for i := 0; i < len(resultCipherArray); i++ {
-			isZero := lab.Ahe.IsZero(resultCipherArray[i])
-			if isZero {
-				timecheck = time.Since(timestart)
-				fmt.Println("Alice postprocessing in online phase is done")
-				fmt.Fprintln(w, timecheck.Microseconds())
-				return true
-			}
+		if mode&t.WellFormed != 0 && !t.CheckWellFormed(lab, tester, alicePolicy != nil && alicePolicy.Regulator != nil, slicedCipher, resultCipherArray) {
+			return false
+		}
+		if mode&t.TesterLearns != 0 {
+			return t.VerdictForTester(w, lab, tester, resultCipherArray)
 		}
-		timecheck = time.Since(timestart)
-		fmt.Println("Alice postprocessing in online phase is done")
-		fmt.Fprintln(w, timecheck.Microseconds())
-		return false
 
-	} else if rangeProof == 1 { // ccs08
+		return verdict(w, lab, resultCipherArray)
 
-		var lproof, hproof ccs08.CCS08Custom
+	} else if rangeProof == 1 { // ccs08
 
-		// generate lower bound proof
-		lproof.Setup(0, int64(tester.RangeStart))
-		lproof.Prove(big.NewInt(int64(positions[startIndexm1])))
+		// load the parameters published by the tester
+		var params ccs08.CCS08Params
+		if err := params.UnmarshalBinary(publishedCCS08Params); err != nil {
+			fmt.Println("CCS08 parameters are invalid, so ABORT!")
+			return false
+		}
+		intervalsA, intervalsB := tester.RangeProofIntervals()
+		intervals := params.Intervals()
+		h := lab.BPparams.H
 
-		// generate upper bound proof
-		hproof.Setup(int64(tester.RangeEnd+1), int64(lab.GetMaxHumanGenomeSize()+10))
-		hproof.Prove(big.NewInt(int64(positions[endIndexp1])))
+		// generate lower and upper bound proofs, with the salts of their signed commitments
+		lproof, err_l := ccs08.ProveInterval(big.NewInt(int64(positions[startIndexm1])), salts[startIndexm1], h, intervalsA[0], intervalsB[0], intervals)
+		hproof, err_h := ccs08.ProveInterval(big.NewInt(int64(positions[endIndexp1])), salts[endIndexp1], h, intervalsA[1], intervalsB[1], intervals)
+		if err_l != nil || err_h != nil {
+			fmt.Println("Boundary positions are not outside of the range, so ABORT!")
+			return false
+		}
+		lproofData, _ := lproof.MarshalBinary()
+		hproofData, _ := hproof.MarshalBinary()
 
 		timecheck = time.Since(timestart)
 		fmt.Println("Alice preprocessing in online phase is done")
 		fmt.Fprintln(w, timecheck.Microseconds())
 
 		timestart = time.Now()
-		resultCipherArray := tester.TestingSNPRangeCCS08(slicedComm, slicedCipher, slicedSig, &lproof, &hproof, withOpt)
+		resultCipherArray := tester.TestingSNPRangeCCS08(slicedComm, slicedCipher, slicedSig, lproofData, hproofData, withOpt)
 		timecheck = time.Since(timestart)
 		fmt.Println("Tester online phase is done")
 		fmt.Fprintln(w, timecheck.Microseconds())
+		if mode&t.WellFormed != 0 && !t.CheckWellFormed(lab, tester, alicePolicy != nil && alicePolicy.Regulator != nil, slicedCipher, resultCipherArray) {
+			return false
+		}
+		if mode&t.TesterLearns != 0 {
+			return t.VerdictForTester(w, lab, tester, resultCipherArray)
+		}
 
-		timestart = time.Now()
-		for i := 0; i < len(resultCipherArray); i++ {
-			isZero := lab.Ahe.IsZero(resultCipherArray[i])
-			if isZero {
-				timecheck = time.Since(timestart)
-				fmt.Println("Alice postprocessing in online phase is done")
-				fmt.Fprintln(w, timecheck.Microseconds())
-				return true
+		return verdict(w, lab, resultCipherArray)
+
+	} else {
+
+		fmt.Println("rp should be 0 (bulletproofs) or 1 (ccs08).")
+
+	}
+
+	return false
+
+}
+
+// MainSet runs FES-SPH-PSM for a sparse panel of positions, the positions of the tester's marker, instead of a range.
+// Alice reveals the same slice as in Main, and proves for each revealed position that it is in the panel or in a gap of
+// it (see Tester.TestingSNPSet), with CCS08 proofs over the signed commitments. Which proof she sends for each position
+// is public, so the tester learns how her positions interleave with the panel. If alicePolicy is not nil, Alice answers
+// only a query that it allows.
+func MainSet(w *bufio.Writer, lab *sl.SequencingLab, tester *t.Tester, alice_genome, tester_genome []*env.Base, withOpt bool, alicePolicy *policy.Policy) bool {
+
+	/* Offline Phase */
+	positions, aliceCiphers, salts, aliceSigs, commitments := commitAndSign(w, lab, alice_genome)
+
+	timestart := time.Now()
+	if err := tester.SetupSet(lab, tester_genome); err != nil {
+		panic(err)
+	}
+	tester.PrecomputeTesting(len(aliceCiphers) - 2)
+	publishedSetParams, _ := tester.CCS08SetParams.MarshalBinary()
+	timecheck := time.Since(timestart)
+	fmt.Println("Tester offline phase is done")
+	fmt.Fprintln(w, timecheck.Microseconds())
+
+	/* Online Phase */
+	timestart = time.Now()
+	startIndex, endIndex := env.ComputeBoundaryIndicesWRTRange(positions, tester.RangeStart, tester.RangeEnd)
+
+	startIndexm1 := startIndex - 1
+	if startIndexm1 < 0 {
+		startIndexm1 = 0
+	}
+	endIndexp1 := endIndex + 1
+	if endIndexp1 > uint32(len(aliceSigs)) {
+		endIndexp1 = uint32(len(aliceSigs)) - 1
+	}
+	endIndexp2 := endIndex + 2
+	if endIndexp2 > uint32(len(aliceCiphers)) {
+		endIndexp2 = uint32(len(aliceCiphers)) - 1
+	}
+
+	slicedCipher := aliceCiphers[startIndexm1:endIndexp2]
+	slicedComm := commitments[startIndexm1:endIndexp2]
+	slicedSig := aliceSigs[startIndexm1:endIndexp1]
+	if alicePolicy != nil && !allowed(lab, alicePolicy, tester, len(slicedCipher)) {
+		return false
+	}
+
+	// load the parameters published by the tester
+	var params ccs08.CCS08SetParams
+	if err := params.UnmarshalBinary(publishedSetParams); err != nil {
+		fmt.Println("CCS08 set parameters are invalid, so ABORT!")
+		return false
+	}
+	h := lab.BPparams.H
+
+	// generate the proofs of the boundaries
+	intervalsA, intervalsB := tester.RangeProofIntervals()
+	var proofs t.SetQueryProofs
+	lproof, err_l := ccs08.ProveInterval(big.NewInt(int64(positions[startIndexm1])), salts[startIndexm1], h, intervalsA[0], intervalsB[0], &params)
+	hproof, err_h := ccs08.ProveInterval(big.NewInt(int64(positions[endIndexp1])), salts[endIndexp1], h, intervalsA[1], intervalsB[1], &params)
+	if err_l != nil || err_h != nil {
+		fmt.Println("Boundary positions are not outside of the panel, so ABORT!")
+		return false
+	}
+	proofs.Lower, _ = lproof.MarshalBinary()
+	proofs.Upper, _ = hproof.MarshalBinary()
+
+	// generate the membership or gap proof of each revealed position
+	var wg sync.WaitGroup
+	n := len(slicedCipher) - 2
+	proofs.Members = make([]bool, n)
+	proofs.Interior = make([][]byte, n)
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func(i int, wg *sync.WaitGroup) {
+			k := int(startIndexm1) + i + 1
+			position := big.NewInt(int64(positions[k]))
+			if _, _, inGap := params.Gap(int64(positions[k])); inGap {
+				proof, _ := ccs08.ProveGap(position, salts[k], h, &params)
+				proofs.Interior[i], _ = proof.MarshalBinary()
+			} else {
+				proof, _ := ccs08.ProveMembership(position, salts[k], h, &params)
+				proofs.Members[i] = true
+				proofs.Interior[i], _ = proof.MarshalBinary()
 			}
+			wg.Done()
+		}(i, &wg)
+	}
+	wg.Wait()
+
+	timecheck = time.Since(timestart)
+	fmt.Println("Alice preprocessing in online phase is done")
+	fmt.Fprintln(w, timecheck.Microseconds())
+
+	timestart = time.Now()
+	resultCipherArray := tester.TestingSNPSet(slicedComm, slicedCipher, slicedSig, &proofs, withOpt)
+	timecheck = time.Since(timestart)
+	fmt.Println("Tester online phase is done")
+	fmt.Fprintln(w, timecheck.Microseconds())
+
+	return verdict(w, lab, resultCipherArray)
+
+}
+
+// MainRanges runs FES-SPH-PSM for a query over several disjoint regions, one marker per region (see Tester.SetupRanges).
+// Alice reveals one slice per range, as in Main, and proves the boundaries of all of them with aggregated Bulletproofs,
+// or with a pair of CCS08 proofs per range. She learns whether any of the markers matches, but not which one.
+func MainRanges(w *bufio.Writer, lab *sl.SequencingLab, tester *t.Tester, alice_genome []*env.Base, tester_genomes [][]*env.Base, secParam uint32, withOpt bool, rangeProof int, alicePolicy *policy.Policy) bool {
+	// rangeProof - 0: BulletProofs, 1: CCS08
+	// alicePolicy - if not nil, Alice answers only a query that it allows
+
+	/* Offline Phase */
+	positions, aliceCiphers, salts, aliceSigs, commitments := commitAndSign(w, lab, alice_genome)
+
+	timestart := time.Now()
+	if err := tester.SetupRanges(lab, tester_genomes, secParam); err != nil {
+		panic(err)
+	}
+	tester.PrecomputeTesting(len(aliceCiphers) - 2)
+	var publishedCCS08Params []byte
+	if rangeProof == 1 { // the setup ceremony, run once by the tester
+		if err := tester.SetupCCS08(lab); err != nil {
+			panic(err)
 		}
-		timecheck = time.Since(timestart)
-		fmt.Println("Alice postprocessing in online phase is done")
-		fmt.Fprintln(w, timecheck.Microseconds())
+		publishedCCS08Params, _ = tester.CCS08Params.MarshalBinary()
+	}
+	timecheck := time.Since(timestart)
+	fmt.Println("Tester offline phase is done")
+	fmt.Fprintln(w, timecheck.Microseconds())
+
+	/* Online Phase */
+	timestart = time.Now()
+	slices, proofs, lproofData, hproofData, err := proveRanges(lab, tester, positions, aliceCiphers, commitments, salts, aliceSigs, rangeProof, publishedCCS08Params)
+	if err != nil {
+		fmt.Println(err)
+		return false
+	}
+	if alicePolicy != nil && !allowed(lab, alicePolicy, tester, numOfCiphers(slices)) {
 		return false
+	}
+	timecheck = time.Since(timestart)
+	fmt.Println("Alice preprocessing in online phase is done")
+	fmt.Fprintln(w, timecheck.Microseconds())
 
+	timestart = time.Now()
+	var resultCipherArray []*env.Cipher
+	if rangeProof == 0 {
+		resultCipherArray = tester.TestingSNPRanges(slices, proofs, withOpt)
 	} else {
+		resultCipherArray = tester.TestingSNPRangesCCS08(slices, lproofData, hproofData, withOpt)
+	}
+	timecheck = time.Since(timestart)
+	fmt.Println("Tester online phase is done")
+	fmt.Fprintln(w, timecheck.Microseconds())
 
-		fmt.Println("rp should be 0 (bulletproofs) or 1 (ccs08).")
+	return verdict(w, lab, resultCipherArray)
+
+}
+
+// MainPanel runs FES-SPH-PSM for a panel of named markers in one session (see Tester.SetupPanel): Alice reveals one slice
+// per merged range, and the tester verifies it once and tests every marker of the range against it. It returns whether
+// any marker matches and, if the panel asks for verdicts per marker and Alice's policy allows them, the verdict of each
+// marker by name.
+func MainPanel(w *bufio.Writer, lab *sl.SequencingLab, tester *t.Tester, alice_genome []*env.Base, panel *t.Panel, secParam uint32, withOpt bool, rangeProof int, alicePolicy *policy.Policy) (bool, map[string]bool) {
+	// rangeProof - 0: BulletProofs, 1: CCS08
+	// alicePolicy - if not nil, Alice answers only a query that it allows; verdicts per marker need one that allows them
+
+	/* Offline Phase */
+	positions, aliceCiphers, salts, aliceSigs, commitments := commitAndSign(w, lab, alice_genome)
 
+	timestart := time.Now()
+	if err := tester.SetupPanel(lab, panel, secParam); err != nil {
+		panic(err)
+	}
+	tester.PrecomputeTesting(len(panel.Markers) * (len(aliceCiphers) - 2))
+	var publishedCCS08Params []byte
+	if rangeProof == 1 { // the setup ceremony, run once by the tester
+		if err := tester.SetupCCS08(lab); err != nil {
+			panic(err)
+		}
+		publishedCCS08Params, _ = tester.CCS08Params.MarshalBinary()
 	}
+	timecheck := time.Since(timestart)
+	fmt.Println("Tester offline phase is done")
+	fmt.Fprintln(w, timecheck.Microseconds())
 
-	return false
+	/* Online Phase */
+	timestart = time.Now()
+	slices, proofs, lproofData, hproofData, err := proveRanges(lab, tester, positions, aliceCiphers, commitments, salts, aliceSigs, rangeProof, publishedCCS08Params)
+	if err != nil {
+		fmt.Println(err)
+		return false, nil
+	}
+	if tester.PerMarker && alicePolicy == nil {
+		fmt.Println("verdicts per marker are not allowed by a policy of Alice, so ABORT!")
+		return false, nil
+	}
+	if alicePolicy != nil && !allowed(lab, alicePolicy, tester, numOfCiphers(slices)) {
+		return false, nil
+	}
+	timecheck = time.Since(timestart)
+	fmt.Println("Alice preprocessing in online phase is done")
+	fmt.Fprintln(w, timecheck.Microseconds())
+
+	timestart = time.Now()
+	var results []*t.PanelResult
+	if rangeProof == 0 {
+		results = tester.TestingSNPPanel(slices, proofs, withOpt)
+	} else {
+		results = tester.TestingSNPPanelCCS08(slices, lproofData, hproofData, withOpt)
+	}
+	timecheck = time.Since(timestart)
+	fmt.Println("Tester online phase is done")
+	fmt.Fprintln(w, timecheck.Microseconds())
+
+	// a verdict per named result; the combined result of a panel without verdicts per marker has no name
+	timestart = time.Now()
+	match := false
+	var verdicts map[string]bool
+	for _, result := range results {
+		matched, err := matches(lab, result.Results)
+		if err != nil {
+			fmt.Println("result of the tester is invalid (", err, "), so ABORT!")
+			return false, nil
+		}
+		match = match || matched
+		if result.Name != "" {
+			if verdicts == nil {
+				verdicts = make(map[string]bool)
+			}
+			verdicts[result.Name] = matched
+		}
+	}
+	timecheck = time.Since(timestart)
+	fmt.Println("Alice postprocessing in online phase is done")
+	fmt.Fprintln(w, timecheck.Microseconds())
+	return match, verdicts
+
+}
+
+// MainLogic runs FES-SPH-PSM for a boolean expression over the markers of a panel (see Tester.SetupLogic): Alice reveals
+// one slice per marker, and learns only whether the expression holds.
+func MainLogic(w *bufio.Writer, lab *sl.SequencingLab, tester *t.Tester, alice_genome []*env.Base, panel *t.Panel, expression string, rangeProof int, alicePolicy *policy.Policy) bool {
+	// rangeProof - 0: BulletProofs, 1: CCS08
+	// alicePolicy - if not nil, Alice answers only a query that it allows
+
+	/* Offline Phase */
+	positions, aliceCiphers, salts, aliceSigs, commitments := commitAndSign(w, lab, alice_genome)
+
+	timestart := time.Now()
+	if err := tester.SetupLogic(lab, panel, expression); err != nil {
+		panic(err)
+	}
+	var publishedCCS08Params []byte
+	if rangeProof == 1 { // the setup ceremony, run once by the tester
+		if err := tester.SetupCCS08(lab); err != nil {
+			panic(err)
+		}
+		publishedCCS08Params, _ = tester.CCS08Params.MarshalBinary()
+	}
+	timecheck := time.Since(timestart)
+	fmt.Println("Tester offline phase is done")
+	fmt.Fprintln(w, timecheck.Microseconds())
+
+	/* Online Phase */
+	timestart = time.Now()
+	if !t.BasesInAlphabet(alice_genome, tester.Ranges) {
+		fmt.Println("A base of Alice in the ranges is not in the alphabet, so ABORT!")
+		return false
+	}
+	slices, proofs, lproofData, hproofData, err := proveRanges(lab, tester, positions, aliceCiphers, commitments, salts, aliceSigs, rangeProof, publishedCCS08Params)
+	if err != nil {
+		fmt.Println(err)
+		return false
+	}
+	if alicePolicy != nil && !allowed(lab, alicePolicy, tester, numOfCiphers(slices)) {
+		return false
+	}
+	timecheck = time.Since(timestart)
+	fmt.Println("Alice preprocessing in online phase is done")
+	fmt.Fprintln(w, timecheck.Microseconds())
+
+	timestart = time.Now()
+	var resultCipherArray []*env.Cipher
+	if rangeProof == 0 {
+		resultCipherArray = tester.TestingSNPLogic(slices, proofs)
+	} else {
+		resultCipherArray = tester.TestingSNPLogicCCS08(slices, lproofData, hproofData)
+	}
+	timecheck = time.Since(timestart)
+	fmt.Println("Tester online phase is done")
+	fmt.Fprintln(w, timecheck.Microseconds())
+
+	return verdict(w, lab, resultCipherArray)
+
+}
+
+// commitAndSign is the offline phase of the lab and of Alice: the lab sequences Alice's genome and signs the tuples of
+// her variants, and Alice commits to their positions with the salts of the tuples, for her proofs of the positions.
+func commitAndSign(w *bufio.Writer, lab *sl.SequencingLab, alice_genome []*env.Base) ([]uint32, []*env.Cipher, []*big.Int, []*env.ECDSASignature, []*p256.P256) {
+
+	var wg sync.WaitGroup
+
+	timestart := time.Now()
+	positions, aliceCiphers, salts, aliceSigs := lab.SequenceSNPSetRange(alice_genome)
+	timecheck := time.Since(timestart)
+	fmt.Println("SL offline phase is done")
+	fmt.Fprintln(w, timecheck.Microseconds())
+
+	timestart = time.Now()
+	commitments := make([]*p256.P256, len(positions))
+	wg.Add(len(positions))
+	for i := uint32(0); i < uint32(len(positions)); i++ {
+		go func(i uint32, wg *sync.WaitGroup) {
+			commitments[i], _ = lab.CommitTables.CommitG1(big.NewInt(int64(positions[i])), salts[i])
+			wg.Done()
+		}(i, &wg)
+	}
+	wg.Wait()
+	timecheck = time.Since(timestart)
+	fmt.Println("Alice offline phase is done")
+	fmt.Fprintln(w, timecheck.Microseconds())
+	return positions, aliceCiphers, salts, aliceSigs, commitments
+
+}
+
+// allowed is Alice's check of the query of the tester against her policy (see t.CheckQuery), with the signature that the
+// tester sends with it, for the given number of her ciphertexts. The results of FES-SPH-PSM depend only on her variants
+// in the queried ranges, since she proves their boundaries.
+func allowed(lab *sl.SequencingLab, alicePolicy *policy.Policy, tester *t.Tester, variants int) bool {
+
+	querySig, err := tester.SignQuery() // the tester sends its query signed, Alice only verifies it
+	if err != nil && err != t.ErrNoIdentity {
+		fmt.Println("tester cannot sign its query (", err, "), so ABORT!")
+		return false
+	}
+	return t.CheckQuery(lab, alicePolicy, tester, querySig, variants, true)
+
+}
+
+// verdict is Alice's postprocessing of the results of the tester: whether one of them is an encryption of 0. Results that
+// she cannot test abort the protocol, with no match.
+func verdict(w *bufio.Writer, lab *sl.SequencingLab, results []*env.Cipher) bool {
+
+	timestart := time.Now()
+	match, err := matches(lab, results)
+	if err != nil {
+		fmt.Println("result of the tester is invalid (", err, "), so ABORT!")
+		return false
+	}
+	timecheck := time.Since(timestart)
+	fmt.Println("Alice postprocessing in online phase is done")
+	fmt.Fprintln(w, timecheck.Microseconds())
+	return match
+
+}
+
+// matches returns whether one of the results is an encryption of 0, testing them in order up to the first one.
+func matches(lab *sl.SequencingLab, results []*env.Cipher) (bool, error) {
+
+	for _, result := range results {
+		isZero, err := lab.Ahe.IsZero(result)
+		if err != nil {
+			return false, err
+		}
+		if isZero {
+			return true, nil
+		}
+	}
+	return false, nil
+
+}
+
+// proveRanges returns Alice's slices for the ranges of the tester, with the proofs of their boundaries: one aggregated
+// Bulletproof for every RangesPerProof ranges if rangeProof is 0, and a pair of CCS08 interval proofs per range, with the
+// parameters published by the tester, if it is 1. Both are made with the salts of the boundaries, so that they prove the
+// positions of the signed commitments of the slices.
+func proveRanges(lab *sl.SequencingLab, tester *t.Tester, positions []uint32, ciphers []*env.Cipher, comms []*p256.P256, salts []*big.Int, sigs []*env.ECDSASignature, rangeProof int, publishedCCS08Params []byte) ([]*t.RangeSlice, []*bp.AggregatedBulletProof, [][]byte, [][]byte, error) {
+
+	slices := make([]*t.RangeSlice, len(tester.Ranges))
+	lower := make([]*big.Int, len(tester.Ranges))
+	upper := make([]*big.Int, len(tester.Ranges))
+	lowerSalts := make([]*big.Int, len(tester.Ranges))
+	upperSalts := make([]*big.Int, len(tester.Ranges))
+	for j, query := range tester.Ranges {
+		var startIndexm1, endIndexp1 uint32
+		slices[j], startIndexm1, endIndexp1 = sliceForRange(positions, ciphers, comms, sigs, query.RangeStart, query.RangeEnd)
+		lower[j] = big.NewInt(int64(positions[startIndexm1]))
+		upper[j] = big.NewInt(int64(positions[endIndexp1]))
+		lowerSalts[j], upperSalts[j] = salts[startIndexm1], salts[endIndexp1]
+	}
+
+	if rangeProof == 0 { // bulletproof
+
+		// generate one proof for the boundaries of every RangesPerProof ranges
+		proofs := make([]*bp.AggregatedBulletProof, tester.NumOfRangeProofs())
+		for k := range proofs {
+			intervalsA, intervalsB := tester.RangesProofIntervals(k)
+			var secrets, randomness []*big.Int
+			for j := k * t.RangesPerProof; j < (k+1)*t.RangesPerProof && j < len(slices); j++ {
+				secrets = append(secrets, lower[j], upper[j])
+				randomness = append(randomness, lowerSalts[j], upperSalts[j])
+			}
+			params, err := bp.NewGenericAggregated(intervalsA, intervalsB, lab.BPparams)
+			if err != nil {
+				return nil, nil, nil, nil, err
+			}
+			proof, err := bp.ProveGenericAggregatedCommitted(secrets, randomness, params)
+			if err != nil {
+				return nil, nil, nil, nil, err
+			}
+			proofs[k] = &proof
+		}
+		return slices, proofs, nil, nil, nil
+
+	} else if rangeProof == 1 { // ccs08
+
+		// load the parameters published by the tester
+		var params ccs08.CCS08Params
+		if err := params.UnmarshalBinary(publishedCCS08Params); err != nil {
+			return nil, nil, nil, nil, errors.New("CCS08 parameters are invalid, so ABORT!")
+		}
+
+		// generate the lower and upper bound proofs of each range
+		intervals := params.Intervals()
+		h := lab.BPparams.H
+		lproofData := make([][]byte, len(slices))
+		hproofData := make([][]byte, len(slices))
+		for k := 0; k < tester.NumOfRangeProofs(); k++ {
+			intervalsA, intervalsB := tester.RangesProofIntervals(k)
+			for i := 0; i < len(intervalsA)/2; i++ {
+				j := k*t.RangesPerProof + i
+				lproof, err_l := ccs08.ProveInterval(lower[j], lowerSalts[j], h, intervalsA[2*i], intervalsB[2*i], intervals)
+				hproof, err_h := ccs08.ProveInterval(upper[j], upperSalts[j], h, intervalsA[2*i+1], intervalsB[2*i+1], intervals)
+				if err_l != nil || err_h != nil {
+					return nil, nil, nil, nil, errors.New("Boundary positions are not outside of the ranges, so ABORT!")
+				}
+				lproofData[j], _ = lproof.MarshalBinary()
+				hproofData[j], _ = hproof.MarshalBinary()
+			}
+		}
+		return slices, nil, lproofData, hproofData, nil
+
+	}
+	return nil, nil, nil, nil, errors.New("rp should be 0 (bulletproofs) or 1 (ccs08).")
+
+}
+
+// numOfCiphers returns the number of Alice's ciphertexts in the slices, with their boundaries.
+func numOfCiphers(slices []*t.RangeSlice) int {
+
+	n := 0
+	for _, slice := range slices {
+		n += len(slice.Cipher)
+	}
+	return n
+
+}
+
+// sliceForRange returns Alice's slice for the range, as Main computes it, with the indices of its lower and upper
+// boundary positions.
+func sliceForRange(positions []uint32, ciphers []*env.Cipher, comms []*p256.P256, sigs []*env.ECDSASignature, rangeStart, rangeEnd uint32) (*t.RangeSlice, uint32, uint32) {
+
+	startIndex, endIndex := env.ComputeBoundaryIndicesWRTRange(positions, rangeStart, rangeEnd)
+
+	// the sentinel positions 0 and N+1 of the lab are outside of every range
+	startIndexm1 := startIndex - 1
+	endIndexp1 := endIndex + 1
+
+	slice := &t.RangeSlice{
+		Comm:   comms[startIndexm1 : endIndexp1+1],
+		Cipher: ciphers[startIndexm1 : endIndexp1+1],
+		Sig:    sigs[startIndexm1:endIndexp1],
+	}
+	return slice, startIndexm1, endIndexp1
 
 }
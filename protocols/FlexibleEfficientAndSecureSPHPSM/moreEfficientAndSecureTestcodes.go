@@ -4,13 +4,17 @@ package FlexibleEfficientAndSecureSPHPSM
 
 import (
 	"bufio"
+	"crypto/ecdsa"
 	"fmt"
 	"log"
 
+	"github.com/eozturk1/genomic-security-journal-code/entities/policy"
+	"github.com/eozturk1/genomic-security-journal-code/entities/regulator"
 	sl "github.com/eozturk1/genomic-security-journal-code/entities/sequencinglab"
 	t "github.com/eozturk1/genomic-security-journal-code/entities/tester"
 	ahe "github.com/eozturk1/genomic-security-journal-code/helpers/addhomencer"
 	env "github.com/eozturk1/genomic-security-journal-code/helpers/env"
+	"github.com/eozturk1/genomic-security-journal-code/helpers/profiles"
 )
 
 func TestElGamalExactMatching(w *bufio.Writer, fileA, fileTm string, secParam uint32, withOpt bool, rp int) {
@@ -19,15 +23,15 @@ func TestElGamalExactMatching(w *bufio.Writer, fileA, fileTm string, secParam ui
 	tester_genome := env.ReadGenomeFromFile(fileTm)
 
 	scheme := ahe.AHElGamal{}
-	scheme.Setup()
+	scheme.SetupProfile(profiles.Default)
 
 	lab := sl.SequencingLab{}
-	lab.Setup(&scheme)
+	lab.Setup(&scheme, profiles.Default)
 
 	tester := t.Tester{}
 
 	fmt.Println("fes protocol, matching test with ElGamal starts!")
-	result := Main(w, &lab, &tester, alice_genome, tester_genome, secParam, withOpt, rp)
+	result := Main(w, &lab, &tester, alice_genome, tester_genome, secParam, withOpt, rp, t.PerWindow, nil)
 	if !result {
 		log.Fatal("Exact matching test: Failed\n")
 	}
@@ -40,15 +44,15 @@ func TestElGamalNoMatching(w *bufio.Writer, fileA, fileTnm string, secParam uint
 	tester_genome := env.ReadGenomeFromFile(fileTnm)
 
 	scheme := ahe.AHElGamal{}
-	scheme.Setup()
+	scheme.SetupProfile(profiles.Default)
 
 	lab := sl.SequencingLab{}
-	lab.Setup(&scheme)
+	lab.Setup(&scheme, profiles.Default)
 
 	tester := t.Tester{}
 
 	fmt.Println("fes protocol, no matching test with ElGamal starts!")
-	result := Main(w, &lab, &tester, alice_genome, tester_genome, secParam, withOpt, rp)
+	result := Main(w, &lab, &tester, alice_genome, tester_genome, secParam, withOpt, rp, t.PerWindow, nil)
 	if result {
 		log.Fatal("No matching test: Failed\n")
 	}
@@ -64,15 +68,15 @@ func TestPaillierExactMatching(w *bufio.Writer, fileA, fileTm string, secParam u
 	tester_genome := env.ReadGenomeFromFile(fileTm)
 
 	scheme := ahe.GoGoGadgetPaillier{}
-	scheme.Setup()
+	scheme.SetupProfile(profiles.Default)
 
 	lab := sl.SequencingLab{}
-	lab.Setup(&scheme)
+	lab.Setup(&scheme, profiles.Default)
 
 	tester := t.Tester{}
 
 	fmt.Println("fes protocol, matching test with Paillier starts!")
-	result := Main(w, &lab, &tester, alice_genome, tester_genome, secParam, withOpt, rp)
+	result := Main(w, &lab, &tester, alice_genome, tester_genome, secParam, withOpt, rp, t.PerWindow, nil)
 	if !result {
 		log.Fatal("Exact matching test: Failed\n")
 	}
@@ -86,18 +90,376 @@ func TestPaillierNoMatching(w *bufio.Writer, fileA, fileTnm string, secParam uin
 	tester_genome := env.ReadGenomeFromFile(fileTnm)
 
 	scheme := ahe.GoGoGadgetPaillier{}
-	scheme.Setup()
+	scheme.SetupProfile(profiles.Default)
 
 	lab := sl.SequencingLab{}
-	lab.Setup(&scheme)
+	lab.Setup(&scheme, profiles.Default)
 
 	tester := t.Tester{}
 
 	fmt.Println("fes protocol, no matching test with Paillier starts!")
-	result := Main(w, &lab, &tester, alice_genome, tester_genome, secParam, withOpt, rp)
+	result := Main(w, &lab, &tester, alice_genome, tester_genome, secParam, withOpt, rp, t.PerWindow, nil)
 	if result {
 		log.Fatal("No matching test: Failed\n")
 	}
 	fmt.Println("fes protocol, no matching test with Paillier finished!")
 
 }
+
+//---------------
+
+func TestDamgardJurikExactMatching(w *bufio.Writer, fileA, fileTm string, secParam uint32, withOpt bool, rp int, s int) {
+
+	alice_genome := env.ReadGenomeFromFile(fileA)
+	tester_genome := env.ReadGenomeFromFile(fileTm)
+
+	scheme := ahe.DamgardJurik{S: s}
+	scheme.SetupProfile(profiles.Default)
+
+	lab := sl.SequencingLab{}
+	lab.Setup(&scheme, profiles.Default)
+
+	tester := t.Tester{}
+
+	fmt.Println("fes protocol, matching test with Damgard-Jurik starts!")
+	result := Main(w, &lab, &tester, alice_genome, tester_genome, secParam, withOpt, rp, t.PerWindow, nil)
+	if !result {
+		log.Fatal("Exact matching test: Failed\n")
+	}
+	fmt.Println("fes protocol, matching test with Damgard-Jurik finished!")
+
+}
+
+func TestDamgardJurikNoMatching(w *bufio.Writer, fileA, fileTnm string, secParam uint32, withOpt bool, rp int, s int) {
+
+	alice_genome := env.ReadGenomeFromFile(fileA)
+	tester_genome := env.ReadGenomeFromFile(fileTnm)
+
+	scheme := ahe.DamgardJurik{S: s}
+	scheme.SetupProfile(profiles.Default)
+
+	lab := sl.SequencingLab{}
+	lab.Setup(&scheme, profiles.Default)
+
+	tester := t.Tester{}
+
+	fmt.Println("fes protocol, no matching test with Damgard-Jurik starts!")
+	result := Main(w, &lab, &tester, alice_genome, tester_genome, secParam, withOpt, rp, t.PerWindow, nil)
+	if result {
+		log.Fatal("No matching test: Failed\n")
+	}
+	fmt.Println("fes protocol, no matching test with Damgard-Jurik finished!")
+
+}
+
+//---------------
+
+func TestElGamalSetMatching(w *bufio.Writer, fileA, fileTm string, withOpt bool) {
+
+	alice_genome := env.ReadGenomeFromFile(fileA)
+	tester_genome := env.ReadGenomeFromFile(fileTm)
+
+	scheme := ahe.AHElGamal{}
+	scheme.SetupProfile(profiles.Default)
+
+	lab := sl.SequencingLab{}
+	lab.Setup(&scheme, profiles.Default)
+
+	tester := t.Tester{}
+
+	fmt.Println("fes protocol, set matching test with ElGamal starts!")
+	result := MainSet(w, &lab, &tester, alice_genome, tester_genome, withOpt, nil)
+	if !result {
+		log.Fatal("Set matching test: Failed\n")
+	}
+	fmt.Println("fes protocol, set matching test with ElGamal finished!")
+}
+
+func TestElGamalSetNoMatching(w *bufio.Writer, fileA, fileTnm string, withOpt bool) {
+
+	alice_genome := env.ReadGenomeFromFile(fileA)
+	tester_genome := env.ReadGenomeFromFile(fileTnm)
+
+	scheme := ahe.AHElGamal{}
+	scheme.SetupProfile(profiles.Default)
+
+	lab := sl.SequencingLab{}
+	lab.Setup(&scheme, profiles.Default)
+
+	tester := t.Tester{}
+
+	fmt.Println("fes protocol, set no matching test with ElGamal starts!")
+	result := MainSet(w, &lab, &tester, alice_genome, tester_genome, withOpt, nil)
+	if result {
+		log.Fatal("Set no matching test: Failed\n")
+	}
+	fmt.Println("fes protocol, set no matching test with ElGamal finished!")
+
+}
+
+//---------------
+
+func TestElGamalRangesMatching(w *bufio.Writer, fileA string, filesTm []string, secParam uint32, withOpt bool, rp int) {
+
+	alice_genome := env.ReadGenomeFromFile(fileA)
+	tester_genomes := make([][]*env.Base, len(filesTm))
+	for j, fileTm := range filesTm {
+		tester_genomes[j] = env.ReadGenomeFromFile(fileTm)
+	}
+
+	scheme := ahe.AHElGamal{}
+	scheme.SetupProfile(profiles.Default)
+
+	lab := sl.SequencingLab{}
+	lab.Setup(&scheme, profiles.Default)
+
+	tester := t.Tester{}
+
+	fmt.Println("fes protocol, multi-range matching test with ElGamal starts!")
+	result := MainRanges(w, &lab, &tester, alice_genome, tester_genomes, secParam, withOpt, rp, nil)
+	if !result {
+		log.Fatal("Multi-range matching test: Failed\n")
+	}
+	fmt.Println("fes protocol, multi-range matching test with ElGamal finished!")
+}
+
+func TestElGamalRangesNoMatching(w *bufio.Writer, fileA string, filesTnm []string, secParam uint32, withOpt bool, rp int) {
+
+	alice_genome := env.ReadGenomeFromFile(fileA)
+	tester_genomes := make([][]*env.Base, len(filesTnm))
+	for j, fileTnm := range filesTnm {
+		tester_genomes[j] = env.ReadGenomeFromFile(fileTnm)
+	}
+
+	scheme := ahe.AHElGamal{}
+	scheme.SetupProfile(profiles.Default)
+
+	lab := sl.SequencingLab{}
+	lab.Setup(&scheme, profiles.Default)
+
+	tester := t.Tester{}
+
+	fmt.Println("fes protocol, multi-range no matching test with ElGamal starts!")
+	result := MainRanges(w, &lab, &tester, alice_genome, tester_genomes, secParam, withOpt, rp, nil)
+	if result {
+		log.Fatal("Multi-range no matching test: Failed\n")
+	}
+	fmt.Println("fes protocol, multi-range no matching test with ElGamal finished!")
+
+}
+
+//---------------
+
+// TestElGamalPanel tests a panel of the markers of filesTm, which match, and of filesTnm, which do not, named after their
+// files: once with a verdict per marker, under a policy of Alice that allows them, and once with the combined verdict
+// only. A query for verdicts per marker under a policy that does not allow them is refused.
+func TestElGamalPanel(w *bufio.Writer, fileA string, filesTm, filesTnm []string, secParam uint32, withOpt bool, rp int) {
+
+	alice_genome := env.ReadGenomeFromFile(fileA)
+	panel := &t.Panel{}
+	expected := make(map[string]bool)
+	for _, files := range []struct {
+		names []string
+		match bool
+	}{{filesTm, true}, {filesTnm, false}} {
+		for _, fileT := range files.names {
+			panel.Markers = append(panel.Markers, &t.Marker{Name: fileT, Bases: env.ReadGenomeFromFile(fileT)})
+			expected[fileT] = files.match
+		}
+	}
+
+	scheme := ahe.AHElGamal{}
+	scheme.SetupProfile(profiles.Default)
+
+	lab := sl.SequencingLab{}
+	lab.Setup(&scheme, profiles.Default)
+
+	fmt.Println("fes protocol, panel test with ElGamal starts!")
+	panel.PerMarker = true
+	tester := t.Tester{}
+	if match, verdicts := MainPanel(w, &lab, &tester, alice_genome, panel, secParam, withOpt, rp, &policy.Policy{}); match || verdicts != nil {
+		log.Fatal("Panel test: verdicts per marker against the policy\n")
+	}
+	for _, perMarker := range []bool{true, false} {
+		panel.PerMarker = perMarker
+		tester := t.Tester{}
+		match, verdicts := MainPanel(w, &lab, &tester, alice_genome, panel, secParam, withOpt, rp, &policy.Policy{AllowPerMarker: perMarker})
+		if match != (len(filesTm) > 0) {
+			log.Fatal("Panel test: Failed\n")
+		}
+		if perMarker != (verdicts != nil) {
+			log.Fatal("Panel test: verdicts per marker against the policy\n")
+		}
+		for name, verdict := range verdicts {
+			if verdict != expected[name] {
+				log.Fatal("Panel test: Failed for marker ", name, "\n")
+			}
+		}
+	}
+	fmt.Println("fes protocol, panel test with ElGamal finished!")
+
+}
+
+//---------------
+
+// TestElGamalLogic tests the expression over a panel of the markers of filesT, named after their files.
+func TestElGamalLogic(w *bufio.Writer, fileA string, filesT []string, expression string, expected bool, rp int) {
+
+	alice_genome := env.ReadGenomeFromFile(fileA)
+	panel := &t.Panel{}
+	for _, fileT := range filesT {
+		panel.Markers = append(panel.Markers, &t.Marker{Name: fileT, Bases: env.ReadGenomeFromFile(fileT)})
+	}
+
+	scheme := ahe.AHElGamal{}
+	scheme.SetupProfile(profiles.Default)
+
+	lab := sl.SequencingLab{}
+	lab.Setup(&scheme, profiles.Default)
+
+	tester := t.Tester{}
+
+	fmt.Println("fes protocol, logic test with ElGamal starts!")
+	result := MainLogic(w, &lab, &tester, alice_genome, panel, expression, rp, nil)
+	if result != expected {
+		log.Fatal("Logic test: Failed for ", expression, "\n")
+	}
+	fmt.Println("fes protocol, logic test with ElGamal finished!")
+
+}
+
+func TestDamgardJurikSingleBit(w *bufio.Writer, fileA, fileT string, secParam uint32, withOpt bool, rp int, s int, expected bool) {
+
+	alice_genome := env.ReadGenomeFromFile(fileA)
+	tester_genome := env.ReadGenomeFromFile(fileT)
+
+	scheme := ahe.DamgardJurik{S: s}
+	scheme.SetupProfile(profiles.Default)
+
+	lab := sl.SequencingLab{}
+	lab.Setup(&scheme, profiles.Default)
+
+	tester := t.Tester{}
+
+	fmt.Println("fes protocol, single-bit test with Damgard-Jurik starts!")
+	result := Main(w, &lab, &tester, alice_genome, tester_genome, secParam, withOpt, rp, t.SingleBit, nil)
+	if result != expected {
+		log.Fatal("Single-bit test: Failed\n")
+	}
+	fmt.Println("fes protocol, single-bit test with Damgard-Jurik finished!")
+
+}
+
+func TestElGamalTesterLearns(w *bufio.Writer, fileA, fileT string, secParam uint32, withOpt bool, rp int, expected bool) {
+
+	alice_genome := env.ReadGenomeFromFile(fileA)
+	tester_genome := env.ReadGenomeFromFile(fileT)
+
+	scheme := ahe.AHElGamal{}
+	scheme.SetupProfile(profiles.Default)
+
+	lab := sl.SequencingLab{}
+	lab.Setup(&scheme, profiles.Default)
+
+	tester := t.Tester{}
+
+	fmt.Println("fes protocol, tester-learns test with ElGamal starts!")
+	result := Main(w, &lab, &tester, alice_genome, tester_genome, secParam, withOpt, rp, t.TesterLearns, nil)
+	if result != expected {
+		log.Fatal("Tester-learns test: Failed\n")
+	}
+	fmt.Println("fes protocol, tester-learns test with ElGamal finished!")
+
+}
+
+func TestElGamalWellFormed(w *bufio.Writer, fileA, fileT string, secParam uint32, withOpt bool, rp int, expected bool) {
+
+	alice_genome := env.ReadGenomeFromFile(fileA)
+	tester_genome := env.ReadGenomeFromFile(fileT)
+
+	scheme := ahe.AHElGamal{}
+	scheme.SetupProfile(profiles.Default)
+
+	lab := sl.SequencingLab{}
+	lab.Setup(&scheme, profiles.Default)
+
+	tester := t.Tester{}
+
+	fmt.Println("fes protocol, well-formed test with ElGamal starts!")
+	result := Main(w, &lab, &tester, alice_genome, tester_genome, secParam, withOpt, rp, t.WellFormed, nil)
+	if result != expected {
+		log.Fatal("Well-formed test: Failed\n")
+	}
+	fmt.Println("fes protocol, well-formed test with ElGamal finished!")
+
+}
+
+// TestElGamalCertified runs a test that Alice accepts only if the marker is certified by her regulator. If certified,
+// the regulator certifies the marker of the tester, and the results of the test are proven too.
+func TestElGamalCertified(w *bufio.Writer, fileA, fileT string, secParam uint32, withOpt bool, rp int, certified bool, expected bool) {
+
+	alice_genome := env.ReadGenomeFromFile(fileA)
+	tester_genome := env.ReadGenomeFromFile(fileT)
+
+	scheme := ahe.AHElGamal{}
+	scheme.SetupProfile(profiles.Default)
+
+	lab := sl.SequencingLab{}
+	lab.Setup(&scheme, profiles.Default)
+
+	reg := regulator.Regulator{}
+	reg.Setup(profiles.Default)
+
+	tester := t.Tester{}
+	if certified {
+		// the regulator approves the marker for the range that the tester queries
+		rangeStart, rangeEnd := tester_genome[0].Position, tester_genome[len(tester_genome)-1].Position+secParam
+		if rangeStart > secParam {
+			rangeStart -= secParam
+		} else {
+			rangeStart = 1
+		}
+		cert, openings, err := reg.Certify(&lab, tester_genome, rangeStart, rangeEnd, "clinical test")
+		if err != nil {
+			log.Fatal(err)
+		}
+		tester.SetCertificate(cert, openings)
+	}
+
+	fmt.Println("fes protocol, certified test with ElGamal starts!")
+	result := Main(w, &lab, &tester, alice_genome, tester_genome, secParam, withOpt, rp, t.WellFormed, &policy.Policy{Regulator: reg.VerifyingKey})
+	if result != expected {
+		log.Fatal("Certified test: Failed\n")
+	}
+	fmt.Println("fes protocol, certified test with ElGamal finished!")
+
+}
+
+// TestElGamalPolicy runs a test of the tester with the given ID and key, which Alice answers only if her policy allows
+// it, and prints the decision of the policy. A nil key leaves the query unsigned.
+func TestElGamalPolicy(w *bufio.Writer, fileA, fileT string, secParam uint32, withOpt bool, rp int, testerID string, testerKey *ecdsa.PrivateKey, alicePolicy *policy.Policy, expected bool) {
+
+	alice_genome := env.ReadGenomeFromFile(fileA)
+	tester_genome := env.ReadGenomeFromFile(fileT)
+
+	scheme := ahe.AHElGamal{}
+	scheme.SetupProfile(profiles.Default)
+
+	lab := sl.SequencingLab{}
+	lab.Setup(&scheme, profiles.Default)
+
+	tester := t.Tester{}
+	tester.SetIdentity(testerID, testerKey)
+
+	fmt.Println("fes protocol, policy test with ElGamal starts!")
+	result := Main(w, &lab, &tester, alice_genome, tester_genome, secParam, withOpt, rp, t.PerWindow, alicePolicy)
+	decisions := alicePolicy.Decisions()
+	decision := decisions[len(decisions)-1]
+	fmt.Printf("policy decision on %s (authenticated %v) %v (%d variants): allowed %v, %v\n", decision.Query.Tester,
+		decision.Authenticated, decision.Query.Ranges, decision.Query.Variants, decision.Allowed(), decision.Reason)
+	if result != expected {
+		log.Fatal("Policy test: Failed\n")
+	}
+	fmt.Println("fes protocol, policy test with ElGamal finished!")
+
+}
@@ -7,12 +7,16 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/eozturk1/genomic-security-journal-code/entities/policy"
 	sl "github.com/eozturk1/genomic-security-journal-code/entities/sequencinglab"
 	t "github.com/eozturk1/genomic-security-journal-code/entities/tester"
 	"github.com/eozturk1/genomic-security-journal-code/helpers/env"
 )
 
-func Main(w *bufio.Writer, lab *sl.SequencingLab, tester *t.Tester, alice_genome, tester_genome []*env.Base) bool {
+// Main runs the protocol. In t.TesterLearns mode, the tester learns the verdict of Alice too; the other flags of mode
+// are about the windows of a range, so they make no difference here. If alicePolicy is not nil, Alice answers only a
+// query that it allows: she sends her whole genome, so the results are not bound to the queried range.
+func Main(w *bufio.Writer, lab *sl.SequencingLab, tester *t.Tester, alice_genome, tester_genome []*env.Base, mode t.ResultMode, alicePolicy *policy.Policy) bool {
 
 	/* Offline Phase */
 	timestart := time.Now()
@@ -26,6 +30,16 @@ func Main(w *bufio.Writer, lab *sl.SequencingLab, tester *t.Tester, alice_genome
 	timecheck = time.Since(timestart)
 	fmt.Println("Tester offline phase is done")
 	fmt.Fprintln(w, timecheck.Microseconds())
+	if alicePolicy != nil {
+		querySig, err := tester.SignQuery() // the tester sends its query signed, Alice only verifies it
+		if err != nil && err != t.ErrNoIdentity {
+			fmt.Println("tester cannot sign its query (", err, "), so ABORT!")
+			return false
+		}
+		if !t.CheckQuery(lab, alicePolicy, tester, querySig, len(aliceCiphers), false) {
+			return false
+		}
+	}
 
 	/* Online Phase */
 	timestart = time.Now()
@@ -33,12 +47,80 @@ func Main(w *bufio.Writer, lab *sl.SequencingLab, tester *t.Tester, alice_genome
 	timecheck = time.Since(timestart)
 	fmt.Println("Tester online phase is done")
 	fmt.Fprintln(w, timecheck.Microseconds())
+	if resultCipher == nil {
+		return false
+	}
+
+	if mode&t.TesterLearns != 0 {
+		return t.VerdictForTester(w, lab, tester, []*env.Cipher{resultCipher})
+	}
+
+	timestart = time.Now()
+	testingResult, err := lab.Ahe.IsZero(resultCipher)
+	timecheck = time.Since(timestart)
+	fmt.Println("Alice online phase is done")
+	fmt.Fprintln(w, timecheck.Microseconds())
+	if err != nil {
+		fmt.Println("result of the tester is invalid (", err, "), so ABORT!")
+		return false
+	}
+
+	return testingResult
+
+}
+
+// MainPacked runs the protocol with packed blocks (see Tester.TestingWholePacked). The lab encrypts the genome in packed
+// blocks only, and, once the tester is set up, the positions of the blocks at both ends of the marker one by one (see
+// Tester.UnpackedBlocks); the time of both is the offline phase of the lab. Packing is only implemented for this protocol.
+// If alicePolicy is not nil, Alice answers only a query that it allows, before she sends the unpacked positions.
+func MainPacked(w *bufio.Writer, lab *sl.SequencingLab, tester *t.Tester, alice_genome, tester_genome []*env.Base, alicePolicy *policy.Policy) bool {
+
+	/* Offline Phase */
+	timestart := time.Now()
+	aliceBlocks, aliceBlockSigs := lab.SequenceWholeSetPacked(alice_genome)
+	labTime := time.Since(timestart)
+
+	timestart = time.Now()
+	tester.SetupPacked(lab, tester_genome, 0)
+	timecheck := time.Since(timestart)
+	if alicePolicy != nil {
+		querySig, err := tester.SignQuery() // the tester sends its query signed, Alice only verifies it
+		if err != nil && err != t.ErrNoIdentity {
+			fmt.Println("tester cannot sign its query (", err, "), so ABORT!")
+			return false
+		}
+		if !t.CheckQuery(lab, alicePolicy, tester, querySig, len(alice_genome), false) {
+			return false
+		}
+	}
+
+	timestart = time.Now()
+	aliceCiphers, aliceSigs := lab.SequenceWholeSetUnpacked(alice_genome, tester.UnpackedBlocks())
+	labTime += time.Since(timestart)
+	fmt.Println("SL offline phase is done")
+	fmt.Fprintln(w, labTime.Microseconds())
+	fmt.Println("Tester offline phase is done")
+	fmt.Fprintln(w, timecheck.Microseconds())
+
+	/* Online Phase */
+	timestart = time.Now()
+	resultCipher := tester.TestingWholePacked(aliceCiphers, aliceSigs, aliceBlocks, aliceBlockSigs)
+	timecheck = time.Since(timestart)
+	fmt.Println("Tester online phase is done")
+	fmt.Fprintln(w, timecheck.Microseconds())
+	if resultCipher == nil {
+		return false
+	}
 
 	timestart = time.Now()
-	testingResult := lab.Ahe.IsZero(resultCipher)
+	testingResult, err := lab.Ahe.IsZero(resultCipher)
 	timecheck = time.Since(timestart)
 	fmt.Println("Alice online phase is done")
 	fmt.Fprintln(w, timecheck.Microseconds())
+	if err != nil {
+		fmt.Println("result of the tester is invalid (", err, "), so ABORT!")
+		return false
+	}
 
 	return testingResult
 
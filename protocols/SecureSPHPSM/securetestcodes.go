@@ -11,6 +11,7 @@ import (
 	t "github.com/eozturk1/genomic-security-journal-code/entities/tester"
 	ahe "github.com/eozturk1/genomic-security-journal-code/helpers/addhomencer"
 	env "github.com/eozturk1/genomic-security-journal-code/helpers/env"
+	"github.com/eozturk1/genomic-security-journal-code/helpers/profiles"
 )
 
 func TestElGamalExactMatching(w *bufio.Writer, fileA, fileTm string) {
@@ -19,15 +20,15 @@ func TestElGamalExactMatching(w *bufio.Writer, fileA, fileTm string) {
 	tester_genome := env.ReadGenomeFromFile(fileTm)
 
 	scheme := ahe.AHElGamal{}
-	scheme.Setup()
+	scheme.SetupProfile(profiles.Default)
 
 	lab := sl.SequencingLab{}
-	lab.Setup(&scheme)
+	lab.Setup(&scheme, profiles.Default)
 
 	tester := t.Tester{}
 
 	fmt.Println("secure protocol, matching test with ElGamal starts!")
-	result := Main(w, &lab, &tester, alice_genome, tester_genome)
+	result := Main(w, &lab, &tester, alice_genome, tester_genome, t.PerWindow, nil)
 	if !result {
 		log.Fatal("Exact matching test: Failed\n")
 	}
@@ -40,15 +41,15 @@ func TestElGamalNoMatching(w *bufio.Writer, fileA, fileTnm string) {
 	tester_genome := env.ReadGenomeFromFile(fileTnm)
 
 	scheme := ahe.AHElGamal{}
-	scheme.Setup()
+	scheme.SetupProfile(profiles.Default)
 
 	lab := sl.SequencingLab{}
-	lab.Setup(&scheme)
+	lab.Setup(&scheme, profiles.Default)
 
 	tester := t.Tester{}
 
 	fmt.Println("secure protocol, no matching test with ElGamal starts!")
-	result := Main(w, &lab, &tester, alice_genome, tester_genome)
+	result := Main(w, &lab, &tester, alice_genome, tester_genome, t.PerWindow, nil)
 	if result {
 		log.Fatal("No matching test: Failed\n")
 	}
@@ -63,15 +64,15 @@ func TestPaillierExactMatching(w *bufio.Writer, fileA, fileTm string) {
 	tester_genome := env.ReadGenomeFromFile(fileTm)
 
 	scheme := ahe.GoGoGadgetPaillier{}
-	scheme.Setup()
+	scheme.SetupProfile(profiles.Default)
 
 	lab := sl.SequencingLab{}
-	lab.Setup(&scheme)
+	lab.Setup(&scheme, profiles.Default)
 
 	tester := t.Tester{}
 
 	fmt.Println("secure protocol, matching test with Paillier starts!")
-	result := Main(w, &lab, &tester, alice_genome, tester_genome)
+	result := Main(w, &lab, &tester, alice_genome, tester_genome, t.PerWindow, nil)
 	if !result {
 		log.Fatal("Exact matching test: Failed\n")
 	}
@@ -85,18 +86,134 @@ func TestPaillierNoMatching(w *bufio.Writer, fileA, fileTnm string) {
 	tester_genome := env.ReadGenomeFromFile(fileTnm)
 
 	scheme := ahe.GoGoGadgetPaillier{}
-	scheme.Setup()
+	scheme.SetupProfile(profiles.Default)
 
 	lab := sl.SequencingLab{}
-	lab.Setup(&scheme)
+	lab.Setup(&scheme, profiles.Default)
 
 	tester := t.Tester{}
 
 	fmt.Println("secure protocol, no matching test with Paillier starts!")
-	result := Main(w, &lab, &tester, alice_genome, tester_genome)
+	result := Main(w, &lab, &tester, alice_genome, tester_genome, t.PerWindow, nil)
 	if result {
 		log.Fatal("No matching test: Failed\n")
 	}
 	fmt.Println("secure protocol, no matching test with Paillier finished!")
 
 }
+
+//---------------
+
+func TestDamgardJurikExactMatching(w *bufio.Writer, fileA, fileTm string, s int) {
+
+	alice_genome := env.ReadGenomeFromFile(fileA)
+	tester_genome := env.ReadGenomeFromFile(fileTm)
+
+	scheme := ahe.DamgardJurik{S: s}
+	scheme.SetupProfile(profiles.Default)
+
+	lab := sl.SequencingLab{}
+	lab.Setup(&scheme, profiles.Default)
+
+	tester := t.Tester{}
+
+	fmt.Println("secure protocol, matching test with Damgard-Jurik starts!")
+	result := Main(w, &lab, &tester, alice_genome, tester_genome, t.PerWindow, nil)
+	if !result {
+		log.Fatal("Exact matching test: Failed\n")
+	}
+	fmt.Println("secure protocol, matching test with Damgard-Jurik finished!")
+
+}
+
+func TestDamgardJurikNoMatching(w *bufio.Writer, fileA, fileTnm string, s int) {
+
+	alice_genome := env.ReadGenomeFromFile(fileA)
+	tester_genome := env.ReadGenomeFromFile(fileTnm)
+
+	scheme := ahe.DamgardJurik{S: s}
+	scheme.SetupProfile(profiles.Default)
+
+	lab := sl.SequencingLab{}
+	lab.Setup(&scheme, profiles.Default)
+
+	tester := t.Tester{}
+
+	fmt.Println("secure protocol, no matching test with Damgard-Jurik starts!")
+	result := Main(w, &lab, &tester, alice_genome, tester_genome, t.PerWindow, nil)
+	if result {
+		log.Fatal("No matching test: Failed\n")
+	}
+	fmt.Println("secure protocol, no matching test with Damgard-Jurik finished!")
+
+}
+
+//---------------
+
+// Packed tests run with DamgardJurik, which is Paillier for s = 1: GetGroupOrder has to be the plaintext order.
+
+func TestDamgardJurikPackedExactMatching(w *bufio.Writer, fileA, fileTm string, s int) {
+
+	alice_genome := env.ReadGenomeFromFile(fileA)
+	tester_genome := env.ReadGenomeFromFile(fileTm)
+
+	scheme := ahe.DamgardJurik{S: s}
+	scheme.SetupProfile(profiles.Default)
+
+	lab := sl.SequencingLab{}
+	lab.Setup(&scheme, profiles.Default)
+
+	tester := t.Tester{}
+
+	fmt.Println("secure protocol, packed matching test with Damgard-Jurik starts!")
+	result := MainPacked(w, &lab, &tester, alice_genome, tester_genome, nil)
+	if !result {
+		log.Fatal("Exact matching test: Failed\n")
+	}
+	fmt.Println("secure protocol, packed matching test with Damgard-Jurik finished!")
+
+}
+
+func TestDamgardJurikPackedNoMatching(w *bufio.Writer, fileA, fileTnm string, s int) {
+
+	alice_genome := env.ReadGenomeFromFile(fileA)
+	tester_genome := env.ReadGenomeFromFile(fileTnm)
+
+	scheme := ahe.DamgardJurik{S: s}
+	scheme.SetupProfile(profiles.Default)
+
+	lab := sl.SequencingLab{}
+	lab.Setup(&scheme, profiles.Default)
+
+	tester := t.Tester{}
+
+	fmt.Println("secure protocol, packed no matching test with Damgard-Jurik starts!")
+	result := MainPacked(w, &lab, &tester, alice_genome, tester_genome, nil)
+	if result {
+		log.Fatal("No matching test: Failed\n")
+	}
+	fmt.Println("secure protocol, packed no matching test with Damgard-Jurik finished!")
+
+}
+
+func TestElGamalTesterLearns(w *bufio.Writer, fileA, fileT string, expected bool) {
+
+	alice_genome := env.ReadGenomeFromFile(fileA)
+	tester_genome := env.ReadGenomeFromFile(fileT)
+
+	scheme := ahe.AHElGamal{}
+	scheme.SetupProfile(profiles.Default)
+
+	lab := sl.SequencingLab{}
+	lab.Setup(&scheme, profiles.Default)
+
+	tester := t.Tester{}
+
+	fmt.Println("secure protocol, tester-learns test with ElGamal starts!")
+	result := Main(w, &lab, &tester, alice_genome, tester_genome, t.TesterLearns, nil)
+	if result != expected {
+		log.Fatal("Tester-learns test: Failed\n")
+	}
+	fmt.Println("secure protocol, tester-learns test with ElGamal finished!")
+
+}
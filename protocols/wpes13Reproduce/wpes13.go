@@ -4,8 +4,6 @@ package wpes13Reproduce
 
 import (
 	"bufio"
-	"crypto/rand"
-	"crypto/sha256"
 	"fmt"
 	"hash"
 	"math/big"
@@ -14,6 +12,7 @@ import (
 
 	ahe "github.com/eozturk1/genomic-security-journal-code/helpers/addhomencer"
 	env "github.com/eozturk1/genomic-security-journal-code/helpers/env"
+	"github.com/eozturk1/genomic-security-journal-code/helpers/profiles"
 )
 
 func Main2013(w *bufio.Writer, lab *SequencingLab2013, tester *Tester2013, alice_genome, tester_genome []*env.Base) bool {
@@ -33,16 +32,24 @@ func Main2013(w *bufio.Writer, lab *SequencingLab2013, tester *Tester2013, alice
 
 	/* Online Phase */
 	timestart = time.Now()
-	encryptedResult := tester.Online(aliceCiphers)
+	encryptedResult, err := tester.Online(aliceCiphers)
 	timecheck = time.Since(timestart)
 	fmt.Println("Tester online phase is done")
 	fmt.Fprintln(w, timecheck.Microseconds())
+	if err != nil {
+		fmt.Println("testing failed (", err, "), so ABORT!")
+		return false
+	}
 
 	timestart = time.Now()
-	testingResult := lab.Ahe.IsZero(encryptedResult)
+	testingResult, err := lab.Ahe.IsZero(encryptedResult)
 	timecheck = time.Since(timestart)
 	fmt.Println("Alice online phase is done")
 	fmt.Fprintln(w, timecheck.Microseconds())
+	if err != nil {
+		fmt.Println("result of the tester is invalid (", err, "), so ABORT!")
+		return false
+	}
 
 	return testingResult
 
@@ -59,9 +66,12 @@ type Tester2013 struct {
 	startingPosition uint32
 }
 
-func (lab *SequencingLab2013) Setup(scheme ahe.AddHomEncer) {
+func (lab *SequencingLab2013) Setup(scheme ahe.AddHomEncer, profile *profiles.Profile) {
+	if scheme.Profile() != profile {
+		panic("SL setup error: " + profiles.ErrProfileMismatch.Error())
+	}
 	lab.Ahe = scheme
-	lab.Hash = sha256.New()
+	lab.Hash = profile.NewHash()
 }
 
 func AliceOfflineSetup(lab *SequencingLab2013, baseArray []*env.Base) []*env.Cipher {
@@ -112,31 +122,26 @@ func (t *Tester2013) OfflineSetup(lab *SequencingLab2013, baseArray []*env.Base)
 
 }
 
-func (t *Tester2013) Online(aliceCiphers []*env.Cipher) *env.Cipher {
+func (t *Tester2013) Online(aliceCiphers []*env.Cipher) (*env.Cipher, error) {
 	// For the marker's positions, Alice's encrypted bases are homomorphically added to Tester's encrypted bases and output the encrypted result after randomization
 	// i.e., If matching, output Enc(0), or Enc(random number), otherwise.
-	var wg sync.WaitGroup
-
 	n := len(t.EncryptedMarker)
-	result := t.lab.Ahe.Encrypt(big.NewInt(0))
-
-	wg.Add(n)
+	factors := make([]*env.Cipher, 0, 2*n+1)
+	factors = append(factors, t.lab.Ahe.Encrypt(big.NewInt(0)))
 
 	for i := uint32(0); i < uint32(n); i++ {
-
-		go func(i uint32, wg *sync.WaitGroup) {
-			j := t.startingPosition + i - 1
-			mult := t.lab.Ahe.MultCiphers(aliceCiphers[j], t.EncryptedMarker[i])
-			result = t.lab.Ahe.MultCiphers(result, mult)
-			wg.Done()
-		}(i, &wg)
-
+		j := t.startingPosition + i - 1
+		factors = append(factors, aliceCiphers[j], t.EncryptedMarker[i])
+	}
+	result, err := t.lab.Ahe.MultCipherArray(factors)
+	if err != nil {
+		return nil, err
 	}
-	wg.Wait()
-
-	r, _ := rand.Int(rand.Reader, t.lab.Ahe.GetGroupOrder())
-	result = t.lab.Ahe.HideCipherWithR(result, r)
 
-	return result
+	r, err := ahe.RandomBlinding(t.lab.Ahe)
+	if err != nil {
+		return nil, err
+	}
+	return t.lab.Ahe.HideCipherWithR(result, r)
 
 }
@@ -9,6 +9,7 @@ import (
 
 	ahe "github.com/eozturk1/genomic-security-journal-code/helpers/addhomencer"
 	env "github.com/eozturk1/genomic-security-journal-code/helpers/env"
+	"github.com/eozturk1/genomic-security-journal-code/helpers/profiles"
 )
 
 func TestExactMatching(w *bufio.Writer, fileA, fileTm string) {
@@ -17,10 +18,10 @@ func TestExactMatching(w *bufio.Writer, fileA, fileTm string) {
 	tester_genome := env.ReadGenomeFromFile(fileTm)
 
 	scheme := ahe.AHElGamal{}
-	scheme.Setup()
+	scheme.SetupProfile(profiles.Default)
 
 	lab := SequencingLab2013{}
-	lab.Setup(&scheme)
+	lab.Setup(&scheme, profiles.Default)
 
 	tester := Tester2013{}
 
@@ -40,10 +41,10 @@ func TestNoMatching(w *bufio.Writer, fileA, fileTnm string) {
 	tester_genome := env.ReadGenomeFromFile(fileTnm)
 
 	scheme := ahe.AHElGamal{}
-	scheme.Setup()
+	scheme.SetupProfile(profiles.Default)
 
 	lab := SequencingLab2013{}
-	lab.Setup(&scheme)
+	lab.Setup(&scheme, profiles.Default)
 
 	tester := Tester2013{}
 
@@ -56,3 +57,51 @@ func TestNoMatching(w *bufio.Writer, fileA, fileTnm string) {
 
 	return
 }
+
+//---------------
+
+func TestDamgardJurikExactMatching(w *bufio.Writer, fileA, fileTm string, s int) {
+
+	alice_genome := env.ReadGenomeFromFile(fileA)
+	tester_genome := env.ReadGenomeFromFile(fileTm)
+
+	scheme := ahe.DamgardJurik{S: s}
+	scheme.SetupProfile(profiles.Default)
+
+	lab := SequencingLab2013{}
+	lab.Setup(&scheme, profiles.Default)
+
+	tester := Tester2013{}
+
+	fmt.Println("wpes13 reproduced protocol, matching test with Damgard-Jurik starts!")
+	result := Main2013(w, &lab, &tester, alice_genome, tester_genome)
+	if !result {
+		log.Fatal("Exact matching test: Failed\n")
+	}
+	fmt.Println("wpes13 reproduced protocol, matching test with Damgard-Jurik finished!")
+
+	return
+}
+
+func TestDamgardJurikNoMatching(w *bufio.Writer, fileA, fileTnm string, s int) {
+
+	alice_genome := env.ReadGenomeFromFile(fileA)
+	tester_genome := env.ReadGenomeFromFile(fileTnm)
+
+	scheme := ahe.DamgardJurik{S: s}
+	scheme.SetupProfile(profiles.Default)
+
+	lab := SequencingLab2013{}
+	lab.Setup(&scheme, profiles.Default)
+
+	tester := Tester2013{}
+
+	fmt.Println("wpes13 reproduced protocol, no matching test with Damgard-Jurik starts!")
+	result := Main2013(w, &lab, &tester, alice_genome, tester_genome)
+	if result {
+		log.Fatal("No matching test: Failed\n")
+	}
+	fmt.Println("wpes13 reproduced protocol, no matching test with Damgard-Jurik finished!")
+
+	return
+}
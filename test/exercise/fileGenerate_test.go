@@ -32,6 +32,19 @@ func TestFileGenerate(test *testing.T) {
 
 	env.GenFiles(fileA, fileTm, fileTnm, n, s, e, ns, ne)
 
+	// markers of disjoint regions for the queries over several ranges: two in [s, e], which match, and two after e
+	env.GenMarkerFiles("testerFrom100000to300000", 100000, 300000)
+	env.GenMarkerFiles("testerFrom400000to600000", 400000, 600000)
+	env.GenMarkerFiles("testerFrom700000to800000", 700000, 800000)
+	env.GenMarkerFiles("testerFrom850000to950000", 850000, 950000)
+	// and one across e, for panels of overlapping ranges, which does not match
+	env.GenMarkerFiles("testerFrom500000to700000", 500000, 700000)
+
+	// markers of contiguous positions for the boolean tests, whose results grow with the length of the markers: one in
+	// [s, e] and one after e
+	env.GenMarkerFiles("testerFrom200000to200009", 200000, 200009)
+	env.GenMarkerFiles("testerFrom700000to700009", 700000, 700009)
+
 	/* Test_1 files */
 
 	n = 10000
@@ -13,6 +13,7 @@ import (
 	sl "github.com/eozturk1/genomic-security-journal-code/entities/sequencinglab"
 	ahe "github.com/eozturk1/genomic-security-journal-code/helpers/addhomencer"
 	env "github.com/eozturk1/genomic-security-journal-code/helpers/env"
+	"github.com/eozturk1/genomic-security-journal-code/helpers/profiles"
 
 	//	t "github.com/eozturk1/genomic-security-journal-code/entities/tester"
 
@@ -35,10 +36,10 @@ func TestEtcOperationTimes(test *testing.T) {
 	w := bufio.NewWriter(output)
 
 	scheme := ahe.AHElGamal{}
-	scheme.Setup()
+	scheme.SetupProfile(profiles.Default)
 
 	lab := sl.SequencingLab{}
-	lab.Setup(&scheme)
+	lab.Setup(&scheme, profiles.Default)
 
 	signingKey := lab.GetSigningKey()
 
@@ -54,6 +55,7 @@ func TestEtcOperationTimes(test *testing.T) {
 	var verifySig1List []int64
 	var verifySig2List []int64
 	var commGenList []int64
+	var commTableGenList []int64
 	var RPGenList []int64
 	var RPVerifyList []int64
 	var ccs08RPGenList []int64
@@ -116,6 +118,14 @@ func TestEtcOperationTimes(test *testing.T) {
 		//fmt.Fprintln(w, timecheck.Microseconds())
 		commGenList = append(commGenList, timecheck.Microseconds())
 
+		timestart = time.Now()
+		commitmentTable, _ := lab.CommitTables.CommitG1(big.NewInt(int64(base.Position)), salt)
+		timecheck = time.Since(timestart)
+		if !env.CompareP256s(commitment, commitmentTable) {
+			fmt.Println("commitment with fixed-base tables is wrong")
+		}
+		commTableGenList = append(commTableGenList, timecheck.Microseconds())
+
 		commitment2, _ := util.CommitG1(big.NewInt(int64(base2.Position)), salt2, lab.BPparams.H)
 
 		timestart = time.Now()
@@ -152,7 +162,7 @@ func TestEtcOperationTimes(test *testing.T) {
 		RPGenList = append(RPGenList, timecheck.Microseconds())
 
 		timestart = time.Now()
-		ok, _ := proof.Verify()
+		ok, _ := proof.Verify(params)
 		if !ok {
 			fmt.Println("range proof verification is failed")
 			break
@@ -196,9 +206,10 @@ func TestEtcOperationTimes(test *testing.T) {
 	sumRPVerify := int64(0)
 	sumCcs08RPGen := int64(0)
 	sumCcs08RPVerify := int64(0)
+	sumcommTableGen := int64(0)
 
 	for i := 0; i < 10; i++ {
-		fmt.Fprintln(w, hash1List[i], hash2List[i], hash3List[i], saltGenList[i], sign1List[i], sign2List[i], verifySig1List[i], verifySig2List[i], commGenList[i], RPGenList[i], RPVerifyList[i], ccs08RPGenList[i], ccs08RPVerifyList[i])
+		fmt.Fprintln(w, hash1List[i], hash2List[i], hash3List[i], saltGenList[i], sign1List[i], sign2List[i], verifySig1List[i], verifySig2List[i], commGenList[i], RPGenList[i], RPVerifyList[i], ccs08RPGenList[i], ccs08RPVerifyList[i], commTableGenList[i])
 
 		sumhash1 += hash1List[i]
 		sumhash2 += hash2List[i]
@@ -213,10 +224,11 @@ func TestEtcOperationTimes(test *testing.T) {
 		sumRPVerify += RPVerifyList[i]
 		sumCcs08RPGen += ccs08RPGenList[i]
 		sumCcs08RPVerify += ccs08RPVerifyList[i]
+		sumcommTableGen += commTableGenList[i]
 
 	}
-	fmt.Fprintln(w, sumhash1, sumhash2, sumhash3, sumsaltGen, sumsign1, sumsign2, sumverifySig1, sumverifySig2, sumcommGen, sumRPGen, sumRPVerify, sumCcs08RPGen, sumCcs08RPVerify)
-	fmt.Fprintln(w, "Average values of 10 executions: (H(pos, base) / H(pos, cipher) / H(tuple) / gen(salt) / sign(H(pos,cipher)) / sign(H(tuple)) / verify(sig1) / verify(sig2) / gen(commitment) / gen(range_proof) / verify(range_proof) / ccs08_gen(range_proof) / ccs08_verify(range_proof) ) ")
+	fmt.Fprintln(w, sumhash1, sumhash2, sumhash3, sumsaltGen, sumsign1, sumsign2, sumverifySig1, sumverifySig2, sumcommGen, sumRPGen, sumRPVerify, sumCcs08RPGen, sumCcs08RPVerify, sumcommTableGen)
+	fmt.Fprintln(w, "Average values of 10 executions: (H(pos, base) / H(pos, cipher) / H(tuple) / gen(salt) / sign(H(pos,cipher)) / sign(H(tuple)) / verify(sig1) / verify(sig2) / gen(commitment) / gen(range_proof) / verify(range_proof) / ccs08_gen(range_proof) / ccs08_verify(range_proof) / gen(commitment, fixed-base tables) ) ")
 	fmt.Fprintln(w, float64(sumhash1)/10.0)
 	fmt.Fprintln(w, float64(sumhash2)/10.0)
 	fmt.Fprintln(w, float64(sumhash3)/10.0)
@@ -230,6 +242,7 @@ func TestEtcOperationTimes(test *testing.T) {
 	fmt.Fprintln(w, float64(sumRPVerify)/10.0)
 	fmt.Fprintln(w, float64(sumCcs08RPGen)/10.0)
 	fmt.Fprintln(w, float64(sumCcs08RPVerify)/10.0)
+	fmt.Fprintln(w, float64(sumcommTableGen)/10.0)
 
 	w.Flush()
 
@@ -243,10 +256,10 @@ func TestElGamalOperationTimes(test *testing.T) {
 	w := bufio.NewWriter(output)
 
 	scheme := ahe.AHElGamal{}
-	scheme.Setup()
+	scheme.SetupProfile(profiles.Default)
 
 	lab := sl.SequencingLab{}
-	lab.Setup(&scheme)
+	lab.Setup(&scheme, profiles.Default)
 
 	base := env.Base{Position: uint32(10), Letter: 'T'}
 	hashBase := env.HashPositionAndBase(lab.Hash, base.Position, &base)
@@ -274,7 +287,7 @@ func TestElGamalOperationTimes(test *testing.T) {
 		encInvList = append(encInvList, timecheck.Microseconds())
 
 		timestart = time.Now()
-		multciphers := scheme.MultCiphers(cipher1, cipher2)
+		multciphers, _ := scheme.MultCiphers(cipher1, cipher2)
 		timecheck = time.Since(timestart)
 		//fmt.Fprintln(w, "ElGamal mult ciphers time:")
 		//fmt.Fprintln(w, timecheck.Microseconds())
@@ -282,14 +295,14 @@ func TestElGamalOperationTimes(test *testing.T) {
 
 		r, _ := rand.Int(rand.Reader, scheme.GetGroupOrder())
 		timestart = time.Now()
-		randomized := scheme.HideCipherWithR(multciphers, r)
+		randomized, _ := scheme.HideCipherWithR(multciphers, r)
 		timecheck = time.Since(timestart)
 		//fmt.Fprintln(w, "ElGamal randomization time:")
 		//fmt.Fprintln(w, timecheck.Microseconds())
 		multConstantList = append(multConstantList, timecheck.Microseconds())
 
 		timestart = time.Now()
-		isZero := scheme.IsZero(randomized)
+		isZero, _ := scheme.IsZero(randomized)
 		timecheck = time.Since(timestart)
 		//fmt.Fprintln(w, "ElGamal isZero time:")
 		//fmt.Fprintln(w, timecheck.Microseconds())
@@ -329,6 +342,73 @@ func TestElGamalOperationTimes(test *testing.T) {
 
 }
 
+func TestElGamalDecryptionTimes(test *testing.T) {
+
+	outputFileName := "../../testResults/ElGamalDecryption_times.txt"
+	output, _ := os.Create(outputFileName)
+	defer output.Close()
+	w := bufio.NewWriter(output)
+
+	scheme := ahe.AHElGamal{}
+	scheme.SetupProfile(profiles.Default)
+
+	bound := uint64(1 << 32)
+
+	timestart := time.Now()
+	scheme.SetDecryptionBound(bound)
+	timecheck := time.Since(timestart)
+	fmt.Fprintln(w, "Building the table for bound 2^32:")
+	fmt.Fprintln(w, timecheck.Microseconds())
+
+	tableFileName := "../../tmpFiles/elgamal_dlog_table.gob"
+	os.Mkdir("../../tmpFiles", 0755)
+	if err := scheme.SaveDecryptionTable(tableFileName); err != nil {
+		test.Fatal(err)
+	}
+	defer os.Remove(tableFileName)
+
+	timestart = time.Now()
+	if err := scheme.LoadDecryptionTable(tableFileName); err != nil {
+		test.Fatal(err)
+	}
+	timecheck = time.Since(timestart)
+	fmt.Fprintln(w, "Loading the table from the file:")
+	fmt.Fprintln(w, timecheck.Microseconds())
+
+	var decList []int64
+
+	for i := 0; i < 10; i++ {
+
+		m, _ := rand.Int(rand.Reader, new(big.Int).SetUint64(bound))
+		cipher := scheme.Encrypt(m)
+
+		timestart = time.Now()
+		plain, err := scheme.Decrypt(cipher)
+		timecheck = time.Since(timestart)
+		decList = append(decList, timecheck.Microseconds())
+
+		if err != nil || plain.Cmp(m) != 0 {
+			test.Fatalf("decrypting %v failed: %v, %v", m, plain, err)
+		}
+	}
+
+	sumDec := int64(0)
+	for i := 0; i < 10; i++ {
+		fmt.Fprintln(w, decList[i])
+		sumDec += decList[i]
+	}
+	fmt.Fprintln(w, "Average values of 10 executions: (dec) ")
+	fmt.Fprintln(w, float64(sumDec)/10.0)
+
+	// a plaintext out of the bound must be reported, not wrapped around
+	if _, err := scheme.Decrypt(scheme.Encrypt(new(big.Int).SetUint64(bound))); err != ahe.ErrPlaintextOutOfBound {
+		test.Errorf("expected ErrPlaintextOutOfBound, got %v", err)
+	}
+
+	w.Flush()
+
+}
+
 func TestPaillierOperationTimes(test *testing.T) {
 
 	outputFileName := "../../testResults/PaillierOps_times.txt"
@@ -337,10 +417,10 @@ func TestPaillierOperationTimes(test *testing.T) {
 	w := bufio.NewWriter(output)
 
 	scheme := ahe.GoGoGadgetPaillier{}
-	scheme.Setup()
+	scheme.SetupProfile(profiles.Default)
 
 	lab := sl.SequencingLab{}
-	lab.Setup(&scheme)
+	lab.Setup(&scheme, profiles.Default)
 
 	base := env.Base{Position: uint32(10), Letter: 'T'}
 	hashBase := env.HashPositionAndBase(lab.Hash, base.Position, &base)
@@ -368,7 +448,7 @@ func TestPaillierOperationTimes(test *testing.T) {
 		encInvList = append(encInvList, timecheck.Microseconds())
 
 		timestart = time.Now()
-		multciphers := scheme.MultCiphers(cipher1, cipher2)
+		multciphers, _ := scheme.MultCiphers(cipher1, cipher2)
 		timecheck = time.Since(timestart)
 		//fmt.Fprintln(w, "Paillier mult ciphers time:")
 		//fmt.Fprintln(w, timecheck.Microseconds())
@@ -376,14 +456,14 @@ func TestPaillierOperationTimes(test *testing.T) {
 
 		r, _ := rand.Int(rand.Reader, scheme.GetGroupOrder())
 		timestart = time.Now()
-		randomized := scheme.HideCipherWithR(multciphers, r)
+		randomized, _ := scheme.HideCipherWithR(multciphers, r)
 		timecheck = time.Since(timestart)
 		//fmt.Fprintln(w, "Paillier randomization time:")
 		//fmt.Fprintln(w, timecheck.Microseconds())
 		multConstantList = append(multConstantList, timecheck.Microseconds())
 
 		timestart = time.Now()
-		isZero := scheme.IsZero(randomized)
+		isZero, _ := scheme.IsZero(randomized)
 		timecheck = time.Since(timestart)
 		//fmt.Fprintln(w, "Paillier isZero time:")
 		//fmt.Fprintln(w, timecheck.Microseconds())
@@ -422,3 +502,118 @@ func TestPaillierOperationTimes(test *testing.T) {
 	w.Flush()
 
 }
+
+func TestNativePaillierOperationTimes(test *testing.T) {
+
+	outputFileName := "../../testResults/NativePaillierOps_times.txt"
+	output, _ := os.Create(outputFileName)
+	defer output.Close()
+	w := bufio.NewWriter(output)
+
+	scheme := ahe.Paillier{}
+	scheme.SetupProfile(profiles.Default)
+
+	lab := sl.SequencingLab{}
+	lab.Setup(&scheme, profiles.Default)
+
+	base := env.Base{Position: uint32(10), Letter: 'T'}
+	hashBase := env.HashPositionAndBase(lab.Hash, base.Position, &base)
+
+	// offline phase: r^N for the 20 encryptions below
+	timestart := time.Now()
+	scheme.PrecomputeRandomness(20)
+	timecheck := time.Since(timestart)
+	fmt.Fprintln(w, "Precomputing 20 r^N values:")
+	fmt.Fprintln(w, timecheck.Microseconds())
+
+	var encList []int64
+	var encInvList []int64
+	var multCiphersList []int64
+	var multConstantList []int64
+	var isZeroList []int64
+	var decList []int64
+
+	for i := 0; i < 10; i++ {
+
+		timestart := time.Now()
+		cipher1 := scheme.Encrypt(new(big.Int).SetBytes(hashBase))
+		timecheck := time.Since(timestart)
+		encList = append(encList, timecheck.Microseconds())
+
+		timestart = time.Now()
+		cipher2 := scheme.EncryptInverse(new(big.Int).SetBytes(hashBase))
+		timecheck = time.Since(timestart)
+		encInvList = append(encInvList, timecheck.Microseconds())
+
+		timestart = time.Now()
+		multciphers, _ := scheme.MultCiphers(cipher1, cipher2)
+		timecheck = time.Since(timestart)
+		multCiphersList = append(multCiphersList, timecheck.Microseconds())
+
+		r, _ := rand.Int(rand.Reader, scheme.GetGroupOrder())
+		timestart = time.Now()
+		randomized, _ := scheme.HideCipherWithR(multciphers, r)
+		timecheck = time.Since(timestart)
+		multConstantList = append(multConstantList, timecheck.Microseconds())
+
+		timestart = time.Now()
+		isZero, _ := scheme.IsZero(randomized)
+		timecheck = time.Since(timestart)
+		isZeroList = append(isZeroList, timecheck.Microseconds())
+		if isZero {
+			fmt.Println("Native Paillier time check is done")
+		} else {
+			fmt.Println("really? sth is wrong...")
+		}
+
+		timestart = time.Now()
+		plain, _ := scheme.Decrypt(cipher1)
+		timecheck = time.Since(timestart)
+		decList = append(decList, timecheck.Microseconds())
+		if plain.Cmp(new(big.Int).Mod(new(big.Int).SetBytes(hashBase), scheme.Pk.N)) != 0 {
+			test.Errorf("decryption does not match the plaintext")
+		}
+	}
+
+	//calculate the avaerages
+	sumEnc := int64(0)
+	sumEncInv := int64(0)
+	sumMultCiphers := int64(0)
+	sumMultConstant := int64(0)
+	sumIsZero := int64(0)
+	sumDec := int64(0)
+
+	for i := 0; i < 10; i++ {
+		fmt.Fprintln(w, encList[i], encInvList[i], multCiphersList[i], multConstantList[i], isZeroList[i], decList[i])
+
+		sumEnc += encList[i]
+		sumEncInv += encInvList[i]
+		sumMultCiphers += multCiphersList[i]
+		sumMultConstant += multConstantList[i]
+		sumIsZero += isZeroList[i]
+		sumDec += decList[i]
+	}
+	fmt.Fprintln(w, "Average values of 10 executions: (enc/encInv/multCiphers/multConstant/isZero/dec) ")
+	fmt.Fprintln(w, float64(sumEnc)/10.0)
+	fmt.Fprintln(w, float64(sumEncInv)/10.0)
+	fmt.Fprintln(w, float64(sumMultCiphers)/10.0)
+	fmt.Fprintln(w, float64(sumMultConstant)/10.0)
+	fmt.Fprintln(w, float64(sumIsZero)/10.0)
+	fmt.Fprintln(w, float64(sumDec)/10.0)
+
+	// a serialized key has to decrypt what the original one encrypted
+	skBytes, _ := scheme.Sk.MarshalBinary()
+	var sk ahe.PaillierPrivateKey
+	if err := sk.UnmarshalBinary(skBytes); err != nil {
+		test.Fatal(err)
+	}
+	restored := ahe.Paillier{}
+	restored.SetPrivateKey(&sk)
+	plain, _ := restored.Decrypt(scheme.Encrypt(big.NewInt(42)))
+	if plain.Cmp(big.NewInt(42)) != 0 {
+		test.Errorf("restored key decrypted %v instead of 42", plain)
+	}
+
+	w.Flush()
+
+}
@@ -14,6 +14,7 @@ import (
 	sl "github.com/eozturk1/genomic-security-journal-code/entities/sequencinglab"
 	ahe "github.com/eozturk1/genomic-security-journal-code/helpers/addhomencer"
 	env "github.com/eozturk1/genomic-security-journal-code/helpers/env"
+	"github.com/eozturk1/genomic-security-journal-code/helpers/profiles"
 
 	//	t "github.com/eozturk1/genomic-security-journal-code/entities/tester"
 
@@ -36,10 +37,10 @@ func TestEtcOperationTimes(test *testing.T) {
 	w := bufio.NewWriter(output)
 
 	scheme := ahe.AHElGamal{}
-	scheme.Setup()
+	scheme.SetupProfile(profiles.Default)
 
 	lab := sl.SequencingLab{}
-	lab.Setup(&scheme)
+	lab.Setup(&scheme, profiles.Default)
 
 	signingKey := lab.GetSigningKey()
 
@@ -153,7 +154,7 @@ func TestEtcOperationTimes(test *testing.T) {
 		RPGenList = append(RPGenList, timecheck.Microseconds())
 
 		timestart = time.Now()
-		ok, _ := proof.Verify()
+		ok, _ := proof.Verify(params)
 		if !ok {
 			fmt.Println("range proof verification is failed")
 			break
@@ -244,10 +245,10 @@ func TestElGamalOperationTimes(test *testing.T) {
 	w := bufio.NewWriter(output)
 
 	scheme := ahe.AHElGamal{}
-	scheme.Setup()
+	scheme.SetupProfile(profiles.Default)
 
 	lab := sl.SequencingLab{}
-	lab.Setup(&scheme)
+	lab.Setup(&scheme, profiles.Default)
 
 	base := env.Base{Position: uint32(10), Letter: 'T'}
 	hashBase := env.HashPositionAndBase(lab.Hash, base.Position, &base)
@@ -275,7 +276,7 @@ func TestElGamalOperationTimes(test *testing.T) {
 		encInvList = append(encInvList, timecheck.Microseconds())
 
 		timestart = time.Now()
-		multciphers := scheme.MultCiphers(cipher1, cipher2)
+		multciphers, _ := scheme.MultCiphers(cipher1, cipher2)
 		timecheck = time.Since(timestart)
 		//fmt.Fprintln(w, "ElGamal mult ciphers time:")
 		//fmt.Fprintln(w, timecheck.Microseconds())
@@ -283,14 +284,14 @@ func TestElGamalOperationTimes(test *testing.T) {
 
 		r, _ := rand.Int(rand.Reader, scheme.GetGroupOrder())
 		timestart = time.Now()
-		randomized := scheme.HideCipherWithR(multciphers, r)
+		randomized, _ := scheme.HideCipherWithR(multciphers, r)
 		timecheck = time.Since(timestart)
 		//fmt.Fprintln(w, "ElGamal randomization time:")
 		//fmt.Fprintln(w, timecheck.Microseconds())
 		multConstantList = append(multConstantList, timecheck.Microseconds())
 
 		timestart = time.Now()
-		isZero := scheme.IsZero(randomized)
+		isZero, _ := scheme.IsZero(randomized)
 		timecheck = time.Since(timestart)
 		//fmt.Fprintln(w, "ElGamal isZero time:")
 		//fmt.Fprintln(w, timecheck.Microseconds())
@@ -338,10 +339,10 @@ func TestPaillierOperationTimes(test *testing.T) {
 	w := bufio.NewWriter(output)
 
 	scheme := ahe.GoGoGadgetPaillier{}
-	scheme.Setup()
+	scheme.SetupProfile(profiles.Default)
 
 	lab := sl.SequencingLab{}
-	lab.Setup(&scheme)
+	lab.Setup(&scheme, profiles.Default)
 
 	base := env.Base{Position: uint32(10), Letter: 'T'}
 	hashBase := env.HashPositionAndBase(lab.Hash, base.Position, &base)
@@ -369,7 +370,7 @@ func TestPaillierOperationTimes(test *testing.T) {
 		encInvList = append(encInvList, timecheck.Microseconds())
 
 		timestart = time.Now()
-		multciphers := scheme.MultCiphers(cipher1, cipher2)
+		multciphers, _ := scheme.MultCiphers(cipher1, cipher2)
 		timecheck = time.Since(timestart)
 		//fmt.Fprintln(w, "Paillier mult ciphers time:")
 		//fmt.Fprintln(w, timecheck.Microseconds())
@@ -377,14 +378,14 @@ func TestPaillierOperationTimes(test *testing.T) {
 
 		r, _ := rand.Int(rand.Reader, scheme.GetGroupOrder())
 		timestart = time.Now()
-		randomized := scheme.HideCipherWithR(multciphers, r)
+		randomized, _ := scheme.HideCipherWithR(multciphers, r)
 		timecheck = time.Since(timestart)
 		//fmt.Fprintln(w, "Paillier randomization time:")
 		//fmt.Fprintln(w, timecheck.Microseconds())
 		multConstantList = append(multConstantList, timecheck.Microseconds())
 
 		timestart = time.Now()
-		isZero := scheme.IsZero(randomized)
+		isZero, _ := scheme.IsZero(randomized)
 		timecheck = time.Since(timestart)
 		//fmt.Fprintln(w, "Paillier isZero time:")
 		//fmt.Fprintln(w, timecheck.Microseconds())
@@ -12,6 +12,7 @@ import (
 	sl "github.com/eozturk1/genomic-security-journal-code/entities/sequencinglab"
 	ahe "github.com/eozturk1/genomic-security-journal-code/helpers/addhomencer"
 	env "github.com/eozturk1/genomic-security-journal-code/helpers/env"
+	"github.com/eozturk1/genomic-security-journal-code/helpers/profiles"
 	wpes13 "github.com/eozturk1/genomic-security-journal-code/protocols/wpes13Reproduce"
 )
 
@@ -23,13 +24,13 @@ func TestFour(test *testing.T) {
 	w := bufio.NewWriter(f)
 
 	scheme := ahe.AHElGamal{}
-	scheme.Setup()
+	scheme.SetupProfile(profiles.Default)
 
 	lab13 := wpes13.SequencingLab2013{}
-	lab13.Setup(&scheme)
+	lab13.Setup(&scheme, profiles.Default)
 
 	labS := sl.SequencingLab{}
-	labS.Setup(&scheme)
+	labS.Setup(&scheme, profiles.Default)
 
 	for n := 10000; n <= 1000000; n *= 10 {
 
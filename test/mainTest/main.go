@@ -2,13 +2,18 @@ package main
 
 import (
 	"bufio"
+	"crypto/ecdsa"
+	"crypto/rand"
 	"fmt"
 	"os"
 	"strconv"
 
 	"runtime"
 
+	"github.com/eozturk1/genomic-security-journal-code/entities/policy"
+	t "github.com/eozturk1/genomic-security-journal-code/entities/tester"
 	//	env "github.com/eozturk1/genomic-security-journal-code/env"
+	"github.com/eozturk1/genomic-security-journal-code/helpers/profiles"
 	sae "github.com/eozturk1/genomic-security-journal-code/protocols/EfficientAndSecureSPHPSM"
 	fes "github.com/eozturk1/genomic-security-journal-code/protocols/FlexibleEfficientAndSecureSPHPSM"
 	secure "github.com/eozturk1/genomic-security-journal-code/protocols/SecureSPHPSM"
@@ -58,6 +63,51 @@ func main() {
 	callMatchingTests(w, fileA, fileTm, fileTnm, param, withOpt, rp)
 	fmt.Fprintln(w, "Test_extra - comparison of using ElGamal and Paillier - is done.")
 
+	// markers of regions, generated with the files of Test_extra: fileR1 and fileR2 match, and fileR3 and fileR4 do not.
+	// The SNPs of fileTnm are all in [s, e], where Alice's match, so the tests on SNPs use fileR3 as the marker that
+	// does not match.
+	fileR1, fileR2 := "testerFrom100000to300000", "testerFrom400000to600000"
+	fileR3, fileR4 := "testerFrom700000to800000", "testerFrom850000to950000"
+
+	/* Test_set: query over the panel of positions of the marker instead of a range, n = 10^6 */
+	callSetTests(w, fileA, fileTm, fileR3, withOpt)
+	fmt.Fprintln(w, "Test_set - query over a panel of positions - is done.")
+
+	/* Test_ranges: query over several disjoint ranges */
+	callRangesTests(w, fileA, []string{fileR1, fileR2}, []string{fileR3, fileR4}, param, withOpt, rp)
+	fmt.Fprintln(w, "Test_ranges - query over several ranges - is done.")
+
+	/* Test_panel: panel of markers with overlapping ranges, fileX across e overlapping fileR2 and fileR3 */
+	fileX := "testerFrom500000to700000"
+	callPanelTests(w, fileA, []string{fileR1, fileR2}, []string{fileX, fileR3}, param, withOpt, rp)
+	fmt.Fprintln(w, "Test_panel - panel of markers - is done.")
+
+	/* Test_logic: boolean tests over markers of contiguous positions, on the whole genome: fileL1 matches and fileL2 does not */
+	fileL1, fileL2 := "testerFrom200000to200009", "testerFrom700000to700009"
+	callLogicTests(w, fileA, fileL1, fileL2, rp)
+	fmt.Fprintln(w, "Test_logic - boolean tests over a panel - is done.")
+
+	/* Test_singlebit: results that disclose only whether some window matches, with Damgard-Jurik (s = 2) */
+	callSingleBitTests(w, fileA, fileTm, fileR3, param, withOpt, rp, 2)
+	fmt.Fprintln(w, "Test_singlebit - single-bit results - is done.")
+
+	/* Test_testerlearns: the tester learns the verdict, which Alice proves */
+	callTesterLearnsTests(w, fileA, fileTm, fileR3, param, withOpt, rp)
+	fmt.Fprintln(w, "Test_testerlearns - proven verdicts for the tester - is done.")
+
+	/* Test_wellformed: Alice checks the proofs of the marker and of the results */
+	// The proofs of a marker are linear in its range, so the test uses the short markers of Test_logic.
+	callWellFormedTests(w, fileA, fileL1, fileL2, param, withOpt, rp)
+	fmt.Fprintln(w, "Test_wellformed - proven marker and results - is done.")
+
+	/* Test_certified: Alice accepts only markers certified by her regulator, with the markers of Test_logic */
+	callCertifiedTests(w, fileA, fileL1, fileL2, param, withOpt, rp)
+	fmt.Fprintln(w, "Test_certified - certified markers - is done.")
+
+	/* Test_policy: Alice answers only the queries that her policy allows, and saves her decisions */
+	callPolicyTests(w, fileA, fileTm, fileR3, fileL1, param, withOpt, rp)
+	fmt.Fprintln(w, "Test_policy - policies of Alice - is done.")
+
 	/* Test_0: comparing ElGamal and Paillier operations */
 	// This test is located in separate test file (opTimeCheck_test.go). It can be done by "go test opTimeCheck_test.go -timeout 30m -v" in /test/exercise/ directory.
 	fmt.Fprintln(w, "Test_0 - comparison of ElGamal and Paillier operations - needs to be done separately(, using opTimeCheck_test.go).")
@@ -209,6 +259,45 @@ func callAll(w *bufio.Writer, fileA, fileTm, fileTnm string, param uint32, withO
 	fes.TestPaillierExactMatching(w, aliceSnp, testerSnpM, param, withOpt, rp)
 	fes.TestPaillierNoMatching(w, aliceSnp, testerSnpNM, param, withOpt, rp)
 
+	callDamgardJurikTests(w, fileA, fileTm, fileTnm, param, withOpt, rp, 2)
+	callPackedTests(w, fileA, fileTm, fileTnm)
+
+}
+
+func callDamgardJurikTests(w *bufio.Writer, fileA, fileTm, fileTnm string, param uint32, withOpt bool, rp int, s int) {
+
+	aliceWhole := fileA + ".txt"
+	testerWholeM := fileTm + ".txt"
+	testerWholeNM := fileTnm + ".txt"
+
+	aliceSnp := fileA + "_snp.txt"
+	testerSnpM := fileTm + "_snp.txt"
+	testerSnpNM := fileTnm + "_snp.txt"
+
+	wpes13.TestDamgardJurikExactMatching(w, aliceWhole, testerWholeM, s)
+	wpes13.TestDamgardJurikNoMatching(w, aliceWhole, testerWholeNM, s)
+
+	secure.TestDamgardJurikExactMatching(w, aliceWhole, testerWholeM, s)
+	secure.TestDamgardJurikNoMatching(w, aliceWhole, testerWholeNM, s)
+
+	sae.TestDamgardJurikExactMatching(w, aliceSnp, testerSnpM, withOpt, s)
+	sae.TestDamgardJurikNoMatching(w, aliceSnp, testerSnpNM, withOpt, s)
+
+	fes.TestDamgardJurikExactMatching(w, aliceSnp, testerSnpM, param, withOpt, rp, s)
+	fes.TestDamgardJurikNoMatching(w, aliceSnp, testerSnpNM, param, withOpt, rp, s)
+
+}
+
+func callPackedTests(w *bufio.Writer, fileA, fileTm, fileTnm string) {
+
+	aliceWhole := fileA + ".txt"
+	testerWholeM := fileTm + ".txt"
+	testerWholeNM := fileTnm + ".txt"
+
+	// s = 1: Paillier
+	secure.TestDamgardJurikPackedExactMatching(w, aliceWhole, testerWholeM, 1)
+	secure.TestDamgardJurikPackedNoMatching(w, aliceWhole, testerWholeNM, 1)
+
 }
 
 func callMatchingTests(w *bufio.Writer, fileA, fileTm, fileTnm string, param uint32, withOpt bool, rp int) {
@@ -253,3 +342,164 @@ func callSNPElGamalTests(w *bufio.Writer, fileA, fileTm string, param uint32, wi
 	fes.TestElGamalExactMatching(w, aliceSnp, testerSnpM, param, withOpt, rp)
 
 }
+
+func callSetTests(w *bufio.Writer, fileA, fileTm, fileTnm string, withOpt bool) {
+
+	aliceSnp := fileA + "_snp.txt"
+	testerSnpM := fileTm + "_snp.txt"
+	testerSnpNM := fileTnm + "_snp.txt"
+
+	fes.TestElGamalSetMatching(w, aliceSnp, testerSnpM, withOpt)
+	fes.TestElGamalSetNoMatching(w, aliceSnp, testerSnpNM, withOpt)
+
+}
+
+func callRangesTests(w *bufio.Writer, fileA string, filesTm, filesTnm []string, param uint32, withOpt bool, rp int) {
+
+	aliceSnp := fileA + "_snp.txt"
+
+	fes.TestElGamalRangesMatching(w, aliceSnp, snpFiles(filesTm), param, withOpt, rp)
+	fes.TestElGamalRangesNoMatching(w, aliceSnp, snpFiles(filesTnm), param, withOpt, rp)
+
+}
+
+func callPanelTests(w *bufio.Writer, fileA string, filesTm, filesTnm []string, param uint32, withOpt bool, rp int) {
+
+	aliceSnp := fileA + "_snp.txt"
+
+	fes.TestElGamalPanel(w, aliceSnp, snpFiles(filesTm), snpFiles(filesTnm), param, withOpt, rp)
+
+}
+
+func callLogicTests(w *bufio.Writer, fileA, fileTm, fileTnm string, rp int) {
+
+	aliceWhole := fileA + ".txt"
+	testerWholeM := fileTm + ".txt"
+	testerWholeNM := fileTnm + ".txt"
+	files := []string{testerWholeM, testerWholeNM}
+
+	fes.TestElGamalLogic(w, aliceWhole, files, testerWholeM+" & !"+testerWholeNM, true, rp)
+	fes.TestElGamalLogic(w, aliceWhole, files, testerWholeM+" & "+testerWholeNM, false, rp)
+	fes.TestElGamalLogic(w, aliceWhole, files, "atleast(1, "+testerWholeM+", "+testerWholeNM+")", true, rp)
+
+}
+
+func callSingleBitTests(w *bufio.Writer, fileA, fileTm, fileTnm string, param uint32, withOpt bool, rp int, s int) {
+
+	aliceSnp := fileA + "_snp.txt"
+	testerSnpM := fileTm + "_snp.txt"
+	testerSnpNM := fileTnm + "_snp.txt"
+
+	sae.TestDamgardJurikSingleBit(w, aliceSnp, testerSnpM, withOpt, s, true)
+	sae.TestDamgardJurikSingleBit(w, aliceSnp, testerSnpNM, withOpt, s, false)
+
+	fes.TestDamgardJurikSingleBit(w, aliceSnp, testerSnpM, param, withOpt, rp, s, true)
+	fes.TestDamgardJurikSingleBit(w, aliceSnp, testerSnpNM, param, withOpt, rp, s, false)
+
+}
+
+func callTesterLearnsTests(w *bufio.Writer, fileA, fileTm, fileTnm string, param uint32, withOpt bool, rp int) {
+
+	aliceWhole := fileA + ".txt"
+	testerWholeM := fileTm + ".txt"
+	testerWholeNM := fileTnm + ".txt"
+	aliceSnp := fileA + "_snp.txt"
+	testerSnpM := fileTm + "_snp.txt"
+	testerSnpNM := fileTnm + "_snp.txt"
+
+	secure.TestElGamalTesterLearns(w, aliceWhole, testerWholeM, true)
+	secure.TestElGamalTesterLearns(w, aliceWhole, testerWholeNM, false)
+
+	sae.TestElGamalTesterLearns(w, aliceSnp, testerSnpM, withOpt, true)
+	sae.TestElGamalTesterLearns(w, aliceSnp, testerSnpNM, withOpt, false)
+
+	fes.TestElGamalTesterLearns(w, aliceSnp, testerSnpM, param, withOpt, rp, true)
+	fes.TestElGamalTesterLearns(w, aliceSnp, testerSnpNM, param, withOpt, rp, false)
+
+}
+
+func callWellFormedTests(w *bufio.Writer, fileA, fileTm, fileTnm string, param uint32, withOpt bool, rp int) {
+
+	aliceSnp := fileA + "_snp.txt"
+	testerSnpM := fileTm + "_snp.txt"
+	testerSnpNM := fileTnm + "_snp.txt"
+
+	sae.TestElGamalWellFormed(w, aliceSnp, testerSnpM, withOpt, true)
+	sae.TestElGamalWellFormed(w, aliceSnp, testerSnpNM, withOpt, false)
+
+	fes.TestElGamalWellFormed(w, aliceSnp, testerSnpM, param, withOpt, rp, true)
+	fes.TestElGamalWellFormed(w, aliceSnp, testerSnpNM, param, withOpt, rp, false)
+
+}
+
+func callCertifiedTests(w *bufio.Writer, fileA, fileTm, fileTnm string, param uint32, withOpt bool, rp int) {
+
+	aliceSnp := fileA + "_snp.txt"
+	testerSnpM := fileTm + "_snp.txt"
+	testerSnpNM := fileTnm + "_snp.txt"
+
+	sae.TestElGamalCertified(w, aliceSnp, testerSnpM, withOpt, true, true)
+	sae.TestElGamalCertified(w, aliceSnp, testerSnpNM, withOpt, true, false)
+	sae.TestElGamalCertified(w, aliceSnp, testerSnpM, withOpt, false, false)
+
+	fes.TestElGamalCertified(w, aliceSnp, testerSnpM, param, withOpt, rp, true, true)
+	fes.TestElGamalCertified(w, aliceSnp, testerSnpNM, param, withOpt, rp, true, false)
+	fes.TestElGamalCertified(w, aliceSnp, testerSnpM, param, withOpt, rp, false, false)
+
+}
+
+func callPolicyTests(w *bufio.Writer, fileA, fileTm, fileR, fileL string, param uint32, withOpt bool, rp int) {
+
+	aliceSnp := fileA + "_snp.txt"
+	testerSnpM := fileTm + "_snp.txt"
+	testerSnpR := fileR + "_snp.txt"
+	testerSnpL := fileL + "_snp.txt"
+
+	clinicKey, err := ecdsa.GenerateKey(profiles.Default.SignatureCurve, rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	intruderKey, err := ecdsa.GenerateKey(profiles.Default.SignatureCurve, rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	testers := map[string]*ecdsa.PublicKey{"clinic": &clinicKey.PublicKey}
+
+	// only the signed queries of the clinic
+	open := &policy.Policy{AllowedTesters: testers}
+	sae.TestElGamalPolicy(w, aliceSnp, testerSnpM, withOpt, t.PerWindow, "clinic", clinicKey, open, true)
+	sae.TestElGamalPolicy(w, aliceSnp, testerSnpM, withOpt, t.PerWindow, "clinic", nil, open, false)
+	fes.TestElGamalPolicy(w, aliceSnp, testerSnpM, param, withOpt, rp, "clinic", clinicKey, open, true)
+	fes.TestElGamalPolicy(w, aliceSnp, testerSnpM, param, withOpt, rp, "clinic", intruderKey, open, false)
+	fes.TestElGamalPolicy(w, aliceSnp, testerSnpM, param, withOpt, rp, "intruder", intruderKey, open, false)
+
+	// range rules, which bind the results of sae only in WellFormed mode
+	rules := &policy.Policy{AllowedTesters: testers, ForbiddenRegions: []policy.Region{{Name: "forbidden", Start: 750000, End: 760000}}, MaxRangeWidth: 200000}
+	sae.TestElGamalPolicy(w, aliceSnp, testerSnpL, withOpt, t.PerWindow, "clinic", clinicKey, rules, false)
+	sae.TestElGamalPolicy(w, aliceSnp, testerSnpL, withOpt, t.WellFormed, "clinic", clinicKey, rules, true)
+	fes.TestElGamalPolicy(w, aliceSnp, testerSnpL, param, withOpt, rp, "clinic", clinicKey, rules, true)
+	fes.TestElGamalPolicy(w, aliceSnp, testerSnpR, param, withOpt, rp, "clinic", clinicKey, rules, false)
+	fes.TestElGamalPolicy(w, aliceSnp, testerSnpM, param, withOpt, rp, "clinic", clinicKey, rules, false)
+
+	// the slice of fileL, with its boundaries, is 3 ciphertexts
+	variants := &policy.Policy{MaxVariants: 3}
+	fes.TestElGamalPolicy(w, aliceSnp, testerSnpL, param, withOpt, rp, "", nil, variants, true)
+	variants.MaxVariants = 2
+	fes.TestElGamalPolicy(w, aliceSnp, testerSnpL, param, withOpt, rp, "", nil, variants, false)
+
+	if err := rules.SaveDecisions("../../testResults/policyDecisions.json"); err != nil {
+		panic(err)
+	}
+
+}
+
+// snpFiles returns the files of the SNPs of the markers of files.
+func snpFiles(files []string) []string {
+
+	snp := make([]string, len(files))
+	for j, file := range files {
+		snp[j] = file + "_snp.txt"
+	}
+	return snp
+
+}